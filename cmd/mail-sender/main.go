@@ -5,15 +5,28 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 	"weather-app/internal/database"
+	"weather-app/internal/database/models"
 	"weather-app/internal/database/repository"
+	"weather-app/internal/links"
 	"weather-app/internal/mail"
+	"weather-app/internal/notify"
 	"weather-app/internal/scheduler"
+	"weather-app/internal/sms"
+	"weather-app/internal/team"
+	"weather-app/internal/telegram"
+	"weather-app/internal/tokens"
+	"weather-app/internal/weather"
+	"weather-app/internal/weather/cache"
+	"weather-app/internal/webpush"
 )
 
-const dailyUpdateHour = 12
+// Subscriptions pick their own local send time, so the sender wakes up often
+// enough to catch every time zone offset (including :30 and :45 ones)
+const tickInterval = 15 * time.Minute
 
 func main() {
 	log.Println("Starting Mail Sending Service...")
@@ -24,40 +37,128 @@ func main() {
 		log.Fatalf("database initialization failed: %v", err)
 	}
 
-	userRepo := repository.NewUserRepository(db)
+	hasher, err := tokens.NewHasher(os.Getenv("TOKEN_HASH_KEY"))
+	if err != nil {
+		log.Fatalf("token hasher initialization failed: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db, hasher)
+	subRepo := repository.NewSubscriptionRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	tokenRepo := repository.NewTokenRepository(db, hasher)
+
+	signer, err := links.SignerFromEnv()
+	if err != nil {
+		log.Fatalf("link signer initialization failed: %v", err)
+	}
+
+	linkBuilder := links.NewBuilder(os.Getenv("BASE_URL"), signer)
 
 	APIKey := os.Getenv("MAILSENDER_API_KEY")
 	msw := mail.NewMailSenderWrapper(APIKey)
-	mailService := mail.NewMailService(userRepo, msw)
+	deliveryRepo := repository.NewDeliveryRepository(db)
+	weatherService := weather.NewWeatherService(nil, os.Getenv("WEATHER_API"), cache.NewWeatherCache(time.Minute*30))
+	mailService := mail.NewMailService(userRepo, deliveryRepo, msw, linkBuilder, weatherService)
+
+	// Channels besides email. Team channels need no credentials, Telegram,
+	// SMS and Web Push are enabled by their provider settings
+	teamRepo := repository.NewTeamRepository(db)
+	channels := []notify.Channel{
+		{
+			Name:       models.TeamChannelSlack,
+			Recipients: teamRepo.ChannelRecipients(models.TeamChannelSlack),
+			Notifier:   team.NewSlackNotifier(nil),
+		},
+		{
+			Name:       models.TeamChannelWebhook,
+			Recipients: teamRepo.ChannelRecipients(models.TeamChannelWebhook),
+			Notifier:   team.NewJSONNotifier(nil),
+		},
+	}
+	if botToken := os.Getenv("TELEGRAM_BOT_TOKEN"); botToken != "" {
+		telegramClient := telegram.NewClient(os.Getenv("TELEGRAM_API_URL"), botToken, nil)
+		channels = append(channels, notify.Channel{
+			Name:       telegram.ChannelName,
+			Recipients: repository.NewTelegramRepository(db),
+			Notifier:   telegram.NewNotifier(telegramClient),
+		})
+	}
+
+	smsSender, err := sms.SenderFromEnv()
+	if err != nil {
+		log.Fatalf("sms sender initialization failed: %v", err)
+	}
+	if smsSender != nil {
+		channels = append(channels, notify.Channel{
+			Name:       sms.ChannelName,
+			Recipients: repository.NewSMSRepository(db),
+			Notifier:   sms.NewNotifier(smsSender),
+		})
+	}
+
+	vapid, err := webpush.VAPIDFromEnv()
+	if err != nil {
+		log.Fatalf("VAPID initialization failed: %v", err)
+	}
+	if vapid != nil {
+		pushRepo := repository.NewPushRepository(db)
+		channels = append(channels, notify.Channel{
+			Name:       webpush.ChannelName,
+			Recipients: pushRepo,
+			Notifier:   webpush.NewNotifier(webpush.NewSender(vapid, nil), pushRepo),
+		})
+	}
+
+	notifyService := notify.NewNotifyService(weatherService, deliveryRepo, channels...)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	done := scheduler.Start(ctx, time.Hour, func(currentTime time.Time) {
-		regularUpdate := make(chan struct{})
+	done := scheduler.Start(ctx, tickInterval, func(currentTime time.Time) {
+		from := currentTime.Add(-tickInterval)
 
-		go func() {
-			defer close(regularUpdate)
-			log.Println("Regular update started")
+		resumed, err := subRepo.ResumeExpiredPauses(currentTime)
+		if err != nil {
+			log.Printf("Resume paused subscriptions error: %s\n", err.Error())
+		} else if resumed > 0 {
+			log.Printf("Resumed %d paused subscriptions\n", resumed)
+		}
+
+		expired, err := idempotencyRepo.DeleteExpired(currentTime)
+		if err != nil {
+			log.Printf("Delete expired idempotency keys error: %s\n", err.Error())
+		} else if expired > 0 {
+			log.Printf("Deleted %d expired idempotency keys\n", expired)
+		}
+
+		expiredTokens, err := tokenRepo.DeleteExpiredEmailChangeTokens(currentTime)
+		if err != nil {
+			log.Printf("Delete expired email change tokens error: %s\n", err.Error())
+		} else if expiredTokens > 0 {
+			log.Printf("Deleted %d expired email change tokens\n", expiredTokens)
+		}
 
-			err := mailService.SendWeatherUpdate(mail.Hourly)
+		var wg sync.WaitGroup
 
-			if err != nil {
-				log.Printf("Regular update error: %s\n", err.Error())
-			}
+		for _, updateType := range mail.UpdateTypes {
+			wg.Add(1)
 
-		}()
+			go func() {
+				defer wg.Done()
+				log.Printf("%s update started\n", updateType)
 
-		if currentTime.Hour() == dailyUpdateHour && currentTime.Minute() == 0 {
-			log.Println("Daily update started")
+				err := mailService.SendWeatherUpdate(updateType, from, currentTime)
 
-			err := mailService.SendWeatherUpdate(mail.Daily)
+				if err != nil {
+					log.Printf("%s update error: %s\n", updateType, err.Error())
+				}
 
-			if err != nil {
-				log.Printf("Daily update error: %s\n", err.Error())
-			}
+				if err := notifyService.SendWeatherUpdate(updateType.String(), from, currentTime); err != nil {
+					log.Printf("%s notify error: %s\n", updateType, err.Error())
+				}
+			}()
 		}
 
-		<-regularUpdate
+		wg.Wait()
 	})
 
 	// Handle SIGINT/SIGTERM
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"weather-app/internal/webpush"
+)
+
+// Prints a new VAPID key pair for Web Push. Keep the private key: browsers
+// subscribed with the public key can only be reached with it
+func main() {
+	privateKey, publicKey, err := webpush.GenerateVAPIDKey()
+	if err != nil {
+		log.Fatalf("key generation failed: %v", err)
+	}
+
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n", privateKey)
+	fmt.Printf("# Public key, served at /api/push/vapid-public-key: %s\n", publicKey)
+}
@@ -3,25 +3,64 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/rs/cors"
 
+	"weather-app/internal/admin"
+	"weather-app/internal/apikey"
+	"weather-app/internal/apiv2"
+	"weather-app/internal/audit"
+	"weather-app/internal/challenge"
+	"weather-app/internal/churn"
 	"weather-app/internal/database"
 	"weather-app/internal/database/repository"
+	"weather-app/internal/emailaddr"
+	"weather-app/internal/idempotency"
+	"weather-app/internal/links"
 	"weather-app/internal/mail"
+	"weather-app/internal/openapi"
+	"weather-app/internal/privacy"
+	"weather-app/internal/problem"
+	"weather-app/internal/ratelimit"
+	"weather-app/internal/scheduler"
+	"weather-app/internal/sms"
 	"weather-app/internal/subscription"
+	"weather-app/internal/team"
+	"weather-app/internal/telegram"
+	"weather-app/internal/tokens"
 	"weather-app/internal/weather"
 	"weather-app/internal/weather/cache"
+	"weather-app/internal/webhook"
+	"weather-app/internal/webpush"
 )
 
+// Per-client limits on public endpoints. Subscribe sends an email, so it is
+// limited per address as well. Token endpoints are limited against guessing
+const (
+	subscribeIPLimit    = 10
+	subscribeEmailLimit = 3
+	subscribeWindow     = time.Hour
+
+	tokenIPLimit  = 30
+	tokenIPWindow = time.Minute
+)
+
+// How long subscribe responses are kept for replay to retried requests
+const idempotencyTTL = 24 * time.Hour
+
+// How often queued webhook deliveries are sent
+const webhookInterval = 15 * time.Second
+
 // Use for cases like "/api/confirm" instead "/api/confirm/"
 func wrongQueryHandler(w http.ResponseWriter, req *http.Request) {
-	http.Error(w, "404 page not found", http.StatusNotFound)
+	problem.NotFound(w, req)
 }
 
 func main() {
@@ -33,35 +72,220 @@ func main() {
 		log.Fatalf("database initialization failed: %v", err)
 	}
 
-	userRepo := repository.NewUserRepository(db)
-	tokenRepo := repository.NewTokenRepository(db)
+	hasher, err := tokens.NewHasher(os.Getenv("TOKEN_HASH_KEY"))
+	if err != nil {
+		log.Fatalf("token hasher initialization failed: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository(db, hasher)
+	tokenRepo := repository.NewTokenRepository(db, hasher)
+
+	if err := tokenRepo.MigratePlaintextTokens(); err != nil {
+		log.Fatalf("token migration failed: %v", err)
+	}
+
+	if err := userRepo.MigrateEmailCase(); err != nil {
+		log.Fatalf("email migration failed: %v", err)
+	}
+
+	deliveryRepo := repository.NewDeliveryRepository(db)
+	if err := deliveryRepo.MigrateRecipientIDs(); err != nil {
+		log.Fatalf("delivery migration failed: %v", err)
+	}
+
+	subRepo := repository.NewSubscriptionRepository(db)
+	eventRepo := repository.NewEventRepository(db)
+
+	signer, err := links.SignerFromEnv()
+	if err != nil {
+		log.Fatalf("link signer initialization failed: %v", err)
+	}
+
+	linkBuilder := links.NewBuilder(os.Getenv("BASE_URL"), signer)
 
 	APIKey := os.Getenv("MAILSENDER_API_KEY")
 	msw := mail.NewMailSenderWrapper(APIKey)
-	mailService := mail.NewMailService(userRepo, msw)
+	weatherCache := cache.NewWeatherCache(time.Minute * 30)
+	weatherService := weather.NewWeatherService(nil, os.Getenv("WEATHER_API"), weatherCache)
+	mailService := mail.NewMailService(userRepo, deliveryRepo, msw, linkBuilder, weatherService)
+
+	blocklist, err := emailaddr.LoadBlocklist(os.Getenv("DISPOSABLE_DOMAINS_FILE"))
+	if err != nil {
+		log.Fatalf("disposable domain blocklist loading failed: %v", err)
+	}
+
+	// MX lookups add DNS latency to every signup, so they are opt-in
+	var resolver emailaddr.Resolver
+	if os.Getenv("EMAIL_MX_CHECK") == "true" {
+		resolver = net.DefaultResolver
+	}
+	emailVerifier := emailaddr.NewVerifier(blocklist, resolver)
+
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, nil)
+	webhookHandler := webhook.NewHandler(webhookRepo)
+	teamHandler := team.NewHandler(repository.NewTeamRepository(db))
 
-	subService := subscription.NewSubscriptionService(userRepo, tokenRepo, mailService)
+	subService := subscription.NewSubscriptionService(userRepo, tokenRepo, subRepo, eventRepo, mailService, linkBuilder, emailVerifier, webhookDispatcher)
 	subHandler := subscription.NewHandler(subService)
 
-	weatherCache := cache.NewWeatherCache(time.Minute * 30)
-	weatherService := weather.NewWeatherService(nil, os.Getenv("WEATHER_API"), weatherCache)
 	weatherHandler := weather.NewHandler(weatherService)
 
+	privacyRepo := repository.NewPrivacyRepository(db, hasher)
+	privacyService := privacy.NewPrivacyService(subService, privacyRepo)
+	privacyHandler := privacy.NewHandler(privacyService)
+
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+
+	churnRepo := repository.NewChurnRepository(db)
+	if err := churnRepo.MigrateCreatedAtToDate(); err != nil {
+		log.Fatalf("churn migration failed: %v", err)
+	}
+
+	churnHandler := churn.NewHandler(churnRepo)
+
+	adminKey := os.Getenv("ADMIN_API_KEY")
+	auditHandler := audit.NewHandler(eventRepo)
+	adminHandler := admin.NewHandler(userRepo, subService, mailService)
+
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	apiKeyHandler := apikey.NewHandler(apiKeyRepo, hasher)
+
+	anonymous, err := apikey.AnonymousFromEnv()
+	if err != nil {
+		log.Fatalf("api key configuration failed: %v", err)
+	}
+
+	// Abuse protection
+	byIP := ratelimit.ByIP(audit.TrustedProxies())
+
 	// Weather service
-	http.HandleFunc("/api/weather", weatherHandler.Handler)
+	authenticator := apikey.NewAuthenticator(apiKeyRepo, hasher, anonymous, byIP)
+	http.HandleFunc("/api/weather", apikey.Middleware(authenticator, weatherHandler.Handler))
+
+	// API description
+	http.HandleFunc("/api/openapi.json", openapi.SpecHandler)
+	http.HandleFunc("/api/docs", openapi.DocsHandler)
+	http.HandleFunc("/api/docs/", openapi.DocsAssetHandler)
+
+	subscribeIPLimiter := ratelimit.NewLimiter(subscribeIPLimit, subscribeWindow)
+	subscribeEmailLimiter := ratelimit.NewLimiter(subscribeEmailLimit, subscribeWindow)
+	tokenLimiter := ratelimit.NewLimiter(tokenIPLimit, tokenIPWindow)
+
+	limitTokens := func(next http.HandlerFunc) http.HandlerFunc {
+		return ratelimit.Middleware(tokenLimiter, byIP, next)
+	}
+
+	var challengeVerifier challenge.Verifier
+	if verifyURL := os.Getenv("CHALLENGE_VERIFY_URL"); verifyURL != "" {
+		challengeVerifier = challenge.NewSiteVerifier(verifyURL, os.Getenv("CHALLENGE_SECRET"), nil)
+	}
 
 	// Subscription service
-	http.HandleFunc("/api/subscribe", subHandler.SubscribeHandler)
-	http.HandleFunc("/api/confirm/", subHandler.ConfirmHandler)
+	// Replays skip the email limiter and the challenge, a retry must not fail
+	// on a used captcha token or count against the address twice
+	http.HandleFunc("/api/subscribe",
+		ratelimit.Middleware(subscribeIPLimiter, byIP,
+			idempotency.Middleware(idempotencyRepo, idempotencyTTL,
+				ratelimit.Middleware(subscribeEmailLimiter, ratelimit.ByEmail,
+					challenge.Require(challengeVerifier, subHandler.SubscribeHandler)))))
+	http.HandleFunc("/api/confirm/", limitTokens(subHandler.ConfirmHandler))
 	http.HandleFunc("/api/confirm", wrongQueryHandler)
-	http.HandleFunc("/api/unsubscribe/", subHandler.UnsubscribeHandler)
+	http.HandleFunc("/api/unsubscribe/", limitTokens(subHandler.UnsubscribeHandler))
 	http.HandleFunc("/api/unsubscribe", wrongQueryHandler)
+	http.HandleFunc("/api/unsubscribe-reason/", limitTokens(churnHandler.ReasonHandler))
+	http.HandleFunc("/api/pause/", limitTokens(subHandler.PauseHandler))
+	http.HandleFunc("/api/pause", wrongQueryHandler)
+	http.HandleFunc("/api/resume/", limitTokens(subHandler.ResumeHandler))
+	http.HandleFunc("/api/resume", wrongQueryHandler)
+	http.HandleFunc("/api/change-email/", limitTokens(subHandler.ChangeEmailHandler))
+	http.HandleFunc("/api/change-email", wrongQueryHandler)
+	http.HandleFunc("/api/confirm-email/", limitTokens(subHandler.ConfirmEmailChangeHandler))
+	http.HandleFunc("/api/confirm-email", wrongQueryHandler)
+	http.HandleFunc("/api/preferences/", limitTokens(subHandler.PreferencesHandler))
+	http.HandleFunc("/api/preferences", wrongQueryHandler)
+
+	// API v2, same services and protection as above
+	v2Handler := apiv2.NewHandler(weatherService, subService)
+
+	http.HandleFunc("/api/v2/weather", apikey.Middleware(authenticator, v2Handler.WeatherHandler))
+	http.HandleFunc("/api/v2/subscriptions",
+		ratelimit.Middleware(subscribeIPLimiter, byIP,
+			idempotency.Middleware(idempotencyRepo, idempotencyTTL,
+				ratelimit.Middleware(subscribeEmailLimiter, ratelimit.ByEmail,
+					challenge.Require(challengeVerifier, v2Handler.SubscriptionsHandler)))))
+	http.HandleFunc("/api/v2/subscriptions/", limitTokens(v2Handler.SubscriptionHandler))
+
+	// Telegram bot, enabled by TELEGRAM_BOT_TOKEN
+	if botToken := os.Getenv("TELEGRAM_BOT_TOKEN"); botToken != "" {
+		webhookSecret := os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+		if webhookSecret == "" {
+			log.Fatalf("TELEGRAM_WEBHOOK_SECRET is required with TELEGRAM_BOT_TOKEN")
+		}
+
+		telegramClient := telegram.NewClient(os.Getenv("TELEGRAM_API_URL"), botToken, nil)
+		bot := telegram.NewBot(telegramClient, subService, weatherService, os.Getenv("TELEGRAM_BOT_USERNAME"), webhookSecret)
+
+		http.HandleFunc("/api/telegram/webhook", bot.WebhookHandler)
+		http.HandleFunc("/api/telegram/link/", limitTokens(bot.LinkHandler))
+		http.HandleFunc("/api/telegram/link", wrongQueryHandler)
+
+		webhookURL := strings.TrimSuffix(os.Getenv("BASE_URL"), "/") + "/api/telegram/webhook"
+		if err := telegramClient.SetWebhook(webhookURL, webhookSecret); err != nil {
+			log.Printf("Telegram webhook registration failed: %v", err)
+		}
+	}
+
+	// SMS updates, enabled by SMS_PROVIDER
+	smsSender, err := sms.SenderFromEnv()
+	if err != nil {
+		log.Fatalf("sms sender initialization failed: %v", err)
+	}
+	if smsSender != nil {
+		smsService := sms.NewService(subService, repository.NewSMSRepository(db), eventRepo, smsSender, hasher)
+		smsHandler := sms.NewHandler(smsService)
+
+		http.HandleFunc("/api/phone/", limitTokens(smsHandler.PhoneHandler))
+		http.HandleFunc("/api/phone", wrongQueryHandler)
+	}
+
+	// Web Push, enabled by VAPID_PRIVATE_KEY
+	vapid, err := webpush.VAPIDFromEnv()
+	if err != nil {
+		log.Fatalf("VAPID initialization failed: %v", err)
+	}
+	if vapid != nil {
+		pushHandler := webpush.NewHandler(repository.NewPushRepository(db), vapid, webpush.AllowedHostsFromEnv())
+
+		http.HandleFunc("/api/push/vapid-public-key", pushHandler.KeyHandler)
+		http.HandleFunc("/api/push/subscribe", ratelimit.Middleware(subscribeIPLimiter, byIP, pushHandler.SubscribeHandler))
+		http.HandleFunc("/api/push/unsubscribe", limitTokens(pushHandler.UnsubscribeHandler))
+	}
+
+	// Data subject requests
+	http.HandleFunc("/api/me/export", limitTokens(privacyHandler.ExportHandler))
+	http.HandleFunc("/api/me", limitTokens(privacyHandler.EraseHandler))
+
+	// Admin
+	http.HandleFunc("/admin/events", admin.RequireKey(adminKey, auditHandler.EventsHandler))
+	http.HandleFunc("/admin/churn", admin.RequireKey(adminKey, churnHandler.ReportHandler))
+	http.HandleFunc("/admin/api/users", admin.RequireKey(adminKey, adminHandler.UsersHandler))
+	http.HandleFunc("/admin/api/users/", admin.RequireKey(adminKey, adminHandler.UserHandler))
+	http.HandleFunc("/admin/api/erasures", admin.RequireKey(adminKey, privacyHandler.AdminEraseHandler))
+	http.HandleFunc("/admin/webhooks", admin.RequireKey(adminKey, webhookHandler.EndpointsHandler))
+	http.HandleFunc("/admin/webhooks/", admin.RequireKey(adminKey, webhookHandler.EndpointHandler))
+	http.HandleFunc("/admin/webhook-deliveries/", admin.RequireKey(adminKey, webhookHandler.ReplayHandler))
+	http.HandleFunc("/admin/team-subscriptions", admin.RequireKey(adminKey, teamHandler.SubscriptionsHandler))
+	http.HandleFunc("/admin/team-subscriptions/", admin.RequireKey(adminKey, teamHandler.SubscriptionHandler))
+	http.HandleFunc("/admin/api-keys", admin.RequireKey(adminKey, apiKeyHandler.KeysHandler))
+	http.HandleFunc("/admin/api-keys/", admin.RequireKey(adminKey, apiKeyHandler.KeyHandler))
 
 	// fix CORS problem
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", idempotency.HeaderName, apikey.Header},
+		ExposedHeaders:   []string{"Retry-After", idempotency.ReplayedHeader, apikey.QuotaLimitHeader, apikey.QuotaRemainingHeader},
 		AllowCredentials: true,
 	})
 	handlerWithCORS := c.Handler(http.DefaultServeMux)
@@ -81,9 +305,15 @@ func main() {
 		}
 	}()
 
+	dispatchCtx, stopDispatch := context.WithCancel(context.Background())
+	dispatchDone := scheduler.Start(dispatchCtx, webhookInterval, webhookDispatcher.DeliverDue)
+
 	<-sigChan
 	log.Println("Shutdown signal received")
 
+	stopDispatch()
+	<-dispatchDone
+
 	// Create a context with timeout for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"weather-app/internal/problem"
+)
+
+// Protects admin endpoints with a static key sent as "Authorization: Bearer <key>".
+// Empty key disables the endpoints entirely
+func RequireKey(key string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if key == "" {
+			problem.NotFound(w, req)
+			return
+		}
+
+		provided, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(key)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Admin key is missing or invalid")
+			return
+		}
+
+		next(w, req)
+	}
+}
@@ -0,0 +1,42 @@
+package admin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weather-app/internal/admin"
+)
+
+func okHandler(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestRequireKey(t *testing.T) {
+	cases := []struct {
+		name   string
+		key    string
+		header string
+		want   int
+	}{
+		{"valid key", "secret", "Bearer secret", http.StatusOK},
+		{"wrong key", "secret", "Bearer nope", http.StatusUnauthorized},
+		{"missing header", "secret", "", http.StatusUnauthorized},
+		{"disabled", "", "Bearer ", http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/events", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			w := httptest.NewRecorder()
+
+			admin.RequireKey(tc.key, okHandler)(w, req)
+
+			if w.Code != tc.want {
+				t.Errorf("expected %d, got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
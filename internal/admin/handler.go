@@ -0,0 +1,266 @@
+package admin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/problem"
+	"weather-app/internal/schedule"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+
+	usersPath = "/admin/api/users"
+)
+
+var (
+	ErrInvalidCursor    = errors.New("cursor parameter is invalid")
+	ErrInvalidLimit     = errors.New("limit parameter is invalid")
+	ErrInvalidConfirmed = errors.New("confirmed parameter is invalid")
+	ErrUnknownAction    = errors.New("unknown action")
+)
+
+var problems = problem.Mappings{
+	{Err: schedule.ErrInvalidFrequency, Status: http.StatusBadRequest, Code: "invalid_frequency"},
+	{Err: ErrInvalidCursor, Status: http.StatusBadRequest, Code: "invalid_cursor"},
+	{Err: ErrInvalidLimit, Status: http.StatusBadRequest, Code: "invalid_limit"},
+	{Err: ErrInvalidConfirmed, Status: http.StatusBadRequest, Code: "invalid_confirmed"},
+	{Err: ErrUnknownAction, Status: http.StatusNotFound, Code: "unknown_action"},
+	{Err: subscription.ErrUserNotFound, Status: http.StatusNotFound, Code: "user_not_found"},
+}
+
+type UserRepositoryInterface interface {
+	ListUsers(filter repository.UserFilter) ([]repository.UserListEntry, error)
+}
+
+type SubscriptionServiceInterface interface {
+	ForceConfirm(userID uuid.UUID, meta audit.Meta) error
+	DeleteUser(userID uuid.UUID, meta audit.Meta) error
+}
+
+type TestSenderInterface interface {
+	SendTestUpdate(userID uuid.UUID) error
+}
+
+type AdminHandler struct {
+	users  UserRepositoryInterface
+	subs   SubscriptionServiceInterface
+	sender TestSenderInterface
+}
+
+func NewHandler(users UserRepositoryInterface, subs SubscriptionServiceInterface, sender TestSenderInterface) *AdminHandler {
+	return &AdminHandler{users: users, subs: subs, sender: sender}
+}
+
+type User struct {
+	ID          uuid.UUID  `json:"id"`
+	Email       string     `json:"email"`
+	Confirmed   bool       `json:"confirmed"`
+	CreatedAt   time.Time  `json:"created_at"`
+	City        string     `json:"city"`
+	Frequency   string     `json:"frequency"`
+	Timezone    string     `json:"timezone"`
+	PausedAt    *time.Time `json:"paused_at,omitempty"`
+	PausedUntil *time.Time `json:"paused_until,omitempty"`
+}
+
+type UserPage struct {
+	Users []User `json:"users"`
+	// Pass as cursor to get the next page, empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Lists subscribers oldest first. Filters: email (substring), city,
+// frequency, confirmed; paging: limit and the cursor of the previous page
+func (h *AdminHandler) UsersHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	filter, err := parseUserFilter(req)
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	pageSize := filter.Limit
+	// One extra row tells whether another page exists
+	filter.Limit++
+
+	entries, err := h.users.ListUsers(filter)
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	page := UserPage{Users: []User{}}
+
+	if len(entries) > pageSize {
+		entries = entries[:pageSize]
+		last := entries[pageSize-1]
+		page.NextCursor = encodeCursor(repository.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	for _, e := range entries {
+		page.Users = append(page.Users, User{
+			ID:          e.ID,
+			Email:       e.Email,
+			Confirmed:   e.IsConfirmed,
+			CreatedAt:   e.CreatedAt,
+			City:        e.City,
+			Frequency:   e.Frequency,
+			Timezone:    e.Timezone,
+			PausedAt:    e.PausedAt,
+			PausedUntil: e.PausedUntil,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
+
+// Handles a single subscriber:
+//
+//	DELETE /admin/api/users/{id}
+//	POST   /admin/api/users/{id}/confirm
+//	POST   /admin/api/users/{id}/test-send
+func (h *AdminHandler) UserHandler(w http.ResponseWriter, req *http.Request) {
+	rawID, action, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, usersPath+"/"), "/")
+
+	userID, err := uuid.Parse(rawID)
+	if err != nil {
+		problems.Write(w, subscription.ErrUserNotFound)
+		return
+	}
+
+	method := "POST"
+	if action == "" {
+		method = "DELETE"
+	}
+
+	if req.Method != method {
+		problem.MethodNotAllowed(w, req, method)
+		return
+	}
+
+	meta := audit.MetaFromRequest(req)
+
+	switch action {
+	case "":
+		err = h.subs.DeleteUser(userID, meta)
+	case "confirm":
+		err = h.subs.ForceConfirm(userID, meta)
+	case "test-send":
+		err = h.sender.SendTestUpdate(userID)
+	default:
+		problems.Write(w, ErrUnknownAction)
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, subscription.ErrUserNotFound), repository.IsErrNotFound(err):
+			problems.Write(w, subscription.ErrUserNotFound)
+		case action == "test-send":
+			// Provider errors can carry internal details, the reason is only logged
+			log.Printf("Test update for user %s failed: %s\n", userID, err.Error())
+			problem.Write(w, http.StatusBadGateway, "test_send_failed", "Test update failed, see the server log")
+		default:
+			log.Println(err.Error())
+			problem.Internal(w)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseUserFilter(req *http.Request) (repository.UserFilter, error) {
+	query := req.URL.Query()
+
+	filter := repository.UserFilter{
+		Email:     query.Get("email"),
+		City:      query.Get("city"),
+		Frequency: query.Get("frequency"),
+		Limit:     defaultPageSize,
+	}
+
+	if filter.Frequency != "" && !slices.Contains(schedule.Frequencies, filter.Frequency) {
+		return filter, schedule.ErrInvalidFrequency
+	}
+
+	if value := query.Get("confirmed"); value != "" {
+		confirmed, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, ErrInvalidConfirmed
+		}
+		filter.Confirmed = &confirmed
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return filter, ErrInvalidLimit
+		}
+		filter.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil {
+			return filter, ErrInvalidCursor
+		}
+		filter.After = &cursor
+	}
+
+	return filter, nil
+}
+
+// Cursors are opaque to clients: base64url of "<created_at>|<id>"
+func encodeCursor(c repository.UserCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(value string) (repository.UserCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return repository.UserCursor{}, err
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return repository.UserCursor{}, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return repository.UserCursor{}, err
+	}
+
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return repository.UserCursor{}, err
+	}
+
+	return repository.UserCursor{CreatedAt: t, ID: userID}, nil
+}
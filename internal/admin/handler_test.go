@@ -0,0 +1,231 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/admin"
+	"weather-app/internal/audit"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+)
+
+type mockUserRepo struct {
+	ListUsersFunc func(filter repository.UserFilter) ([]repository.UserListEntry, error)
+}
+
+func (m *mockUserRepo) ListUsers(filter repository.UserFilter) ([]repository.UserListEntry, error) {
+	return m.ListUsersFunc(filter)
+}
+
+type mockSubscriptionService struct {
+	ForceConfirmFunc func(userID uuid.UUID, meta audit.Meta) error
+	DeleteUserFunc   func(userID uuid.UUID, meta audit.Meta) error
+}
+
+func (m *mockSubscriptionService) ForceConfirm(userID uuid.UUID, meta audit.Meta) error {
+	return m.ForceConfirmFunc(userID, meta)
+}
+
+func (m *mockSubscriptionService) DeleteUser(userID uuid.UUID, meta audit.Meta) error {
+	return m.DeleteUserFunc(userID, meta)
+}
+
+type mockTestSender struct {
+	SendTestUpdateFunc func(userID uuid.UUID) error
+}
+
+func (m *mockTestSender) SendTestUpdate(userID uuid.UUID) error {
+	return m.SendTestUpdateFunc(userID)
+}
+
+func listEntries(n int) []repository.UserListEntry {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := make([]repository.UserListEntry, n)
+	for i := range entries {
+		entries[i] = repository.UserListEntry{
+			ID:        uuid.New(),
+			Email:     "user@example.com",
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+			City:      "Kyiv",
+			Frequency: "daily",
+		}
+	}
+	return entries
+}
+
+func TestUsersHandler_Filters(t *testing.T) {
+	var got repository.UserFilter
+	repo := &mockUserRepo{
+		ListUsersFunc: func(filter repository.UserFilter) ([]repository.UserListEntry, error) {
+			got = filter
+			return listEntries(1), nil
+		},
+	}
+	h := admin.NewHandler(repo, nil, nil)
+
+	req := httptest.NewRequest("GET", "/admin/api/users?email=ex&city=Kyiv&frequency=daily&confirmed=false&limit=10", nil)
+	w := httptest.NewRecorder()
+
+	h.UsersHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got.Email != "ex" || got.City != "Kyiv" || got.Frequency != "daily" || got.Confirmed == nil || *got.Confirmed {
+		t.Errorf("unexpected filter %+v", got)
+	}
+	if got.Limit != 11 {
+		t.Errorf("expected one extra row to be requested, got limit %d", got.Limit)
+	}
+
+	var page admin.UserPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(page.Users) != 1 || page.NextCursor != "" {
+		t.Errorf("expected a single final page, got %+v", page)
+	}
+}
+
+func TestUsersHandler_Pagination(t *testing.T) {
+	entries := listEntries(3)
+
+	var got repository.UserFilter
+	repo := &mockUserRepo{
+		ListUsersFunc: func(filter repository.UserFilter) ([]repository.UserListEntry, error) {
+			got = filter
+			if filter.After != nil {
+				return entries[2:], nil
+			}
+			return entries, nil
+		},
+	}
+	h := admin.NewHandler(repo, nil, nil)
+
+	w := httptest.NewRecorder()
+	h.UsersHandler(w, httptest.NewRequest("GET", "/admin/api/users?limit=2", nil))
+
+	var page admin.UserPage
+	json.NewDecoder(w.Body).Decode(&page)
+
+	if len(page.Users) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected 2 users and a cursor, got %+v", page)
+	}
+
+	w = httptest.NewRecorder()
+	h.UsersHandler(w, httptest.NewRequest("GET", "/admin/api/users?limit=2&cursor="+page.NextCursor, nil))
+
+	if got.After == nil || got.After.ID != entries[1].ID || !got.After.CreatedAt.Equal(entries[1].CreatedAt) {
+		t.Fatalf("expected cursor after the second user, got %+v", got.After)
+	}
+
+	page = admin.UserPage{}
+	json.NewDecoder(w.Body).Decode(&page)
+
+	if len(page.Users) != 1 || page.NextCursor != "" {
+		t.Errorf("expected the last page, got %+v", page)
+	}
+}
+
+func TestUsersHandler_InvalidParams(t *testing.T) {
+	h := admin.NewHandler(&mockUserRepo{}, nil, nil)
+
+	for _, query := range []string{"limit=0", "limit=1000", "confirmed=maybe", "cursor=bm90LWEtY3Vyc29y", "frequency=yearly"} {
+		w := httptest.NewRecorder()
+		h.UsersHandler(w, httptest.NewRequest("GET", "/admin/api/users?"+query, nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestUserHandler_Actions(t *testing.T) {
+	userID := uuid.New()
+
+	var called string
+	subs := &mockSubscriptionService{
+		ForceConfirmFunc: func(id uuid.UUID, meta audit.Meta) error {
+			called = "confirm"
+			return nil
+		},
+		DeleteUserFunc: func(id uuid.UUID, meta audit.Meta) error {
+			called = "delete"
+			return nil
+		},
+	}
+	sender := &mockTestSender{
+		SendTestUpdateFunc: func(id uuid.UUID) error {
+			called = "test-send"
+			return nil
+		},
+	}
+	h := admin.NewHandler(nil, subs, sender)
+
+	cases := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"DELETE", "/admin/api/users/" + userID.String(), "delete"},
+		{"POST", "/admin/api/users/" + userID.String() + "/confirm", "confirm"},
+		{"POST", "/admin/api/users/" + userID.String() + "/test-send", "test-send"},
+	}
+
+	for _, tc := range cases {
+		called = ""
+		w := httptest.NewRecorder()
+		h.UserHandler(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+		if w.Code != http.StatusOK || called != tc.want {
+			t.Errorf("%s %s: expected 200 and %s, got %d and %q", tc.method, tc.path, tc.want, w.Code, called)
+		}
+	}
+}
+
+func TestUserHandler_Errors(t *testing.T) {
+	userID := uuid.New().String()
+
+	subs := &mockSubscriptionService{
+		DeleteUserFunc: func(id uuid.UUID, meta audit.Meta) error {
+			return subscription.ErrUserNotFound
+		},
+	}
+	sender := &mockTestSender{
+		SendTestUpdateFunc: func(id uuid.UUID) error {
+			return errors.New("weather API unavailable")
+		},
+	}
+	h := admin.NewHandler(nil, subs, sender)
+
+	cases := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{"DELETE", "/admin/api/users/" + userID, http.StatusNotFound},
+		{"POST", "/admin/api/users/" + userID + "/test-send", http.StatusBadGateway},
+		{"POST", "/admin/api/users/" + userID + "/unknown", http.StatusNotFound},
+		{"POST", "/admin/api/users/not-a-uuid/confirm", http.StatusNotFound},
+		{"GET", "/admin/api/users/" + userID, http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		h.UserHandler(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+		if w.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.want, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "weather API unavailable") {
+			t.Errorf("%s %s: raw error leaked into the response", tc.method, tc.path)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package apikey
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+const (
+	keysPath = "/admin/api-keys"
+
+	defaultRateLimit = 60
+	maxRateLimit     = 6000
+
+	defaultUsageDays = 30
+	maxUsageDays     = 366
+)
+
+var (
+	ErrInvalidRateLimit  = errors.New("rate_limit parameter is invalid")
+	ErrInvalidDailyQuota = errors.New("daily_quota parameter is invalid")
+	ErrInvalidDays       = errors.New("days parameter is invalid")
+	ErrKeyNotFound       = errors.New("api key not found")
+)
+
+var adminProblems = problem.Mappings{
+	{Err: ErrInvalidRateLimit, Status: http.StatusBadRequest, Code: "invalid_rate_limit"},
+	{Err: ErrInvalidDailyQuota, Status: http.StatusBadRequest, Code: "invalid_daily_quota"},
+	{Err: ErrInvalidDays, Status: http.StatusBadRequest, Code: "invalid_days"},
+	{Err: ErrKeyNotFound, Status: http.StatusNotFound, Code: "api_key_not_found"},
+}
+
+type RepositoryInterface interface {
+	Create(key *models.APIKey) error
+	List() ([]models.APIKey, error)
+	Revoke(id uuid.UUID, at time.Time) error
+	ListUsage(keyID uuid.UUID, from time.Time) ([]models.APIKeyUsage, error)
+	UsageOn(day time.Time) (map[uuid.UUID]int64, error)
+}
+
+// Admin endpoints that issue and revoke keys
+type Handler struct {
+	repo   RepositoryInterface
+	hasher HasherInterface
+}
+
+func NewHandler(repo RepositoryInterface, hasher HasherInterface) *Handler {
+	return &Handler{repo: repo, hasher: hasher}
+}
+
+type KeyResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	Key           string     `json:"key,omitempty"`
+	Prefix        string     `json:"prefix"`
+	RateLimit     int        `json:"rate_limit"`
+	DailyQuota    int        `json:"daily_quota"`
+	RequestsToday int64      `json:"requests_today"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+}
+
+type UsageResponse struct {
+	Day      string `json:"day"` // YYYY-MM-DD, UTC
+	Requests int64  `json:"requests"`
+}
+
+// GET lists keys with today's usage. POST issues one from form fields
+// "name", "rate_limit" (requests per minute, default 60) and "daily_quota"
+// (requests per UTC day, default 0 for unlimited). The key is only returned here
+func (h *Handler) KeysHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "GET":
+		h.list(w)
+	case "POST":
+		h.create(w, req)
+	default:
+		problem.MethodNotAllowed(w, req, "GET", "POST")
+	}
+}
+
+func (h *Handler) list(w http.ResponseWriter) {
+	keys, err := h.repo.List()
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	usage, err := h.repo.UsageOn(Day(time.Now()))
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	response := []KeyResponse{}
+	for _, k := range keys {
+		r := keyResponse(k)
+		r.RequestsToday = usage[k.ID]
+		response = append(response, r)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) create(w http.ResponseWriter, req *http.Request) {
+	rateLimit, err := intFormValue(req, "rate_limit", defaultRateLimit, 1, maxRateLimit)
+	if err != nil {
+		adminProblems.Write(w, ErrInvalidRateLimit)
+		return
+	}
+
+	dailyQuota, err := intFormValue(req, "daily_quota", 0, 0, -1)
+	if err != nil {
+		adminProblems.Write(w, ErrInvalidDailyQuota)
+		return
+	}
+
+	value, prefix, err := Generate()
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	key := models.APIKey{
+		Name:       req.FormValue("name"),
+		KeyHash:    h.hasher.Hash(value),
+		Prefix:     prefix,
+		RateLimit:  rateLimit,
+		DailyQuota: dailyQuota,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := h.repo.Create(&key); err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	response := keyResponse(key)
+	response.Key = value
+
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// Handles a single key:
+//
+//	DELETE /admin/api-keys/{id}         revokes it
+//	GET    /admin/api-keys/{id}/usage   daily requests of the last "days" days, default 30
+func (h *Handler) KeyHandler(w http.ResponseWriter, req *http.Request) {
+	rawID, action, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, keysPath+"/"), "/")
+
+	id, err := uuid.Parse(rawID)
+	if err != nil || (action != "" && action != "usage") {
+		adminProblems.Write(w, ErrKeyNotFound)
+		return
+	}
+
+	method := "DELETE"
+	if action == "usage" {
+		method = "GET"
+	}
+
+	if req.Method != method {
+		problem.MethodNotAllowed(w, req, method)
+		return
+	}
+
+	if action == "usage" {
+		h.usage(w, req, id)
+		return
+	}
+
+	if err := h.repo.Revoke(id, time.Now()); err != nil {
+		if repository.IsErrNotFound(err) {
+			adminProblems.Write(w, ErrKeyNotFound)
+			return
+		}
+
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) usage(w http.ResponseWriter, req *http.Request, id uuid.UUID) {
+	days, err := intFormValue(req, "days", defaultUsageDays, 1, maxUsageDays)
+	if err != nil {
+		adminProblems.Write(w, ErrInvalidDays)
+		return
+	}
+
+	usage, err := h.repo.ListUsage(id, Day(time.Now()).AddDate(0, 0, 1-days))
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	response := []UsageResponse{}
+	for _, u := range usage {
+		response = append(response, UsageResponse{Day: u.Day.Format(time.DateOnly), Requests: u.Requests})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Parses an optional integer form or query value within [min, max], max
+// below zero means no upper bound
+func intFormValue(req *http.Request, name string, fallback, min, max int) (int, error) {
+	value := req.FormValue(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || (max >= 0 && n > max) {
+		return 0, strconv.ErrRange
+	}
+
+	return n, nil
+}
+
+func keyResponse(k models.APIKey) KeyResponse {
+	return KeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		RateLimit:  k.RateLimit,
+		DailyQuota: k.DailyQuota,
+		CreatedAt:  k.CreatedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
@@ -0,0 +1,176 @@
+package apikey_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/apikey"
+
+	"github.com/google/uuid"
+)
+
+func postForm(target string, values url.Values) *http.Request {
+	req := httptest.NewRequest("POST", target, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestKeysHandler_Create(t *testing.T) {
+	repo := newMemoryRepo()
+	h := apikey.NewHandler(repo, plainHasher{})
+
+	w := serve(h.KeysHandler, postForm("/admin/api-keys", url.Values{
+		"name":        {"Partner"},
+		"rate_limit":  {"120"},
+		"daily_quota": {"5000"},
+	}))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var created apikey.KeyResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Key == "" || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Errorf("expected the key once with its prefix, got %+v", created)
+	}
+
+	stored := repo.keys[0]
+	if stored.KeyHash != (plainHasher{}).Hash(created.Key) {
+		t.Error("expected only the hash of the key to be stored")
+	}
+	if stored.Name != "Partner" || stored.RateLimit != 120 || stored.DailyQuota != 5000 {
+		t.Errorf("unexpected stored key %+v", stored)
+	}
+}
+
+func TestKeysHandler_CreateDefaults(t *testing.T) {
+	repo := newMemoryRepo()
+	h := apikey.NewHandler(repo, plainHasher{})
+
+	w := serve(h.KeysHandler, postForm("/admin/api-keys", url.Values{"name": {"Widget"}}))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if stored := repo.keys[0]; stored.RateLimit != 60 || stored.DailyQuota != 0 {
+		t.Errorf("expected 60 a minute without quota, got %+v", stored)
+	}
+}
+
+func TestKeysHandler_CreateInvalid(t *testing.T) {
+	h := apikey.NewHandler(newMemoryRepo(), plainHasher{})
+
+	cases := map[string]struct {
+		values url.Values
+		code   string
+	}{
+		"zero rate":      {url.Values{"rate_limit": {"0"}}, "invalid_rate_limit"},
+		"text rate":      {url.Values{"rate_limit": {"fast"}}, "invalid_rate_limit"},
+		"negative quota": {url.Values{"daily_quota": {"-1"}}, "invalid_daily_quota"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			expectProblem(t, serve(h.KeysHandler, postForm("/admin/api-keys", tc.values)), http.StatusBadRequest, tc.code)
+		})
+	}
+}
+
+func TestKeysHandler_List(t *testing.T) {
+	repo := newMemoryRepo()
+	key := repo.add("wk_listed", 60, 0)
+	repo.IncrementUsage(key.ID, apikey.Day(time.Now()))
+	repo.IncrementUsage(key.ID, apikey.Day(time.Now()))
+
+	h := apikey.NewHandler(repo, plainHasher{})
+
+	w := serve(h.KeysHandler, httptest.NewRequest("GET", "/admin/api-keys", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var keys []apikey.KeyResponse
+	if err := json.NewDecoder(w.Body).Decode(&keys); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 1 || keys[0].ID != key.ID || keys[0].RequestsToday != 2 || keys[0].Key != "" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+}
+
+func TestKeyHandler_Revoke(t *testing.T) {
+	repo := newMemoryRepo()
+	key := repo.add("wk_revoke", 60, 0)
+
+	h := apikey.NewHandler(repo, plainHasher{})
+
+	w := serve(h.KeyHandler, httptest.NewRequest("DELETE", "/admin/api-keys/"+key.ID.String(), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if repo.keys[0].RevokedAt == nil {
+		t.Error("expected the key to be revoked")
+	}
+
+	// Revoked keys stop authenticating
+	mw := apikey.Middleware(apikey.NewAuthenticator(repo, plainHasher{}, apikey.Anonymous{Mode: apikey.AnonymousAllow, Limit: 1}, byRemoteAddr), okHandler)
+	expectProblem(t, serve(mw, weatherRequest("wk_revoke")), http.StatusUnauthorized, "invalid_api_key")
+
+	w = serve(h.KeyHandler, httptest.NewRequest("DELETE", "/admin/api-keys/"+key.ID.String(), nil))
+	expectProblem(t, w, http.StatusNotFound, "api_key_not_found")
+
+	w = serve(h.KeyHandler, httptest.NewRequest("DELETE", "/admin/api-keys/not-a-uuid", nil))
+	expectProblem(t, w, http.StatusNotFound, "api_key_not_found")
+}
+
+func TestKeyHandler_Usage(t *testing.T) {
+	repo := newMemoryRepo()
+	key := repo.add("wk_usage", 60, 0)
+
+	today := apikey.Day(time.Now())
+	repo.IncrementUsage(key.ID, today)
+	repo.IncrementUsage(key.ID, today.AddDate(0, 0, -40))
+
+	h := apikey.NewHandler(repo, plainHasher{})
+
+	w := serve(h.KeyHandler, httptest.NewRequest("GET", "/admin/api-keys/"+key.ID.String()+"/usage", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var usage []apikey.UsageResponse
+	if err := json.NewDecoder(w.Body).Decode(&usage); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(usage) != 1 || usage[0].Day != today.Format(time.DateOnly) || usage[0].Requests != 1 {
+		t.Errorf("expected today's usage only, got %+v", usage)
+	}
+
+	w = serve(h.KeyHandler, httptest.NewRequest("GET", "/admin/api-keys/"+key.ID.String()+"/usage?days=0", nil))
+	expectProblem(t, w, http.StatusBadRequest, "invalid_days")
+}
+
+func TestKeyHandler_MethodNotAllowed(t *testing.T) {
+	h := apikey.NewHandler(newMemoryRepo(), plainHasher{})
+	id := uuid.New().String()
+
+	w := serve(h.KeyHandler, httptest.NewRequest("GET", "/admin/api-keys/"+id, nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "DELETE" {
+		t.Errorf("expected 405 allowing DELETE, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	w = serve(h.KeyHandler, httptest.NewRequest("DELETE", "/admin/api-keys/"+id+"/usage", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET" {
+		t.Errorf("expected 405 allowing GET, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+}
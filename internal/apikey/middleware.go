@@ -0,0 +1,229 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/problem"
+	"weather-app/internal/ratelimit"
+
+	"github.com/google/uuid"
+)
+
+const (
+	Header = "X-API-Key"
+
+	QuotaLimitHeader     = "X-Quota-Limit"
+	QuotaRemainingHeader = "X-Quota-Remaining"
+
+	// Keys look like "wk_<43 base64url characters>"
+	keyPrefix    = "wk_"
+	prefixLength = len(keyPrefix) + 8
+)
+
+// What requests without a key get
+const (
+	AnonymousAllow    = "allow"    // Same as with a key, without quota
+	AnonymousThrottle = "throttle" // Limited per client IP
+	AnonymousDeny     = "deny"     // 401
+)
+
+const defaultAnonymousLimit = 30
+
+var (
+	ErrKeyRequired   = errors.New("X-API-Key header is required")
+	ErrInvalidKey    = errors.New("api key is invalid or revoked")
+	ErrRateLimited   = errors.New("too many requests")
+	ErrQuotaExceeded = errors.New("daily quota of the api key is used up")
+
+	ErrInvalidAnonymousAccess = errors.New("WEATHER_ANONYMOUS_ACCESS must be allow, throttle or deny")
+	ErrInvalidAnonymousLimit  = errors.New("WEATHER_ANONYMOUS_LIMIT must be a positive number")
+)
+
+var problems = problem.Mappings{
+	{Err: ErrKeyRequired, Status: http.StatusUnauthorized, Code: "api_key_required"},
+	{Err: ErrInvalidKey, Status: http.StatusUnauthorized, Code: "invalid_api_key"},
+	{Err: ErrRateLimited, Status: http.StatusTooManyRequests, Code: problem.CodeRateLimited},
+	{Err: ErrQuotaExceeded, Status: http.StatusTooManyRequests, Code: "quota_exceeded"},
+}
+
+type HasherInterface interface {
+	Hash(value string) string
+}
+
+type AuthRepositoryInterface interface {
+	GetActiveByHash(hash string) (*models.APIKey, error)
+	IncrementUsage(keyID uuid.UUID, day time.Time) (int64, error)
+}
+
+// Access of requests without a key
+type Anonymous struct {
+	Mode  string // AnonymousAllow, AnonymousThrottle or AnonymousDeny
+	Limit int    // Requests per minute per client IP, used by AnonymousThrottle
+}
+
+// Reads WEATHER_ANONYMOUS_ACCESS, allow by default, and
+// WEATHER_ANONYMOUS_LIMIT, 30 a minute by default
+func AnonymousFromEnv() (Anonymous, error) {
+	anonymous := Anonymous{Mode: AnonymousAllow, Limit: defaultAnonymousLimit}
+
+	switch mode := os.Getenv("WEATHER_ANONYMOUS_ACCESS"); mode {
+	case "":
+	case AnonymousAllow, AnonymousThrottle, AnonymousDeny:
+		anonymous.Mode = mode
+	default:
+		return Anonymous{}, ErrInvalidAnonymousAccess
+	}
+
+	if value := os.Getenv("WEATHER_ANONYMOUS_LIMIT"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return Anonymous{}, ErrInvalidAnonymousLimit
+		}
+		anonymous.Limit = limit
+	}
+
+	return anonymous, nil
+}
+
+// Checks API keys, their rate limits and daily quotas
+type Authenticator struct {
+	repo      AuthRepositoryInterface
+	hasher    HasherInterface
+	anonymous Anonymous
+	byIP      ratelimit.KeyFunc
+
+	anonymousLimiter *ratelimit.Limiter
+
+	mu sync.Mutex
+	// Keys with the same rate share a limiter, buckets are per key ID
+	limiters map[int]*ratelimit.Limiter
+}
+
+func NewAuthenticator(repo AuthRepositoryInterface, hasher HasherInterface, anonymous Anonymous, byIP ratelimit.KeyFunc) *Authenticator {
+	return &Authenticator{
+		repo:             repo,
+		hasher:           hasher,
+		anonymous:        anonymous,
+		byIP:             byIP,
+		anonymousLimiter: ratelimit.NewLimiter(anonymous.Limit, time.Minute),
+		limiters:         make(map[int]*ratelimit.Limiter),
+	}
+}
+
+// Lets requests through with a valid key in the X-API-Key header, or
+// without one as configured for anonymous access
+func Middleware(a *Authenticator, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		now := time.Now()
+
+		var err error
+		if value := req.Header.Get(Header); value != "" {
+			err = a.checkKey(w, value, now)
+		} else {
+			err = a.checkAnonymous(w, req, now)
+		}
+
+		if err != nil {
+			if errors.Is(err, ErrKeyRequired) || errors.Is(err, ErrInvalidKey) {
+				w.Header().Set("WWW-Authenticate", `APIKey header="`+Header+`"`)
+			}
+
+			problems.Write(w, err)
+			return
+		}
+
+		next(w, req)
+	}
+}
+
+func (a *Authenticator) checkKey(w http.ResponseWriter, value string, now time.Time) error {
+	key, err := a.repo.GetActiveByHash(a.hasher.Hash(value))
+	if err != nil {
+		if repository.IsErrNotFound(err) {
+			return ErrInvalidKey
+		}
+		return err
+	}
+
+	if ok, wait := a.limiter(key.RateLimit).Allow(key.ID.String(), now); !ok {
+		setRetryAfter(w, wait)
+		return ErrRateLimited
+	}
+
+	used, err := a.repo.IncrementUsage(key.ID, Day(now))
+	if err != nil {
+		return err
+	}
+
+	if key.DailyQuota > 0 {
+		w.Header().Set(QuotaLimitHeader, strconv.Itoa(key.DailyQuota))
+		w.Header().Set(QuotaRemainingHeader, strconv.FormatInt(max(int64(key.DailyQuota)-used, 0), 10))
+
+		if used > int64(key.DailyQuota) {
+			setRetryAfter(w, Day(now).AddDate(0, 0, 1).Sub(now))
+			return ErrQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
+func (a *Authenticator) checkAnonymous(w http.ResponseWriter, req *http.Request, now time.Time) error {
+	switch a.anonymous.Mode {
+	case AnonymousDeny:
+		return ErrKeyRequired
+
+	case AnonymousThrottle:
+		if ok, wait := a.anonymousLimiter.Allow(a.byIP(req), now); !ok {
+			setRetryAfter(w, wait)
+			return ErrRateLimited
+		}
+	}
+
+	return nil
+}
+
+func (a *Authenticator) limiter(perMinute int) *ratelimit.Limiter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	l, ok := a.limiters[perMinute]
+	if !ok {
+		l = ratelimit.NewLimiter(perMinute, time.Minute)
+		a.limiters[perMinute] = l
+	}
+
+	return l
+}
+
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+}
+
+// Start of t's UTC day, quotas reset then
+func Day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// New random key and the prefix stored to recognize it
+func Generate() (key, prefix string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	return key, key[:prefixLength], nil
+}
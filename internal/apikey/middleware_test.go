@@ -0,0 +1,297 @@
+package apikey_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/apikey"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+type plainHasher struct{}
+
+func (plainHasher) Hash(value string) string {
+	return "hash:" + value
+}
+
+type memoryRepo struct {
+	keys  []models.APIKey
+	usage map[uuid.UUID]map[time.Time]int64
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{usage: map[uuid.UUID]map[time.Time]int64{}}
+}
+
+// Stores an active key for value and returns it
+func (m *memoryRepo) add(value string, rateLimit, dailyQuota int) models.APIKey {
+	key := models.APIKey{
+		ID:         uuid.New(),
+		Name:       "test",
+		KeyHash:    plainHasher{}.Hash(value),
+		RateLimit:  rateLimit,
+		DailyQuota: dailyQuota,
+		CreatedAt:  time.Now(),
+	}
+	m.keys = append(m.keys, key)
+	return key
+}
+
+func (m *memoryRepo) Create(key *models.APIKey) error {
+	key.ID = uuid.New()
+	m.keys = append(m.keys, *key)
+	return nil
+}
+
+func (m *memoryRepo) List() ([]models.APIKey, error) {
+	return m.keys, nil
+}
+
+func (m *memoryRepo) GetActiveByHash(hash string) (*models.APIKey, error) {
+	for _, k := range m.keys {
+		if k.KeyHash == hash && k.RevokedAt == nil {
+			return &k, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: api key not found", repository.ErrNotFound)
+}
+
+func (m *memoryRepo) Revoke(id uuid.UUID, at time.Time) error {
+	for i, k := range m.keys {
+		if k.ID == id && k.RevokedAt == nil {
+			m.keys[i].RevokedAt = &at
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: api key not found", repository.ErrNotFound)
+}
+
+func (m *memoryRepo) IncrementUsage(keyID uuid.UUID, day time.Time) (int64, error) {
+	if m.usage[keyID] == nil {
+		m.usage[keyID] = map[time.Time]int64{}
+	}
+	m.usage[keyID][day]++
+	return m.usage[keyID][day], nil
+}
+
+func (m *memoryRepo) ListUsage(keyID uuid.UUID, from time.Time) ([]models.APIKeyUsage, error) {
+	var usage []models.APIKeyUsage
+	for day, n := range m.usage[keyID] {
+		if !day.Before(from) {
+			usage = append(usage, models.APIKeyUsage{KeyID: keyID, Day: day, Requests: n})
+		}
+	}
+	return usage, nil
+}
+
+func (m *memoryRepo) UsageOn(day time.Time) (map[uuid.UUID]int64, error) {
+	counts := map[uuid.UUID]int64{}
+	for id, days := range m.usage {
+		counts[id] = days[day]
+	}
+	return counts, nil
+}
+
+func okHandler(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func byRemoteAddr(req *http.Request) string {
+	return req.RemoteAddr
+}
+
+func weatherRequest(key string) *http.Request {
+	req := httptest.NewRequest("GET", "/api/weather?city=Kyiv", nil)
+	if key != "" {
+		req.Header.Set(apikey.Header, key)
+	}
+	return req
+}
+
+func serve(h http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h(w, req)
+	return w
+}
+
+func expectProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Fatalf("expected %d, got %d: %s", status, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"code":"`+code+`"`) {
+		t.Errorf("expected code %q, got %s", code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("expected %s, got %q", problem.ContentType, ct)
+	}
+}
+
+func TestMiddleware_ValidKey(t *testing.T) {
+	repo := newMemoryRepo()
+	key := repo.add("wk_valid", 10, 0)
+
+	h := apikey.Middleware(apikey.NewAuthenticator(repo, plainHasher{}, apikey.Anonymous{Mode: apikey.AnonymousDeny, Limit: 1}, byRemoteAddr), okHandler)
+
+	w := serve(h, weatherRequest("wk_valid"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if got := repo.usage[key.ID][apikey.Day(time.Now())]; got != 1 {
+		t.Errorf("expected one counted request, got %d", got)
+	}
+	if w.Header().Get(apikey.QuotaLimitHeader) != "" {
+		t.Error("keys without a quota don't get quota headers")
+	}
+}
+
+func TestMiddleware_InvalidKey(t *testing.T) {
+	repo := newMemoryRepo()
+	key := repo.add("wk_revoked", 10, 0)
+	repo.Revoke(key.ID, time.Now())
+
+	h := apikey.Middleware(apikey.NewAuthenticator(repo, plainHasher{}, apikey.Anonymous{Mode: apikey.AnonymousAllow, Limit: 1}, byRemoteAddr), okHandler)
+
+	for _, value := range []string{"wk_unknown", "wk_revoked"} {
+		w := serve(h, weatherRequest(value))
+		expectProblem(t, w, http.StatusUnauthorized, "invalid_api_key")
+
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: expected WWW-Authenticate", value)
+		}
+	}
+}
+
+func TestMiddleware_RateLimit(t *testing.T) {
+	repo := newMemoryRepo()
+	repo.add("wk_a", 2, 0)
+	repo.add("wk_b", 2, 0)
+
+	h := apikey.Middleware(apikey.NewAuthenticator(repo, plainHasher{}, apikey.Anonymous{Mode: apikey.AnonymousDeny, Limit: 1}, byRemoteAddr), okHandler)
+
+	for i := 0; i < 2; i++ {
+		if w := serve(h, weatherRequest("wk_a")); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, w.Code)
+		}
+	}
+
+	w := serve(h, weatherRequest("wk_a"))
+	expectProblem(t, w, http.StatusTooManyRequests, problem.CodeRateLimited)
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After")
+	}
+
+	// Keys with the same rate have their own buckets
+	if w := serve(h, weatherRequest("wk_b")); w.Code != http.StatusOK {
+		t.Errorf("expected another key to pass, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_DailyQuota(t *testing.T) {
+	repo := newMemoryRepo()
+	repo.add("wk_quota", 100, 2)
+
+	h := apikey.Middleware(apikey.NewAuthenticator(repo, plainHasher{}, apikey.Anonymous{Mode: apikey.AnonymousDeny, Limit: 1}, byRemoteAddr), okHandler)
+
+	for i, remaining := range []string{"1", "0"} {
+		w := serve(h, weatherRequest("wk_quota"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i+1, w.Code)
+		}
+		if got := w.Header().Get(apikey.QuotaLimitHeader); got != "2" {
+			t.Errorf("expected quota limit 2, got %q", got)
+		}
+		if got := w.Header().Get(apikey.QuotaRemainingHeader); got != remaining {
+			t.Errorf("request %d: expected %s remaining, got %q", i+1, remaining, got)
+		}
+	}
+
+	w := serve(h, weatherRequest("wk_quota"))
+	expectProblem(t, w, http.StatusTooManyRequests, "quota_exceeded")
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After until the quota resets")
+	}
+}
+
+func TestMiddleware_Anonymous(t *testing.T) {
+	repo := newMemoryRepo()
+
+	allow := apikey.Middleware(apikey.NewAuthenticator(repo, plainHasher{}, apikey.Anonymous{Mode: apikey.AnonymousAllow, Limit: 1}, byRemoteAddr), okHandler)
+	for i := 0; i < 3; i++ {
+		if w := serve(allow, weatherRequest("")); w.Code != http.StatusOK {
+			t.Fatalf("allow: expected 200, got %d", w.Code)
+		}
+	}
+
+	deny := apikey.Middleware(apikey.NewAuthenticator(repo, plainHasher{}, apikey.Anonymous{Mode: apikey.AnonymousDeny, Limit: 1}, byRemoteAddr), okHandler)
+	w := serve(deny, weatherRequest(""))
+	expectProblem(t, w, http.StatusUnauthorized, "api_key_required")
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("expected WWW-Authenticate")
+	}
+
+	throttle := apikey.Middleware(apikey.NewAuthenticator(repo, plainHasher{}, apikey.Anonymous{Mode: apikey.AnonymousThrottle, Limit: 1}, byRemoteAddr), okHandler)
+	if w := serve(throttle, weatherRequest("")); w.Code != http.StatusOK {
+		t.Fatalf("throttle: expected 200, got %d", w.Code)
+	}
+	expectProblem(t, serve(throttle, weatherRequest("")), http.StatusTooManyRequests, problem.CodeRateLimited)
+
+	other := weatherRequest("")
+	other.RemoteAddr = "192.0.2.10:1234"
+	if w := serve(throttle, other); w.Code != http.StatusOK {
+		t.Errorf("throttle: expected another client to pass, got %d", w.Code)
+	}
+}
+
+func TestAnonymousFromEnv(t *testing.T) {
+	t.Setenv("WEATHER_ANONYMOUS_ACCESS", "")
+	t.Setenv("WEATHER_ANONYMOUS_LIMIT", "")
+
+	anonymous, err := apikey.AnonymousFromEnv()
+	if err != nil || anonymous.Mode != apikey.AnonymousAllow {
+		t.Fatalf("expected allow by default, got %+v, %v", anonymous, err)
+	}
+
+	t.Setenv("WEATHER_ANONYMOUS_ACCESS", "throttle")
+	t.Setenv("WEATHER_ANONYMOUS_LIMIT", "5")
+	anonymous, err = apikey.AnonymousFromEnv()
+	if err != nil || anonymous != (apikey.Anonymous{Mode: apikey.AnonymousThrottle, Limit: 5}) {
+		t.Fatalf("unexpected %+v, %v", anonymous, err)
+	}
+
+	t.Setenv("WEATHER_ANONYMOUS_ACCESS", "sometimes")
+	if _, err := apikey.AnonymousFromEnv(); err != apikey.ErrInvalidAnonymousAccess {
+		t.Errorf("expected ErrInvalidAnonymousAccess, got %v", err)
+	}
+
+	t.Setenv("WEATHER_ANONYMOUS_ACCESS", "throttle")
+	t.Setenv("WEATHER_ANONYMOUS_LIMIT", "0")
+	if _, err := apikey.AnonymousFromEnv(); err != apikey.ErrInvalidAnonymousLimit {
+		t.Errorf("expected ErrInvalidAnonymousLimit, got %v", err)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	key, prefix, err := apikey.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(key, "wk_") || !strings.HasPrefix(key, prefix) || len(prefix) >= len(key) {
+		t.Errorf("unexpected key %q with prefix %q", key, prefix)
+	}
+
+	other, _, _ := apikey.Generate()
+	if other == key {
+		t.Error("expected distinct keys")
+	}
+}
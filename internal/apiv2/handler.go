@@ -0,0 +1,277 @@
+package apiv2
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/problem"
+	"weather-app/internal/schedule"
+	"weather-app/internal/subscription"
+	"weather-app/internal/weather"
+
+	"github.com/google/uuid"
+)
+
+const (
+	Prefix            = "/api/v2"
+	subscriptionsPath = Prefix + "/subscriptions"
+)
+
+// Values of WeatherMeta.Cache
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// Values of Subscription.Status
+const (
+	StatusPending = "pending" // Waiting for the address to be confirmed
+	StatusActive  = "active"
+	StatusPaused  = "paused"
+)
+
+var problems = slices.Concat(subscription.FormProblems, problem.Mappings{
+	{Err: weather.ErrEmptyCity, Status: http.StatusBadRequest, Code: "empty_city"},
+	{Err: weather.ErrInvalidLanguage, Status: http.StatusBadRequest, Code: "invalid_lang"},
+	{Err: weather.ErrCityNotFound, Status: http.StatusNotFound, Code: "city_not_found"},
+	{Err: subscription.ErrSubscriptionNotFound, Status: http.StatusNotFound, Code: "subscription_not_found"},
+	{Err: subscription.ErrUserAlreadyExists, Status: http.StatusConflict, Code: "user_already_exists"},
+	{Err: subscription.ErrEmailSuppressed, Status: http.StatusForbidden, Code: "email_suppressed"},
+	{Err: subscription.ErrDisposableEmail, Status: http.StatusBadRequest, Code: "disposable_email"},
+	{Err: subscription.ErrNoMailServer, Status: http.StatusBadRequest, Code: "no_mail_server"},
+})
+
+type WeatherServiceInterface interface {
+	GetReport(city, lang string) (*weather.Report, error)
+}
+
+type SubscriptionServiceInterface interface {
+	CreateSubscription(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) (*models.Subscription, error)
+	GetSubscription(id uuid.UUID) (*subscription.Details, error)
+}
+
+// Serves /api/v2 with the services behind /api. Responses wrap the resource
+// in "data", weather adds "meta" about where the data comes from
+type Handler struct {
+	weather       WeatherServiceInterface
+	subscriptions SubscriptionServiceInterface
+}
+
+func NewHandler(weatherService WeatherServiceInterface, subService SubscriptionServiceInterface) *Handler {
+	return &Handler{weather: weatherService, subscriptions: subService}
+}
+
+type WeatherResponse struct {
+	Data weather.WeatherData `json:"data"`
+	Meta WeatherMeta         `json:"meta"`
+}
+
+type WeatherMeta struct {
+	Location   Location   `json:"location"`
+	ObservedAt *time.Time `json:"observed_at,omitempty"`
+	FetchedAt  time.Time  `json:"fetched_at"`
+	Source     string     `json:"source"`
+	Cache      string     `json:"cache"` // CacheHit or CacheMiss
+}
+
+// City the query was matched to, may differ from the query's spelling
+type Location struct {
+	Name    string  `json:"name"`
+	Country string  `json:"country,omitempty"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+type SubscriptionResponse struct {
+	Data Subscription `json:"data"`
+}
+
+type Subscription struct {
+	ID          uuid.UUID           `json:"id"`
+	City        string              `json:"city"`
+	Status      string              `json:"status"`
+	Schedule    Schedule            `json:"schedule"`
+	Preferences content.Preferences `json:"preferences"`
+	PausedUntil *time.Time          `json:"paused_until,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// Only the fields used by the frequency are set
+type Schedule struct {
+	Frequency     string `json:"frequency"`
+	Timezone      string `json:"timezone"`
+	SendTime      string `json:"send_time,omitempty"`
+	Weekday       string `json:"weekday,omitempty"`
+	IntervalHours int    `json:"interval_hours,omitempty"`
+	Cron          string `json:"cron,omitempty"`
+}
+
+// GET /api/v2/weather with the query parameters of /api/weather
+func (h *Handler) WeatherHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	query := req.URL.Query()
+
+	city := query.Get("city")
+	if city == "" {
+		problems.Write(w, weather.ErrEmptyCity)
+		return
+	}
+
+	lang := query.Get("lang")
+	if lang != "" && !weather.IsValidLanguage(lang) {
+		problems.Write(w, weather.ErrInvalidLanguage)
+		return
+	}
+
+	report, err := h.weather.GetReport(city, lang)
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, weatherResponse(report))
+}
+
+// POST /api/v2/subscriptions with the form fields of /api/subscribe. Answers
+// 201 with the subscription, which stays pending until the address is confirmed
+func (h *Handler) SubscriptionsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	}
+
+	data, err := subscription.ParseForm(req)
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	sub, err := h.subscriptions.CreateSubscription(data.Email, data.City, data.Schedule, data.Content, audit.MetaFromRequest(req))
+	if err != nil {
+		// The subscription exists, the address can't be confirmed yet
+		if !errors.Is(err, subscription.ErrConfirmationMailError) || sub == nil {
+			problems.Write(w, err)
+			return
+		}
+
+		log.Println(err.Error())
+	}
+
+	w.Header().Set("Location", subscriptionsPath+"/"+sub.ID.String())
+	writeJSON(w, http.StatusCreated, SubscriptionResponse{Data: subscriptionResource(*sub, false)})
+}
+
+// GET /api/v2/subscriptions/{id}. The ID is only known to whoever created
+// the subscription, the response leaves out the email address
+func (h *Handler) SubscriptionHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := uuid.Parse(strings.TrimPrefix(req.URL.Path, subscriptionsPath+"/"))
+	if err != nil {
+		problems.Write(w, subscription.ErrSubscriptionNotFound)
+		return
+	}
+
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	details, err := h.subscriptions.GetSubscription(id)
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SubscriptionResponse{Data: subscriptionResource(details.Subscription, details.Confirmed)})
+}
+
+func weatherResponse(report *weather.Report) WeatherResponse {
+	meta := WeatherMeta{
+		Location: Location{
+			Name:    report.Location.Name,
+			Country: report.Location.Country,
+			Lat:     report.Location.Lat,
+			Lon:     report.Location.Lon,
+		},
+		FetchedAt: report.FetchedAt,
+		Source:    report.Source,
+		Cache:     CacheMiss,
+	}
+
+	if !report.ObservedAt.IsZero() {
+		meta.ObservedAt = &report.ObservedAt
+	}
+
+	if report.Cached {
+		meta.Cache = CacheHit
+	}
+
+	return WeatherResponse{Data: report.Data, Meta: meta}
+}
+
+func subscriptionResource(sub models.Subscription, confirmed bool) Subscription {
+	resource := Subscription{
+		ID:          sub.ID,
+		City:        sub.City,
+		Status:      StatusActive,
+		Schedule:    scheduleResource(sub),
+		Preferences: content.FromStored(sub.ContentFields, sub.Units, sub.Language),
+		PausedUntil: sub.PausedUntil,
+		CreatedAt:   sub.CreatedAt,
+	}
+
+	switch {
+	case !confirmed:
+		resource.Status = StatusPending
+	case sub.PausedAt != nil:
+		resource.Status = StatusPaused
+	}
+
+	return resource
+}
+
+func scheduleResource(sub models.Subscription) Schedule {
+	sched := schedule.Schedule{
+		Frequency:     sub.Frequency,
+		Timezone:      sub.Timezone,
+		SendTime:      sub.SendTime,
+		Weekday:       time.Weekday(sub.Weekday),
+		IntervalHours: sub.IntervalHours,
+		CronExpr:      sub.CronExpr,
+	}.WithDefaults()
+
+	resource := Schedule{Frequency: sched.Frequency, Timezone: sched.Timezone}
+
+	switch sched.Frequency {
+	case schedule.FrequencyDaily, schedule.FrequencyWeekdays:
+		resource.SendTime = sched.SendTime
+	case schedule.FrequencyWeekly:
+		resource.SendTime = sched.SendTime
+		resource.Weekday = strings.ToLower(sched.Weekday.String())
+	case schedule.FrequencyEveryNHours:
+		resource.IntervalHours = sched.IntervalHours
+	case schedule.FrequencyCron:
+		resource.Cron = sched.CronExpr
+	}
+
+	return resource
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
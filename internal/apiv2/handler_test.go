@@ -0,0 +1,286 @@
+package apiv2_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/apiv2"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/schedule"
+	"weather-app/internal/subscription"
+	"weather-app/internal/weather"
+
+	"github.com/google/uuid"
+)
+
+type mockWeatherService struct {
+	GetReportFunc func(city, lang string) (*weather.Report, error)
+}
+
+func (m *mockWeatherService) GetReport(city, lang string) (*weather.Report, error) {
+	return m.GetReportFunc(city, lang)
+}
+
+type mockSubscriptionService struct {
+	CreateSubscriptionFunc func(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) (*models.Subscription, error)
+	GetSubscriptionFunc    func(id uuid.UUID) (*subscription.Details, error)
+}
+
+func (m *mockSubscriptionService) CreateSubscription(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) (*models.Subscription, error) {
+	return m.CreateSubscriptionFunc(email, city, sched, prefs, meta)
+}
+
+func (m *mockSubscriptionService) GetSubscription(id uuid.UUID) (*subscription.Details, error) {
+	return m.GetSubscriptionFunc(id)
+}
+
+func postForm(target string, values url.Values) *http.Request {
+	req := httptest.NewRequest("POST", target, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestWeatherHandler_Envelope(t *testing.T) {
+	observed := time.Date(2025, 6, 1, 8, 50, 0, 0, time.UTC)
+	fetched := observed.Add(5 * time.Minute)
+
+	for _, cached := range []bool{false, true} {
+		service := &mockWeatherService{
+			GetReportFunc: func(city, lang string) (*weather.Report, error) {
+				return &weather.Report{
+					Data:       weather.WeatherData{Temperature: 21.5, Humidity: 40, Description: "clear sky"},
+					Location:   weather.Location{Name: "Kyiv", Country: "UA", Lat: 50.45, Lon: 30.52},
+					ObservedAt: observed,
+					FetchedAt:  fetched,
+					Source:     weather.Provider,
+					Cached:     cached,
+				}, nil
+			},
+		}
+		h := apiv2.NewHandler(service, &mockSubscriptionService{})
+
+		w := httptest.NewRecorder()
+		h.WeatherHandler(w, httptest.NewRequest("GET", "/api/v2/weather?city=kyiv", nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp apiv2.WeatherResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.Data.Temperature != 21.5 || resp.Data.Description != "clear sky" {
+			t.Errorf("unexpected data %+v", resp.Data)
+		}
+
+		meta := resp.Meta
+		if meta.Location != (apiv2.Location{Name: "Kyiv", Country: "UA", Lat: 50.45, Lon: 30.52}) {
+			t.Errorf("unexpected location %+v", meta.Location)
+		}
+		if meta.ObservedAt == nil || !meta.ObservedAt.Equal(observed) || !meta.FetchedAt.Equal(fetched) {
+			t.Errorf("unexpected times %v, %v", meta.ObservedAt, meta.FetchedAt)
+		}
+		if meta.Source != "openweathermap" {
+			t.Errorf("unexpected source %q", meta.Source)
+		}
+
+		expected := apiv2.CacheMiss
+		if cached {
+			expected = apiv2.CacheHit
+		}
+		if meta.Cache != expected {
+			t.Errorf("expected cache %q, got %q", expected, meta.Cache)
+		}
+	}
+}
+
+func TestWeatherHandler_Errors(t *testing.T) {
+	service := &mockWeatherService{
+		GetReportFunc: func(city, lang string) (*weather.Report, error) {
+			return nil, weather.ErrCityNotFound
+		},
+	}
+	h := apiv2.NewHandler(service, &mockSubscriptionService{})
+
+	cases := map[string]struct {
+		target string
+		status int
+		code   string
+	}{
+		"empty city":   {"/api/v2/weather", http.StatusBadRequest, "empty_city"},
+		"invalid lang": {"/api/v2/weather?city=Kyiv&lang=english", http.StatusBadRequest, "invalid_lang"},
+		"unknown city": {"/api/v2/weather?city=Atlantis", http.StatusNotFound, "city_not_found"},
+	}
+
+	for name, tc := range cases {
+		w := httptest.NewRecorder()
+		h.WeatherHandler(w, httptest.NewRequest("GET", tc.target, nil))
+
+		if w.Code != tc.status || !strings.Contains(w.Body.String(), `"code":"`+tc.code+`"`) {
+			t.Errorf("%s: expected %d %s, got %d %s", name, tc.status, tc.code, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestSubscriptionsHandler_Create(t *testing.T) {
+	id := uuid.New()
+
+	var gotSchedule schedule.Schedule
+	service := &mockSubscriptionService{
+		CreateSubscriptionFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) (*models.Subscription, error) {
+			gotSchedule = sched
+			return &models.Subscription{
+				ID: id, City: city, Frequency: sched.Frequency, Timezone: sched.Timezone,
+				SendTime: sched.SendTime, Weekday: int(sched.Weekday),
+				ContentFields: prefs.StoredFields(), Units: prefs.Units, Language: prefs.Language,
+			}, nil
+		},
+	}
+	h := apiv2.NewHandler(&mockWeatherService{}, service)
+
+	w := httptest.NewRecorder()
+	h.SubscriptionsHandler(w, postForm("/api/v2/subscriptions", url.Values{
+		"email":     {"user@example.com"},
+		"city":      {"Kyiv"},
+		"frequency": {"weekly"},
+		"weekday":   {"monday"},
+		"send_time": {"08:00"},
+		"fields":    {"wind"},
+	}))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/v2/subscriptions/"+id.String() {
+		t.Errorf("unexpected Location %q", loc)
+	}
+	if gotSchedule.Weekday != time.Monday {
+		t.Errorf("expected the form to be parsed like /api/subscribe, got %+v", gotSchedule)
+	}
+
+	var resp apiv2.SubscriptionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := resp.Data
+	if sub.ID != id || sub.Status != apiv2.StatusPending || sub.City != "Kyiv" {
+		t.Errorf("unexpected subscription %+v", sub)
+	}
+	if sub.Schedule != (apiv2.Schedule{Frequency: "weekly", Timezone: "UTC", SendTime: "08:00", Weekday: "monday"}) {
+		t.Errorf("unexpected schedule %+v", sub.Schedule)
+	}
+	if len(sub.Preferences.Fields) != 1 || sub.Preferences.Fields[0] != "wind" {
+		t.Errorf("unexpected preferences %+v", sub.Preferences)
+	}
+}
+
+func TestSubscriptionsHandler_MailFailureStillCreates(t *testing.T) {
+	service := &mockSubscriptionService{
+		CreateSubscriptionFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) (*models.Subscription, error) {
+			return &models.Subscription{ID: uuid.New(), City: city, Frequency: sched.Frequency},
+				fmt.Errorf("%w: smtp down", subscription.ErrConfirmationMailError)
+		},
+	}
+	h := apiv2.NewHandler(&mockWeatherService{}, service)
+
+	w := httptest.NewRecorder()
+	h.SubscriptionsHandler(w, postForm("/api/v2/subscriptions", url.Values{
+		"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"daily"},
+	}))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestSubscriptionsHandler_Errors(t *testing.T) {
+	service := &mockSubscriptionService{
+		CreateSubscriptionFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) (*models.Subscription, error) {
+			return nil, subscription.ErrUserAlreadyExists
+		},
+	}
+	h := apiv2.NewHandler(&mockWeatherService{}, service)
+
+	w := httptest.NewRecorder()
+	h.SubscriptionsHandler(w, postForm("/api/v2/subscriptions", url.Values{"email": {"user@example.com"}, "city": {"Kyiv"}}))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_frequency") {
+		t.Errorf("expected invalid_frequency, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.SubscriptionsHandler(w, postForm("/api/v2/subscriptions", url.Values{
+		"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"daily"},
+	}))
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.SubscriptionsHandler(w, httptest.NewRequest("GET", "/api/v2/subscriptions", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+}
+
+func TestSubscriptionHandler_Get(t *testing.T) {
+	id := uuid.New()
+	until := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	pausedAt := until.AddDate(0, 0, -14)
+
+	service := &mockSubscriptionService{
+		GetSubscriptionFunc: func(got uuid.UUID) (*subscription.Details, error) {
+			if got != id {
+				return nil, subscription.ErrSubscriptionNotFound
+			}
+			return &subscription.Details{
+				Subscription: models.Subscription{
+					ID: id, City: "Lviv", Frequency: "every_n_hours", IntervalHours: 6, Timezone: "Europe/Kyiv",
+					PausedAt: &pausedAt, PausedUntil: &until,
+				},
+				Confirmed: true,
+			}, nil
+		},
+	}
+	h := apiv2.NewHandler(&mockWeatherService{}, service)
+
+	w := httptest.NewRecorder()
+	h.SubscriptionHandler(w, httptest.NewRequest("GET", "/api/v2/subscriptions/"+id.String(), nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "email") {
+		t.Error("the email address must not be exposed")
+	}
+
+	var resp apiv2.SubscriptionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := resp.Data
+	if sub.Status != apiv2.StatusPaused || sub.PausedUntil == nil || !sub.PausedUntil.Equal(until) {
+		t.Errorf("expected a paused subscription, got %+v", sub)
+	}
+	if sub.Schedule != (apiv2.Schedule{Frequency: "every_n_hours", Timezone: "Europe/Kyiv", IntervalHours: 6}) {
+		t.Errorf("unexpected schedule %+v", sub.Schedule)
+	}
+
+	for _, target := range []string{"/api/v2/subscriptions/" + uuid.NewString(), "/api/v2/subscriptions/abc"} {
+		w := httptest.NewRecorder()
+		h.SubscriptionHandler(w, httptest.NewRequest("GET", target, nil))
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "subscription_not_found") {
+			t.Errorf("%s: expected 404, got %d %s", target, w.Code, w.Body.String())
+		}
+	}
+}
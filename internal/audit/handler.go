@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/emailaddr"
+	"weather-app/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+var ErrEmptyEmail = errors.New("email parameter is empty")
+
+type EventRepositoryInterface interface {
+	ListByEmail(email string) ([]models.SubscriptionEvent, error)
+}
+
+type AuditHandler struct {
+	repo EventRepositoryInterface
+}
+
+func NewHandler(repo EventRepositoryInterface) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
+type EventResponse struct {
+	Type      string     `json:"type"`
+	Email     string     `json:"email"`
+	UserID    uuid.UUID  `json:"user_id"`
+	TokenID   *uuid.UUID `json:"token_id,omitempty"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"user_agent"`
+	Details   string     `json:"details,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Lists audit events for the "email" query parameter
+func (h *AuditHandler) EventsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	email := req.URL.Query().Get("email")
+	if email == "" {
+		problem.Write(w, http.StatusBadRequest, "empty_email", ErrEmptyEmail.Error())
+		return
+	}
+
+	// Events are stored under the normalized address
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		problem.Write(w, http.StatusBadRequest, "invalid_email", err.Error())
+		return
+	}
+
+	events, err := h.repo.ListByEmail(email)
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	response := make([]EventResponse, 0, len(events))
+	for _, e := range events {
+		response = append(response, EventResponse{
+			Type:      e.Type,
+			Email:     e.Email,
+			UserID:    e.UserID,
+			TokenID:   e.TokenID,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			Details:   e.Details,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
@@ -0,0 +1,148 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weather-app/internal/audit"
+	"weather-app/internal/database/models"
+)
+
+type mockEventRepo struct {
+	ListByEmailFunc func(email string) ([]models.SubscriptionEvent, error)
+}
+
+func (r *mockEventRepo) ListByEmail(email string) ([]models.SubscriptionEvent, error) {
+	return r.ListByEmailFunc(email)
+}
+
+func TestEventsHandler_Success(t *testing.T) {
+	repo := &mockEventRepo{
+		ListByEmailFunc: func(email string) ([]models.SubscriptionEvent, error) {
+			return []models.SubscriptionEvent{
+				{Type: models.EventSubscribed, Email: email, IP: "10.0.0.1"},
+				{Type: models.EventConfirmed, Email: email},
+			}, nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/admin/events?email=test@example.com", nil)
+	w := httptest.NewRecorder()
+
+	audit.NewHandler(repo).EventsHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var events []audit.EventResponse
+	if err := json.NewDecoder(w.Body).Decode(&events); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+
+	if len(events) != 2 || events[0].Type != models.EventSubscribed || events[0].IP != "10.0.0.1" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestEventsHandler_MissingEmail(t *testing.T) {
+	req := httptest.NewRequest("GET", "/admin/events", nil)
+	w := httptest.NewRecorder()
+
+	audit.NewHandler(&mockEventRepo{}).EventsHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestEventsHandler_DBError(t *testing.T) {
+	repo := &mockEventRepo{
+		ListByEmailFunc: func(email string) ([]models.SubscriptionEvent, error) {
+			return nil, errors.New("db down")
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/admin/events?email=test@example.com", nil)
+	w := httptest.NewRecorder()
+
+	audit.NewHandler(repo).EventsHandler(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+}
+
+func TestMetaFromRequest_ForwardedFor(t *testing.T) {
+	t.Setenv("TRUST_PROXY", "2")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	req.Header.Set("User-Agent", "test-agent")
+
+	meta := audit.MetaFromRequest(req)
+
+	if meta.IP != "203.0.113.7" || meta.UserAgent != "test-agent" {
+		t.Errorf("unexpected meta: %+v", meta)
+	}
+}
+
+func TestMetaFromRequest_ForgedForwardedForIgnored(t *testing.T) {
+	t.Setenv("TRUST_PROXY", "true")
+
+	// The client sent its own header, the proxy appended the real address
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.99, 203.0.113.7")
+
+	if meta := audit.MetaFromRequest(req); meta.IP != "203.0.113.7" {
+		t.Errorf("expected the address added by the proxy, got %q", meta.IP)
+	}
+}
+
+func TestMetaFromRequest_IgnoresForwardedForWithoutTrustProxy(t *testing.T) {
+	t.Setenv("TRUST_PROXY", "")
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	if meta := audit.MetaFromRequest(req); meta.IP != "192.0.2.1" {
+		t.Errorf("expected remote address, got %q", meta.IP)
+	}
+}
+
+func TestEventsHandler_NormalizesEmail(t *testing.T) {
+	var got string
+	repo := &mockEventRepo{
+		ListByEmailFunc: func(email string) ([]models.SubscriptionEvent, error) {
+			got = email
+			return nil, nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/admin/events?email=%20Test@Example.COM%20", nil)
+	w := httptest.NewRecorder()
+
+	audit.NewHandler(repo).EventsHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got != "test@example.com" {
+		t.Errorf("expected the normalized email to be looked up, got %q", got)
+	}
+}
+
+func TestEventsHandler_InvalidEmail(t *testing.T) {
+	req := httptest.NewRequest("GET", "/admin/events?email=not-an-email", nil)
+	w := httptest.NewRecorder()
+
+	audit.NewHandler(&mockEventRepo{}).EventsHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
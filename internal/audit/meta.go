@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Client details recorded with every subscription event
+type Meta struct {
+	IP        string
+	UserAgent string
+}
+
+// Extracts client details using ClientIP
+func MetaFromRequest(req *http.Request) Meta {
+	return Meta{
+		IP:        ClientIP(req),
+		UserAgent: req.UserAgent(),
+	}
+}
+
+// Client address behind the proxies configured by TRUST_PROXY
+func ClientIP(req *http.Request) string {
+	return RemoteIP(req, TrustedProxies())
+}
+
+// Number of proxies in front of the service from TRUST_PROXY. "true" means
+// one, a number sets the count for chains, anything else none
+func TrustedProxies() int {
+	value := os.Getenv("TRUST_PROXY")
+	if value == "true" {
+		return 1
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return n
+}
+
+// Client address. Each trusted proxy appends the address it was connected
+// from to X-Forwarded-For, so the entry trustedProxies hops from the right is
+// the client. Entries left of it are whatever the client sent
+func RemoteIP(req *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		var entries []string
+		for _, header := range req.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(header, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					entries = append(entries, entry)
+				}
+			}
+		}
+
+		// Fewer entries than proxies means the outer ones were skipped, every
+		// entry present was still written by a trusted proxy
+		if len(entries) > 0 {
+			return entries[max(len(entries)-trustedProxies, 0)]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
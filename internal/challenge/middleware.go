@@ -0,0 +1,33 @@
+package challenge
+
+import (
+	"net/http"
+	"weather-app/internal/audit"
+	"weather-app/internal/problem"
+)
+
+// Form field carrying the widget's response token
+const ResponseField = "challenge"
+
+var problems = problem.Mappings{
+	{Err: ErrChallengeRequired, Status: http.StatusBadRequest, Code: "challenge_required"},
+	{Err: ErrChallengeFailed, Status: http.StatusForbidden, Code: "challenge_failed"},
+}
+
+// Requires a solved challenge before calling next. A nil verifier disables
+// the check
+func Require(v Verifier, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if v == nil {
+			next(w, req)
+			return
+		}
+
+		if err := v.Verify(req.Context(), req.FormValue(ResponseField), audit.ClientIP(req)); err != nil {
+			problems.Write(w, err)
+			return
+		}
+
+		next(w, req)
+	}
+}
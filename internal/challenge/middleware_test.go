@@ -0,0 +1,54 @@
+package challenge_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"weather-app/internal/challenge"
+)
+
+type mockVerifier struct {
+	VerifyFunc func(response string) error
+}
+
+func (m *mockVerifier) Verify(ctx context.Context, response, remoteIP string) error {
+	return m.VerifyFunc(response)
+}
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier challenge.Verifier
+		wantCode int
+	}{
+		{"disabled", nil, http.StatusOK},
+		{"passed", &mockVerifier{VerifyFunc: func(string) error { return nil }}, http.StatusOK},
+		{"missing", &mockVerifier{VerifyFunc: func(string) error { return challenge.ErrChallengeRequired }}, http.StatusBadRequest},
+		{"failed", &mockVerifier{VerifyFunc: func(string) error { return challenge.ErrChallengeFailed }}, http.StatusForbidden},
+		{"provider down", &mockVerifier{VerifyFunc: func(string) error { return errors.New("timeout") }}, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Set(challenge.ResponseField, "tok")
+
+			req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			h := challenge.Require(tt.verifier, func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			h(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected %d, got %d", tt.wantCode, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package challenge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrChallengeRequired = errors.New("challenge parameter is required")
+	ErrChallengeFailed   = errors.New("challenge verification failed")
+)
+
+// Checks a captcha-style response token produced by a widget in the browser
+type Verifier interface {
+	Verify(ctx context.Context, response, remoteIP string) error
+}
+
+// Verifies tokens with a siteverify endpoint. hCaptcha, Cloudflare Turnstile
+// and reCAPTCHA share the same protocol: a form POST of secret, response and
+// remoteip answered with {"success": bool}
+type SiteVerifier struct {
+	verifyURL string
+	secret    string
+	client    *http.Client
+}
+
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+func NewSiteVerifier(verifyURL, secret string, client *http.Client) *SiteVerifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &SiteVerifier{verifyURL: verifyURL, secret: secret, client: client}
+}
+
+func (v *SiteVerifier) Verify(ctx context.Context, response, remoteIP string) error {
+	if response == "" {
+		return ErrChallengeRequired
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.secret)
+	form.Set("response", response)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("error building challenge request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error verifying challenge: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("challenge provider returned %d", resp.StatusCode)
+	}
+
+	var result siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("error decoding challenge response: %w", err)
+	}
+
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrChallengeFailed, strings.Join(result.ErrorCodes, ","))
+	}
+
+	return nil
+}
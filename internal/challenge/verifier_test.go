@@ -0,0 +1,62 @@
+package challenge_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"weather-app/internal/challenge"
+)
+
+func TestSiteVerifier_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("secret") != "s3cret" || r.PostForm.Get("response") != "tok" || r.PostForm.Get("remoteip") != "192.0.2.1" {
+			t.Errorf("unexpected form: %v", r.PostForm)
+		}
+		w.Write([]byte(`{"success": true}`))
+	}))
+	defer server.Close()
+
+	v := challenge.NewSiteVerifier(server.URL, "s3cret", nil)
+
+	if err := v.Verify(context.Background(), "tok", "192.0.2.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestSiteVerifier_Failed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	}))
+	defer server.Close()
+
+	v := challenge.NewSiteVerifier(server.URL, "s3cret", nil)
+
+	if err := v.Verify(context.Background(), "tok", ""); !errors.Is(err, challenge.ErrChallengeFailed) {
+		t.Fatalf("expected ErrChallengeFailed, got %v", err)
+	}
+}
+
+func TestSiteVerifier_EmptyResponse(t *testing.T) {
+	v := challenge.NewSiteVerifier("http://unused", "s3cret", nil)
+
+	if err := v.Verify(context.Background(), "", ""); !errors.Is(err, challenge.ErrChallengeRequired) {
+		t.Fatalf("expected ErrChallengeRequired, got %v", err)
+	}
+}
+
+func TestSiteVerifier_ProviderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	v := challenge.NewSiteVerifier(server.URL, "s3cret", nil)
+
+	err := v.Verify(context.Background(), "tok", "")
+	if err == nil || errors.Is(err, challenge.ErrChallengeFailed) {
+		t.Fatalf("expected transport error, got %v", err)
+	}
+}
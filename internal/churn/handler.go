@@ -0,0 +1,201 @@
+package churn
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/mail/page_templates"
+	"weather-app/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Reported for unsubscriptions without a submitted reason
+	unspecifiedReason = "unspecified"
+
+	defaultReportPeriod = 90 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidReason = errors.New("reason parameter is invalid")
+	ErrInvalidPeriod = errors.New("from and to parameters are invalid")
+	ErrChurnNotFound = errors.New("unsubscription not found")
+)
+
+var problems = problem.Mappings{
+	{Err: ErrInvalidReason, Status: http.StatusBadRequest, Code: "invalid_reason"},
+	{Err: ErrInvalidPeriod, Status: http.StatusBadRequest, Code: "invalid_period"},
+	{Err: ErrChurnNotFound, Status: http.StatusNotFound, Code: "unsubscription_not_found"},
+}
+
+type ChurnRepositoryInterface interface {
+	SetReason(id uuid.UUID, reason string) error
+	CountByReason(from, to time.Time) ([]repository.ChurnCount, error)
+	CountByAge(from, to time.Time) ([]repository.ChurnCount, error)
+}
+
+type ChurnHandler struct {
+	repo ChurnRepositoryInterface
+}
+
+func NewHandler(repo ChurnRepositoryInterface) *ChurnHandler {
+	return &ChurnHandler{repo: repo}
+}
+
+// Receives the optional reason form from the unsubscribe page
+func (h *ChurnHandler) ReasonHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	}
+
+	id, err := uuid.Parse(strings.TrimPrefix(req.URL.Path, "/api/unsubscribe-reason/"))
+	if err != nil {
+		problems.Write(w, ErrChurnNotFound)
+		return
+	}
+
+	reason := req.FormValue("reason")
+	if !models.IsValidChurnReason(reason) {
+		problems.Write(w, ErrInvalidReason)
+		return
+	}
+
+	if err := h.repo.SetReason(id, reason); err != nil {
+		if repository.IsErrNotFound(err) {
+			problems.Write(w, ErrChurnNotFound)
+			return
+		}
+
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	html, err := page_templates.FormReasonThanksPage()
+	if err != nil {
+		log.Println(err.Error())
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(html))
+}
+
+type ReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+type AgeCount struct {
+	Days  string `json:"days"` // Subscription age bucket, e.g. "7-29"
+	Count int64  `json:"count"`
+}
+
+type Report struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Total    int64         `json:"total"`
+	ByReason []ReasonCount `json:"by_reason"`
+	ByAge    []AgeCount    `json:"by_age"`
+}
+
+// Aggregates unsubscriptions in [from, to). Both query parameters take a
+// date or RFC 3339 time, the default is the last 90 days
+func (h *ChurnHandler) ReportHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	from, to, err := parsePeriod(req.URL.Query().Get("from"), req.URL.Query().Get("to"), time.Now())
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	byReason, err := h.repo.CountByReason(from, to)
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	byAge, err := h.repo.CountByAge(from, to)
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	report := Report{From: from, To: to, ByReason: []ReasonCount{}}
+
+	for _, c := range byReason {
+		reason := c.Key
+		if reason == "" {
+			reason = unspecifiedReason
+		}
+
+		report.ByReason = append(report.ByReason, ReasonCount{Reason: reason, Count: c.Count})
+		report.Total += c.Count
+	}
+
+	// Every bucket is listed, in age order, so empty ones show as zero
+	ageCounts := make(map[string]int64, len(byAge))
+	for _, c := range byAge {
+		ageCounts[c.Key] = c.Count
+	}
+
+	for _, bucket := range repository.ChurnAgeBuckets {
+		report.ByAge = append(report.ByAge, AgeCount{Days: bucket, Count: ageCounts[bucket]})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
+
+func parsePeriod(fromValue, toValue string, now time.Time) (time.Time, time.Time, error) {
+	to := now
+	if toValue != "" {
+		t, err := parseTime(toValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidPeriod
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultReportPeriod)
+	if fromValue != "" {
+		t, err := parseTime(fromValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, ErrInvalidPeriod
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, ErrInvalidPeriod
+	}
+
+	return from, to, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.DateOnly, value)
+}
@@ -0,0 +1,151 @@
+package churn_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/churn"
+	"weather-app/internal/database/repository"
+
+	"github.com/google/uuid"
+)
+
+type mockChurnRepo struct {
+	SetReasonFunc     func(id uuid.UUID, reason string) error
+	CountByReasonFunc func(from, to time.Time) ([]repository.ChurnCount, error)
+	CountByAgeFunc    func(from, to time.Time) ([]repository.ChurnCount, error)
+}
+
+func (m *mockChurnRepo) SetReason(id uuid.UUID, reason string) error {
+	return m.SetReasonFunc(id, reason)
+}
+
+func (m *mockChurnRepo) CountByReason(from, to time.Time) ([]repository.ChurnCount, error) {
+	return m.CountByReasonFunc(from, to)
+}
+
+func (m *mockChurnRepo) CountByAge(from, to time.Time) ([]repository.ChurnCount, error) {
+	return m.CountByAgeFunc(from, to)
+}
+
+func postReason(h *churn.ChurnHandler, path, reason string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	form.Set("reason", reason)
+
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	h.ReasonHandler(w, req)
+
+	return w
+}
+
+func TestReasonHandler_Success(t *testing.T) {
+	id := uuid.New()
+
+	var gotID uuid.UUID
+	var gotReason string
+	h := churn.NewHandler(&mockChurnRepo{
+		SetReasonFunc: func(i uuid.UUID, reason string) error {
+			gotID, gotReason = i, reason
+			return nil
+		},
+	})
+
+	w := postReason(h, "/api/unsubscribe-reason/"+id.String(), "wrong_city")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if gotID != id || gotReason != "wrong_city" {
+		t.Errorf("unexpected arguments %s %q", gotID, gotReason)
+	}
+}
+
+func TestReasonHandler_InvalidReason(t *testing.T) {
+	h := churn.NewHandler(&mockChurnRepo{})
+
+	w := postReason(h, "/api/unsubscribe-reason/"+uuid.New().String(), "because")
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestReasonHandler_UnknownID(t *testing.T) {
+	h := churn.NewHandler(&mockChurnRepo{
+		SetReasonFunc: func(i uuid.UUID, reason string) error {
+			return fmt.Errorf("%w: churn not found", repository.ErrNotFound)
+		},
+	})
+
+	if w := postReason(h, "/api/unsubscribe-reason/not-a-uuid", "other"); w.Code != http.StatusNotFound {
+		t.Errorf("malformed id: expected 404, got %d", w.Code)
+	}
+	if w := postReason(h, "/api/unsubscribe-reason/"+uuid.New().String(), "other"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown id: expected 404, got %d", w.Code)
+	}
+}
+
+func TestReportHandler(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	h := churn.NewHandler(&mockChurnRepo{
+		CountByReasonFunc: func(from, to time.Time) ([]repository.ChurnCount, error) {
+			gotFrom, gotTo = from, to
+			return []repository.ChurnCount{{Key: "too_frequent", Count: 5}, {Key: "", Count: 3}}, nil
+		},
+		CountByAgeFunc: func(from, to time.Time) ([]repository.ChurnCount, error) {
+			return []repository.ChurnCount{{Key: "7-29", Count: 8}}, nil
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/admin/churn?from=2025-01-01&to=2025-02-01", nil)
+	w := httptest.NewRecorder()
+
+	h.ReportHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	if !gotFrom.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || !gotTo.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected period %s - %s", gotFrom, gotTo)
+	}
+
+	var report churn.Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Total != 8 {
+		t.Errorf("expected total 8, got %d", report.Total)
+	}
+	if len(report.ByReason) != 2 || report.ByReason[1].Reason != "unspecified" {
+		t.Errorf("unexpected reasons %+v", report.ByReason)
+	}
+	if len(report.ByAge) != len(repository.ChurnAgeBuckets) || report.ByAge[1].Days != "7-29" || report.ByAge[1].Count != 8 {
+		t.Errorf("unexpected ages %+v", report.ByAge)
+	}
+	if report.ByAge[0].Count != 0 {
+		t.Errorf("expected empty buckets to be zero, got %+v", report.ByAge[0])
+	}
+}
+
+func TestReportHandler_InvalidPeriod(t *testing.T) {
+	h := churn.NewHandler(&mockChurnRepo{})
+
+	req := httptest.NewRequest("GET", "/admin/churn?from=2025-02-01&to=2025-01-01", nil)
+	w := httptest.NewRecorder()
+
+	h.ReportHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,87 @@
+package content
+
+// Static text of update messages in one language
+type Messages struct {
+	Title             string // Formatted with the city
+	Intro             string
+	Outro             string
+	UnsubscribePrompt string
+	UnsubscribeLink   string
+
+	Temperature string
+	Conditions  string
+	Humidity    string
+	FeelsLike   string
+	Wind        string
+	Pressure    string
+	Sunrise     string
+	Sunset      string
+	Forecast    string
+	AirQuality  string
+
+	Range            string // Formatted with the low and high temperature
+	SpeedMetric      string
+	SpeedImperial    string
+	PressureUnit     string
+	AirQualityLevels [5]string // OpenWeatherMap AQI 1 to 5
+}
+
+var messages = map[string]Messages{
+	LanguageEnglish: {
+		Title:             "Weather update for %s",
+		Intro:             "Here's your latest forecast:",
+		Outro:             "Stay safe and dress appropriately for today's weather!",
+		UnsubscribePrompt: "Don’t want to receive updates?",
+		UnsubscribeLink:   "Unsubscribe here",
+
+		Temperature: "Temperature",
+		Conditions:  "Conditions",
+		Humidity:    "Humidity",
+		FeelsLike:   "Feels like",
+		Wind:        "Wind",
+		Pressure:    "Pressure",
+		Sunrise:     "Sunrise",
+		Sunset:      "Sunset",
+		Forecast:    "Next 24 hours",
+		AirQuality:  "Air quality",
+
+		Range:            "%s to %s",
+		SpeedMetric:      "m/s",
+		SpeedImperial:    "mph",
+		PressureUnit:     "hPa",
+		AirQualityLevels: [5]string{"good", "fair", "moderate", "poor", "very poor"},
+	},
+	LanguageUkrainian: {
+		Title:             "Оновлення погоди для %s",
+		Intro:             "Ваш свіжий прогноз:",
+		Outro:             "Бережіть себе та одягайтеся по погоді!",
+		UnsubscribePrompt: "Не хочете отримувати оновлення?",
+		UnsubscribeLink:   "Відпишіться тут",
+
+		Temperature: "Температура",
+		Conditions:  "Погода",
+		Humidity:    "Вологість",
+		FeelsLike:   "Відчувається як",
+		Wind:        "Вітер",
+		Pressure:    "Тиск",
+		Sunrise:     "Схід сонця",
+		Sunset:      "Захід сонця",
+		Forecast:    "Наступні 24 години",
+		AirQuality:  "Якість повітря",
+
+		Range:            "від %s до %s",
+		SpeedMetric:      "м/с",
+		SpeedImperial:    "миль/год",
+		PressureUnit:     "гПа",
+		AirQualityLevels: [5]string{"добра", "задовільна", "помірна", "погана", "дуже погана"},
+	},
+}
+
+// Falls back to English for unknown languages
+func MessagesFor(language string) Messages {
+	if m, ok := messages[language]; ok {
+		return m
+	}
+
+	return messages[LanguageEnglish]
+}
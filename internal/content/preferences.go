@@ -0,0 +1,161 @@
+package content
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"weather-app/internal/weather"
+)
+
+// Optional fields of update messages. Temperature, humidity and conditions
+// are always shown
+const (
+	FieldFeelsLike  = "feels_like"
+	FieldWind       = "wind"
+	FieldPressure   = "pressure"
+	FieldSun        = "sun" // Sunrise and sunset
+	FieldForecast   = "forecast"
+	FieldAirQuality = "air_quality"
+)
+
+// Optional fields in the order messages show them
+var Fields = []string{FieldFeelsLike, FieldWind, FieldPressure, FieldSun, FieldForecast, FieldAirQuality}
+
+const (
+	UnitsMetric   = "metric"   // °C and m/s
+	UnitsImperial = "imperial" // °F and mph
+)
+
+var Units = []string{UnitsMetric, UnitsImperial}
+
+const (
+	LanguageEnglish   = "en"
+	LanguageUkrainian = "uk"
+)
+
+// Languages with translated messages, also passed to the weather API
+var Languages = []string{LanguageEnglish, LanguageUkrainian}
+
+var (
+	ErrInvalidFields   = errors.New("fields parameter is invalid")
+	ErrInvalidUnits    = errors.New("units parameter is invalid")
+	ErrInvalidLanguage = errors.New("lang parameter is invalid")
+)
+
+// What a subscriber's update messages contain
+type Preferences struct {
+	Fields   []string `json:"fields"` // Subset of Fields, in Fields order
+	Units    string   `json:"units"`
+	Language string   `json:"lang"`
+}
+
+// Metric, English and no optional fields, the messages sent before
+// preferences existed
+func Default() Preferences {
+	return Preferences{Fields: []string{}, Units: UnitsMetric, Language: LanguageEnglish}
+}
+
+// Parses comma-separated fields, units and language. Empty values fall back
+// to defaults
+func Parse(fields, units, language string) (Preferences, error) {
+	prefs := Default()
+
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(Fields, field) {
+			return Preferences{}, ErrInvalidFields
+		}
+		if !slices.Contains(prefs.Fields, field) {
+			prefs.Fields = append(prefs.Fields, field)
+		}
+	}
+	prefs.Fields = ordered(prefs.Fields)
+
+	if units != "" {
+		if !slices.Contains(Units, units) {
+			return Preferences{}, ErrInvalidUnits
+		}
+		prefs.Units = units
+	}
+
+	if language != "" {
+		if !slices.Contains(Languages, language) {
+			return Preferences{}, ErrInvalidLanguage
+		}
+		prefs.Language = language
+	}
+
+	return prefs, nil
+}
+
+// Reads the "fields", "units" and "lang" form fields. Fields may be
+// comma-separated or repeated, as sent by checkboxes
+func ParseForm(req *http.Request) (Preferences, error) {
+	if err := req.ParseForm(); err != nil {
+		return Preferences{}, err
+	}
+
+	return Parse(strings.Join(req.Form["fields"], ","), req.FormValue("units"), req.FormValue("lang"))
+}
+
+// Restores preferences from database columns. Values no longer supported
+// fall back to defaults instead of failing deliveries
+func FromStored(fields, units, language string) Preferences {
+	prefs := Default()
+
+	for _, field := range strings.Split(fields, ",") {
+		if slices.Contains(Fields, field) && !slices.Contains(prefs.Fields, field) {
+			prefs.Fields = append(prefs.Fields, field)
+		}
+	}
+	prefs.Fields = ordered(prefs.Fields)
+
+	if slices.Contains(Units, units) {
+		prefs.Units = units
+	}
+	if slices.Contains(Languages, language) {
+		prefs.Language = language
+	}
+
+	return prefs
+}
+
+// Weather data beyond the current conditions the fields need
+func (p Preferences) WeatherExtras() weather.Extras {
+	return weather.Extras{
+		Forecast:   slices.Contains(p.Fields, FieldForecast),
+		AirQuality: slices.Contains(p.Fields, FieldAirQuality),
+	}
+}
+
+// Fields as stored in the database
+func (p Preferences) StoredFields() string {
+	return strings.Join(p.Fields, ",")
+}
+
+// Fills in empty units and language
+func (p Preferences) withDefaults() Preferences {
+	if p.Units == "" {
+		p.Units = UnitsMetric
+	}
+	if p.Language == "" {
+		p.Language = LanguageEnglish
+	}
+
+	return p
+}
+
+func ordered(fields []string) []string {
+	result := []string{}
+	for _, field := range Fields {
+		if slices.Contains(fields, field) {
+			result = append(result, field)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,72 @@
+package content_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"weather-app/internal/content"
+)
+
+func TestParse_Defaults(t *testing.T) {
+	prefs, err := content.Parse("", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(prefs.Fields) != 0 || prefs.Units != content.UnitsMetric || prefs.Language != content.LanguageEnglish {
+		t.Errorf("unexpected defaults %+v", prefs)
+	}
+}
+
+func TestParse_OrdersAndDeduplicatesFields(t *testing.T) {
+	prefs, err := content.Parse("air_quality, wind,feels_like,wind", "imperial", "uk")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if prefs.StoredFields() != "feels_like,wind,air_quality" || prefs.Units != "imperial" || prefs.Language != "uk" {
+		t.Errorf("unexpected preferences %+v", prefs)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		fields, units, language string
+		want                    error
+	}{
+		{"wind,tides", "", "", content.ErrInvalidFields},
+		{"", "kelvin", "", content.ErrInvalidUnits},
+		{"", "", "xx", content.ErrInvalidLanguage},
+	}
+
+	for _, tt := range tests {
+		if _, err := content.Parse(tt.fields, tt.units, tt.language); !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q, %q, %q): expected %v, got %v", tt.fields, tt.units, tt.language, tt.want, err)
+		}
+	}
+}
+
+func TestParseForm_RepeatedFields(t *testing.T) {
+	form := url.Values{"fields": {"sun", "pressure,wind"}, "units": {"metric"}}
+	req := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	prefs, err := content.ParseForm(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if prefs.StoredFields() != "wind,pressure,sun" {
+		t.Errorf("unexpected fields %v", prefs.Fields)
+	}
+}
+
+func TestFromStored_DropsUnsupportedValues(t *testing.T) {
+	prefs := content.FromStored("sun,uv_index,wind", "kelvin", "")
+
+	if prefs.StoredFields() != "wind,sun" || prefs.Units != content.UnitsMetric || prefs.Language != content.LanguageEnglish {
+		t.Errorf("unexpected preferences %+v", prefs)
+	}
+}
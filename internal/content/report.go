@@ -0,0 +1,135 @@
+package content
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"weather-app/internal/weather"
+)
+
+const mpsToMph = 2.236936
+
+type Line struct {
+	Label string
+	Value string
+}
+
+// Update message for one subscriber, in their language and units. Channels
+// lay it out in their own format
+type Report struct {
+	Title       string // "Weather update for Kyiv"
+	Temperature string // "21.5°C"
+	Degrees     float64
+	Scale       string // "C" or "F"
+	Description string
+	Humidity    string // "40%"
+	Details     []Line // Chosen fields
+	Text        Messages
+}
+
+func Render(city string, data weather.WeatherData, prefs Preferences) Report {
+	prefs = prefs.withDefaults()
+	text := MessagesFor(prefs.Language)
+	temperature := temperatureFormatter(prefs.Units)
+
+	report := Report{
+		Title:       fmt.Sprintf(text.Title, city),
+		Temperature: temperature(data.Temperature),
+		Degrees:     convertTemperature(data.Temperature, prefs.Units),
+		Scale:       "C",
+		Description: data.Description,
+		Humidity:    fmt.Sprintf("%d%%", data.Humidity),
+		Text:        text,
+	}
+	if prefs.Units == UnitsImperial {
+		report.Scale = "F"
+	}
+
+	add := func(label, value string) {
+		report.Details = append(report.Details, Line{Label: label, Value: value})
+	}
+
+	for _, field := range prefs.Fields {
+		switch field {
+		case FieldFeelsLike:
+			add(text.FeelsLike, temperature(data.FeelsLike))
+		case FieldWind:
+			if prefs.Units == UnitsImperial {
+				add(text.Wind, fmt.Sprintf("%.1f %s", data.WindSpeed*mpsToMph, text.SpeedImperial))
+			} else {
+				add(text.Wind, fmt.Sprintf("%.1f %s", data.WindSpeed, text.SpeedMetric))
+			}
+		case FieldPressure:
+			if data.Pressure != 0 {
+				add(text.Pressure, fmt.Sprintf("%d %s", data.Pressure, text.PressureUnit))
+			}
+		case FieldSun:
+			if data.Sunrise != 0 && data.Sunset != 0 {
+				add(text.Sunrise, localTime(data.Sunrise, data.UTCOffset))
+				add(text.Sunset, localTime(data.Sunset, data.UTCOffset))
+			}
+		case FieldForecast:
+			if f := data.Forecast; f != (weather.Forecast{}) {
+				add(text.Forecast, fmt.Sprintf(text.Range, temperature(f.Low), temperature(f.High))+", "+f.Description)
+			}
+		case FieldAirQuality:
+			if data.AirQuality >= 1 && data.AirQuality <= len(text.AirQualityLevels) {
+				add(text.AirQuality, text.AirQualityLevels[data.AirQuality-1])
+			}
+		}
+	}
+
+	return report
+}
+
+// Temperature, humidity, conditions and details as labeled lines
+func (r Report) Lines() []Line {
+	lines := []Line{
+		{Label: r.Text.Temperature, Value: r.Temperature},
+		{Label: r.Text.Humidity, Value: r.Humidity},
+		{Label: r.Text.Conditions, Value: r.Description},
+	}
+
+	return append(lines, r.Details...)
+}
+
+// Conditions, humidity and details in running text, e.g. "clear sky",
+// "humidity 40%"
+func (r Report) Brief() []string {
+	parts := []string{r.Description, strings.ToLower(r.Text.Humidity) + " " + r.Humidity}
+	for _, line := range r.Details {
+		parts = append(parts, strings.ToLower(line.Label)+" "+line.Value)
+	}
+
+	return parts
+}
+
+// One line summary, e.g. "21.5°C, clear sky, humidity 40%"
+func (r Report) Summary() string {
+	return r.Temperature + ", " + strings.Join(r.Brief(), ", ")
+}
+
+func temperatureFormatter(units string) func(celsius float64) string {
+	if units == UnitsImperial {
+		return func(celsius float64) string {
+			return fmt.Sprintf("%.1f°F", convertTemperature(celsius, units))
+		}
+	}
+
+	return func(celsius float64) string {
+		return fmt.Sprintf("%.1f°C", celsius)
+	}
+}
+
+func convertTemperature(celsius float64, units string) float64 {
+	if units == UnitsImperial {
+		return celsius*9/5 + 32
+	}
+
+	return celsius
+}
+
+// "HH:MM" in the city's own time
+func localTime(unix int64, utcOffset int) string {
+	return time.Unix(unix, 0).In(time.FixedZone("", utcOffset)).Format("15:04")
+}
@@ -0,0 +1,113 @@
+package content_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"weather-app/internal/content"
+	"weather-app/internal/weather"
+)
+
+var testWeather = weather.WeatherData{
+	Temperature: 20,
+	Humidity:    40,
+	Description: "clear sky",
+	FeelsLike:   18.5,
+	Pressure:    1012,
+	WindSpeed:   4,
+	Sunrise:     1700000000, // 22:13 UTC
+	Sunset:      1700030000,
+	UTCOffset:   2 * 3600,
+	Forecast:    weather.Forecast{Low: 10, High: 25, Description: "light rain"},
+	AirQuality:  2,
+}
+
+func TestRender_Default(t *testing.T) {
+	report := content.Render("Kyiv", testWeather, content.Default())
+
+	if report.Title != "Weather update for Kyiv" {
+		t.Errorf("unexpected title %q", report.Title)
+	}
+	if got := report.Summary(); got != "20.0°C, clear sky, humidity 40%" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if len(report.Lines()) != 3 {
+		t.Errorf("expected only the base lines, got %+v", report.Lines())
+	}
+}
+
+func TestRender_AllFields(t *testing.T) {
+	prefs, _ := content.Parse(strings.Join(content.Fields, ","), "", "")
+	report := content.Render("Kyiv", testWeather, prefs)
+
+	var lines []string
+	for _, line := range report.Lines() {
+		lines = append(lines, line.Label+": "+line.Value)
+	}
+
+	expected := []string{
+		"Temperature: 20.0°C",
+		"Humidity: 40%",
+		"Conditions: clear sky",
+		"Feels like: 18.5°C",
+		"Wind: 4.0 m/s",
+		"Pressure: 1012 hPa",
+		"Sunrise: 00:13",
+		"Sunset: 08:33",
+		"Next 24 hours: 10.0°C to 25.0°C, light rain",
+		"Air quality: fair",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected lines:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestRender_ImperialUkrainian(t *testing.T) {
+	prefs, _ := content.Parse("feels_like,wind", "imperial", "uk")
+	report := content.Render("Київ", testWeather, prefs)
+
+	if report.Title != "Оновлення погоди для Київ" {
+		t.Errorf("unexpected title %q", report.Title)
+	}
+	if report.Degrees != 68 || report.Scale != "F" {
+		t.Errorf("unexpected temperature %v%s", report.Degrees, report.Scale)
+	}
+	if got := report.Summary(); got != "68.0°F, clear sky, вологість 40%, відчувається як 65.3°F, вітер 8.9 миль/год" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
+
+func TestRender_SkipsMissingData(t *testing.T) {
+	prefs, _ := content.Parse("pressure,sun,forecast,air_quality", "", "")
+	report := content.Render("Kyiv", weather.WeatherData{Temperature: 20}, prefs)
+
+	if len(report.Details) != 0 {
+		t.Errorf("expected no details without data, got %+v", report.Details)
+	}
+}
+
+func TestNewValues(t *testing.T) {
+	prefs, _ := content.Parse("wind,sun,forecast", "imperial", "")
+	body, err := json.Marshal(content.NewValues(testWeather, prefs))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var values map[string]any
+	if err := json.Unmarshal(body, &values); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if values["units"] != "imperial" || values["temperature"] != 68.0 {
+		t.Errorf("unexpected values %s", body)
+	}
+	if _, ok := values["feels_like"]; ok {
+		t.Errorf("expected feels_like to be left out, got %s", body)
+	}
+	if values["sunrise"] != "2023-11-15T00:13:20+02:00" {
+		t.Errorf("expected sunrise in local time, got %v", values["sunrise"])
+	}
+	if forecast, _ := values["forecast"].(map[string]any); forecast["high"] != 77.0 {
+		t.Errorf("expected converted forecast, got %v", values["forecast"])
+	}
+}
@@ -0,0 +1,74 @@
+package content
+
+import (
+	"time"
+	"weather-app/internal/weather"
+)
+
+// Weather for machine readable payloads. Temperatures and wind speed are in
+// Units, fields the subscriber didn't choose are left out
+type Values struct {
+	Units       string   `json:"units"`
+	Language    string   `json:"lang"`
+	Temperature float64  `json:"temperature"`
+	Humidity    int      `json:"humidity"`
+	Description string   `json:"description"`
+	FeelsLike   *float64 `json:"feels_like,omitempty"`
+	WindSpeed   *float64 `json:"wind_speed,omitempty"`
+	Pressure    *int     `json:"pressure,omitempty"` // hPa in both unit systems
+
+	Sunrise *time.Time `json:"sunrise,omitempty"` // In the city's UTC offset
+	Sunset  *time.Time `json:"sunset,omitempty"`
+
+	Forecast   *weather.Forecast `json:"forecast,omitempty"`
+	AirQuality *int              `json:"air_quality,omitempty"` // 1 (good) to 5 (very poor)
+}
+
+func NewValues(data weather.WeatherData, prefs Preferences) Values {
+	prefs = prefs.withDefaults()
+
+	values := Values{
+		Units:       prefs.Units,
+		Language:    prefs.Language,
+		Temperature: convertTemperature(data.Temperature, prefs.Units),
+		Humidity:    data.Humidity,
+		Description: data.Description,
+	}
+
+	for _, field := range prefs.Fields {
+		switch field {
+		case FieldFeelsLike:
+			feelsLike := convertTemperature(data.FeelsLike, prefs.Units)
+			values.FeelsLike = &feelsLike
+		case FieldWind:
+			speed := data.WindSpeed
+			if prefs.Units == UnitsImperial {
+				speed *= mpsToMph
+			}
+			values.WindSpeed = &speed
+		case FieldPressure:
+			if data.Pressure != 0 {
+				values.Pressure = &data.Pressure
+			}
+		case FieldSun:
+			if data.Sunrise != 0 && data.Sunset != 0 {
+				zone := time.FixedZone("", data.UTCOffset)
+				sunrise, sunset := time.Unix(data.Sunrise, 0).In(zone), time.Unix(data.Sunset, 0).In(zone)
+				values.Sunrise, values.Sunset = &sunrise, &sunset
+			}
+		case FieldForecast:
+			if data.Forecast != (weather.Forecast{}) {
+				forecast := data.Forecast
+				forecast.Low = convertTemperature(forecast.Low, prefs.Units)
+				forecast.High = convertTemperature(forecast.High, prefs.Units)
+				values.Forecast = &forecast
+			}
+		case FieldAirQuality:
+			if data.AirQuality != 0 {
+				values.AirQuality = &data.AirQuality
+			}
+		}
+	}
+
+	return values
+}
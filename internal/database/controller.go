@@ -52,7 +52,10 @@ func InitDB() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to DB: %w", err)
 	}
 
-	err = db.AutoMigrate(&models.User{}, &models.Subscription{}, &models.Token{})
+	err = db.AutoMigrate(&models.User{}, &models.Subscription{}, &models.Token{}, &models.SubscriptionEvent{},
+		&models.Delivery{}, &models.Suppression{}, &models.IdempotencyKey{}, &models.Churn{}, &models.WebhookEndpoint{}, &models.WebhookDelivery{},
+		&models.TelegramChat{}, &models.TeamSubscription{}, &models.PhoneNumber{}, &models.PushSubscription{},
+		&models.APIKey{}, &models.APIKeyUsage{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate DB: %w", err)
 	}
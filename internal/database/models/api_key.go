@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Credential of a /api/weather client. Only the keyed hash of the key is
+// stored, the key itself is shown once when it is issued
+type APIKey struct {
+	ID      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name    string    // Label shown to admins, e.g. the client
+	KeyHash string    `gorm:"not null;uniqueIndex"`
+	Prefix  string    `gorm:"not null"` // Start of the key, tells keys apart without revealing them
+
+	RateLimit  int `gorm:"not null"`           // Requests per minute
+	DailyQuota int `gorm:"not null;default:0"` // Requests per UTC day, 0 is unlimited
+
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	k.ID = uuid.New()
+	return nil
+}
+
+// Requests made with a key in one UTC day
+type APIKeyUsage struct {
+	KeyID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Day      time.Time `gorm:"type:date;primaryKey"`
+	Requests int64     `gorm:"not null;default:0"`
+}
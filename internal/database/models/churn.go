@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ChurnReasonTooFrequent = "too_frequent"
+	ChurnReasonWrongCity   = "wrong_city"
+	ChurnReasonNotUseful   = "not_useful"
+	ChurnReasonInaccurate  = "inaccurate"
+	ChurnReasonOther       = "other"
+)
+
+var ChurnReasons = []string{
+	ChurnReasonTooFrequent,
+	ChurnReasonWrongCity,
+	ChurnReasonNotUseful,
+	ChurnReasonInaccurate,
+	ChurnReasonOther,
+}
+
+// Anonymous record of an unsubscription. Nothing links it back to the user,
+// the random ID is only known to the unsubscribe page that reports the reason
+type Churn struct {
+	ID               uuid.UUID `gorm:"type:uuid;primaryKey"`
+	City             string    `gorm:"not null"`
+	Frequency        string    `gorm:"not null"`
+	SubscriptionDays int       `gorm:"not null"` // Whole days between subscribing and leaving
+	Reason           string    // Empty until the user picks one
+	CreatedAt        time.Time `gorm:"index"` // Day of leaving, midnight UTC
+}
+
+func (c *Churn) BeforeCreate(tx *gorm.DB) error {
+	c.ID = uuid.New()
+	return nil
+}
+
+func IsValidChurnReason(reason string) bool {
+	for _, r := range ChurnReasons {
+		if r == reason {
+			return true
+		}
+	}
+
+	return false
+}
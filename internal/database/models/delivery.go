@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// One sent weather update, kept as send history
+type Delivery struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID      uuid.UUID  `gorm:"type:uuid;index;not null"` // Unset for push and team channels
+	RecipientID *uuid.UUID `gorm:"type:uuid;index"`          // Push or team subscription, for channels without a user
+	Channel     string     `gorm:"not null"`                 // "email", "telegram", "sms", "push", "slack", "webhook"
+	Frequency   string     `gorm:"not null"`
+	City        string     `gorm:"not null"`
+	StatusCode  int        // Provider response status
+	SentAt      time.Time  `gorm:"index"`
+}
+
+func (d *Delivery) BeforeCreate(tx *gorm.DB) error {
+	d.ID = uuid.New()
+	return nil
+}
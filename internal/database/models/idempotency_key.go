@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stored outcome of a request sent with an Idempotency-Key header. A key is
+// reserved before the request runs, CompletedAt stays nil while it is in flight
+// and ExpiresAt is the end of the reservation's lease until then
+type IdempotencyKey struct {
+	Key         string `gorm:"primaryKey"`
+	RequestHash string `gorm:"not null"`
+
+	// New for every reservation. A request only completes or releases its own,
+	// not one that took the key over after its lease ran out
+	ReservationID uuid.UUID `gorm:"type:uuid"`
+
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
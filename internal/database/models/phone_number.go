@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Phone number of a subscriber. Once confirmed with a one-time code it gets
+// the same scheduled updates as the subscriber's inbox by SMS
+type PhoneNumber struct {
+	UserID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Number string    `gorm:"not null"` // E.164, e.g. "+380501234567"
+
+	CodeHash      string     // Keyed hash of the pending one-time code
+	CodeExpiresAt *time.Time // Nil once confirmed
+	Attempts      int        `gorm:"not null;default:0"` // Wrong codes entered for the pending code
+
+	ConfirmedAt *time.Time // Nil until the code is entered
+	CreatedAt   time.Time
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Browser push subscription with its own city and schedule. The browser's
+// permission prompt is the consent, so there is no user and no confirmation
+type PushSubscription struct {
+	ID       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Endpoint string    `gorm:"uniqueIndex;not null"` // Push service URL, unguessable
+	P256dh   string    `gorm:"not null"`             // Browser public key, base64url
+	Auth     string    `gorm:"not null"`             // Browser auth secret, base64url
+
+	City      string `gorm:"not null"`
+	Frequency string `gorm:"not null"`                 // See schedule.Frequencies
+	Timezone  string `gorm:"not null;default:'UTC'"`   // IANA timezone name
+	SendTime  string `gorm:"not null;default:'12:00'"` // Local "HH:MM" for daily updates
+
+	Weekday       int    `gorm:"not null;default:0"` // 0 is Sunday, used by "weekly"
+	IntervalHours int    `gorm:"not null;default:0"` // Used by "every_n_hours"
+	CronExpr      string // Used by "cron"
+
+	// Update content, see content.Preferences
+	ContentFields string // Comma-separated content.Fields
+	Units         string `gorm:"not null;default:'metric'"`
+	Language      string `gorm:"not null;default:'en'"`
+
+	CreatedAt time.Time
+}
+
+func (s *PushSubscription) BeforeCreate(tx *gorm.DB) error {
+	s.ID = uuid.New()
+	return nil
+}
@@ -11,7 +11,22 @@ type Subscription struct {
 	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
 	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
 	City      string    `gorm:"not null"`
-	Frequency string    `gorm:"not null"` // "hourly" or "daily"
+	Frequency string    `gorm:"not null"`                 // See schedule.Frequencies
+	Timezone  string    `gorm:"not null;default:'UTC'"`   // IANA timezone name
+	SendTime  string    `gorm:"not null;default:'12:00'"` // Local "HH:MM" for daily updates
+
+	Weekday       int    `gorm:"not null;default:0"` // 0 is Sunday, used by "weekly"
+	IntervalHours int    `gorm:"not null;default:0"` // Used by "every_n_hours"
+	CronExpr      string // Used by "cron"
+
+	// Update content, see content.Preferences
+	ContentFields string // Comma-separated content.Fields
+	Units         string `gorm:"not null;default:'metric'"`
+	Language      string `gorm:"not null;default:'en'"`
+
+	PausedAt    *time.Time // Set while the subscription is paused
+	PausedUntil *time.Time // Nil pauses indefinitely
+
 	CreatedAt time.Time
 }
 
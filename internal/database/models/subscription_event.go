@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	EventSubscribed   = "subscribed"
+	EventConfirmed    = "confirmed"
+	EventUnsubscribed = "unsubscribed"
+	EventPaused       = "paused"
+	EventResumed      = "resumed"
+	EventErased       = "erased"
+
+	EventEmailChangeRequested = "email_change_requested"
+	EventEmailChanged         = "email_changed"
+
+	EventPreferencesChanged = "preferences_changed"
+
+	EventTelegramLinked   = "telegram_linked"
+	EventTelegramUnlinked = "telegram_unlinked"
+
+	EventPhoneAdded     = "phone_added" // Code sent, not confirmed yet
+	EventPhoneConfirmed = "phone_confirmed"
+	EventPhoneRemoved   = "phone_removed"
+)
+
+// Append-only record of consent related actions. Rows outlive the user they
+// describe, so the email is stored directly. The only update ever made is
+// anonymization on erasure requests
+type SubscriptionEvent struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	Type      string     `gorm:"not null"`
+	Email     string     `gorm:"index;not null"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index"`
+	TokenID   *uuid.UUID `gorm:"type:uuid"` // Token used for confirmation
+	IP        string
+	UserAgent string
+	Details   string    // Free-form context, e.g. city and frequency on subscribe
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (e *SubscriptionEvent) BeforeCreate(tx *gorm.DB) error {
+	e.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	SuppressionReasonErasure = "erasure"
+)
+
+// Tombstone for an address that must never be mailed again. Only a hash of
+// the address is kept
+type Suppression struct {
+	EmailHash string `gorm:"primaryKey"`
+	Reason    string `gorm:"not null"`
+	CreatedAt time.Time
+}
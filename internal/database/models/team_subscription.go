@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	TeamChannelSlack   = "slack"   // Slack incoming webhook, gets Block Kit messages
+	TeamChannelWebhook = "webhook" // Any HTTPS endpoint, gets signed JSON
+)
+
+// Weather updates for a team, posted to a channel instead of a mailbox.
+// Created by admins, so there is no user and no confirmation
+type TeamSubscription struct {
+	ID      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name    string    // Label shown to admins, e.g. the team
+	Channel string    `gorm:"not null;index"` // TeamChannelSlack or TeamChannelWebhook
+	URL     string    `gorm:"not null"`
+	Secret  string    // HMAC key of TeamChannelWebhook payloads
+
+	City      string `gorm:"not null"`
+	Frequency string `gorm:"not null"`                 // See schedule.Frequencies
+	Timezone  string `gorm:"not null;default:'UTC'"`   // IANA timezone name
+	SendTime  string `gorm:"not null;default:'12:00'"` // Local "HH:MM" for daily updates
+
+	Weekday       int    `gorm:"not null;default:0"` // 0 is Sunday, used by "weekly"
+	IntervalHours int    `gorm:"not null;default:0"` // Used by "every_n_hours"
+	CronExpr      string // Used by "cron"
+
+	// Update content, see content.Preferences
+	ContentFields string // Comma-separated content.Fields
+	Units         string `gorm:"not null;default:'metric'"`
+	Language      string `gorm:"not null;default:'en'"`
+
+	CreatedAt time.Time
+}
+
+func (s *TeamSubscription) BeforeCreate(tx *gorm.DB) error {
+	s.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Telegram chat linked to a subscriber. It gets the same scheduled updates
+// as the subscriber's inbox
+type TelegramChat struct {
+	ChatID   int64     `gorm:"primaryKey;autoIncrement:false"`
+	UserID   uuid.UUID `gorm:"type:uuid;index;not null"`
+	Username string
+	LinkedAt time.Time
+}
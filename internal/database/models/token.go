@@ -10,17 +10,30 @@ import (
 const (
 	TokenTypeConfirm     = "confirm"
 	TokenTypeUnsubscribe = "unsubscribe"
+	TokenTypeEmailChange = "email_change"
+	TokenTypeTelegram    = "telegram" // Deep-link parameter that links a Telegram chat
 )
 
+// How long the new address has to confirm an email change
+const EmailChangeTokenTTL = 24 * time.Hour
+
 type Token struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
-	Value     string    `gorm:"uniqueIndex;not null"`
-	Type      string    `gorm:"not null"` // "confirm", "unsubscribe"
-	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
-	CreatedAt time.Time
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Hash       string    `gorm:"uniqueIndex"` // Keyed hash of the value, see tokens.Hasher
+	LegacyHash string    `gorm:"index"`       // Hash of a value issued before hashing, keeps old links working
+	Type       string    `gorm:"not null"`    // "confirm", "unsubscribe", "email_change", "telegram"
+	UserID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	NewEmail   string    // Address waiting for verification, email_change tokens only
+	CreatedAt  time.Time
+
+	// Plaintext value, only known right after creation and never stored
+	Value string `gorm:"-"`
 }
 
 func (s *Token) BeforeCreate(tx *gorm.DB) error {
-	s.ID = uuid.New()
+	// Value is derived from the ID, so it may be assigned before create
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
 	return nil
 }
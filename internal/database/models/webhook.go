@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	WebhookStatusPending   = "pending"
+	WebhookStatusSucceeded = "succeeded"
+	WebhookStatusFailed    = "failed" // Out of retries
+)
+
+// Receiver of subscription lifecycle events
+type WebhookEndpoint struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	URL       string    `gorm:"not null"`
+	Secret    string    `gorm:"not null"` // HMAC key for signatures, kept in plain text to sign with
+	Events    string    // Comma-separated event types, empty means all
+	CreatedAt time.Time
+}
+
+func (e *WebhookEndpoint) BeforeCreate(tx *gorm.DB) error {
+	e.ID = uuid.New()
+	return nil
+}
+
+// One event sent, or to be sent, to one endpoint
+type WebhookDelivery struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
+	EndpointID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	EventID        uuid.UUID `gorm:"type:uuid;not null"` // Same for all endpoints and replays of an event
+	EventType      string    `gorm:"not null"`
+	UserID         uuid.UUID `gorm:"type:uuid;index"`
+	Payload        []byte    `gorm:"not null"`
+	Status         string    `gorm:"not null;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int       `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time `gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time `gorm:"index"`
+	DeliveredAt    *time.Time
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	d.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"weather-app/internal/database/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type APIKeyRepository struct {
+	*BaseRepository
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+func (r *APIKeyRepository) Create(key *models.APIKey) error {
+	if err := r.db.Create(key).Error; err != nil {
+		return HandleDBError(err, "api key")
+	}
+
+	return nil
+}
+
+// Revoked keys included, newest last
+func (r *APIKeyRepository) List() ([]models.APIKey, error) {
+	var keys []models.APIKey
+
+	if err := r.db.Order("created_at ASC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Active key with the given hash. Returns ErrNotFound for unknown and revoked keys
+func (r *APIKeyRepository) GetActiveByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+
+	if err := r.db.Where("key_hash = ? AND revoked_at IS NULL", hash).First(&key).Error; err != nil {
+		return nil, HandleDBError(err, "api key")
+	}
+
+	return &key, nil
+}
+
+// Keeps the key and its usage for the record. Returns ErrNotFound for
+// unknown and already revoked keys
+func (r *APIKeyRepository) Revoke(id uuid.UUID, at time.Time) error {
+	result := r.db.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return HandleDBError(result.Error, "api key")
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: api key not found", ErrNotFound)
+	}
+
+	return nil
+}
+
+// Counts a request against the key's day and returns the day's count
+func (r *APIKeyRepository) IncrementUsage(keyID uuid.UUID, day time.Time) (int64, error) {
+	usage := models.APIKeyUsage{KeyID: keyID, Day: day, Requests: 1}
+
+	err := r.db.Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key_id"}, {Name: "day"}},
+			DoUpdates: clause.Assignments(map[string]any{"requests": gorm.Expr("api_key_usages.requests + 1")}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "requests"}}},
+	).Create(&usage).Error
+	if err != nil {
+		return 0, HandleDBError(err, "api key usage")
+	}
+
+	return usage.Requests, nil
+}
+
+// Daily counts of the key since from, newest first
+func (r *APIKeyRepository) ListUsage(keyID uuid.UUID, from time.Time) ([]models.APIKeyUsage, error) {
+	var usage []models.APIKeyUsage
+
+	err := r.db.Where("key_id = ? AND day >= ?", keyID, from).
+		Order("day DESC").
+		Find(&usage).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api key usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// Requests of every key on day
+func (r *APIKeyRepository) UsageOn(day time.Time) (map[uuid.UUID]int64, error) {
+	var usage []models.APIKeyUsage
+
+	if err := r.db.Where("day = ?", day).Find(&usage).Error; err != nil {
+		return nil, fmt.Errorf("failed to get api key usage: %w", err)
+	}
+
+	counts := make(map[uuid.UUID]int64, len(usage))
+	for _, u := range usage {
+		counts[u.KeyID] = u.Requests
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"time"
+	"weather-app/internal/content"
+	"weather-app/internal/schedule"
+
+	"github.com/google/uuid"
+)
+
+// Someone to notify through a channel other than email, with the schedule of
+// the subscription they follow
+type ChannelRecipient struct {
+	UserID        uuid.UUID // Unset for push and team channels
+	RecipientID   uuid.UUID // Push or team subscription ID, unset for channels of users
+	Target        string    // Channel address, e.g. a Telegram chat ID or a webhook URL
+	Secret        string    // Signing key of channels that sign payloads
+	City          string
+	Timezone      string
+	SendTime      string
+	Weekday       int
+	IntervalHours int
+	CronExpr      string
+
+	ContentFields string
+	Units         string
+	Language      string
+}
+
+func (r ChannelRecipient) Schedule(frequency string) schedule.Schedule {
+	return schedule.Schedule{
+		Frequency:     frequency,
+		Timezone:      r.Timezone,
+		SendTime:      r.SendTime,
+		Weekday:       time.Weekday(r.Weekday),
+		IntervalHours: r.IntervalHours,
+		CronExpr:      r.CronExpr,
+	}
+}
+
+func (r ChannelRecipient) Preferences() content.Preferences {
+	return content.FromStored(r.ContentFields, r.Units, r.Language)
+}
+
+const subscriptionScheduleColumns = "subscriptions.city, subscriptions.timezone, subscriptions.send_time, " +
+	"subscriptions.weekday, subscriptions.interval_hours, subscriptions.cron_expr, " +
+	"subscriptions.content_fields, subscriptions.units, subscriptions.language"
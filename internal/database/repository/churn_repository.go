@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"weather-app/internal/database/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ChurnRepository struct {
+	*BaseRepository
+}
+
+func NewChurnRepository(db *gorm.DB) *ChurnRepository {
+	return &ChurnRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Stores the reason for a churn record. The first reason sticks, returns
+// ErrNotFound for unknown IDs and records that already have one
+func (r *ChurnRepository) SetReason(id uuid.UUID, reason string) error {
+	result := r.db.Model(&models.Churn{}).
+		Where("id = ? AND (reason IS NULL OR reason = '')", id).
+		Update("reason", reason)
+	if result.Error != nil {
+		return HandleDBError(result.Error, "churn")
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: churn not found", ErrNotFound)
+	}
+
+	return nil
+}
+
+// Truncates created_at of records written before only the day was stored
+func (r *ChurnRepository) MigrateCreatedAtToDate() error {
+	result := r.db.Exec(`UPDATE churns
+		SET created_at = date_trunc('day', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+		WHERE created_at <> date_trunc('day', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'`)
+	if result.Error != nil {
+		return fmt.Errorf("failed to truncate churn times: %w", result.Error)
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Truncated %d churn records to their day\n", result.RowsAffected)
+	}
+
+	return nil
+}
+
+type ChurnCount struct {
+	Key   string
+	Count int64
+}
+
+// Subscription age buckets in days, upper bound exclusive
+const churnAgeBuckets = `CASE
+	WHEN subscription_days < 7 THEN '0-6'
+	WHEN subscription_days < 30 THEN '7-29'
+	WHEN subscription_days < 90 THEN '30-89'
+	WHEN subscription_days < 365 THEN '90-364'
+	ELSE '365+'
+END`
+
+var ChurnAgeBuckets = []string{"0-6", "7-29", "30-89", "90-364", "365+"}
+
+// Counts churn records created in [from, to), grouped by reason
+func (r *ChurnRepository) CountByReason(from, to time.Time) ([]ChurnCount, error) {
+	return r.countBy("reason", from, to)
+}
+
+// Counts churn records created in [from, to), grouped by ChurnAgeBuckets
+func (r *ChurnRepository) CountByAge(from, to time.Time) ([]ChurnCount, error) {
+	return r.countBy(churnAgeBuckets, from, to)
+}
+
+func (r *ChurnRepository) countBy(expr string, from, to time.Time) ([]ChurnCount, error) {
+	var counts []ChurnCount
+
+	err := r.db.Model(&models.Churn{}).
+		Select(expr+" AS key, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group("key").
+		Order("count DESC").
+		Scan(&counts).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to count churn: %w", err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"weather-app/internal/database/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DeliveryRepository struct {
+	*BaseRepository
+}
+
+func NewDeliveryRepository(db *gorm.DB) *DeliveryRepository {
+	return &DeliveryRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+func (r *DeliveryRepository) Create(delivery *models.Delivery) error {
+	if err := r.db.Create(delivery).Error; err != nil {
+		return HandleDBError(err, "delivery")
+	}
+
+	return nil
+}
+
+// Moves the subscription ID of push and team deliveries, recorded as user_id
+// before recipient_id existed, to recipient_id. Safe to run on every start
+func (r *DeliveryRepository) MigrateRecipientIDs() error {
+	result := r.db.Exec(`UPDATE deliveries SET recipient_id = user_id, user_id = ?
+		WHERE channel IN ? AND recipient_id IS NULL`,
+		uuid.Nil, []string{"push", models.TeamChannelSlack, models.TeamChannelWebhook})
+	if result.Error != nil {
+		return fmt.Errorf("failed to migrate delivery recipients: %w", result.Error)
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Moved %d push and team deliveries to recipient IDs\n", result.RowsAffected)
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"fmt"
+	"weather-app/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// Stores subscription audit events. Intentionally exposes no update or delete
+type EventRepository struct {
+	*BaseRepository
+}
+
+func NewEventRepository(db *gorm.DB) *EventRepository {
+	return &EventRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+func (r *EventRepository) Create(event *models.SubscriptionEvent) error {
+	if err := r.db.Create(event).Error; err != nil {
+		return HandleDBError(err, "subscription event")
+	}
+
+	return nil
+}
+
+// Returns events for the email, oldest first
+func (r *EventRepository) ListByEmail(email string) ([]models.SubscriptionEvent, error) {
+	if email == "" {
+		return nil, fmt.Errorf("%w: email is required", ErrInvalidInput)
+	}
+
+	var events []models.SubscriptionEvent
+
+	err := r.db.Where("email = ?", email).Order("created_at ASC").Find(&events).Error
+	if err != nil {
+		return nil, HandleDBError(err, "subscription event")
+	}
+
+	return events, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"weather-app/internal/database/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	*BaseRepository
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Claims key for a new request until now+lease. When the key is already
+// taken, the existing record is returned with reserved false. Expired keys and
+// reservations past their lease can be claimed again
+func (r *IdempotencyRepository) Reserve(key, requestHash string, now time.Time, lease time.Duration) (*models.IdempotencyKey, bool, error) {
+	err := r.db.Where("key = ? AND expires_at <= ?", key, now).Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to clear expired idempotency key: %w", err)
+	}
+
+	record := models.IdempotencyKey{
+		Key:           key,
+		RequestHash:   requestHash,
+		ReservationID: uuid.New(),
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(lease),
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return nil, false, HandleDBError(result.Error, "idempotency key")
+	}
+
+	if result.RowsAffected == 1 {
+		return &record, true, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := r.db.Where("key = ?", key).First(&existing).Error; err != nil {
+		return nil, false, HandleDBError(err, "idempotency key")
+	}
+
+	return &existing, false, nil
+}
+
+// Stores the response of a reserved request and keeps it until expiresAt.
+// Returns ErrNotFound when the reservation was taken over by another request
+func (r *IdempotencyRepository) Complete(key string, reservationID uuid.UUID, statusCode int, contentType string, body []byte, expiresAt time.Time) error {
+	result := r.db.Model(&models.IdempotencyKey{}).
+		Where("key = ? AND reservation_id = ?", key, reservationID).
+		Updates(map[string]any{
+			"status_code":  statusCode,
+			"content_type": contentType,
+			"body":         body,
+			"completed_at": time.Now(),
+			"expires_at":   expiresAt,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: idempotency key reservation was taken over", ErrNotFound)
+	}
+
+	return nil
+}
+
+// Frees a reserved key, so the request can be retried. A reservation that was
+// taken over stays
+func (r *IdempotencyRepository) Release(key string, reservationID uuid.UUID) error {
+	err := r.db.Where("key = ? AND reservation_id = ?", key, reservationID).Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Deletes keys past their TTL. Returns number of deleted keys
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/tokens"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Everything stored about one subscriber
+type UserData struct {
+	User          models.User
+	Subscriptions []models.Subscription
+	Tokens        []models.Token
+	Deliveries    []models.Delivery
+	Events        []models.SubscriptionEvent
+	TelegramChats []models.TelegramChat
+	PhoneNumbers  []models.PhoneNumber
+}
+
+type PrivacyRepository struct {
+	*BaseRepository
+	hasher *tokens.Hasher
+}
+
+func NewPrivacyRepository(db *gorm.DB, hasher *tokens.Hasher) *PrivacyRepository {
+	return &PrivacyRepository{
+		BaseRepository: NewBaseRepository(db),
+		hasher:         hasher,
+	}
+}
+
+// Unkeyed hash tombstones were stored with before, see UserRepository.IsSuppressed
+func legacyEmailHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *PrivacyRepository) ExportUserData(userID uuid.UUID) (*UserData, error) {
+	var data UserData
+
+	if err := r.db.Where("id = ?", userID).First(&data.User).Error; err != nil {
+		return nil, HandleDBError(err, "user")
+	}
+
+	if err := r.db.Where("user_id = ?", userID).Find(&data.Subscriptions).Error; err != nil {
+		return nil, HandleDBError(err, "subscription")
+	}
+
+	if err := r.db.Where("user_id = ?", userID).Find(&data.Tokens).Error; err != nil {
+		return nil, HandleDBError(err, "token")
+	}
+
+	if err := r.db.Where("user_id = ?", userID).Order("sent_at ASC").Find(&data.Deliveries).Error; err != nil {
+		return nil, HandleDBError(err, "delivery")
+	}
+
+	if err := r.db.Where("user_id = ?", userID).Find(&data.TelegramChats).Error; err != nil {
+		return nil, HandleDBError(err, "telegram chat")
+	}
+
+	if err := r.db.Where("user_id = ?", userID).Find(&data.PhoneNumbers).Error; err != nil {
+		return nil, HandleDBError(err, "phone number")
+	}
+
+	err := r.db.Where("user_id = ? OR email = ?", userID, data.User.Email).
+		Order("created_at ASC").
+		Find(&data.Events).Error
+	if err != nil {
+		return nil, HandleDBError(err, "subscription event")
+	}
+
+	return &data, nil
+}
+
+// Removes every row about the user, anonymizes their audit events and leaves
+// a suppression tombstone, all in one transaction
+func (r *PrivacyRepository) EraseUser(user *models.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.eraseTx(tx, user.Email, []uuid.UUID{user.ID})
+	})
+}
+
+// Like EraseUser, for an address that may have unsubscribed already. Send
+// history and events of former subscriptions are found by their user IDs in
+// the address's events
+func (r *PrivacyRepository) EraseEmail(email string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var userIDs, formerIDs []uuid.UUID
+
+		if err := tx.Model(&models.User{}).Where("email = ?", email).Pluck("id", &userIDs).Error; err != nil {
+			return fmt.Errorf("failed to find user: %w", err)
+		}
+
+		err := tx.Model(&models.SubscriptionEvent{}).
+			Where("email = ? AND user_id IS NOT NULL", email).
+			Distinct().
+			Pluck("user_id", &formerIDs).Error
+		if err != nil {
+			return fmt.Errorf("failed to find former subscriptions: %w", err)
+		}
+
+		for _, id := range formerIDs {
+			if id != uuid.Nil && !slices.Contains(userIDs, id) {
+				userIDs = append(userIDs, id)
+			}
+		}
+
+		return r.eraseTx(tx, email, userIDs)
+	})
+}
+
+// The current user, if any, comes first in userIDs and owns the erasure event
+func (r *PrivacyRepository) eraseTx(tx *gorm.DB, email string, userIDs []uuid.UUID) error {
+	emailHash := r.hasher.EmailHash(email)
+	anonymized := "erased:" + emailHash
+
+	if err := tx.Where("user_id IN ?", userIDs).Delete(&models.Token{}).Error; err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+
+	if err := tx.Where("user_id IN ?", userIDs).Delete(&models.Subscription{}).Error; err != nil {
+		return fmt.Errorf("failed to delete subscriptions: %w", err)
+	}
+
+	if err := tx.Where("user_id IN ?", userIDs).Delete(&models.Delivery{}).Error; err != nil {
+		return fmt.Errorf("failed to delete deliveries: %w", err)
+	}
+
+	if err := tx.Where("user_id IN ?", userIDs).Delete(&models.TelegramChat{}).Error; err != nil {
+		return fmt.Errorf("failed to delete telegram chats: %w", err)
+	}
+
+	if err := tx.Where("user_id IN ?", userIDs).Delete(&models.PhoneNumber{}).Error; err != nil {
+		return fmt.Errorf("failed to delete phone number: %w", err)
+	}
+
+	// Webhook payloads carry the address
+	if err := tx.Where("user_id IN ?", userIDs).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+
+	err := tx.Model(&models.SubscriptionEvent{}).
+		Where("user_id IN ? OR email = ?", userIDs, email).
+		Updates(map[string]any{
+			"email":      anonymized,
+			"ip":         "",
+			"user_agent": "",
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to anonymize events: %w", err)
+	}
+
+	if err := tx.Delete(&models.User{}, "id IN ?", userIDs).Error; err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	suppression := models.Suppression{
+		EmailHash: emailHash,
+		Reason:    models.SuppressionReasonErasure,
+		CreatedAt: time.Now(),
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&suppression).Error; err != nil {
+		return fmt.Errorf("failed to create suppression: %w", err)
+	}
+
+	event := models.SubscriptionEvent{
+		Type:      models.EventErased,
+		Email:     anonymized,
+		CreatedAt: time.Now(),
+	}
+	if len(userIDs) > 0 {
+		event.UserID = userIDs[0]
+	}
+	if err := tx.Create(&event).Error; err != nil {
+		return fmt.Errorf("failed to record erasure: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"fmt"
+	"weather-app/internal/database/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PushRepository struct {
+	*BaseRepository
+}
+
+func NewPushRepository(db *gorm.DB) *PushRepository {
+	return &PushRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Stores the subscription. Subscribing the same endpoint again replaces its
+// keys, city, schedule and content preferences
+func (r *PushRepository) Save(sub *models.PushSubscription) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "endpoint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"p256dh", "auth", "city", "frequency", "timezone",
+			"send_time", "weekday", "interval_hours", "cron_expr", "content_fields", "units", "language"}),
+	}).Create(sub).Error
+	if err != nil {
+		return HandleDBError(err, "push subscription")
+	}
+
+	return nil
+}
+
+// Deletes the subscription with its send history. Returns ErrNotFound for
+// unknown endpoints
+func (r *PushRepository) DeleteByEndpoint(endpoint string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var sub models.PushSubscription
+		if err := tx.Where("endpoint = ?", endpoint).First(&sub).Error; err != nil {
+			return HandleDBError(err, "push subscription")
+		}
+
+		if err := tx.Where("recipient_id = ?", sub.ID).Delete(&models.Delivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete push deliveries: %w", err)
+		}
+
+		if err := tx.Delete(&models.PushSubscription{}, "id = ?", sub.ID).Error; err != nil {
+			return HandleDBError(err, "push subscription")
+		}
+
+		return nil
+	})
+}
+
+// Push subscriptions with the given frequency. The secret is "<p256dh>.<auth>"
+func (r *PushRepository) Recipients(limit, offset int, frequency string) ([]ChannelRecipient, error) {
+	var results []ChannelRecipient
+
+	err := r.db.Model(&models.PushSubscription{}).
+		Select("id AS recipient_id, endpoint AS target, p256dh || '.' || auth AS secret, "+
+			"city, timezone, send_time, weekday, interval_hours, cron_expr, content_fields, units, language").
+		Where("frequency = ?", frequency).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&results).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"weather-app/internal/database/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SMSRepository struct {
+	*BaseRepository
+}
+
+func NewSMSRepository(db *gorm.DB) *SMSRepository {
+	return &SMSRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+func (r *SMSRepository) GetPhone(userID uuid.UUID) (*models.PhoneNumber, error) {
+	var phone models.PhoneNumber
+
+	if err := r.db.Where("user_id = ?", userID).First(&phone).Error; err != nil {
+		return nil, HandleDBError(err, "phone number")
+	}
+
+	return &phone, nil
+}
+
+// Stores the number with a new pending code. A previous number, confirmed or
+// not, is replaced and stops getting updates until the new one is confirmed
+func (r *SMSRepository) SavePendingPhone(userID uuid.UUID, number, codeHash string, expiresAt time.Time) error {
+	phone := models.PhoneNumber{
+		UserID:        userID,
+		Number:        number,
+		CodeHash:      codeHash,
+		CodeExpiresAt: &expiresAt,
+		CreatedAt:     time.Now(),
+	}
+
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"number":          number,
+			"code_hash":       codeHash,
+			"code_expires_at": expiresAt,
+			"attempts":        0,
+			"confirmed_at":    nil,
+			"created_at":      phone.CreatedAt,
+		}),
+	}).Create(&phone).Error
+	if err != nil {
+		return HandleDBError(err, "phone number")
+	}
+
+	return nil
+}
+
+func (r *SMSRepository) RecordFailedAttempt(userID uuid.UUID) error {
+	err := r.db.Model(&models.PhoneNumber{}).
+		Where("user_id = ?", userID).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+	if err != nil {
+		return HandleDBError(err, "phone number")
+	}
+
+	return nil
+}
+
+// Marks the number confirmed and drops the used code
+func (r *SMSRepository) ConfirmPhone(userID uuid.UUID, confirmedAt time.Time) error {
+	err := r.db.Model(&models.PhoneNumber{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]any{
+			"code_hash":       "",
+			"code_expires_at": nil,
+			"attempts":        0,
+			"confirmed_at":    confirmedAt,
+		}).Error
+	if err != nil {
+		return HandleDBError(err, "phone number")
+	}
+
+	return nil
+}
+
+// Returns ErrNotFound if the user has no number
+func (r *SMSRepository) DeletePhone(userID uuid.UUID) error {
+	result := r.db.Delete(&models.PhoneNumber{}, "user_id = ?", userID)
+	if result.Error != nil {
+		return HandleDBError(result.Error, "phone number")
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: phone number not found", ErrNotFound)
+	}
+
+	return nil
+}
+
+// Confirmed numbers of confirmed, active subscriptions with the given frequency
+func (r *SMSRepository) Recipients(limit, offset int, frequency string) ([]ChannelRecipient, error) {
+	var results []ChannelRecipient
+
+	err := r.db.Model(&models.PhoneNumber{}).
+		Select("phone_numbers.user_id, phone_numbers.number AS target, "+subscriptionScheduleColumns).
+		Joins("JOIN users ON users.id = phone_numbers.user_id AND users.is_confirmed = true").
+		Joins("JOIN subscriptions ON subscriptions.user_id = phone_numbers.user_id AND subscriptions.frequency = ?", frequency).
+		Where("phone_numbers.confirmed_at IS NOT NULL").
+		Where("subscriptions.paused_at IS NULL OR (subscriptions.paused_until IS NOT NULL AND subscriptions.paused_until <= NOW())").
+		Order("phone_numbers.confirmed_at ASC, phone_numbers.user_id ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&results).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return results, nil
+}
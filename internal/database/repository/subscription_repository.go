@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SubscriptionRepository struct {
+	*BaseRepository
+}
+
+func NewSubscriptionRepository(db *gorm.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+func (r *SubscriptionRepository) GetByID(id uuid.UUID) (*models.Subscription, error) {
+	var sub models.Subscription
+
+	err := r.db.Where("id = ?", id).First(&sub).Error
+	if err != nil {
+		return nil, HandleDBError(err, "subscription")
+	}
+
+	return &sub, nil
+}
+
+func (r *SubscriptionRepository) GetByUserID(userID uuid.UUID) (*models.Subscription, error) {
+	var sub models.Subscription
+
+	err := r.db.Where("user_id = ?", userID).First(&sub).Error
+	if err != nil {
+		return nil, HandleDBError(err, "subscription")
+	}
+
+	return &sub, nil
+}
+
+// Pauses deliveries for the user. Nil until pauses indefinitely
+func (r *SubscriptionRepository) Pause(userID uuid.UUID, until *time.Time) error {
+	err := r.db.Model(&models.Subscription{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]any{
+			"paused_at":    time.Now(),
+			"paused_until": until,
+		}).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to pause subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SubscriptionRepository) Resume(userID uuid.UUID) error {
+	err := r.db.Model(&models.Subscription{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]any{
+			"paused_at":    nil,
+			"paused_until": nil,
+		}).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to resume subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SubscriptionRepository) UpdatePreferences(userID uuid.UUID, prefs content.Preferences) error {
+	err := r.db.Model(&models.Subscription{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]any{
+			"content_fields": prefs.StoredFields(),
+			"units":          prefs.Units,
+			"language":       prefs.Language,
+		}).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to update preferences: %w", err)
+	}
+
+	return nil
+}
+
+// Clears pauses which ended before now. Returns number of resumed subscriptions
+func (r *SubscriptionRepository) ResumeExpiredPauses(now time.Time) (int64, error) {
+	result := r.db.Model(&models.Subscription{}).
+		Where("paused_at IS NOT NULL AND paused_until IS NOT NULL AND paused_until <= ?", now).
+		Updates(map[string]any{
+			"paused_at":    nil,
+			"paused_until": nil,
+		})
+
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to resume expired pauses: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+	"weather-app/internal/database/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TeamRepository struct {
+	*BaseRepository
+}
+
+func NewTeamRepository(db *gorm.DB) *TeamRepository {
+	return &TeamRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+func (r *TeamRepository) Create(sub *models.TeamSubscription) error {
+	if err := r.db.Create(sub).Error; err != nil {
+		return HandleDBError(err, "team subscription")
+	}
+
+	return nil
+}
+
+func (r *TeamRepository) List() ([]models.TeamSubscription, error) {
+	var subs []models.TeamSubscription
+
+	if err := r.db.Order("created_at ASC").Find(&subs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list team subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// Deletes the subscription with its send history. Returns ErrNotFound for unknown IDs
+func (r *TeamRepository) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("recipient_id = ?", id).Delete(&models.Delivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete team deliveries: %w", err)
+		}
+
+		result := tx.Delete(&models.TeamSubscription{}, "id = ?", id)
+		if result.Error != nil {
+			return HandleDBError(result.Error, "team subscription")
+		}
+
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: team subscription not found", ErrNotFound)
+		}
+
+		return nil
+	})
+}
+
+// Recipient source of one team channel, see notify.Channel
+func (r *TeamRepository) ChannelRecipients(channel string) *TeamRecipients {
+	return &TeamRecipients{repo: r, channel: channel}
+}
+
+type TeamRecipients struct {
+	repo    *TeamRepository
+	channel string
+}
+
+// Team subscriptions of the channel with the given frequency
+func (t *TeamRecipients) Recipients(limit, offset int, frequency string) ([]ChannelRecipient, error) {
+	var results []ChannelRecipient
+
+	err := t.repo.db.Model(&models.TeamSubscription{}).
+		Select("id AS recipient_id, url AS target, secret, city, timezone, send_time, weekday, interval_hours, cron_expr, "+
+			"content_fields, units, language").
+		Where("channel = ? AND frequency = ?", t.channel, frequency).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&results).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"fmt"
+	"weather-app/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+type TelegramRepository struct {
+	*BaseRepository
+}
+
+func NewTelegramRepository(db *gorm.DB) *TelegramRepository {
+	return &TelegramRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// Linked chats of confirmed, active subscriptions with the given frequency
+func (r *TelegramRepository) Recipients(limit, offset int, frequency string) ([]ChannelRecipient, error) {
+	var results []ChannelRecipient
+
+	err := r.db.Model(&models.TelegramChat{}).
+		Select("telegram_chats.user_id, CAST(telegram_chats.chat_id AS TEXT) AS target, "+subscriptionScheduleColumns).
+		Joins("JOIN users ON users.id = telegram_chats.user_id AND users.is_confirmed = true").
+		Joins("JOIN subscriptions ON subscriptions.user_id = telegram_chats.user_id AND subscriptions.frequency = ?", frequency).
+		Where("subscriptions.paused_at IS NULL OR (subscriptions.paused_until IS NOT NULL AND subscriptions.paused_until <= NOW())").
+		Order("telegram_chats.linked_at ASC, telegram_chats.chat_id ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&results).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return results, nil
+}
@@ -1,28 +1,105 @@
 package repository
 
 import (
+	"fmt"
+	"log"
+	"time"
 	"weather-app/internal/database/models"
+	"weather-app/internal/tokens"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type TokenRepository struct {
 	*BaseRepository
+	hasher *tokens.Hasher
 }
 
-func NewTokenRepository(db *gorm.DB) *TokenRepository {
+func NewTokenRepository(db *gorm.DB, hasher *tokens.Hasher) *TokenRepository {
 	return &TokenRepository{
 		BaseRepository: NewBaseRepository(db),
+		hasher:         hasher,
 	}
 }
 
+// Finds a token by its plaintext value. Only hashes are compared
 func (r *TokenRepository) GetToken(value string) (*models.Token, error) {
 	var token models.Token
 
-	err := r.db.Where("value = ?", value).First(&token).Error
+	hash := r.hasher.Hash(value)
+
+	err := r.db.Where("hash = ? OR legacy_hash = ?", hash, hash).First(&token).Error
 	if err != nil {
 		return nil, err
 	}
 
 	return &token, nil
 }
+
+// Deletes email change tokens older than models.EmailChangeTokenTTL. Returns
+// how many were deleted
+func (r *TokenRepository) DeleteExpiredEmailChangeTokens(now time.Time) (int64, error) {
+	result := r.db.
+		Where("type = ? AND created_at <= ?", models.TokenTypeEmailChange, now.Add(-models.EmailChangeTokenTTL)).
+		Delete(&models.Token{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired email change tokens: %w", result.Error)
+	}
+
+	return result.RowsAffected, nil
+}
+
+// Hashes tokens stored in plaintext by older versions and drops the plaintext
+// column. Safe to run on every start
+func (r *TokenRepository) MigratePlaintextTokens() error {
+	migrator := r.db.Migrator()
+
+	if !migrator.HasColumn(&models.Token{}, "value") {
+		return nil
+	}
+
+	type legacyToken struct {
+		ID    uuid.UUID
+		Value string
+	}
+
+	var legacy []legacyToken
+
+	err := r.db.Table("tokens").
+		Select("id, value").
+		Where("value IS NOT NULL AND value <> ''").
+		Scan(&legacy).Error
+	if err != nil {
+		return fmt.Errorf("failed to load plaintext tokens: %w", err)
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		for _, t := range legacy {
+			// Old links keep working through legacy_hash, new emails use the derived value
+			err := tx.Model(&models.Token{}).
+				Where("id = ?", t.ID).
+				Updates(map[string]any{
+					"hash":        r.hasher.Hash(r.hasher.Value(t.ID)),
+					"legacy_hash": r.hasher.Hash(t.Value),
+				}).Error
+			if err != nil {
+				return fmt.Errorf("failed to hash token %s: %w", t.ID, err)
+			}
+		}
+
+		if err := tx.Migrator().DropColumn(&models.Token{}, "value"); err != nil {
+			return fmt.Errorf("failed to drop plaintext column: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Migrated %d plaintext tokens\n", len(legacy))
+
+	return nil
+}
@@ -3,20 +3,27 @@ package repository
 import (
 	"fmt"
 	"log"
+	"strings"
 	"time"
+	"weather-app/internal/content"
 	"weather-app/internal/database/models"
+	"weather-app/internal/schedule"
+	"weather-app/internal/tokens"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository struct {
 	*BaseRepository
+	hasher *tokens.Hasher
 }
 
-func NewUserRepository(db *gorm.DB) *UserRepository {
+func NewUserRepository(db *gorm.DB, hasher *tokens.Hasher) *UserRepository {
 	return &UserRepository{
 		BaseRepository: NewBaseRepository(db),
+		hasher:         hasher,
 	}
 }
 
@@ -26,6 +33,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	}
 
 	var user models.User
+	// Addresses are stored normalized, see MigrateEmailCase for older rows
 	err := r.db.Where("email = ?", email).First(&user).Error
 	if err != nil {
 		return nil, HandleDBError(err, "user")
@@ -34,10 +42,142 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
+// Lowercases addresses stored before signup normalized them, so lookups can
+// use the unique index. Rows that would collide with an existing lowercase
+// address are left as they are and logged. Safe to run on every start
+func (r *UserRepository) MigrateEmailCase() error {
+	result := r.db.Exec(`UPDATE users SET email = LOWER(email)
+		WHERE email <> LOWER(email)
+		AND NOT EXISTS (SELECT 1 FROM users other WHERE other.email = LOWER(users.email))`)
+	if result.Error != nil {
+		return fmt.Errorf("failed to lowercase emails: %w", result.Error)
+	}
+
+	var conflicts int64
+	err := r.db.Model(&models.User{}).Where("email <> LOWER(email)").Count(&conflicts).Error
+	if err != nil {
+		return fmt.Errorf("failed to count mixed case emails: %w", err)
+	}
+
+	if result.RowsAffected > 0 || conflicts > 0 {
+		log.Printf("Lowercased %d emails, %d left because the lowercase address exists\n", result.RowsAffected, conflicts)
+	}
+
+	return nil
+}
+
+func (r *UserRepository) GetByID(id uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return nil, HandleDBError(err, "user")
+	}
+
+	return &user, nil
+}
+
+// Reports whether the address has a suppression tombstone. A tombstone
+// stored with the old unkeyed hash is re-keyed once its address shows up
+func (r *UserRepository) IsSuppressed(email string) (bool, error) {
+	hash, legacy := r.hasher.EmailHash(email), legacyEmailHash(email)
+
+	var suppressions []models.Suppression
+	err := r.db.Where("email_hash IN ?", []string{hash, legacy}).Find(&suppressions).Error
+	if err != nil {
+		return false, HandleDBError(err, "suppression")
+	}
+
+	for _, suppression := range suppressions {
+		if suppression.EmailHash == legacy {
+			if err := r.rekeySuppression(suppression, hash); err != nil {
+				log.Printf("Failed to re-key suppression: %s\n", err.Error())
+			}
+		}
+	}
+
+	return len(suppressions) > 0, nil
+}
+
+func (r *UserRepository) rekeySuppression(legacy models.Suppression, hash string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		rekeyed := models.Suppression{EmailHash: hash, Reason: legacy.Reason, CreatedAt: legacy.CreatedAt}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rekeyed).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&models.SubscriptionEvent{}).
+			Where("email = ?", "erased:"+legacy.EmailHash).
+			Update("email", "erased:"+hash).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.Suppression{}, "email_hash = ?", legacy.EmailHash).Error
+	})
+}
+
 type UserEmailInfo struct {
-	Email      string
-	City       string
-	TokenValue string
+	UserID        uuid.UUID
+	Email         string
+	City          string
+	Timezone      string
+	SendTime      string
+	Weekday       int
+	IntervalHours int
+	CronExpr      string
+	ContentFields string
+	Units         string
+	Language      string
+	TokenID       uuid.UUID
+	TokenValue    string // Derived from TokenID, not stored
+}
+
+// Restores the delivery schedule of the entry's subscription
+func (i UserEmailInfo) Schedule(frequency string) schedule.Schedule {
+	return schedule.Schedule{
+		Frequency:     frequency,
+		Timezone:      i.Timezone,
+		SendTime:      i.SendTime,
+		Weekday:       time.Weekday(i.Weekday),
+		IntervalHours: i.IntervalHours,
+		CronExpr:      i.CronExpr,
+	}
+}
+
+func (i UserEmailInfo) Preferences() content.Preferences {
+	return content.FromStored(i.ContentFields, i.Units, i.Language)
+}
+
+const userEmailInfoColumns = "users.id AS user_id, users.email, subscriptions.city, subscriptions.timezone, subscriptions.send_time, " +
+	"subscriptions.weekday, subscriptions.interval_hours, subscriptions.cron_expr, " +
+	"subscriptions.content_fields, subscriptions.units, subscriptions.language, tokens.id AS token_id"
+
+// Delivery details for one user, whatever their confirmation or pause state
+func (r *UserRepository) GetUserEmailInfo(userID uuid.UUID) (*UserEmailInfo, error) {
+	var results []UserEmailInfo
+
+	err := r.db.Table("users").
+		Select(userEmailInfoColumns).
+		Joins("JOIN subscriptions ON subscriptions.user_id = users.id").
+		Joins("LEFT JOIN tokens ON tokens.user_id = users.id AND tokens.type = ?", models.TokenTypeUnsubscribe).
+		Where("users.id = ?", userID).
+		Limit(1).
+		Scan(&results).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w: user not found", ErrNotFound)
+	}
+
+	info := results[0]
+	if info.TokenID != uuid.Nil {
+		info.TokenValue = r.hasher.Value(info.TokenID)
+	}
+
+	return &info, nil
 }
 
 // TODO: Need to separate this big transactional functions and use BaseRepository::WithTransaction
@@ -45,10 +185,13 @@ func (r *UserRepository) GetUserEmailInfoBatch(limit, offset int, subscriptionFr
 	var results []UserEmailInfo
 
 	err := r.db.Table("users").
-		Select("users.email, subscriptions.city, tokens.value AS token_value").
+		Select(userEmailInfoColumns).
 		Joins("JOIN subscriptions ON subscriptions.user_id = users.id AND subscriptions.frequency = ?", subscriptionFrequency).
-		Joins("JOIN tokens ON tokens.user_id = users.id AND tokens.type = ?", "unsubscribe").
+		// Users with signed links have no token rows
+		Joins("LEFT JOIN tokens ON tokens.user_id = users.id AND tokens.type = ?", "unsubscribe").
 		Where("users.is_confirmed = true").
+		// Paused subscriptions are skipped until their pause ends
+		Where("subscriptions.paused_at IS NULL OR (subscriptions.paused_until IS NOT NULL AND subscriptions.paused_until <= NOW())").
 		Order("users.created_at ASC").
 		Limit(limit).
 		Offset(offset).
@@ -58,6 +201,12 @@ func (r *UserRepository) GetUserEmailInfoBatch(limit, offset int, subscriptionFr
 		return nil, fmt.Errorf("query failed: %w", err)
 	}
 
+	for i := range results {
+		if results[i].TokenID != uuid.Nil {
+			results[i].TokenValue = r.hasher.Value(results[i].TokenID)
+		}
+	}
+
 	return results, nil
 }
 
@@ -68,9 +217,10 @@ type CreateUserWithSubscriptionAndTokensResult struct {
 }
 
 func (r *UserRepository) CreateUserWithSubscriptionAndTokens(
-	email, city, frequency string,
+	email, city string,
+	sched schedule.Schedule,
+	prefs content.Preferences,
 	tokenTypes []string,
-	generateToken func() (string, error),
 ) (*CreateUserWithSubscriptionAndTokensResult, error) {
 
 	result := CreateUserWithSubscriptionAndTokensResult{}
@@ -91,7 +241,18 @@ func (r *UserRepository) CreateUserWithSubscriptionAndTokens(
 		sub := models.Subscription{
 			UserID:    user.ID,
 			City:      city,
-			Frequency: frequency,
+			Frequency: sched.Frequency,
+			Timezone:  sched.Timezone,
+			SendTime:  sched.SendTime,
+
+			Weekday:       int(sched.Weekday),
+			IntervalHours: sched.IntervalHours,
+			CronExpr:      sched.CronExpr,
+
+			ContentFields: prefs.StoredFields(),
+			Units:         prefs.Units,
+			Language:      prefs.Language,
+
 			CreatedAt: createdTime,
 		}
 		if err := tx.Create(&sub).Error; err != nil {
@@ -102,12 +263,12 @@ func (r *UserRepository) CreateUserWithSubscriptionAndTokens(
 
 		// Create Tokens
 		for _, tokenType := range tokenTypes {
-			value, err := generateToken()
-			if err != nil {
-				return fmt.Errorf("failed to generate token: %w", err)
-			}
+			id := uuid.New()
+			value := r.hasher.Value(id)
 
 			token := models.Token{
+				ID:        id,
+				Hash:      r.hasher.Hash(value),
 				Value:     value,
 				Type:      tokenType,
 				UserID:    user.ID,
@@ -117,7 +278,7 @@ func (r *UserRepository) CreateUserWithSubscriptionAndTokens(
 				return fmt.Errorf("failed to create %s token: %w", tokenType, err)
 			}
 
-			log.Printf("Created %s token: %s", tokenType, value)
+			log.Printf("Created %s token for user %s", tokenType, user.ID)
 
 			tokensMap[tokenType] = &token
 		}
@@ -155,23 +316,272 @@ func (r *UserRepository) UpdateUserConfirmationAndDeleteToken(userID uuid.UUID,
 	})
 }
 
-func (r *UserRepository) DeleteUserWithTokensAndSubscription(userID uuid.UUID) error {
+// Removes the user and leaves an anonymous churn record describing the
+// subscription. The record is returned, nil if the user had no subscription
+func (r *UserRepository) DeleteUserWithTokensAndSubscription(userID uuid.UUID) (*models.Churn, error) {
+	var churn *models.Churn
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var sub models.Subscription
+		result := tx.Where("user_id = ?", userID).Limit(1).Find(&sub)
+		if result.Error != nil {
+			return fmt.Errorf("failed to get subscription: %w", result.Error)
+		}
+
+		if result.RowsAffected > 0 {
+			now := time.Now()
+			churn = &models.Churn{
+				City:             sub.City,
+				Frequency:        sub.Frequency,
+				SubscriptionDays: int(now.Sub(sub.CreatedAt).Hours() / 24),
+				// An exact time could be matched to the unsubscribe event
+				CreatedAt: now.UTC().Truncate(24 * time.Hour),
+			}
+			if err := tx.Create(churn).Error; err != nil {
+				return fmt.Errorf("failed to record churn: %w", err)
+			}
+		}
+
+		return deleteUserTx(tx, userID)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return churn, nil
+}
+
+// Removes the user without a churn record, for deletions that aren't the
+// subscriber's choice
+func (r *UserRepository) DeleteUser(userID uuid.UUID) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		// Delete all tokens for the user
-		if err := tx.Where("user_id = ?", userID).Delete(&models.Token{}).Error; err != nil {
-			return fmt.Errorf("failed to delete tokens: %w", err)
+		return deleteUserTx(tx, userID)
+	})
+}
+
+func deleteUserTx(tx *gorm.DB, userID uuid.UUID) error {
+	// Delete all tokens for the user
+	if err := tx.Where("user_id = ?", userID).Delete(&models.Token{}).Error; err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+
+	// Delete subscription for the user
+	if err := tx.Where("user_id = ?", userID).Delete(&models.Subscription{}).Error; err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TelegramChat{}).Error; err != nil {
+		return fmt.Errorf("failed to delete telegram chats: %w", err)
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.PhoneNumber{}).Error; err != nil {
+		return fmt.Errorf("failed to delete phone number: %w", err)
+	}
+
+	// Delete the user
+	if err := tx.Delete(&models.User{}, "id = ?", userID).Error; err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return nil
+}
+
+// Confirms the user without a confirmation link. Pending confirmation tokens
+// are dropped
+func (r *UserRepository) ConfirmUser(userID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND type = ?", userID, models.TokenTypeConfirm).
+			Delete(&models.Token{}).Error; err != nil {
+
+			return fmt.Errorf("failed to delete confirmation tokens: %w", err)
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Update("is_confirmed", true).Error; err != nil {
+
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Position in the user list, which is ordered by creation time and ID
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+type UserFilter struct {
+	Email     string // Case-insensitive substring
+	City      string // Case-insensitive
+	Frequency string
+	Confirmed *bool
+	After     *UserCursor // Keyset pagination, nil starts from the beginning
+	Limit     int
+}
+
+type UserListEntry struct {
+	ID          uuid.UUID
+	Email       string
+	IsConfirmed bool
+	CreatedAt   time.Time
+	City        string
+	Frequency   string
+	Timezone    string
+	PausedAt    *time.Time
+	PausedUntil *time.Time
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *UserRepository) ListUsers(filter UserFilter) ([]UserListEntry, error) {
+	var results []UserListEntry
+
+	query := r.db.Table("users").
+		Select("users.id, users.email, users.is_confirmed, users.created_at, subscriptions.city, " +
+			"subscriptions.frequency, subscriptions.timezone, subscriptions.paused_at, subscriptions.paused_until").
+		Joins("JOIN subscriptions ON subscriptions.user_id = users.id")
+
+	if filter.Email != "" {
+		query = query.Where("users.email ILIKE ?", "%"+likeEscaper.Replace(filter.Email)+"%")
+	}
+	if filter.City != "" {
+		query = query.Where("LOWER(subscriptions.city) = LOWER(?)", filter.City)
+	}
+	if filter.Frequency != "" {
+		query = query.Where("subscriptions.frequency = ?", filter.Frequency)
+	}
+	if filter.Confirmed != nil {
+		query = query.Where("users.is_confirmed = ?", *filter.Confirmed)
+	}
+	if filter.After != nil {
+		query = query.Where("(users.created_at, users.id) > (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+
+	err := query.
+		Order("users.created_at ASC, users.id ASC").
+		Limit(filter.Limit).
+		Scan(&results).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	return results, nil
+}
+
+// Issues a token that verifies newEmail for the user. Earlier pending changes
+// are dropped, only the latest request can be confirmed
+func (r *UserRepository) CreateEmailChangeToken(userID uuid.UUID, newEmail string) (*models.Token, error) {
+	return r.replacePendingToken(userID, models.TokenTypeEmailChange, newEmail)
+}
+
+// Issues a token for a Telegram deep link. Earlier unused link tokens are dropped
+func (r *UserRepository) CreateTelegramToken(userID uuid.UUID) (*models.Token, error) {
+	return r.replacePendingToken(userID, models.TokenTypeTelegram, "")
+}
+
+// Creates a token of tokenType in place of the user's earlier ones
+func (r *UserRepository) replacePendingToken(userID uuid.UUID, tokenType, newEmail string) (*models.Token, error) {
+	id := uuid.New()
+	value := r.hasher.Value(id)
+
+	token := models.Token{
+		ID:        id,
+		Hash:      r.hasher.Hash(value),
+		Value:     value,
+		Type:      tokenType,
+		UserID:    userID,
+		NewEmail:  newEmail,
+		CreatedAt: time.Now(),
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND type = ?", userID, tokenType).
+			Delete(&models.Token{}).Error; err != nil {
+
+			return fmt.Errorf("failed to delete pending %s token: %w", tokenType, err)
 		}
 
-		// Delete subscription for the user
-		if err := tx.Where("user_id = ?", userID).Delete(&models.Subscription{}).Error; err != nil {
-			return fmt.Errorf("failed to delete subscription: %w", err)
+		if err := tx.Create(&token).Error; err != nil {
+			return fmt.Errorf("failed to create %s token: %w", tokenType, err)
 		}
 
-		// Delete the user
-		if err := tx.Delete(&models.User{}, "id = ?", userID).Error; err != nil {
-			return fmt.Errorf("failed to delete user: %w", err)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *UserRepository) ChangeEmailAndDeleteToken(userID uuid.UUID, tokenID uuid.UUID, newEmail string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Delete token
+		if err := tx.Delete(&models.Token{}, "id = ?", tokenID).Error; err != nil {
+			return fmt.Errorf("failed to delete token: %w", err)
+		}
+
+		// Unique index on email catches an address taken since the request
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", userID).
+			Update("email", newEmail).Error; err != nil {
+
+			return HandleDBError(err, "user")
 		}
 
 		return nil
 	})
 }
+
+// Links the chat to the user, moving it over if another user had it. A
+// non-nil tokenID is the deep-link token used, deleted in the same transaction
+func (r *UserRepository) LinkTelegramChat(userID uuid.UUID, chatID int64, username string, tokenID *uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if tokenID != nil {
+			if err := tx.Delete(&models.Token{}, "id = ?", *tokenID).Error; err != nil {
+				return fmt.Errorf("failed to delete token: %w", err)
+			}
+		}
+
+		chat := models.TelegramChat{
+			ChatID:   chatID,
+			UserID:   userID,
+			Username: username,
+			LinkedAt: time.Now(),
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chat_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "username", "linked_at"}),
+		}).Create(&chat).Error
+		if err != nil {
+			return HandleDBError(err, "telegram chat")
+		}
+
+		return nil
+	})
+}
+
+// Removes the chat link and returns it. Returns ErrNotFound for unknown chats
+func (r *UserRepository) UnlinkTelegramChat(chatID int64) (*models.TelegramChat, error) {
+	var chat models.TelegramChat
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("chat_id = ?", chatID).First(&chat).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.TelegramChat{}, "chat_id = ?", chatID).Error
+	})
+	if err != nil {
+		return nil, HandleDBError(err, "telegram chat")
+	}
+
+	return &chat, nil
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+	"weather-app/internal/database/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	*BaseRepository
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+func (r *WebhookRepository) CreateEndpoint(endpoint *models.WebhookEndpoint) error {
+	if err := r.db.Create(endpoint).Error; err != nil {
+		return HandleDBError(err, "webhook endpoint")
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) ListEndpoints() ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+
+	if err := r.db.Order("created_at ASC").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+func (r *WebhookRepository) GetEndpoint(id uuid.UUID) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+
+	if err := r.db.Where("id = ?", id).First(&endpoint).Error; err != nil {
+		return nil, HandleDBError(err, "webhook endpoint")
+	}
+
+	return &endpoint, nil
+}
+
+// Deletes the endpoint with its delivery log. Returns ErrNotFound for unknown IDs
+func (r *WebhookRepository) DeleteEndpoint(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+
+		result := tx.Delete(&models.WebhookEndpoint{}, "id = ?", id)
+		if result.Error != nil {
+			return HandleDBError(result.Error, "webhook endpoint")
+		}
+
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: webhook endpoint not found", ErrNotFound)
+		}
+
+		return nil
+	})
+}
+
+func (r *WebhookRepository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := r.db.Create(&deliveries).Error; err != nil {
+		return HandleDBError(err, "webhook delivery")
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) GetDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+
+	if err := r.db.Where("id = ?", id).First(&delivery).Error; err != nil {
+		return nil, HandleDBError(err, "webhook delivery")
+	}
+
+	return &delivery, nil
+}
+
+// Newest first. Empty status lists all
+func (r *WebhookRepository) ListDeliveries(endpointID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	query := r.db.Where("endpoint_id = ?", endpointID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Picks up to limit pending deliveries that are due and pushes their next
+// attempt lease into the future, so concurrent dispatchers skip them
+func (r *WebhookRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookStatusPending, now).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil {
+			return err
+		}
+
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(deliveries))
+		for i, d := range deliveries {
+			ids[i] = d.ID
+		}
+
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Stores the outcome of an attempt: status, attempts, next_attempt_at,
+// last_status_code, last_error and delivered_at
+func (r *WebhookRepository) SaveAttempt(delivery *models.WebhookDelivery) error {
+	err := r.db.Model(&models.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(map[string]any{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+			"delivered_at":     delivery.DeliveredAt,
+		}).Error
+
+	if err != nil {
+		return fmt.Errorf("failed to save webhook delivery attempt: %w", err)
+	}
+
+	return nil
+}
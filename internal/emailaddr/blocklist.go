@@ -0,0 +1,100 @@
+package emailaddr
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed disposable_domains.txt
+var defaultDisposableDomains string
+
+// Set of disposable (throwaway) email domains. Subdomains of a listed domain
+// are blocked too
+type Blocklist struct {
+	domains map[string]struct{}
+}
+
+func NewBlocklist(domains []string) *Blocklist {
+	b := &Blocklist{domains: make(map[string]struct{}, len(domains))}
+
+	for _, d := range domains {
+		normalized, err := NormalizeDomain(d)
+		if err != nil {
+			continue
+		}
+		b.domains[normalized] = struct{}{}
+	}
+
+	return b
+}
+
+// Reads one domain per line, blank lines and lines starting with # are skipped
+func ParseBlocklist(r io.Reader) (*Blocklist, error) {
+	var domains []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewBlocklist(domains), nil
+}
+
+// The list bundled with the binary
+func DefaultBlocklist() *Blocklist {
+	b, _ := ParseBlocklist(strings.NewReader(defaultDisposableDomains))
+	return b
+}
+
+// Loads a blocklist file, or the bundled list when path is empty
+func LoadBlocklist(path string) (*Blocklist, error) {
+	if path == "" {
+		return DefaultBlocklist(), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening blocklist: %w", err)
+	}
+	defer f.Close()
+
+	return ParseBlocklist(f)
+}
+
+func (b *Blocklist) Contains(domain string) bool {
+	if b == nil {
+		return false
+	}
+
+	for {
+		if _, ok := b.domains[domain]; ok {
+			return true
+		}
+
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+func (b *Blocklist) Len() int {
+	if b == nil {
+		return 0
+	}
+
+	return len(b.domains)
+}
@@ -0,0 +1,72 @@
+package emailaddr_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"weather-app/internal/emailaddr"
+)
+
+func TestBlocklist_Contains(t *testing.T) {
+	b := emailaddr.NewBlocklist([]string{"Mailinator.com", "bücher.example"})
+
+	cases := map[string]bool{
+		"mailinator.com":           true,
+		"eu.mailinator.com":        true,
+		"notmailinator.com":        false,
+		"example.com":              false,
+		"xn--bcher-kva.example":    true,
+		"mx.xn--bcher-kva.example": true,
+	}
+
+	for domain, want := range cases {
+		if got := b.Contains(domain); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", domain, got, want)
+		}
+	}
+}
+
+func TestBlocklist_NilContainsNothing(t *testing.T) {
+	var b *emailaddr.Blocklist
+	if b.Contains("mailinator.com") {
+		t.Error("nil blocklist should not block anything")
+	}
+}
+
+func TestParseBlocklist_SkipsCommentsAndBlanks(t *testing.T) {
+	b, err := emailaddr.ParseBlocklist(strings.NewReader("# comment\n\n  spam.example  \n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if b.Len() != 1 || !b.Contains("spam.example") {
+		t.Errorf("expected only spam.example, got %d domains", b.Len())
+	}
+}
+
+func TestDefaultBlocklist(t *testing.T) {
+	b := emailaddr.DefaultBlocklist()
+	if !b.Contains("mailinator.com") {
+		t.Error("expected bundled list to contain mailinator.com")
+	}
+}
+
+func TestLoadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	if err := os.WriteFile(path, []byte("custom.example\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := emailaddr.LoadBlocklist(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !b.Contains("custom.example") || b.Contains("mailinator.com") {
+		t.Error("expected only the file's domains")
+	}
+
+	if _, err := emailaddr.LoadBlocklist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
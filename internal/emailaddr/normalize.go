@@ -0,0 +1,76 @@
+package emailaddr
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	maxLocalLength  = 64
+	maxDomainLength = 253
+	maxLabelLength  = 63
+)
+
+var ErrInvalidAddress = errors.New("email parameter is invalid")
+
+// Applied after normalization, so the domain is already lowercase ASCII
+var addressRegex = regexp.MustCompile(`^[a-z0-9._%+\-]+@(?:[a-z0-9](?:[a-z0-9\-]*[a-z0-9])?\.)+(?:[a-z]{2,}|xn--[a-z0-9\-]+)$`)
+
+// Canonical form of an address: trimmed, lowercased, with an internationalized
+// domain converted to punycode. Two spellings of the same mailbox normalize to
+// the same string, so it can be used for lookups and uniqueness
+func Normalize(address string) (string, error) {
+	address = strings.TrimSpace(address)
+
+	at := strings.LastIndex(address, "@")
+	if at <= 0 || at == len(address)-1 {
+		return "", ErrInvalidAddress
+	}
+
+	local := strings.ToLower(address[:at])
+	if len(local) > maxLocalLength {
+		return "", ErrInvalidAddress
+	}
+
+	domain, err := NormalizeDomain(address[at+1:])
+	if err != nil {
+		return "", err
+	}
+
+	normalized := local + "@" + domain
+	if !addressRegex.MatchString(normalized) {
+		return "", ErrInvalidAddress
+	}
+
+	return normalized, nil
+}
+
+// Lowercases a domain and converts each non-ASCII label to its xn-- form
+// with the IDNA lookup profile, which also maps full-width dots
+func NormalizeDomain(domain string) (string, error) {
+	domain, err := idna.Lookup.ToASCII(strings.TrimSpace(domain))
+	if err != nil {
+		return "", ErrInvalidAddress
+	}
+
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" || len(domain) > maxDomainLength {
+		return "", ErrInvalidAddress
+	}
+
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > maxLabelLength {
+			return "", ErrInvalidAddress
+		}
+	}
+
+	return domain, nil
+}
+
+// Domain part of an already normalized address
+func Domain(address string) string {
+	return address[strings.LastIndex(address, "@")+1:]
+}
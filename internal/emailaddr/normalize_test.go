@@ -0,0 +1,62 @@
+package emailaddr_test
+
+import (
+	"errors"
+	"testing"
+	"weather-app/internal/emailaddr"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"user@example.com", "user@example.com"},
+		{"  User@Example.COM  ", "user@example.com"},
+		{"first.last+tag@sub.example.co.uk", "first.last+tag@sub.example.co.uk"},
+		{"user@example.com.", "user@example.com"},
+		{"user@münchen.de", "user@xn--mnchen-3ya.de"},
+		{"user@MÜNCHEN.DE", "user@xn--mnchen-3ya.de"},
+		{"user@bücher.example", "user@xn--bcher-kva.example"},
+		{"user@пример.испытание", "user@xn--e1afmkfd.xn--80akhbyknj4f"},
+		{"user@例え。jp", "user@xn--r8jz45g.jp"},
+	}
+
+	for _, tt := range tests {
+		got, err := emailaddr.Normalize(tt.in)
+		if err != nil {
+			t.Errorf("Normalize(%q): unexpected error %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize_Invalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"userexample.com",
+		"@example.com",
+		"user@",
+		"user@localhost",
+		"user@example..com",
+		"user@-example.com",
+		"us er@example.com",
+		"üser@example.com",
+		"user@example.c",
+	}
+
+	for _, in := range inputs {
+		if _, err := emailaddr.Normalize(in); !errors.Is(err, emailaddr.ErrInvalidAddress) {
+			t.Errorf("Normalize(%q): expected ErrInvalidAddress, got %v", in, err)
+		}
+	}
+}
+
+func TestDomain(t *testing.T) {
+	if got := emailaddr.Domain("user@example.com"); got != "example.com" {
+		t.Errorf("expected example.com, got %q", got)
+	}
+}
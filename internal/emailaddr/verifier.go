@@ -0,0 +1,88 @@
+package emailaddr
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"time"
+)
+
+const lookupTimeout = 5 * time.Second
+
+var (
+	ErrDisposableDomain = errors.New("disposable email addresses are not allowed")
+	ErrNoMailServer     = errors.New("email domain does not accept mail")
+)
+
+// Subset of *net.Resolver used for MX checks, so tests can fake DNS
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Checks normalized addresses against the disposable-domain blocklist and,
+// when a resolver is set, that the domain can receive mail
+type Verifier struct {
+	blocklist *Blocklist
+	resolver  Resolver
+}
+
+// A nil resolver disables the MX check
+func NewVerifier(blocklist *Blocklist, resolver Resolver) *Verifier {
+	return &Verifier{blocklist: blocklist, resolver: resolver}
+}
+
+func (v *Verifier) Verify(address string) error {
+	domain := Domain(address)
+
+	if v.blocklist.Contains(domain) {
+		return ErrDisposableDomain
+	}
+
+	if v.resolver == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	return v.checkMailServer(ctx, domain)
+}
+
+// DNS failures other than "no such record" let the address through, so an
+// unreachable resolver doesn't block every signup
+func (v *Verifier) checkMailServer(ctx context.Context, domain string) error {
+	mxs, err := v.resolver.LookupMX(ctx, domain)
+	if err == nil && len(mxs) > 0 {
+		// RFC 7505 null MX: the domain explicitly accepts no mail
+		if len(mxs) == 1 && (mxs[0].Host == "." || mxs[0].Host == "") {
+			return ErrNoMailServer
+		}
+
+		return nil
+	}
+
+	if err != nil && !isNotFound(err) {
+		log.Printf("MX lookup for %s failed: %s\n", domain, err.Error())
+		return nil
+	}
+
+	// No MX records, RFC 5321 falls back to the domain's own address
+	addrs, err := v.resolver.LookupHost(ctx, domain)
+	if err == nil && len(addrs) > 0 {
+		return nil
+	}
+
+	if err != nil && !isNotFound(err) {
+		log.Printf("Host lookup for %s failed: %s\n", domain, err.Error())
+		return nil
+	}
+
+	return ErrNoMailServer
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,63 @@
+package emailaddr_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"weather-app/internal/emailaddr"
+)
+
+type mockResolver struct {
+	mx      []*net.MX
+	mxErr   error
+	hosts   []string
+	hostErr error
+}
+
+func (m *mockResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return m.mx, m.mxErr
+}
+
+func (m *mockResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return m.hosts, m.hostErr
+}
+
+var notFound = &net.DNSError{Err: "no such host", IsNotFound: true}
+
+func TestVerify_Disposable(t *testing.T) {
+	v := emailaddr.NewVerifier(emailaddr.NewBlocklist([]string{"mailinator.com"}), nil)
+
+	if err := v.Verify("user@mailinator.com"); !errors.Is(err, emailaddr.ErrDisposableDomain) {
+		t.Errorf("expected ErrDisposableDomain, got %v", err)
+	}
+	if err := v.Verify("user@example.com"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestVerify_MX(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolver *mockResolver
+		wantErr  error
+	}{
+		{"has mx", &mockResolver{mx: []*net.MX{{Host: "mx.example.com.", Pref: 10}}}, nil},
+		{"null mx", &mockResolver{mx: []*net.MX{{Host: ".", Pref: 0}}}, emailaddr.ErrNoMailServer},
+		{"implicit mx", &mockResolver{mxErr: notFound, hosts: []string{"192.0.2.1"}}, nil},
+		{"no records", &mockResolver{mxErr: notFound, hostErr: notFound}, emailaddr.ErrNoMailServer},
+		{"mx lookup failure fails open", &mockResolver{mxErr: errors.New("timeout")}, nil},
+		{"host lookup failure fails open", &mockResolver{mxErr: notFound, hostErr: errors.New("timeout")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := emailaddr.NewVerifier(nil, tt.resolver)
+
+			err := v.Verify("user@example.com")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
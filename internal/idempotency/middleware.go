@@ -0,0 +1,171 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+const (
+	HeaderName     = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	maxBodySize  = 1 << 20
+
+	// How long a reservation blocks retries while its request runs. A crashed
+	// request frees the key once it runs out
+	reservationLease = time.Minute
+)
+
+var (
+	ErrInvalidKey      = errors.New("Idempotency-Key header is invalid")
+	ErrKeyInProgress   = errors.New("a request with this Idempotency-Key is in progress")
+	ErrPayloadMismatch = errors.New("Idempotency-Key was used with a different payload")
+	ErrBodyTooLarge    = errors.New("request body is too large")
+)
+
+var problems = problem.Mappings{
+	{Err: ErrInvalidKey, Status: http.StatusBadRequest, Code: "invalid_idempotency_key"},
+	{Err: ErrKeyInProgress, Status: http.StatusConflict, Code: "idempotency_key_in_progress"},
+	{Err: ErrPayloadMismatch, Status: http.StatusUnprocessableEntity, Code: "idempotency_key_reused"},
+	{Err: ErrBodyTooLarge, Status: http.StatusRequestEntityTooLarge, Code: "body_too_large"},
+}
+
+type StoreInterface interface {
+	Reserve(key, requestHash string, now time.Time, lease time.Duration) (*models.IdempotencyKey, bool, error)
+	Complete(key string, reservationID uuid.UUID, statusCode int, contentType string, body []byte, expiresAt time.Time) error
+	Release(key string, reservationID uuid.UUID) error
+}
+
+// Makes retries of a request with the same Idempotency-Key safe: the first
+// final response is stored for ttl and replayed for later requests with the
+// same payload. Requests without the header pass through. Other responses,
+// like rate limits, failed challenges or server errors, release the key, so
+// the client can retry
+func Middleware(store StoreInterface, ttl time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(HeaderName)
+		if key == "" {
+			next(w, req)
+			return
+		}
+
+		if len(key) > maxKeyLength {
+			problems.Write(w, ErrInvalidKey)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+		if err != nil {
+			problems.Write(w, err)
+			return
+		}
+		if len(body) > maxBodySize {
+			problems.Write(w, ErrBodyTooLarge)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, reserved, err := store.Reserve(key, requestHash(req, body), time.Now(), reservationLease)
+		if err != nil {
+			problems.Write(w, err)
+			return
+		}
+
+		if !reserved {
+			replay(w, req, body, record)
+			return
+		}
+
+		// Runs on panics too, so a failed request never holds the key
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+			if err := store.Release(key, record.ReservationID); err != nil {
+				log.Println(err.Error())
+			}
+		}()
+
+		rec := &recorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next(rec, req)
+
+		if !isFinal(rec.statusCode) {
+			return
+		}
+
+		err = store.Complete(key, record.ReservationID, rec.statusCode, rec.Header().Get("Content-Type"), rec.body.Bytes(), time.Now().Add(ttl))
+		if err != nil {
+			log.Println(err.Error())
+			return
+		}
+
+		stored = true
+	}
+}
+
+// Successes and conflicts with existing data. Other client errors are either
+// transient, like 429, or leave nothing behind, so running the request again
+// answers the same
+func isFinal(statusCode int) bool {
+	return statusCode < http.StatusMultipleChoices || statusCode == http.StatusConflict
+}
+
+func replay(w http.ResponseWriter, req *http.Request, body []byte, record *models.IdempotencyKey) {
+	switch {
+	case record.RequestHash != requestHash(req, body):
+		problems.Write(w, ErrPayloadMismatch)
+
+	case record.CompletedAt == nil:
+		problems.Write(w, ErrKeyInProgress)
+
+	default:
+		if record.ContentType != "" {
+			w.Header().Set("Content-Type", record.ContentType)
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(record.StatusCode)
+		w.Write(record.Body)
+	}
+}
+
+func requestHash(req *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Passes the response through while keeping a copy for the store
+type recorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
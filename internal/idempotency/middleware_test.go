@@ -0,0 +1,320 @@
+package idempotency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/idempotency"
+
+	"github.com/google/uuid"
+)
+
+// In-memory store with the same semantics as the repository
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyKey
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: map[string]*models.IdempotencyKey{}}
+}
+
+func (s *memoryStore) Reserve(key, requestHash string, now time.Time, lease time.Duration) (*models.IdempotencyKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[key]; ok && existing.ExpiresAt.After(now) {
+		return existing, false, nil
+	}
+
+	record := &models.IdempotencyKey{Key: key, RequestHash: requestHash, ReservationID: uuid.New(), CreatedAt: now, ExpiresAt: now.Add(lease)}
+	s.records[key] = record
+
+	return record, true, nil
+}
+
+func (s *memoryStore) Complete(key string, reservationID uuid.UUID, statusCode int, contentType string, body []byte, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[key]
+	if !ok || r.ReservationID != reservationID {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	r.StatusCode, r.ContentType, r.Body, r.CompletedAt, r.ExpiresAt = statusCode, contentType, body, &now, expiresAt
+
+	return nil
+}
+
+func (s *memoryStore) Release(key string, reservationID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok && r.ReservationID == reservationID {
+		delete(s.records, key)
+	}
+
+	return nil
+}
+
+func newRequest(key, body string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if key != "" {
+		req.Header.Set(idempotency.HeaderName, key)
+	}
+
+	return req
+}
+
+func TestMiddleware_ReplaysStoredResponse(t *testing.T) {
+	calls := 0
+	h := idempotency.Middleware(newMemoryStore(), time.Hour, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		req.ParseForm()
+		if req.FormValue("email") != "a@example.com" {
+			t.Errorf("handler should see the original body")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	h(w, newRequest("k1", "email=a@example.com"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, newRequest("k1", "email=a@example.com"))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected replayed 200, got %d", w.Code)
+	}
+	if w.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Error("expected replay header")
+	}
+	if calls != 1 {
+		t.Errorf("expected handler to run once, ran %d times", calls)
+	}
+}
+
+func TestMiddleware_ReplaysErrorResponse(t *testing.T) {
+	h := idempotency.Middleware(newMemoryStore(), time.Hour, func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "user already exists", http.StatusConflict)
+	})
+
+	h(httptest.NewRecorder(), newRequest("k1", "email=a@example.com"))
+
+	w := httptest.NewRecorder()
+	h(w, newRequest("k1", "email=a@example.com"))
+
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "user already exists") {
+		t.Errorf("expected stored 409, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestMiddleware_PayloadMismatch(t *testing.T) {
+	h := idempotency.Middleware(newMemoryStore(), time.Hour, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h(httptest.NewRecorder(), newRequest("k1", "email=a@example.com"))
+
+	w := httptest.NewRecorder()
+	h(w, newRequest("k1", "email=b@example.com"))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_InProgress(t *testing.T) {
+	var h http.HandlerFunc
+	concurrentCode := 0
+
+	// A retry arriving while the first request is still running
+	h = idempotency.Middleware(newMemoryStore(), time.Hour, func(w http.ResponseWriter, req *http.Request) {
+		if concurrentCode == 0 {
+			retry := httptest.NewRecorder()
+			concurrentCode = -1
+			h(retry, newRequest("k1", "email=a@example.com"))
+			concurrentCode = retry.Code
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h(httptest.NewRecorder(), newRequest("k1", "email=a@example.com"))
+
+	if concurrentCode != http.StatusConflict {
+		t.Errorf("expected 409 for concurrent request, got %d", concurrentCode)
+	}
+}
+
+func TestMiddleware_ServerErrorReleasesKey(t *testing.T) {
+	calls := 0
+	h := idempotency.Middleware(newMemoryStore(), time.Hour, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "Something went wrong", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h(httptest.NewRecorder(), newRequest("k1", "email=a@example.com"))
+
+	w := httptest.NewRecorder()
+	h(w, newRequest("k1", "email=a@example.com"))
+
+	if w.Code != http.StatusOK || calls != 2 {
+		t.Errorf("expected retry to run the handler again, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestMiddleware_NoKeyPassesThrough(t *testing.T) {
+	calls := 0
+	h := idempotency.Middleware(newMemoryStore(), time.Hour, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+	})
+
+	h(httptest.NewRecorder(), newRequest("", "email=a@example.com"))
+	h(httptest.NewRecorder(), newRequest("", "email=a@example.com"))
+
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestMiddleware_KeyTooLong(t *testing.T) {
+	h := idempotency.Middleware(newMemoryStore(), time.Hour, func(w http.ResponseWriter, req *http.Request) {})
+
+	w := httptest.NewRecorder()
+	h(w, newRequest(strings.Repeat("k", 256), ""))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_RetryableResponsesReleaseKey(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusForbidden, http.StatusBadRequest} {
+		calls := 0
+		h := idempotency.Middleware(newMemoryStore(), time.Hour, func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(status)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+
+		h(httptest.NewRecorder(), newRequest("k1", "email=a@example.com"))
+
+		w := httptest.NewRecorder()
+		h(w, newRequest("k1", "email=a@example.com"))
+
+		if w.Code != http.StatusOK || calls != 2 {
+			t.Errorf("%d: expected retry to run the handler again, got %d after %d calls", status, w.Code, calls)
+		}
+	}
+}
+
+func TestMiddleware_PanicReleasesKey(t *testing.T) {
+	store := newMemoryStore()
+	h := idempotency.Middleware(store, time.Hour, func(w http.ResponseWriter, req *http.Request) {
+		panic("handler crashed")
+	})
+
+	func() {
+		defer func() { recover() }()
+		h(httptest.NewRecorder(), newRequest("k1", "email=a@example.com"))
+	}()
+
+	if _, ok := store.records["k1"]; ok {
+		t.Error("expected the key to be released after a panic")
+	}
+}
+
+func TestMiddleware_ReservationLease(t *testing.T) {
+	store := newMemoryStore()
+	var leaseEnd time.Time
+
+	h := idempotency.Middleware(store, 24*time.Hour, func(w http.ResponseWriter, req *http.Request) {
+		leaseEnd = store.records["k1"].ExpiresAt
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h(httptest.NewRecorder(), newRequest("k1", "email=a@example.com"))
+
+	if time.Until(leaseEnd) > 5*time.Minute {
+		t.Errorf("expected a short reservation lease, got %s", time.Until(leaseEnd))
+	}
+	if time.Until(store.records["k1"].ExpiresAt) < 23*time.Hour {
+		t.Errorf("expected the stored response to be kept for the TTL, expires %s", store.records["k1"].ExpiresAt)
+	}
+}
+
+func TestMiddleware_TakenOverReservationIsKept(t *testing.T) {
+	for _, firstStatus := range []int{http.StatusOK, http.StatusInternalServerError} {
+		store := newMemoryStore()
+		started, finish := make(chan struct{}), make(chan struct{})
+		calls := 0
+
+		h := idempotency.Middleware(store, time.Hour, func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			status := http.StatusCreated
+			if calls == 1 {
+				status = firstStatus
+			}
+			started <- struct{}{}
+			<-finish
+			w.WriteHeader(status)
+		})
+
+		run := func() <-chan struct{} {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				h(httptest.NewRecorder(), newRequest("k1", "email=a@example.com"))
+			}()
+			return done
+		}
+
+		// The first request outlives its lease and a retry takes the key over
+		firstDone := run()
+		<-started
+		store.mu.Lock()
+		store.records["k1"].ExpiresAt = time.Now().Add(-time.Second)
+		store.mu.Unlock()
+
+		secondDone := run()
+		<-started
+		finish <- struct{}{}
+		<-firstDone
+
+		w := httptest.NewRecorder()
+		h(w, newRequest("k1", "email=a@example.com"))
+		if w.Code != http.StatusConflict {
+			t.Errorf("first request answered %d: expected the retry to keep the key, got %d", firstStatus, w.Code)
+		}
+
+		finish <- struct{}{}
+		<-secondDone
+
+		w = httptest.NewRecorder()
+		h(w, newRequest("k1", "email=a@example.com"))
+		if w.Code != http.StatusCreated {
+			t.Errorf("first request answered %d: expected the retry's response to be replayed, got %d", firstStatus, w.Code)
+		}
+		if calls != 2 {
+			t.Errorf("expected the handler to run twice, got %d", calls)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package links
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ActionConfirm     = "confirm"
+	ActionUnsubscribe = "unsubscribe"
+
+	// Always backed by a database token, which holds the new address
+	ActionConfirmEmail = "confirm-email"
+)
+
+// How long signed links stay valid. Unsubscribe links are re-issued with every
+// update email, so the TTL only has to outlive a few missed emails
+var signedTTL = map[string]time.Duration{
+	ActionConfirm:     48 * time.Hour,
+	ActionUnsubscribe: 90 * 24 * time.Hour,
+}
+
+// Joins base URL, API path and token
+func BuildURL(base, apiPath, token string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	// Join the path and token properly
+	u.Path = path.Join(u.Path, apiPath, token)
+
+	return u.String(), nil
+}
+
+// Builds action links for emails. With a signer links carry a signed payload,
+// otherwise they carry the database token
+type Builder struct {
+	baseURL string
+	signer  *Signer
+}
+
+func NewBuilder(baseURL string, signer *Signer) *Builder {
+	return &Builder{baseURL: baseURL, signer: signer}
+}
+
+// Reports whether links are stateless, in which case no token rows are needed
+func (b *Builder) Signed() bool {
+	return b.signer != nil
+}
+
+func (b *Builder) Signer() *Signer {
+	return b.signer
+}
+
+// Builds "/api/<action>/<token>" for the user. dbToken is ignored in signed mode
+func (b *Builder) ActionURL(action string, userID uuid.UUID, dbToken string) (string, error) {
+	token := dbToken
+
+	if b.signer != nil {
+		token = b.signer.Sign(Claims{
+			UserID:    userID,
+			Action:    action,
+			ExpiresAt: time.Now().Add(signedTTL[action]),
+		})
+	}
+
+	if token == "" {
+		return "", fmt.Errorf("no token for %s link", action)
+	}
+
+	return BuildURL(b.baseURL, "/api/"+action+"/", token)
+}
+
+// Builds "/api/<action>/<token>" with a database token, also in signed mode.
+// For actions that need state the signed payload can't carry
+func (b *Builder) TokenURL(action, dbToken string) (string, error) {
+	if dbToken == "" {
+		return "", fmt.Errorf("no token for %s link", action)
+	}
+
+	return BuildURL(b.baseURL, "/api/"+action+"/", dbToken)
+}
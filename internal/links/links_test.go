@@ -0,0 +1,80 @@
+package links_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/links"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildURL(t *testing.T) {
+	got, err := links.BuildURL("https://example.com/base", "/api/confirm/", "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != "https://example.com/base/api/confirm/abc" {
+		t.Errorf("unexpected url: %s", got)
+	}
+}
+
+func TestBuilder_DatabaseToken(t *testing.T) {
+	b := links.NewBuilder("https://example.com", nil)
+
+	got, err := b.ActionURL(links.ActionUnsubscribe, uuid.New(), "dbtoken")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got != "https://example.com/api/unsubscribe/dbtoken" {
+		t.Errorf("unexpected url: %s", got)
+	}
+
+	if _, err := b.ActionURL(links.ActionUnsubscribe, uuid.New(), ""); err == nil {
+		t.Error("expected error without database token")
+	}
+}
+
+func TestBuilder_Signed(t *testing.T) {
+	s := newSigner(t, key("a"))
+	b := links.NewBuilder("https://example.com", s)
+	userID := uuid.New()
+
+	got, err := b.ActionURL(links.ActionConfirm, userID, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	token, ok := strings.CutPrefix(got, "https://example.com/api/confirm/")
+	if !ok || !links.IsSigned(token) {
+		t.Fatalf("unexpected url: %s", got)
+	}
+
+	claims, err := s.Verify(token, time.Now())
+	if err != nil || claims.UserID != userID || claims.Action != links.ActionConfirm {
+		t.Errorf("unexpected claims %+v (%v)", claims, err)
+	}
+}
+
+func TestBuilder_TokenURLIgnoresSigner(t *testing.T) {
+	signer, err := links.NewSigner([]links.Key{{ID: "k1", Secret: []byte(strings.Repeat("s", 32))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := links.NewBuilder("https://example.com", signer)
+
+	got, err := b.TokenURL(links.ActionConfirmEmail, "db-token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "https://example.com/api/confirm-email/db-token" {
+		t.Errorf("unexpected url %q", got)
+	}
+
+	if _, err := b.TokenURL(links.ActionConfirmEmail, ""); err == nil {
+		t.Error("expected error for empty token")
+	}
+}
@@ -0,0 +1,174 @@
+package links
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoKeys           = errors.New("no signing keys configured")
+	ErrInvalidKey       = errors.New("signing key is invalid")
+	ErrMalformed        = errors.New("signed link is malformed")
+	ErrUnknownKey       = errors.New("signed link uses unknown key")
+	ErrInvalidSignature = errors.New("signed link signature is invalid")
+	ErrExpired          = errors.New("signed link has expired")
+)
+
+const minSecretLength = 32
+
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Payload carried by a signed link
+type Claims struct {
+	UserID    uuid.UUID
+	Action    string
+	ExpiresAt time.Time
+}
+
+// Signs with the first key and accepts any key in the set, so keys can be
+// rotated by prepending a new one and dropping the old one once its links expire
+type Signer struct {
+	keys []Key
+}
+
+func NewSigner(keys []Key) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	for _, k := range keys {
+		if k.ID == "" || strings.ContainsAny(k.ID, ".:,") || len(k.Secret) < minSecretLength {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidKey, k.ID)
+		}
+	}
+
+	return &Signer{keys: keys}, nil
+}
+
+// Parses "kid1:secret1,kid2:secret2". The first key is used for signing
+func ParseKeys(value string) ([]Key, error) {
+	var keys []Key
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: expected kid:secret", ErrInvalidKey)
+		}
+
+		keys = append(keys, Key{ID: id, Secret: []byte(secret)})
+	}
+
+	return keys, nil
+}
+
+func mac(secret []byte, data string) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// Produces "<payload>.<kid>.<signature>", all parts URL safe
+func (s *Signer) Sign(claims Claims) string {
+	key := s.keys[0]
+
+	raw := fmt.Sprintf("%s|%s|%d", claims.UserID, claims.Action, claims.ExpiresAt.Unix())
+	payload := base64.RawURLEncoding.EncodeToString([]byte(raw))
+	signed := payload + "." + key.ID
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac(key.Secret, signed))
+}
+
+func (s *Signer) Verify(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	payload, kid, signature := parts[0], parts[1], parts[2]
+
+	var key *Key
+	for i := range s.keys {
+		if s.keys[i].ID == kid {
+			key = &s.keys[i]
+			break
+		}
+	}
+
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if !hmac.Equal(got, mac(key.Secret, payload+"."+kid)) {
+		return nil, ErrInvalidSignature
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	fields := strings.Split(string(raw), "|")
+	if len(fields) != 3 {
+		return nil, ErrMalformed
+	}
+
+	userID, err := uuid.Parse(fields[0])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	expUnix, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	claims := Claims{UserID: userID, Action: fields[1], ExpiresAt: time.Unix(expUnix, 0)}
+
+	if !now.Before(claims.ExpiresAt) {
+		return nil, ErrExpired
+	}
+
+	return &claims, nil
+}
+
+// Database tokens never contain dots, signed links always do
+func IsSigned(token string) bool {
+	return strings.Contains(token, ".")
+}
+
+// Reads LINK_SIGNING_KEYS. Returns nil when it is empty, which keeps links
+// backed by database tokens
+func SignerFromEnv() (*Signer, error) {
+	keys, err := ParseKeys(os.Getenv("LINK_SIGNING_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	return NewSigner(keys)
+}
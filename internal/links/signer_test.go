@@ -0,0 +1,97 @@
+package links_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/links"
+
+	"github.com/google/uuid"
+)
+
+func newSigner(t *testing.T, keys ...links.Key) *links.Signer {
+	t.Helper()
+
+	s, err := links.NewSigner(keys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return s
+}
+
+func key(id string) links.Key {
+	return links.Key{ID: id, Secret: []byte(strings.Repeat(id, 32))}
+}
+
+func TestSigner_RoundTrip(t *testing.T) {
+	s := newSigner(t, key("a"))
+
+	claims := links.Claims{UserID: uuid.New(), Action: links.ActionUnsubscribe, ExpiresAt: time.Now().Add(time.Hour)}
+
+	got, err := s.Verify(s.Sign(claims), time.Now())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got.UserID != claims.UserID || got.Action != claims.Action || got.ExpiresAt.Unix() != claims.ExpiresAt.Unix() {
+		t.Errorf("unexpected claims: %+v", got)
+	}
+}
+
+func TestSigner_Expired(t *testing.T) {
+	s := newSigner(t, key("a"))
+
+	token := s.Sign(links.Claims{UserID: uuid.New(), Action: "confirm", ExpiresAt: time.Now().Add(-time.Minute)})
+
+	if _, err := s.Verify(token, time.Now()); !errors.Is(err, links.ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestSigner_Tampered(t *testing.T) {
+	s := newSigner(t, key("a"))
+
+	token := s.Sign(links.Claims{UserID: uuid.New(), Action: "confirm", ExpiresAt: time.Now().Add(time.Hour)})
+	other := s.Sign(links.Claims{UserID: uuid.New(), Action: "unsubscribe", ExpiresAt: time.Now().Add(time.Hour)})
+
+	// Payload of one link with the signature of another
+	parts, otherParts := strings.Split(token, "."), strings.Split(other, ".")
+	forged := otherParts[0] + "." + parts[1] + "." + parts[2]
+
+	if _, err := s.Verify(forged, time.Now()); !errors.Is(err, links.ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestSigner_KeyRotation(t *testing.T) {
+	old := newSigner(t, key("a"))
+	rotated := newSigner(t, key("b"), key("a"))
+	retired := newSigner(t, key("b"))
+
+	token := old.Sign(links.Claims{UserID: uuid.New(), Action: "confirm", ExpiresAt: time.Now().Add(time.Hour)})
+
+	if _, err := rotated.Verify(token, time.Now()); err != nil {
+		t.Errorf("expected old key to be accepted after rotation, got %v", err)
+	}
+
+	if _, err := retired.Verify(token, time.Now()); !errors.Is(err, links.ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey after retiring key, got %v", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := links.ParseKeys("k2:" + strings.Repeat("x", 32) + ", k1:" + strings.Repeat("y", 32))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(keys) != 2 || keys[0].ID != "k2" || keys[1].ID != "k1" {
+		t.Errorf("unexpected keys: %+v", keys)
+	}
+
+	if _, err := links.NewSigner([]links.Key{{ID: "short", Secret: []byte("x")}}); !errors.Is(err, links.ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
@@ -1,36 +1,55 @@
 package mail
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"net/url"
-	"os"
-	"path"
+	"time"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
 	"weather-app/internal/database/repository"
+	"weather-app/internal/links"
 	"weather-app/internal/mail/mail_templates"
+	"weather-app/internal/schedule"
 	"weather-app/internal/weather"
 
+	"github.com/google/uuid"
 	"github.com/mailersend/mailersend-go"
 )
 
 type UserRepositoryInterface interface {
 	GetUserEmailInfoBatch(limit, offset int, subscriptionFrequency string) ([]repository.UserEmailInfo, error)
+	GetUserEmailInfo(userID uuid.UUID) (*repository.UserEmailInfo, error)
 }
 
 type MailSenderWrapperInterface interface {
-	SendMail(subject, html, text string, recipients []mailersend.Recipient) int
+	SendMail(subject, html, text string, recipients []mailersend.Recipient, headers []mailersend.Header) int
+}
+
+type WeatherServiceInterface interface {
+	GetWeatherWithExtras(city, lang string, extras weather.Extras) (*weather.WeatherData, error)
+}
+
+type DeliveryRepositoryInterface interface {
+	Create(delivery *models.Delivery) error
 }
 
 type MailService struct {
-	userRepo UserRepositoryInterface
-	msw      MailSenderWrapperInterface
+	userRepo     UserRepositoryInterface
+	deliveryRepo DeliveryRepositoryInterface
+	msw          MailSenderWrapperInterface
+	links        *links.Builder
+	weather      WeatherServiceInterface
 }
 
-func NewMailService(userRepo UserRepositoryInterface, msw MailSenderWrapperInterface) *MailService {
-	return &MailService{userRepo: userRepo, msw: msw}
+func NewMailService(
+	userRepo UserRepositoryInterface,
+	deliveryRepo DeliveryRepositoryInterface,
+	msw MailSenderWrapperInterface,
+	linkBuilder *links.Builder,
+	weatherService WeatherServiceInterface,
+) *MailService {
+	return &MailService{userRepo: userRepo, deliveryRepo: deliveryRepo, msw: msw, links: linkBuilder, weather: weatherService}
 }
 
 type UpdateType int
@@ -38,24 +57,35 @@ type UpdateType int
 const (
 	Hourly UpdateType = iota
 	Daily
+	Weekly
+	Weekdays
+	EveryNHours
+	Cron
 )
 
 var updateTypeName = map[UpdateType]string{
-	Hourly: "hourly",
-	Daily:  "daily",
+	Hourly:      schedule.FrequencyHourly,
+	Daily:       schedule.FrequencyDaily,
+	Weekly:      schedule.FrequencyWeekly,
+	Weekdays:    schedule.FrequencyWeekdays,
+	EveryNHours: schedule.FrequencyEveryNHours,
+	Cron:        schedule.FrequencyCron,
 }
 
-// TODO: Move to other place. Should be common
-func BuildTokenURL(base, apiPath, token string) (string, error) {
-	u, err := url.Parse(base)
-	if err != nil {
-		return "", fmt.Errorf("invalid base URL: %w", err)
-	}
+// Every update type the mail-sender has to evaluate on each tick
+var UpdateTypes = []UpdateType{Hourly, Daily, Weekly, Weekdays, EveryNHours, Cron}
 
-	// Join the path and token properly
-	u.Path = path.Join(u.Path, apiPath, token)
+func (t UpdateType) String() string {
+	return updateTypeName[t]
+}
 
-	return u.String(), nil
+// RFC 8058 one-click unsubscribe. Mail clients POST "List-Unsubscribe=One-Click"
+// to the same URL the subscriber would open from the email body
+func unsubscribeHeaders(unsubscribeUrl string) []mailersend.Header {
+	return []mailersend.Header{
+		{Name: "List-Unsubscribe", Value: "<" + unsubscribeUrl + ">"},
+		{Name: "List-Unsubscribe-Post", Value: "List-Unsubscribe=One-Click"},
+	}
 }
 
 func (srv *MailService) SendConfirmationMail(email, confirmationUrl, unsubscribeUrl string) error {
@@ -79,65 +109,64 @@ func (srv *MailService) SendConfirmationMail(email, confirmationUrl, unsubscribe
 		},
 	}
 
-	srv.msw.SendMail(subject, html, text, recipients)
+	srv.msw.SendMail(subject, html, text, recipients, unsubscribeHeaders(unsubscribeUrl))
 
 	return nil
 }
 
-func buildWeatherURL(baseURL, city string) (string, error) {
-	u, err := url.Parse(baseURL)
-	if err != nil {
-		return "", fmt.Errorf("invalid base URL: %w", err)
+// Sent to the new address, which only replaces the old one once confirmed
+func (srv *MailService) SendEmailChangeMail(newEmail, confirmationUrl string) error {
+	data := mail_templates.EmailChangeData{
+		NewEmail:   newEmail,
+		ConfirmURL: confirmationUrl,
 	}
 
-	u.Path = "/api/weather"
-
-	// Add query parameters
-	q := u.Query()
-	q.Set("city", city)
-	u.RawQuery = q.Encode()
-
-	return u.String(), nil
-}
-
-func callWeatherAPI(city string) (*weather.WeatherData, error) {
-	var result weather.WeatherData
-
-	url, err := buildWeatherURL(os.Getenv("WEATHER_APP_BASE_URL"), city)
+	subject := "Confirm your new email address"
+	text := fmt.Sprintf("Confirm your new email address using %s.\n The link is valid for 24 hours.", data.ConfirmURL)
+	html, err := mail_templates.FormEmailChangeMail(&data)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	resp, err := http.Get(url)
-	if err != nil {
-		fetchErr := fmt.Errorf("failed to fetch weather: %s", err.Error())
-
-		return nil, fetchErr
+	recipients := []mailersend.Recipient{
+		{
+			Email: newEmail,
+		},
 	}
 
-	defer resp.Body.Close()
+	srv.msw.SendMail(subject, html, text, recipients, nil)
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
+	return nil
+}
 
-		if resp.StatusCode == http.StatusNotFound {
-			return nil, weather.ErrCityNotFound
-		} else {
-			return nil, fmt.Errorf("API error %s", string(body))
-		}
+// Tells the previous address where its updates went
+func (srv *MailService) SendEmailChangedNotice(oldEmail, newEmail string) error {
+	data := mail_templates.EmailChangeData{
+		NewEmail: newEmail,
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		invalidResponceErr := fmt.Errorf("invalid response JSON: %s", err.Error())
+	subject := "Your email address was changed"
+	text := fmt.Sprintf("Weather updates for this address now go to %s.", newEmail)
+	html, err := mail_templates.FormEmailChangedNoticeMail(&data)
 
-		return nil, invalidResponceErr
+	if err != nil {
+		return err
 	}
 
-	return &result, nil
+	recipients := []mailersend.Recipient{
+		{
+			Email: oldEmail,
+		},
+	}
+
+	srv.msw.SendMail(subject, html, text, recipients, nil)
+
+	return nil
 }
 
-func (srv *MailService) SendWeatherUpdate(updateType UpdateType) error {
+// Sends updates to subscribers whose local schedule fires in the (from, to] window
+func (srv *MailService) SendWeatherUpdate(updateType UpdateType, from, to time.Time) error {
 
 	offset := 0
 	limit := 100
@@ -156,45 +185,103 @@ func (srv *MailService) SendWeatherUpdate(updateType UpdateType) error {
 		}
 
 		for _, entry := range batch {
-			log.Printf("Send %s to %s for city %s with token %s\n", updateTypeName[updateType], entry.Email, entry.City, entry.TokenValue)
-			data, err := callWeatherAPI(entry.City)
+			if !entry.Schedule(updateTypeName[updateType]).Due(from, to) {
+				continue
+			}
 
-			if err != nil {
-				log.Printf("call weather API error: %s\n", err.Error())
-				globalError = err
+			log.Printf("Send %s to user %s for city %s\n", updateTypeName[updateType], entry.UserID, entry.City)
 
-				continue
+			if _, err := srv.sendUpdate(entry, updateTypeName[updateType], ""); err != nil {
+				globalError = err
 			}
+		}
 
-			log.Printf("Temperature: %1.f\nHumidity:%d\nDescription:%s", data.Temperature, data.Humidity, data.Description)
+		offset += limit
+	}
 
-			subject := fmt.Sprintf("Weather update for %s", entry.City)
+	return globalError
+}
 
-			unsubscribeUrl, _ := BuildTokenURL(os.Getenv("BASE_URL"), "/api/unsubscribe/", entry.TokenValue)
+// Sends the current weather to one user right away, regardless of schedule,
+// pause or confirmation. Used by admins to check delivery
+func (srv *MailService) SendTestUpdate(userID uuid.UUID) error {
+	entry, err := srv.userRepo.GetUserEmailInfo(userID)
+	if err != nil {
+		return err
+	}
 
-			weatherData := mail_templates.WeatherUpdateData{
-				City:           entry.City,
-				Temperature:    data.Temperature,
-				Humidity:       data.Humidity,
-				Description:    data.Description,
-				UnsubscribeURL: unsubscribeUrl,
-			}
+	log.Printf("Send test update to user %s for city %s\n", entry.UserID, entry.City)
 
-			text := fmt.Sprintf("Weather update for %s", unsubscribeUrl)
-			html, _ := mail_templates.FormWeatherUpdateMail(&weatherData)
+	statusCode, err := srv.sendUpdate(*entry, testFrequency, "[Test] ")
+	if err != nil {
+		return err
+	}
 
-			// TODO: Send not one by one, but group by city
-			recipients := []mailersend.Recipient{
-				{
-					Email: entry.Email,
-				},
-			}
+	if statusCode < 200 || statusCode >= 300 {
+		return fmt.Errorf("%w: status %d", ErrDeliveryFailed, statusCode)
+	}
 
-			srv.msw.SendMail(subject, html, text, recipients)
-		}
+	return nil
+}
 
-		offset += limit
+// Recorded as delivery frequency for test sends
+const testFrequency = "test"
+
+var ErrDeliveryFailed = errors.New("mail provider rejected the message")
+
+func (srv *MailService) sendUpdate(entry repository.UserEmailInfo, frequency, subjectPrefix string) (int, error) {
+	prefs := entry.Preferences()
+
+	data, err := srv.weather.GetWeatherWithExtras(entry.City, prefs.Language, prefs.WeatherExtras())
+
+	if err != nil {
+		log.Printf("get weather error: %s\n", err.Error())
+
+		return 0, err
 	}
 
-	return globalError
+	log.Printf("Temperature: %1.f\nHumidity:%d\nDescription:%s", data.Temperature, data.Humidity, data.Description)
+
+	report := content.Render(entry.City, *data, prefs)
+
+	subject := subjectPrefix + report.Title
+
+	unsubscribeUrl, err := srv.links.ActionURL(links.ActionUnsubscribe, entry.UserID, entry.TokenValue)
+	if err != nil {
+		log.Printf("build unsubscribe url error: %s\n", err.Error())
+
+		return 0, err
+	}
+
+	weatherData := mail_templates.WeatherUpdateData{
+		Report:         report,
+		UnsubscribeURL: unsubscribeUrl,
+	}
+
+	text := fmt.Sprintf("%s: %s\n%s %s", report.Title, report.Summary(), report.Text.UnsubscribePrompt, unsubscribeUrl)
+	html, _ := mail_templates.FormWeatherUpdateMail(&weatherData)
+
+	// TODO: Send not one by one, but group by city
+	recipients := []mailersend.Recipient{
+		{
+			Email: entry.Email,
+		},
+	}
+
+	statusCode := srv.msw.SendMail(subject, html, text, recipients, unsubscribeHeaders(unsubscribeUrl))
+
+	delivery := models.Delivery{
+		UserID:     entry.UserID,
+		Channel:    "email",
+		Frequency:  frequency,
+		City:       entry.City,
+		StatusCode: statusCode,
+		SentAt:     time.Now(),
+	}
+
+	if err := srv.deliveryRepo.Create(&delivery); err != nil {
+		log.Printf("Failed to record delivery: %s\n", err.Error())
+	}
+
+	return statusCode, nil
 }
@@ -0,0 +1,80 @@
+package mail_templates
+
+import (
+	"bytes"
+	"html/template"
+)
+
+const emailChangeEmailHTML = `
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>Confirm your new email address</title>
+  </head>
+  <body style="font-family: sans-serif; background-color: #f7f7f7; padding: 20px;">
+    <div style="max-width: 600px; margin: auto; background: #ffffff; padding: 30px; border-radius: 8px; box-shadow: 0 2px 5px rgba(0,0,0,0.1);">
+      <h2 style="color: #333333;">Confirm your new email address</h2>
+      <p style="font-size: 16px; color: #555555;">
+        Weather updates were requested to move to {{.NewEmail}}. Please confirm by clicking the button below:
+      </p>
+      <p style="text-align: center; margin: 30px 0;">
+        <a href="{{.ConfirmURL}}" style="background-color: #007BFF; color: white; padding: 12px 20px; text-decoration: none; border-radius: 5px;">
+          Confirm Email
+        </a>
+      </p>
+      <p style="font-size: 14px; color: #888888;">
+        The link is valid for 24 hours. If you didn’t request this, you can safely ignore this email.
+      </p>
+    </div>
+  </body>
+</html>
+`
+
+const emailChangedNoticeHTML = `
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <title>Your email address was changed</title>
+  </head>
+  <body style="font-family: sans-serif; background-color: #f7f7f7; padding: 20px;">
+    <div style="max-width: 600px; margin: auto; background: #ffffff; padding: 30px; border-radius: 8px; box-shadow: 0 2px 5px rgba(0,0,0,0.1);">
+      <h2 style="color: #333333;">Your email address was changed</h2>
+      <p style="font-size: 16px; color: #555555;">
+        Weather updates for this address now go to {{.NewEmail}}. You will not receive further emails here.
+      </p>
+      <p style="font-size: 14px; color: #888888;">
+        If you didn’t make this change, please contact us.
+      </p>
+    </div>
+  </body>
+</html>
+`
+
+type EmailChangeData struct {
+	NewEmail   string
+	ConfirmURL string
+}
+
+func FormEmailChangeMail(data *EmailChangeData) (string, error) {
+	return execute("email_change", emailChangeEmailHTML, data)
+}
+
+func FormEmailChangedNoticeMail(data *EmailChangeData) (string, error) {
+	return execute("email_changed", emailChangedNoticeHTML, data)
+}
+
+func execute(name, text string, data any) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -3,6 +3,7 @@ package mail_templates
 import (
 	"bytes"
 	"html/template"
+	"weather-app/internal/content"
 )
 
 const weatherUpdateEmailHTML = `
@@ -10,32 +11,32 @@ const weatherUpdateEmailHTML = `
 <html>
 <head>
   <meta charset="UTF-8">
-  <title>Weather Update</title>
+  <title>{{.Report.Title}}</title>
 </head>
 <body style="font-family: Arial, sans-serif; background-color: #f7f7f7; padding: 20px;">
   <div style="max-width: 600px; margin: auto; background-color: #ffffff; padding: 30px; border-radius: 8px; box-shadow: 0 2px 5px rgba(0,0,0,0.1);">
     
-    <h2 style="color: #333333;">Your Weather Update for {{.City}}</h2>
+    <h2 style="color: #333333;">{{.Report.Title}}</h2>
 
     <p style="font-size: 16px; color: #555555;">
-      Here's your latest forecast:
+      {{.Report.Text.Intro}}
     </p>
 
     <ul style="font-size: 16px; color: #444444;">
-      <li><strong>Temperature:</strong> {{.Temperature}}°C</li>
-      <li><strong>Humidity:</strong> {{.Humidity}}%</li>
-      <li><strong>Condition:</strong> {{.Description}}</li>
+      {{- range .Report.Lines}}
+      <li><strong>{{.Label}}:</strong> {{.Value}}</li>
+      {{- end}}
     </ul>
 
     <p style="margin-top: 30px; font-size: 14px; color: #888888;">
-      Stay safe and dress appropriately for today's weather!
+      {{.Report.Text.Outro}}
     </p>
 
     <hr style="margin: 40px 0; border: none; border-top: 1px solid #eeeeee;">
 
     <p style="font-size: 12px; color: #999999; text-align: center;">
-      Don’t want to receive updates? 
-      <a href="{{.UnsubscribeURL}}" style="color: #007BFF; text-decoration: none;">Unsubscribe here</a>.
+      {{.Report.Text.UnsubscribePrompt}}
+      <a href="{{.UnsubscribeURL}}" style="color: #007BFF; text-decoration: none;">{{.Report.Text.UnsubscribeLink}}</a>.
     </p>
 
   </div>
@@ -43,11 +44,9 @@ const weatherUpdateEmailHTML = `
 </html>
 `
 
+// Report is already in the subscriber's language and units
 type WeatherUpdateData struct {
-	City           string
-	Temperature    float64
-	Humidity       int
-	Description    string
+	Report         content.Report
 	UnsubscribeURL string
 }
 
@@ -5,24 +5,59 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
 	"testing"
+	"time"
+	"weather-app/internal/apikey"
+	"weather-app/internal/database/models"
 	"weather-app/internal/database/repository"
+	"weather-app/internal/links"
 	"weather-app/internal/mail"
+	"weather-app/internal/ratelimit"
 	"weather-app/internal/weather"
 
+	"github.com/google/uuid"
 	"github.com/mailersend/mailersend-go"
 )
 
 type mockSender struct {
 	Called      bool
 	LastSubject string
+	LastHTML    string
+	LastText    string
+	LastHeaders []mailersend.Header
+	StatusCode  int
 }
 
-func (m *mockSender) SendMail(subject, html, text string, recipients []mailersend.Recipient) int {
+func (m *mockSender) SendMail(subject, html, text string, recipients []mailersend.Recipient, headers []mailersend.Header) int {
 	m.Called = true
 	m.LastSubject = subject
-	return 1
+	m.LastHTML = html
+	m.LastText = text
+	m.LastHeaders = headers
+	if m.StatusCode == 0 {
+		return http.StatusAccepted
+	}
+	return m.StatusCode
+}
+
+func headerValue(headers []mailersend.Header, name string) string {
+	for _, h := range headers {
+		if h.Name == name {
+			return h.Value
+		}
+	}
+
+	return ""
+}
+
+type mockDeliveryRepo struct {
+	Deliveries []models.Delivery
+}
+
+func (m *mockDeliveryRepo) Create(delivery *models.Delivery) error {
+	m.Deliveries = append(m.Deliveries, *delivery)
+	return nil
 }
 
 type mockUserRepo struct {
@@ -30,6 +65,16 @@ type mockUserRepo struct {
 	err   error
 }
 
+func (m *mockUserRepo) GetUserEmailInfo(userID uuid.UUID) (*repository.UserEmailInfo, error) {
+	for _, entry := range m.batch {
+		if entry.UserID == userID {
+			return &entry, nil
+		}
+	}
+
+	return nil, repository.ErrNotFound
+}
+
 func (m *mockUserRepo) GetUserEmailInfoBatch(limit, offset int, subscriptionFrequency string) ([]repository.UserEmailInfo, error) {
 	if offset > 0 {
 		return make([]repository.UserEmailInfo, 0), m.err
@@ -38,9 +83,33 @@ func (m *mockUserRepo) GetUserEmailInfoBatch(limit, offset int, subscriptionFreq
 	return m.batch, m.err
 }
 
+type mockWeatherService struct {
+	data       *weather.WeatherData
+	err        error
+	lastLang   string
+	lastExtras weather.Extras
+}
+
+func (m *mockWeatherService) GetWeatherWithExtras(city, lang string, extras weather.Extras) (*weather.WeatherData, error) {
+	m.lastLang, m.lastExtras = lang, extras
+	return m.data, m.err
+}
+
+func weatherWith(data weather.WeatherData) *mockWeatherService {
+	return &mockWeatherService{data: &data}
+}
+
+var testLinks = links.NewBuilder("http://localhost:8080", nil)
+
+// Window that contains the default daily send time in UTC
+var (
+	noonFrom = time.Date(2025, 1, 1, 11, 45, 0, 0, time.UTC)
+	noonTo   = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+)
+
 func TestSendConfirmationMail_Success(t *testing.T) {
 	sender := &mockSender{}
-	svc := mail.NewMailService(nil, sender)
+	svc := mail.NewMailService(nil, &mockDeliveryRepo{}, sender, testLinks, nil)
 
 	err := svc.SendConfirmationMail("user@example.com", "http://confirm", "http://unsubscribe")
 	if err != nil {
@@ -53,19 +122,11 @@ func TestSendConfirmationMail_Success(t *testing.T) {
 }
 
 func TestSendWeatherUpdate_Success(t *testing.T) {
-	// Start mock weather API server
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		data := weather.WeatherData{
-			Temperature: 23.5,
-			Humidity:    60,
-			Description: "sunny",
-		}
-		json.NewEncoder(w).Encode(data)
-	}))
-	defer server.Close()
-
-	os.Setenv("WEATHER_APP_BASE_URL", server.URL)
-	os.Setenv("BASE_URL", "http://localhost:8080")
+	weatherService := weatherWith(weather.WeatherData{
+		Temperature: 23.5,
+		Humidity:    60,
+		Description: "sunny",
+	})
 
 	userRepo := &mockUserRepo{
 		batch: []repository.UserEmailInfo{
@@ -74,9 +135,9 @@ func TestSendWeatherUpdate_Success(t *testing.T) {
 	}
 
 	sender := &mockSender{}
-	svc := mail.NewMailService(userRepo, sender)
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, sender, testLinks, weatherService)
 
-	err := svc.SendWeatherUpdate(mail.Daily)
+	err := svc.SendWeatherUpdate(mail.Daily, noonFrom, noonTo)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -91,23 +152,16 @@ func TestSendWeatherUpdate_DBError(t *testing.T) {
 		err: errors.New("DB failure"),
 	}
 
-	svc := mail.NewMailService(userRepo, &mockSender{})
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, &mockSender{}, testLinks, nil)
 
-	err := svc.SendWeatherUpdate(mail.Hourly)
+	err := svc.SendWeatherUpdate(mail.Hourly, noonFrom, noonTo)
 	if err == nil || err.Error() != "failed to load batch: DB failure" {
 		t.Errorf("expected DB error, got %v", err)
 	}
 }
 
 func TestSendWeatherUpdate_ErrCityNotFound(t *testing.T) {
-	// Start mock weather API server
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		http.Error(w, `{"message":"city not found"}`, http.StatusNotFound)
-	}))
-	defer server.Close()
-
-	os.Setenv("WEATHER_APP_BASE_URL", server.URL)
-	os.Setenv("BASE_URL", "http://localhost:8080")
+	weatherService := &mockWeatherService{err: weather.ErrCityNotFound}
 
 	userRepo := &mockUserRepo{
 		batch: []repository.UserEmailInfo{
@@ -116,24 +170,248 @@ func TestSendWeatherUpdate_ErrCityNotFound(t *testing.T) {
 	}
 
 	sender := &mockSender{}
-	svc := mail.NewMailService(userRepo, sender)
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, sender, testLinks, weatherService)
 
-	err := svc.SendWeatherUpdate(mail.Daily)
+	err := svc.SendWeatherUpdate(mail.Daily, noonFrom, noonTo)
 	if err != weather.ErrCityNotFound {
 		t.Errorf("expected ErrCityNotFound, got %v", err)
 	}
 }
 
-func TestSendWeatherUpdate_OtherHTTPError(t *testing.T) {
-	// Start mock weather API server
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		http.Error(w, "internal error", http.StatusInternalServerError)
+func TestSendWeatherUpdate_WeatherError(t *testing.T) {
+	weatherService := &mockWeatherService{err: errors.New("weather provider is down")}
+
+	userRepo := &mockUserRepo{
+		batch: []repository.UserEmailInfo{
+			{Email: "test@example.com", City: "Kyiv", TokenValue: "abc123"},
+		},
+	}
+
+	sender := &mockSender{}
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, sender, testLinks, weatherService)
+
+	err := svc.SendWeatherUpdate(mail.Daily, noonFrom, noonTo)
+	if err == nil || err.Error() != "weather provider is down" {
+		t.Errorf("expected weather error, got %v", err)
+	}
+}
+
+func TestSendWeatherUpdate_SkipsNotDue(t *testing.T) {
+	userRepo := &mockUserRepo{
+		batch: []repository.UserEmailInfo{
+			// noonTo is a Wednesday, so a Monday subscription must be skipped
+			{Email: "test@example.com", City: "Kyiv", SendTime: "12:00", Weekday: int(time.Monday), TokenValue: "abc123"},
+		},
+	}
+
+	sender := &mockSender{}
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, sender, testLinks, &mockWeatherService{})
+
+	err := svc.SendWeatherUpdate(mail.Weekly, noonFrom, noonTo)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if sender.Called {
+		t.Error("expected SendMail not to be called")
+	}
+}
+
+func TestSendWeatherUpdate_RecordsDelivery(t *testing.T) {
+	weatherService := weatherWith(weather.WeatherData{Temperature: 20})
+
+	userID := uuid.New()
+	userRepo := &mockUserRepo{
+		batch: []repository.UserEmailInfo{
+			{UserID: userID, Email: "test@example.com", City: "Kyiv", TokenValue: "abc123"},
+		},
+	}
+
+	deliveries := &mockDeliveryRepo{}
+	svc := mail.NewMailService(userRepo, deliveries, &mockSender{}, testLinks, weatherService)
+
+	if err := svc.SendWeatherUpdate(mail.Daily, noonFrom, noonTo); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(deliveries.Deliveries) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(deliveries.Deliveries))
+	}
+
+	d := deliveries.Deliveries[0]
+	if d.UserID != userID || d.City != "Kyiv" || d.Frequency != "daily" || d.Channel != "email" {
+		t.Errorf("unexpected delivery: %+v", d)
+	}
+}
+
+func TestSendWeatherUpdate_Preferences(t *testing.T) {
+	weatherService := weatherWith(weather.WeatherData{Temperature: 20, Humidity: 40, Description: "ясно", WindSpeed: 5})
+
+	userRepo := &mockUserRepo{
+		batch: []repository.UserEmailInfo{{
+			UserID: uuid.New(), Email: "test@example.com", City: "Київ", TokenValue: "abc123",
+			ContentFields: "wind", Units: "imperial", Language: "uk",
+		}},
+	}
+
+	sender := &mockSender{}
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, sender, testLinks, weatherService)
+
+	if err := svc.SendWeatherUpdate(mail.Daily, noonFrom, noonTo); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if weatherService.lastLang != "uk" {
+		t.Errorf("expected weather in the subscriber's language, got lang %q", weatherService.lastLang)
+	}
+	if weatherService.lastExtras != (weather.Extras{}) {
+		t.Errorf("expected no forecast or air quality lookups for wind, got %+v", weatherService.lastExtras)
+	}
+	if sender.LastSubject != "Оновлення погоди для Київ" {
+		t.Errorf("unexpected subject %q", sender.LastSubject)
+	}
+	for _, want := range []string{"68.0°F", "ясно", "<strong>Вітер:</strong> 11.2 миль/год", "Відпишіться тут"} {
+		if !strings.Contains(sender.LastHTML, want) {
+			t.Errorf("expected html to contain %q", want)
+		}
+	}
+	if !strings.HasPrefix(sender.LastText, "Оновлення погоди для Київ: 68.0°F, ясно, вологість 40%, вітер 11.2 миль/год") {
+		t.Errorf("unexpected text %q", sender.LastText)
+	}
+}
+
+func TestSendConfirmationMail_ListUnsubscribeHeaders(t *testing.T) {
+	sender := &mockSender{}
+	svc := mail.NewMailService(nil, &mockDeliveryRepo{}, sender, testLinks, nil)
+
+	err := svc.SendConfirmationMail("user@example.com", "https://x/api/confirm/c", "https://x/api/unsubscribe/u")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := headerValue(sender.LastHeaders, "List-Unsubscribe"); got != "<https://x/api/unsubscribe/u>" {
+		t.Errorf("unexpected List-Unsubscribe header: %q", got)
+	}
+	if got := headerValue(sender.LastHeaders, "List-Unsubscribe-Post"); got != "List-Unsubscribe=One-Click" {
+		t.Errorf("unexpected List-Unsubscribe-Post header: %q", got)
+	}
+}
+
+func TestSendWeatherUpdate_ListUnsubscribeHeaders(t *testing.T) {
+	weatherService := weatherWith(weather.WeatherData{Temperature: 20})
+
+	userRepo := &mockUserRepo{
+		batch: []repository.UserEmailInfo{
+			{Email: "test@example.com", City: "Kyiv", TokenValue: "abc123"},
+		},
+	}
+
+	sender := &mockSender{}
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, sender, testLinks, weatherService)
+
+	if err := svc.SendWeatherUpdate(mail.Daily, noonFrom, noonTo); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := headerValue(sender.LastHeaders, "List-Unsubscribe"); got != "<http://localhost:8080/api/unsubscribe/abc123>" {
+		t.Errorf("unexpected List-Unsubscribe header: %q", got)
+	}
+}
+
+func TestSendEmailChangeMail(t *testing.T) {
+	sender := &mockSender{}
+	svc := mail.NewMailService(nil, &mockDeliveryRepo{}, sender, testLinks, nil)
+
+	if err := svc.SendEmailChangeMail("new@example.com", "https://x/api/confirm-email/t"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if sender.LastSubject != "Confirm your new email address" {
+		t.Errorf("unexpected subject %q", sender.LastSubject)
+	}
+	if sender.LastHeaders != nil {
+		t.Error("transactional mail should not carry List-Unsubscribe headers")
+	}
+}
+
+func TestSendEmailChangedNotice(t *testing.T) {
+	sender := &mockSender{}
+	svc := mail.NewMailService(nil, &mockDeliveryRepo{}, sender, testLinks, nil)
+
+	if err := svc.SendEmailChangedNotice("old@example.com", "new@example.com"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if sender.LastSubject != "Your email address was changed" {
+		t.Errorf("unexpected subject %q", sender.LastSubject)
+	}
+}
+
+func TestSendTestUpdate(t *testing.T) {
+	weatherService := weatherWith(weather.WeatherData{Temperature: 20})
+
+	userID := uuid.New()
+	userRepo := &mockUserRepo{
+		batch: []repository.UserEmailInfo{
+			{UserID: userID, Email: "test@example.com", City: "Kyiv", TokenValue: "abc123"},
+		},
+	}
+
+	sender := &mockSender{}
+	deliveries := &mockDeliveryRepo{}
+	svc := mail.NewMailService(userRepo, deliveries, sender, testLinks, weatherService)
+
+	if err := svc.SendTestUpdate(userID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if sender.LastSubject != "[Test] Weather update for Kyiv" {
+		t.Errorf("unexpected subject %q", sender.LastSubject)
+	}
+	if len(deliveries.Deliveries) != 1 || deliveries.Deliveries[0].Frequency != "test" {
+		t.Errorf("expected one test delivery, got %+v", deliveries.Deliveries)
+	}
+}
+
+func TestSendTestUpdate_Rejected(t *testing.T) {
+	weatherService := weatherWith(weather.WeatherData{Temperature: 20})
+
+	userID := uuid.New()
+	userRepo := &mockUserRepo{
+		batch: []repository.UserEmailInfo{
+			{UserID: userID, Email: "test@example.com", City: "Kyiv", TokenValue: "abc123"},
+		},
+	}
+
+	sender := &mockSender{StatusCode: http.StatusUnprocessableEntity}
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, sender, testLinks, weatherService)
+
+	if err := svc.SendTestUpdate(userID); !errors.Is(err, mail.ErrDeliveryFailed) {
+		t.Fatalf("expected ErrDeliveryFailed, got %v", err)
+	}
+}
+
+func TestSendTestUpdate_UnknownUser(t *testing.T) {
+	svc := mail.NewMailService(&mockUserRepo{}, &mockDeliveryRepo{}, &mockSender{}, testLinks, nil)
+
+	if err := svc.SendTestUpdate(uuid.New()); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+// Updates used to fetch /api/weather over HTTP, which rejects callers without
+// an API key when WEATHER_ANONYMOUS_ACCESS=deny
+func TestSendWeatherUpdate_AnonymousAccessDenied(t *testing.T) {
+	auth := apikey.NewAuthenticator(nil, nil, apikey.Anonymous{Mode: apikey.AnonymousDeny, Limit: 1}, ratelimit.ByIP(0))
+	server := httptest.NewServer(apikey.Middleware(auth, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(weather.WeatherData{Temperature: 20})
 	}))
 	defer server.Close()
 
-	os.Setenv("WEATHER_APP_BASE_URL", server.URL)
-	os.Setenv("BASE_URL", "http://localhost:8080")
+	t.Setenv("WEATHER_APP_BASE_URL", server.URL)
+	t.Setenv("WEATHER_ANONYMOUS_ACCESS", apikey.AnonymousDeny)
 
+	weatherService := weatherWith(weather.WeatherData{Temperature: 20})
 	userRepo := &mockUserRepo{
 		batch: []repository.UserEmailInfo{
 			{Email: "test@example.com", City: "Kyiv", TokenValue: "abc123"},
@@ -141,10 +419,31 @@ func TestSendWeatherUpdate_OtherHTTPError(t *testing.T) {
 	}
 
 	sender := &mockSender{}
-	svc := mail.NewMailService(userRepo, sender)
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, sender, testLinks, weatherService)
+
+	if err := svc.SendWeatherUpdate(mail.Daily, noonFrom, noonTo); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !sender.Called {
+		t.Error("expected the update to be sent without access to /api/weather")
+	}
+}
+
+func TestSendWeatherUpdate_FetchesRequestedExtras(t *testing.T) {
+	weatherService := weatherWith(weather.WeatherData{Temperature: 20, Forecast: weather.Forecast{Low: 10, High: 19}})
+	userRepo := &mockUserRepo{
+		batch: []repository.UserEmailInfo{
+			{Email: "test@example.com", City: "Kyiv", TokenValue: "abc123", ContentFields: "forecast,air_quality"},
+		},
+	}
+
+	svc := mail.NewMailService(userRepo, &mockDeliveryRepo{}, &mockSender{}, testLinks, weatherService)
+
+	if err := svc.SendWeatherUpdate(mail.Daily, noonFrom, noonTo); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 
-	err := svc.SendWeatherUpdate(mail.Daily)
-	if err == nil || err.Error() != "API error internal error\n" {
-		t.Errorf("expected API error, got %v", err)
+	if weatherService.lastExtras != (weather.Extras{Forecast: true, AirQuality: true}) {
+		t.Errorf("expected forecast and air quality to be fetched, got %+v", weatherService.lastExtras)
 	}
 }
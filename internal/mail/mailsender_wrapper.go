@@ -19,7 +19,7 @@ func NewMailSenderWrapper(apiKey string) *MailSenderWrapper {
 	return &MailSenderWrapper{apiKey: apiKey, ms: ms}
 }
 
-func (msw *MailSenderWrapper) SendMail(subject, html, text string, recipients []mailersend.Recipient) int {
+func (msw *MailSenderWrapper) SendMail(subject, html, text string, recipients []mailersend.Recipient, headers []mailersend.Header) int {
 	ms := msw.ms
 
 	ctx := context.Background()
@@ -39,6 +39,10 @@ func (msw *MailSenderWrapper) SendMail(subject, html, text string, recipients []
 	message.SetHTML(html)
 	message.SetText(text)
 
+	if len(headers) > 0 {
+		message.SetHeaders(headers)
+	}
+
 	res, _ := ms.Email.Send(ctx, message)
 
 	fmt.Println(res.Header.Get("X-Message-Id"))
@@ -0,0 +1,57 @@
+package page_templates
+
+// States of the landing page of confirmation links
+const (
+	LandingConfirmed        = "confirmed"
+	LandingAlreadyConfirmed = "already_confirmed"
+	LandingExpired          = "expired"
+	LandingInvalid          = "invalid" // Unknown, used up or of the wrong kind
+	LandingError            = "error"
+)
+
+const landingPageHTML = `
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>{{template "heading" .}} · Weather Updates</title>
+  </head>
+  <body style="font-family: sans-serif; background-color: #f7f7f7; padding: 20px;">
+    <div style="max-width: 600px; margin: auto; background: #ffffff; padding: 30px; border-radius: 8px; box-shadow: 0 2px 5px rgba(0,0,0,0.1);">
+      <p style="font-size: 14px; font-weight: bold; color: #007BFF; margin-top: 0;">Weather Updates</p>
+      <h2 style="color: #333333;">{{template "heading" .}}</h2>
+      <p style="font-size: 16px; color: #555555;">
+        {{if eq .State "confirmed"}}
+        Your email address is confirmed. Weather updates will arrive on the schedule you chose.
+        {{else if eq .State "already_confirmed"}}
+        This address was confirmed before, there is nothing left to do.
+        {{else if eq .State "expired"}}
+        Links in our emails are only valid for a while. Subscribe again to get a new one.
+        {{else if eq .State "invalid"}}
+        This link is unknown or has already been used. If you have confirmed your address before, your subscription is active.
+        {{else}}
+        We could not process the link. Please try again in a few minutes.
+        {{end}}
+      </p>
+      {{if and .SubscribeURL (or (eq .State "expired") (eq .State "invalid"))}}
+      <p style="text-align: center; margin: 30px 0;">
+        <a href="{{.SubscribeURL}}" style="background-color: #007BFF; color: white; padding: 12px 20px; text-decoration: none; border-radius: 5px;">
+          Subscribe again
+        </a>
+      </p>
+      {{end}}
+    </div>
+  </body>
+</html>
+{{define "heading"}}{{if eq .State "confirmed"}}Subscription confirmed{{else if eq .State "already_confirmed"}}Already confirmed{{else if eq .State "expired"}}This link has expired{{else if eq .State "invalid"}}This link is not valid{{else}}Something went wrong{{end}}{{end}}
+`
+
+type LandingData struct {
+	State        string // One of the Landing states
+	SubscribeURL string // Empty hides the subscribe button
+}
+
+func FormLandingPage(data *LandingData) (string, error) {
+	return execute("landing", landingPageHTML, data)
+}
@@ -0,0 +1,103 @@
+package page_templates
+
+import (
+	"bytes"
+	"html/template"
+)
+
+const unsubscribedPageHTML = `
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>You have been unsubscribed</title>
+  </head>
+  <body style="font-family: sans-serif; background-color: #f7f7f7; padding: 20px;">
+    <div style="max-width: 600px; margin: auto; background: #ffffff; padding: 30px; border-radius: 8px; box-shadow: 0 2px 5px rgba(0,0,0,0.1);">
+      <p style="font-size: 14px; font-weight: bold; color: #007BFF; margin-top: 0;">Weather Updates</p>
+      <h2 style="color: #333333;">You have been unsubscribed</h2>
+      <p style="font-size: 16px; color: #555555;">
+        You will not receive any more weather updates.
+      </p>
+      {{if .ReasonURL}}
+      <form method="POST" action="{{.ReasonURL}}">
+        <p style="font-size: 16px; color: #555555;">Would you tell us why? This is optional and anonymous.</p>
+        {{range .Reasons}}
+        <p>
+          <label style="font-size: 15px; color: #444444;">
+            <input type="radio" name="reason" value="{{.Value}}" required> {{.Label}}
+          </label>
+        </p>
+        {{end}}
+        <p style="margin-top: 20px;">
+          <button type="submit" style="background-color: #007BFF; color: white; padding: 10px 18px; border: none; border-radius: 5px;">
+            Send
+          </button>
+        </p>
+      </form>
+      {{end}}
+      {{if .SubscribeURL}}
+      <hr style="margin: 30px 0; border: none; border-top: 1px solid #eeeeee;">
+      <p style="font-size: 14px; color: #888888;">
+        Changed your mind?
+        <a href="{{.SubscribeURL}}" style="color: #007BFF; text-decoration: none;">Subscribe again</a>
+      </p>
+      {{end}}
+    </div>
+  </body>
+</html>
+`
+
+const reasonThanksPageHTML = `
+<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>Thank you</title>
+  </head>
+  <body style="font-family: sans-serif; background-color: #f7f7f7; padding: 20px;">
+    <div style="max-width: 600px; margin: auto; background: #ffffff; padding: 30px; border-radius: 8px; box-shadow: 0 2px 5px rgba(0,0,0,0.1);">
+      <p style="font-size: 14px; font-weight: bold; color: #007BFF; margin-top: 0;">Weather Updates</p>
+      <h2 style="color: #333333;">Thank you</h2>
+      <p style="font-size: 16px; color: #555555;">
+        Your feedback helps us improve the weather updates.
+      </p>
+    </div>
+  </body>
+</html>
+`
+
+type ReasonOption struct {
+	Value string
+	Label string
+}
+
+type UnsubscribedData struct {
+	ReasonURL    string // Empty hides the reason form
+	Reasons      []ReasonOption
+	SubscribeURL string // Empty hides the re-subscribe link
+}
+
+func FormUnsubscribedPage(data *UnsubscribedData) (string, error) {
+	return execute("unsubscribed", unsubscribedPageHTML, data)
+}
+
+func FormReasonThanksPage() (string, error) {
+	return execute("reason_thanks", reasonThanksPageHTML, nil)
+}
+
+func execute(name, text string, data any) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"weather-app/internal/content"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/weather"
+)
+
+// Weather update for one recipient
+type Update struct {
+	City        string
+	Weather     weather.WeatherData // Description already in the recipient's language
+	Preferences content.Preferences
+}
+
+// Message content in the recipient's language and units
+func (u Update) Report() content.Report {
+	return content.Render(u.City, u.Weather, u.Preferences)
+}
+
+// Weather for machine readable payloads, see content.Values
+func (u Update) Values() content.Values {
+	return content.NewValues(u.Weather, u.Preferences)
+}
+
+// Delivery channel for weather updates other than email, which stays with
+// MailService. The recipient's Target is the channel address, e.g. a Telegram
+// chat ID or a webhook URL
+type Notifier interface {
+	Notify(recipient repository.ChannelRecipient, update Update) error
+}
+
+// Implemented by notifiers that remove recipients the provider reports gone.
+// Removing them while batches are still loaded by offset would skip others,
+// so it's done in Flush, once the channel's recipients are all sent
+type Flusher interface {
+	Flush()
+}
@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/weather"
+
+	"github.com/google/uuid"
+)
+
+const batchSize = 100
+
+type RecipientRepositoryInterface interface {
+	Recipients(limit, offset int, frequency string) ([]repository.ChannelRecipient, error)
+}
+
+type DeliveryRepositoryInterface interface {
+	Create(delivery *models.Delivery) error
+}
+
+type WeatherServiceInterface interface {
+	GetWeatherWithExtras(city, lang string, extras weather.Extras) (*weather.WeatherData, error)
+}
+
+// Recipients of one channel and the notifier that reaches them
+type Channel struct {
+	Name       string // Recorded in deliveries, e.g. "telegram"
+	Recipients RecipientRepositoryInterface
+	Notifier   Notifier
+}
+
+type NotifyService struct {
+	weather      WeatherServiceInterface
+	deliveryRepo DeliveryRepositoryInterface
+	channels     []Channel
+}
+
+func NewNotifyService(weatherService WeatherServiceInterface, deliveryRepo DeliveryRepositoryInterface, channels ...Channel) *NotifyService {
+	return &NotifyService{
+		weather:      weatherService,
+		deliveryRepo: deliveryRepo,
+		channels:     channels,
+	}
+}
+
+// Sends updates on every channel to recipients whose local schedule fires in
+// the (from, to] window. Returns the last error, one failed recipient does
+// not stop the others
+func (srv *NotifyService) SendWeatherUpdate(frequency string, from, to time.Time) error {
+	var globalError error
+
+	for _, channel := range srv.channels {
+		if err := srv.sendChannel(channel, frequency, from, to); err != nil {
+			globalError = err
+		}
+	}
+
+	return globalError
+}
+
+func (srv *NotifyService) sendChannel(channel Channel, frequency string, from, to time.Time) error {
+	var globalError error
+
+	if flusher, ok := channel.Notifier.(Flusher); ok {
+		defer flusher.Flush()
+	}
+
+	for offset := 0; ; offset += batchSize {
+		batch, err := channel.Recipients.Recipients(batchSize, offset, frequency)
+		if err != nil {
+			return fmt.Errorf("failed to load %s batch: %w", channel.Name, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, recipient := range batch {
+			if !recipient.Schedule(frequency).Due(from, to) {
+				continue
+			}
+
+			// Targets can be credentials, e.g. Slack webhook URLs
+			log.Printf("Send %s %s update to %s for city %s\n", frequency, channel.Name, recipientName(recipient), recipient.City)
+
+			if err := srv.send(channel, recipient, frequency); err != nil {
+				log.Printf("%s update error: %s\n", channel.Name, err.Error())
+				globalError = err
+			}
+		}
+	}
+
+	return globalError
+}
+
+func recipientName(recipient repository.ChannelRecipient) string {
+	if recipient.RecipientID != uuid.Nil {
+		return "subscription " + recipient.RecipientID.String()
+	}
+
+	return "user " + recipient.UserID.String()
+}
+
+func (srv *NotifyService) send(channel Channel, recipient repository.ChannelRecipient, frequency string) error {
+	prefs := recipient.Preferences()
+
+	data, err := srv.weather.GetWeatherWithExtras(recipient.City, prefs.Language, prefs.WeatherExtras())
+	if err != nil {
+		return err
+	}
+
+	update := Update{City: recipient.City, Weather: *data, Preferences: prefs}
+	if err := channel.Notifier.Notify(recipient, update); err != nil {
+		return err
+	}
+
+	delivery := models.Delivery{
+		UserID:     recipient.UserID,
+		Channel:    channel.Name,
+		Frequency:  frequency,
+		City:       recipient.City,
+		StatusCode: http.StatusOK,
+		SentAt:     time.Now(),
+	}
+	if recipient.RecipientID != uuid.Nil {
+		delivery.RecipientID = &recipient.RecipientID
+	}
+
+	if err := srv.deliveryRepo.Create(&delivery); err != nil {
+		log.Printf("Failed to record delivery: %s\n", err.Error())
+	}
+
+	return nil
+}
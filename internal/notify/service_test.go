@@ -0,0 +1,216 @@
+package notify_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/notify"
+	"weather-app/internal/weather"
+
+	"github.com/google/uuid"
+)
+
+type mockRecipients struct {
+	recipients []repository.ChannelRecipient
+	frequency  string
+}
+
+func (m *mockRecipients) Recipients(limit, offset int, frequency string) ([]repository.ChannelRecipient, error) {
+	m.frequency = frequency
+	if offset >= len(m.recipients) {
+		return nil, nil
+	}
+	return m.recipients[offset:min(offset+limit, len(m.recipients))], nil
+}
+
+type sentUpdate struct {
+	recipient string
+	update    notify.Update
+}
+
+type mockNotifier struct {
+	sent []sentUpdate
+	err  error
+}
+
+func (m *mockNotifier) Notify(recipient repository.ChannelRecipient, update notify.Update) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, sentUpdate{recipient: recipient.Target, update: update})
+	return nil
+}
+
+type mockWeather struct{}
+
+func (mockWeather) GetWeatherWithExtras(city, lang string, extras weather.Extras) (*weather.WeatherData, error) {
+	if city == "Atlantis" {
+		return nil, weather.ErrCityNotFound
+	}
+	return &weather.WeatherData{Temperature: 21, Humidity: 40, Description: "clear sky"}, nil
+}
+
+type mockDeliveryRepo struct {
+	deliveries []models.Delivery
+}
+
+func (m *mockDeliveryRepo) Create(delivery *models.Delivery) error {
+	m.deliveries = append(m.deliveries, *delivery)
+	return nil
+}
+
+func TestSendWeatherUpdate_DueRecipients(t *testing.T) {
+	userID := uuid.New()
+	recipients := &mockRecipients{recipients: []repository.ChannelRecipient{
+		{UserID: userID, Target: "100", City: "Kyiv", Timezone: "UTC", SendTime: "08:00"},
+		{UserID: uuid.New(), Target: "200", City: "Lviv", Timezone: "UTC", SendTime: "18:00"},
+	}}
+	notifier := &mockNotifier{}
+	deliveries := &mockDeliveryRepo{}
+
+	svc := notify.NewNotifyService(mockWeather{}, deliveries, notify.Channel{Name: "telegram", Recipients: recipients, Notifier: notifier})
+
+	to := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	if err := svc.SendWeatherUpdate("daily", to.Add(-15*time.Minute), to); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if recipients.frequency != "daily" {
+		t.Errorf("expected daily recipients, got %q", recipients.frequency)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].recipient != "100" || notifier.sent[0].update.City != "Kyiv" {
+		t.Fatalf("expected one update to chat 100, got %+v", notifier.sent)
+	}
+	if notifier.sent[0].update.Weather.Description != "clear sky" {
+		t.Errorf("unexpected weather %+v", notifier.sent[0].update.Weather)
+	}
+	if len(deliveries.deliveries) != 1 || deliveries.deliveries[0].Channel != "telegram" || deliveries.deliveries[0].UserID != userID {
+		t.Errorf("expected telegram delivery to be recorded, got %+v", deliveries.deliveries)
+	}
+}
+
+func TestSendWeatherUpdate_ContinuesAfterFailure(t *testing.T) {
+	recipients := &mockRecipients{recipients: []repository.ChannelRecipient{
+		{Target: "100", City: "Atlantis", Timezone: "UTC"},
+		{Target: "200", City: "Kyiv", Timezone: "UTC"},
+	}}
+	notifier := &mockNotifier{}
+	deliveries := &mockDeliveryRepo{}
+
+	svc := notify.NewNotifyService(mockWeather{}, deliveries, notify.Channel{Name: "telegram", Recipients: recipients, Notifier: notifier})
+
+	to := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	err := svc.SendWeatherUpdate("hourly", to.Add(-15*time.Minute), to)
+
+	if !errors.Is(err, weather.ErrCityNotFound) {
+		t.Errorf("expected ErrCityNotFound, got %v", err)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].recipient != "200" {
+		t.Errorf("expected the second recipient to be notified, got %+v", notifier.sent)
+	}
+}
+
+func TestSendWeatherUpdate_NotifierErrorSkipsDelivery(t *testing.T) {
+	recipients := &mockRecipients{recipients: []repository.ChannelRecipient{{Target: "100", City: "Kyiv", Timezone: "UTC"}}}
+	notifier := &mockNotifier{err: errors.New("bot was blocked")}
+	deliveries := &mockDeliveryRepo{}
+
+	svc := notify.NewNotifyService(mockWeather{}, deliveries, notify.Channel{Name: "telegram", Recipients: recipients, Notifier: notifier})
+
+	to := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	if err := svc.SendWeatherUpdate("hourly", to.Add(-15*time.Minute), to); err == nil {
+		t.Fatal("expected notifier error")
+	}
+	if len(deliveries.deliveries) != 0 {
+		t.Errorf("expected no delivery to be recorded, got %+v", deliveries.deliveries)
+	}
+}
+
+func TestSendWeatherUpdate_DoesNotLogTarget(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	target := "https://hooks.slack.com/services/T000/B000/secret"
+	recipients := &mockRecipients{recipients: []repository.ChannelRecipient{
+		{UserID: uuid.New(), Target: target, City: "Kyiv", Timezone: "UTC", SendTime: "08:00"},
+	}}
+
+	svc := notify.NewNotifyService(mockWeather{}, &mockDeliveryRepo{}, notify.Channel{Name: "slack", Recipients: recipients, Notifier: &mockNotifier{}})
+
+	to := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	if err := svc.SendWeatherUpdate("daily", to.Add(-15*time.Minute), to); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Contains(buf.String(), target) {
+		t.Errorf("webhook URL was logged: %s", buf.String())
+	}
+}
+
+// Removes every recipient it was asked to notify on Flush, like push
+// subscriptions the push service reports gone
+type goneNotifier struct {
+	recipients *mockRecipients
+	sent       []string
+}
+
+func (n *goneNotifier) Notify(recipient repository.ChannelRecipient, update notify.Update) error {
+	n.sent = append(n.sent, recipient.Target)
+	return errors.New("subscription gone")
+}
+
+func (n *goneNotifier) Flush() {
+	n.recipients.recipients = nil
+}
+
+func TestSendWeatherUpdate_FlushesAfterAllBatches(t *testing.T) {
+	recipients := &mockRecipients{}
+	for i := range 250 {
+		recipients.recipients = append(recipients.recipients,
+			repository.ChannelRecipient{RecipientID: uuid.New(), Target: strconv.Itoa(i), City: "Kyiv", Timezone: "UTC"})
+	}
+	notifier := &goneNotifier{recipients: recipients}
+
+	svc := notify.NewNotifyService(mockWeather{}, &mockDeliveryRepo{}, notify.Channel{Name: "push", Recipients: recipients, Notifier: notifier})
+
+	to := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	svc.SendWeatherUpdate("hourly", to.Add(-15*time.Minute), to)
+
+	if len(notifier.sent) != 250 {
+		t.Errorf("expected every recipient to be notified, got %d", len(notifier.sent))
+	}
+	if len(recipients.recipients) != 0 {
+		t.Error("expected the notifier to be flushed")
+	}
+}
+
+func TestSendWeatherUpdate_RecordsRecipientID(t *testing.T) {
+	subscriptionID := uuid.New()
+	recipients := &mockRecipients{recipients: []repository.ChannelRecipient{
+		{RecipientID: subscriptionID, Target: "https://push.example.com/abc", City: "Kyiv", Timezone: "UTC"},
+	}}
+	deliveries := &mockDeliveryRepo{}
+
+	svc := notify.NewNotifyService(mockWeather{}, deliveries, notify.Channel{Name: "push", Recipients: recipients, Notifier: &mockNotifier{}})
+
+	to := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	if err := svc.SendWeatherUpdate("hourly", to.Add(-15*time.Minute), to); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(deliveries.deliveries) != 1 {
+		t.Fatalf("expected one delivery, got %+v", deliveries.deliveries)
+	}
+	d := deliveries.deliveries[0]
+	if d.UserID != uuid.Nil || d.RecipientID == nil || *d.RecipientID != subscriptionID {
+		t.Errorf("expected the subscription as recipient and no user, got %+v", d)
+	}
+}
@@ -0,0 +1,303 @@
+package openapi_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/apiv2"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/openapi"
+	"weather-app/internal/schedule"
+	"weather-app/internal/subscription"
+	"weather-app/internal/weather"
+
+	"github.com/google/uuid"
+)
+
+type mockWeatherService struct {
+	err error
+}
+
+func (m *mockWeatherService) GetLocalizedWeather(city, lang string) (*weather.WeatherData, error) {
+	report, err := m.GetReport(city, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report.Data, nil
+}
+
+func (m *mockWeatherService) GetReport(city, lang string) (*weather.Report, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &weather.Report{
+		Data: weather.WeatherData{
+			Temperature: 21.5,
+			Humidity:    40,
+			Description: "clear sky",
+			FeelsLike:   20.9,
+			Pressure:    1012,
+			WindSpeed:   3.4,
+			Sunrise:     1748743200,
+			Sunset:      1748800800,
+			UTCOffset:   10800,
+		},
+		Location:   weather.Location{Name: "Kyiv", Country: "UA", Lat: 50.45, Lon: 30.52},
+		ObservedAt: time.Unix(1748767800, 0).UTC(),
+		FetchedAt:  time.Unix(1748768100, 0).UTC(),
+		Source:     weather.Provider,
+	}, nil
+}
+
+// Every method fails with err when it is set
+type mockSubscriptionService struct {
+	err error
+}
+
+func (m *mockSubscriptionService) Subscribe(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) error {
+	return m.err
+}
+
+func (m *mockSubscriptionService) CreateSubscription(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) (*models.Subscription, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &models.Subscription{
+		ID: uuid.New(), City: city, Frequency: sched.Frequency, Timezone: sched.Timezone, SendTime: sched.SendTime,
+		Weekday: int(sched.Weekday), ContentFields: prefs.StoredFields(), Units: prefs.Units, Language: prefs.Language,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+func (m *mockSubscriptionService) GetSubscription(id uuid.UUID) (*subscription.Details, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	until := time.Now().AddDate(0, 0, 7)
+	return &subscription.Details{
+		Subscription: models.Subscription{
+			ID: id, City: "Kyiv", Frequency: "cron", CronExpr: "0 8 * * 1-5", Timezone: "Europe/Kyiv",
+			Units: "metric", Language: "en", PausedAt: &until, PausedUntil: &until, CreatedAt: time.Now(),
+		},
+		Confirmed: true,
+	}, nil
+}
+
+func (m *mockSubscriptionService) Confirm(tokenValue string, meta audit.Meta) error {
+	return m.err
+}
+
+func (m *mockSubscriptionService) Unsubscribe(tokenValue string, meta audit.Meta) (uuid.UUID, error) {
+	return uuid.New(), m.err
+}
+
+func (m *mockSubscriptionService) Pause(tokenValue, until string, meta audit.Meta) error {
+	return m.err
+}
+
+func (m *mockSubscriptionService) Resume(tokenValue string, meta audit.Meta) error {
+	return m.err
+}
+
+func (m *mockSubscriptionService) RequestEmailChange(tokenValue, newEmail string, meta audit.Meta) error {
+	return m.err
+}
+
+func (m *mockSubscriptionService) ConfirmEmailChange(tokenValue string, meta audit.Meta) error {
+	return m.err
+}
+
+func (m *mockSubscriptionService) GetPreferences(tokenValue string) (content.Preferences, error) {
+	prefs, _ := content.Parse("wind,sun", "imperial", "uk")
+	return prefs, m.err
+}
+
+func (m *mockSubscriptionService) UpdatePreferences(tokenValue string, prefs content.Preferences, meta audit.Meta) error {
+	return m.err
+}
+
+type handlers struct {
+	weather      *weather.WeatherHandler
+	subscription *subscription.SubscriptionHandler
+	v2           *apiv2.Handler
+}
+
+type contractCase struct {
+	name    string
+	method  string
+	path    string
+	form    url.Values
+	accept  string
+	err     error // Returned by the services
+	handler func(h handlers) http.HandlerFunc
+	status  int
+}
+
+func weatherEndpoint(h handlers) http.HandlerFunc {
+	return h.weather.Handler
+}
+
+func subscriptionEndpoint(pick func(h *subscription.SubscriptionHandler) http.HandlerFunc) func(handlers) http.HandlerFunc {
+	return func(h handlers) http.HandlerFunc {
+		return pick(h.subscription)
+	}
+}
+
+func v2Endpoint(pick func(h *apiv2.Handler) http.HandlerFunc) func(handlers) http.HandlerFunc {
+	return func(h handlers) http.HandlerFunc {
+		return pick(h.v2)
+	}
+}
+
+var (
+	subscribe          = subscriptionEndpoint(func(h *subscription.SubscriptionHandler) http.HandlerFunc { return h.SubscribeHandler })
+	confirm            = subscriptionEndpoint(func(h *subscription.SubscriptionHandler) http.HandlerFunc { return h.ConfirmHandler })
+	unsubscribe        = subscriptionEndpoint(func(h *subscription.SubscriptionHandler) http.HandlerFunc { return h.UnsubscribeHandler })
+	pause              = subscriptionEndpoint(func(h *subscription.SubscriptionHandler) http.HandlerFunc { return h.PauseHandler })
+	resume             = subscriptionEndpoint(func(h *subscription.SubscriptionHandler) http.HandlerFunc { return h.ResumeHandler })
+	changeEmail        = subscriptionEndpoint(func(h *subscription.SubscriptionHandler) http.HandlerFunc { return h.ChangeEmailHandler })
+	confirmEmailChange = subscriptionEndpoint(func(h *subscription.SubscriptionHandler) http.HandlerFunc { return h.ConfirmEmailChangeHandler })
+	preferences        = subscriptionEndpoint(func(h *subscription.SubscriptionHandler) http.HandlerFunc { return h.PreferencesHandler })
+
+	weatherV2       = v2Endpoint(func(h *apiv2.Handler) http.HandlerFunc { return h.WeatherHandler })
+	subscriptionsV2 = v2Endpoint(func(h *apiv2.Handler) http.HandlerFunc { return h.SubscriptionsHandler })
+	subscriptionV2  = v2Endpoint(func(h *apiv2.Handler) http.HandlerFunc { return h.SubscriptionHandler })
+)
+
+var contractCases = []contractCase{
+	{name: "weather", method: "GET", path: "/api/weather?city=Kyiv&lang=uk", handler: weatherEndpoint, status: 200},
+	{name: "weather unknown city", method: "GET", path: "/api/weather?city=Atlantis", err: weather.ErrCityNotFound, handler: weatherEndpoint, status: 404},
+	{name: "weather upstream failure", method: "GET", path: "/api/weather?city=Kyiv", err: errors.New("timeout"), handler: weatherEndpoint, status: 500},
+
+	{name: "subscribe", method: "POST", path: "/api/subscribe", form: url.Values{
+		"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"weekly"}, "weekday": {"monday"},
+		"timezone": {"Europe/Kyiv"}, "send_time": {"08:00"}, "fields": {"wind,forecast"}, "units": {"metric"}, "lang": {"en"},
+	}, handler: subscribe, status: 200},
+	{name: "subscribe invalid schedule", method: "POST", path: "/api/subscribe", form: url.Values{
+		"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"weekly"},
+	}, handler: subscribe, status: 400},
+	{name: "subscribe existing user", method: "POST", path: "/api/subscribe", form: url.Values{
+		"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"daily"},
+	}, err: subscription.ErrUserAlreadyExists, handler: subscribe, status: 409},
+	{name: "subscribe suppressed address", method: "POST", path: "/api/subscribe", form: url.Values{
+		"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"daily"},
+	}, err: subscription.ErrEmailSuppressed, handler: subscribe, status: 403},
+
+	{name: "confirm", method: "GET", path: "/api/confirm/abc", handler: confirm, status: 200},
+	{name: "confirm as json", method: "GET", path: "/api/confirm/abc", accept: "application/json", handler: confirm, status: 200},
+	{name: "confirm again", method: "GET", path: "/api/confirm/abc", accept: "application/json", err: subscription.ErrAlreadyConfirmed, handler: confirm, status: 200},
+	{name: "confirm unknown token", method: "GET", path: "/api/confirm/abc", err: subscription.ErrTokenNotFound, handler: confirm, status: 404},
+	{name: "confirm expired link as json", method: "GET", path: "/api/confirm/abc", accept: "application/json", err: subscription.ErrTokenExpired, handler: confirm, status: 404},
+	{name: "confirm failure", method: "GET", path: "/api/confirm/abc", err: errors.New("database is down"), handler: confirm, status: 500},
+
+	{name: "unsubscribe", method: "GET", path: "/api/unsubscribe/abc", handler: unsubscribe, status: 200},
+	{name: "unsubscribe as json", method: "GET", path: "/api/unsubscribe/abc", accept: "application/json", handler: unsubscribe, status: 200},
+	{name: "unsubscribe wrong token", method: "GET", path: "/api/unsubscribe/abc", err: subscription.ErrTokenWrongType, handler: unsubscribe, status: 400},
+	{name: "unsubscribe wrong token as json", method: "GET", path: "/api/unsubscribe/abc", accept: "application/json", err: subscription.ErrTokenWrongType, handler: unsubscribe, status: 400},
+	{name: "one-click unsubscribe", method: "POST", path: "/api/unsubscribe/abc", form: url.Values{
+		"List-Unsubscribe": {"One-Click"},
+	}, handler: unsubscribe, status: 200},
+
+	{name: "pause", method: "GET", path: "/api/pause/abc?until=2026-01-01", handler: pause, status: 200},
+	{name: "pause invalid end", method: "GET", path: "/api/pause/abc?until=never", err: subscription.ErrInvalidPauseEnd, handler: pause, status: 400},
+	{name: "resume", method: "GET", path: "/api/resume/abc", handler: resume, status: 200},
+
+	{name: "change email", method: "POST", path: "/api/change-email/abc", form: url.Values{
+		"email": {"new@example.com"},
+	}, handler: changeEmail, status: 200},
+	{name: "change email to the same address", method: "POST", path: "/api/change-email/abc", form: url.Values{
+		"email": {"new@example.com"},
+	}, err: subscription.ErrSameEmail, handler: changeEmail, status: 400},
+	{name: "confirm email change", method: "GET", path: "/api/confirm-email/abc", handler: confirmEmailChange, status: 200},
+	{name: "confirm expired email change", method: "GET", path: "/api/confirm-email/abc", err: subscription.ErrEmailChangeExpired, handler: confirmEmailChange, status: 410},
+
+	{name: "get preferences", method: "GET", path: "/api/preferences/abc", handler: preferences, status: 200},
+	{name: "update preferences", method: "POST", path: "/api/preferences/abc", form: url.Values{
+		"fields": {"pressure", "air_quality"}, "units": {"imperial"},
+	}, handler: preferences, status: 200},
+	{name: "update preferences unknown token", method: "POST", path: "/api/preferences/abc", form: url.Values{
+		"units": {"metric"},
+	}, err: subscription.ErrTokenNotFound, handler: preferences, status: 404},
+
+	{name: "v2 weather", method: "GET", path: "/api/v2/weather?city=Kyiv&lang=uk", handler: weatherV2, status: 200},
+	{name: "v2 weather unknown city", method: "GET", path: "/api/v2/weather?city=Atlantis", err: weather.ErrCityNotFound, handler: weatherV2, status: 404},
+	{name: "v2 create subscription", method: "POST", path: "/api/v2/subscriptions", form: url.Values{
+		"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"weekly"}, "weekday": {"friday"},
+		"send_time": {"07:30"}, "fields": {"sun"}, "units": {"imperial"},
+	}, handler: subscriptionsV2, status: 201},
+	{name: "v2 create subscription for an existing user", method: "POST", path: "/api/v2/subscriptions", form: url.Values{
+		"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"daily"},
+	}, err: subscription.ErrUserAlreadyExists, handler: subscriptionsV2, status: 409},
+	{name: "v2 get subscription", method: "GET", path: "/api/v2/subscriptions/8d3f2a64-5d9e-4a8c-9a43-1c0f6f7e2b11", handler: subscriptionV2, status: 200},
+	{name: "v2 get unknown subscription", method: "GET", path: "/api/v2/subscriptions/8d3f2a64-5d9e-4a8c-9a43-1c0f6f7e2b11", err: subscription.ErrSubscriptionNotFound, handler: subscriptionV2, status: 404},
+}
+
+func TestContract(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	covered := map[string]bool{}
+
+	for _, tc := range contractCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var body io.Reader
+			if tc.form != nil {
+				body = strings.NewReader(tc.form.Encode())
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			if tc.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			if err := spec.ValidateRequest(req); err != nil {
+				t.Fatalf("request doesn't match the spec: %v", err)
+			}
+
+			op, _, _ := spec.FindOperation(tc.method, req.URL.Path)
+			covered[op.OperationID] = true
+
+			weatherService := &mockWeatherService{err: tc.err}
+			subService := &mockSubscriptionService{err: tc.err}
+
+			w := httptest.NewRecorder()
+			tc.handler(handlers{
+				weather:      weather.NewHandler(weatherService),
+				subscription: subscription.NewHandler(subService),
+				v2:           apiv2.NewHandler(weatherService, subService),
+			})(w, req)
+
+			if w.Code != tc.status {
+				t.Fatalf("expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+
+			if err := spec.ValidateResponse(req, w.Code, w.Header(), w.Body.Bytes()); err != nil {
+				t.Errorf("response doesn't match the spec: %v", err)
+			}
+		})
+	}
+
+	for _, item := range spec.Paths {
+		for method, op := range item {
+			if !covered[op.OperationID] {
+				t.Errorf("%s %s is not covered by the contract test", strings.ToUpper(method), op.OperationID)
+			}
+		}
+	}
+}
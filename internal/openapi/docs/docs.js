@@ -0,0 +1,109 @@
+// Renders the OpenAPI document without third-party scripts
+(() => {
+  const root = document.getElementById('docs');
+
+  const el = (tag, attrs, ...children) => {
+    const node = document.createElement(tag);
+    Object.entries(attrs || {}).forEach(([name, value]) => node.setAttribute(name, value));
+    children.flat().forEach((child) => {
+      if (child !== null && child !== undefined) {
+        node.append(child instanceof Node ? child : String(child));
+      }
+    });
+    return node;
+  };
+
+  const resolve = (spec, value) => {
+    if (!value || !value.$ref) {
+      return value;
+    }
+    return value.$ref.replace(/^#\//, '').split('/').reduce((node, key) => node && node[key], spec);
+  };
+
+  const refName = (value) => (value && value.$ref ? value.$ref.split('/').pop() : '');
+
+  const schemaType = (spec, schema) => {
+    if (!schema) {
+      return '';
+    }
+    if (schema.$ref) {
+      return refName(schema);
+    }
+    if (schema.type === 'array') {
+      return `${schemaType(spec, schema.items)}[]`;
+    }
+    if (schema.enum) {
+      return `${schema.type || ''} (${schema.enum.join(', ')})`;
+    }
+    return [schema.type, schema.format].filter(Boolean).join(' ');
+  };
+
+  const table = (headers, rows) => el('table', null,
+    el('thead', null, el('tr', null, headers.map((header) => el('th', null, header)))),
+    el('tbody', null, rows.map((row) => el('tr', null, row.map((cell) => el('td', null, cell))))));
+
+  const operation = (spec, path, method, op) => {
+    const params = (op.parameters || []).map((param) => resolve(spec, param));
+    const body = op.requestBody && resolve(spec, op.requestBody);
+    const responses = Object.entries(op.responses || {});
+
+    return el('details', { class: 'operation', id: op.operationId || `${method}-${path}` },
+      el('summary', null,
+        el('span', { class: `method method-${method}` }, method),
+        el('code', null, path), ' ', op.summary || ''),
+      el('div', null,
+        op.description ? el('p', null, op.description) : null,
+        params.length ? [el('h4', null, 'Parameters'), table(['Name', 'In', 'Type', 'Required', 'Description'],
+          params.map((param) => [el('code', null, param.name), param.in, schemaType(spec, param.schema),
+            param.required ? 'yes' : 'no', param.description || '']))] : null,
+        body ? [el('h4', null, 'Request body'), table(['Content type', 'Schema'],
+          Object.entries(body.content || {}).map(([type, media]) => [type, schemaType(spec, media.schema)]))] : null,
+        responses.length ? [el('h4', null, 'Responses'), table(['Status', 'Description', 'Schema'],
+          responses.map(([status, response]) => {
+            const resolved = resolve(spec, response) || {};
+            const media = Object.values(resolved.content || {})[0];
+            return [status, resolved.description || '', media ? schemaType(spec, media.schema) : ''];
+          }))] : null));
+  };
+
+  const schema = (spec, name, value) => {
+    const required = new Set(value.required || []);
+    const props = Object.entries(value.properties || {});
+
+    return el('details', { class: 'operation', id: `schema-${name}` },
+      el('summary', null, el('code', null, name)),
+      el('div', null,
+        value.description ? el('p', null, value.description) : null,
+        props.length ? table(['Property', 'Type', 'Required', 'Description'],
+          props.map(([prop, propSchema]) => [el('code', null, prop), schemaType(spec, propSchema),
+            required.has(prop) ? 'yes' : 'no', (propSchema && propSchema.description) || ''])) :
+          el('p', null, schemaType(spec, value))));
+  };
+
+  const render = (spec) => {
+    const info = spec.info || {};
+    const operations = Object.entries(spec.paths || {}).flatMap(([path, item]) =>
+      Object.entries(item)
+        .filter(([method]) => ['get', 'put', 'post', 'delete', 'patch', 'head', 'options'].includes(method))
+        .map(([method, op]) => operation(spec, path, method, op)));
+    const schemas = Object.entries((spec.components || {}).schemas || {});
+
+    root.replaceChildren(...[
+      el('h1', null, info.title || 'API', ' ', el('small', null, info.version || '')),
+      info.description ? el('p', null, info.description) : null,
+      el('p', null, el('a', { href: root.dataset.spec }, 'OpenAPI document')),
+      el('h2', null, 'Operations'), operations,
+      schemas.length ? [el('h2', null, 'Schemas'), schemas.map(([name, value]) => schema(spec, name, value))] : null,
+    ].flat(2).filter(Boolean));
+  };
+
+  fetch(root.dataset.spec)
+    .then((resp) => {
+      if (!resp.ok) {
+        throw new Error(`spec returned ${resp.status}`);
+      }
+      return resp.json();
+    })
+    .then(render)
+    .catch((err) => root.replaceChildren(el('p', null, `Failed to load the API description: ${err.message}`)));
+})();
@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"embed"
+	"log"
+	"net/http"
+	"weather-app/internal/problem"
+)
+
+// OpenAPI 3 description of the public weather and subscription endpoints.
+// Keep it in step with the handlers, the contract test checks them against it
+//
+//go:embed openapi.json
+var specJSON []byte
+
+// Docs page for the spec. Its assets are served from the binary, so the page
+// runs no third-party code
+//
+//go:embed docs/index.html
+var docsHTML []byte
+
+//go:embed docs
+var docsFiles embed.FS
+
+// Maps /api/docs/{asset} to docs/{asset}
+var docsAssets = http.StripPrefix("/api/", http.FileServer(http.FS(docsFiles)))
+
+// Only the page's own assets and the spec may load
+const docsPolicy = "default-src 'self'; frame-ancestors 'none'"
+
+// GET /api/openapi.json
+func SpecHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(specJSON); err != nil {
+		log.Printf("Writing spec failed: %s", err.Error())
+	}
+}
+
+// GET /api/docs
+func DocsHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", docsPolicy)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(docsHTML); err != nil {
+		log.Printf("Writing docs page failed: %s", err.Error())
+	}
+}
+
+// GET /api/docs/{asset}
+func DocsAssetHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	w.Header().Set("Content-Security-Policy", docsPolicy)
+	docsAssets.ServeHTTP(w, req)
+}
@@ -0,0 +1,109 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"weather-app/internal/openapi"
+)
+
+func TestSpecHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	openapi.SpecHandler(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected application/json, got %q", ct)
+	}
+
+	var doc struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatalf("invalid spec: %v", err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("expected OpenAPI 3, got %q", doc.OpenAPI)
+	}
+	if _, ok := doc.Paths["/api/weather"]["get"]; !ok {
+		t.Error("expected GET /api/weather in the spec")
+	}
+}
+
+func TestDocsHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	openapi.DocsHandler(w, httptest.NewRequest("GET", "/api/docs", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "/api/openapi.json") {
+		t.Error("expected the docs page to load the spec")
+	}
+	if strings.Contains(w.Body.String(), "https://") {
+		t.Error("expected the docs page to load no external assets")
+	}
+	if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'self'") {
+		t.Errorf("unexpected Content-Security-Policy %q", csp)
+	}
+}
+
+func TestDocsAssetHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	openapi.DocsAssetHandler(w, httptest.NewRequest("GET", "/api/docs/docs.js", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, "javascript") {
+		t.Errorf("expected a script, got %q", ct)
+	}
+
+	w = httptest.NewRecorder()
+	openapi.DocsAssetHandler(w, httptest.NewRequest("GET", "/api/docs/missing.js", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestValidateRequest_Rejects(t *testing.T) {
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := func(values url.Values) *http.Request {
+		req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	cases := map[string]*http.Request{
+		"missing query parameter": httptest.NewRequest("GET", "/api/weather", nil),
+		"pattern mismatch":        httptest.NewRequest("GET", "/api/weather?city=Kyiv&lang=english", nil),
+		"missing form field":      form(url.Values{"email": {"user@example.com"}, "city": {"Kyiv"}}),
+		"enum mismatch":           form(url.Values{"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"monthly"}}),
+		"unknown form field":      form(url.Values{"email": {"user@example.com"}, "city": {"Kyiv"}, "frequency": {"daily"}, "color": {"red"}}),
+		"unknown path":            httptest.NewRequest("GET", "/api/forecast", nil),
+	}
+
+	for name, req := range cases {
+		if err := spec.ValidateRequest(req); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	_, _, err = spec.FindOperation("DELETE", "/api/weather")
+	if !errors.Is(err, openapi.ErrNoOperation) {
+		t.Errorf("expected ErrNoOperation, got %v", err)
+	}
+}
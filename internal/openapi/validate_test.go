@@ -0,0 +1,361 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrNoOperation = errors.New("no operation in the spec")
+
+// The subset of OpenAPI 3 used by openapi.json
+type Spec struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema   `json:"schemas"`
+		Parameters map[string]Parameter `json:"parameters"`
+		Responses  map[string]Response  `json:"responses"`
+	} `json:"components"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []Parameter          `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // "path", "query" or "header"
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// No content means an empty body
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Properties map[string]*Schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Items      *Schema            `json:"items"`
+	Enum       []any              `json:"enum"`
+	Pattern    string             `json:"pattern"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+	Minimum    *float64           `json:"minimum"`
+	Maximum    *float64           `json:"maximum"`
+}
+
+// Parses the embedded spec
+func Load() (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(specJSON, &spec); err != nil {
+		return nil, fmt.Errorf("invalid openapi.json: %w", err)
+	}
+
+	return &spec, nil
+}
+
+// Finds the operation for a request path, with the values of its path
+// parameters
+func (s *Spec) FindOperation(method, path string) (*Operation, map[string]string, error) {
+	for template, item := range s.Paths {
+		params, ok := matchPath(template, path)
+		if !ok {
+			continue
+		}
+
+		if op, ok := item[strings.ToLower(method)]; ok {
+			return op, params, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: %s %s", ErrNoOperation, method, path)
+}
+
+// Checks parameters and the form body of req. The body stays readable for
+// handlers through req.Form
+func (s *Spec) ValidateRequest(req *http.Request) error {
+	op, pathParams, err := s.FindOperation(req.Method, req.URL.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, param := range op.Parameters {
+		param = s.resolveParameter(param)
+
+		var values []string
+		switch param.In {
+		case "path":
+			values = []string{pathParams[param.Name]}
+		case "query":
+			values = req.URL.Query()[param.Name]
+		case "header":
+			values = req.Header.Values(param.Name)
+		}
+
+		if err := s.validateValues(param.In+" "+param.Name, values, param.Required, param.Schema); err != nil {
+			return err
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+
+	media, ok := op.RequestBody.Content["application/x-www-form-urlencoded"]
+	if !ok {
+		return fmt.Errorf("%s %s: only form bodies are supported", req.Method, req.URL.Path)
+	}
+
+	if err := req.ParseForm(); err != nil {
+		return fmt.Errorf("request body: %w", err)
+	}
+	if op.RequestBody.Required && len(req.PostForm) == 0 {
+		return errors.New("request body is required")
+	}
+
+	schema := s.resolveSchema(media.Schema)
+	for name := range req.PostForm {
+		if _, ok := schema.Properties[name]; !ok {
+			return fmt.Errorf("form field %s is not in the spec", name)
+		}
+	}
+	for name, property := range schema.Properties {
+		required := slices.Contains(schema.Required, name)
+		if err := s.validateValues("form field "+name, req.PostForm[name], required, property); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Checks that the status is documented for the request's operation and the
+// body matches its content. Objects are closed: properties missing from the
+// spec are reported, so the spec can't fall behind the handlers
+func (s *Spec) ValidateResponse(req *http.Request, status int, header http.Header, body []byte) error {
+	op, _, err := s.FindOperation(req.Method, req.URL.Path)
+	if err != nil {
+		return err
+	}
+
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not documented", req.Method, req.URL.Path, status)
+	}
+	if response.Ref != "" {
+		resolved := s.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+		response = &resolved
+	}
+
+	if len(response.Content) == 0 {
+		if len(body) != 0 {
+			return fmt.Errorf("status %d: expected an empty body, got %q", status, body)
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("status %d: invalid content type: %w", status, err)
+	}
+
+	media, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("status %d: content type %s is not documented", status, mediaType)
+	}
+
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("status %d: invalid json: %w", status, err)
+	}
+
+	return s.validateValue("response", value, media.Schema)
+}
+
+func (s *Spec) validateValues(location string, values []string, required bool, schema *Schema) error {
+	if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+		if required {
+			return fmt.Errorf("%s is required", location)
+		}
+		return nil
+	}
+
+	schema = s.resolveSchema(schema)
+	for _, raw := range values {
+		var value any = raw
+
+		switch schema.Type {
+		case "integer", "number":
+			n, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return fmt.Errorf("%s: expected %s, got %q", location, schema.Type, raw)
+			}
+			value = n
+		case "boolean":
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("%s: expected boolean, got %q", location, raw)
+			}
+			value = b
+		}
+
+		if err := s.validateValue(location, value, schema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Spec) validateValue(location string, value any, schema *Schema) error {
+	schema = s.resolveSchema(schema)
+	if schema == nil {
+		return nil
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", location, value)
+		}
+
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s.%s is required", location, name)
+			}
+		}
+
+		for name, property := range object {
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				return fmt.Errorf("%s.%s is not in the spec", location, name)
+			}
+			if err := s.validateValue(location+"."+name, property, propertySchema); err != nil {
+				return err
+			}
+		}
+
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", location, value)
+		}
+
+		for i, item := range items {
+			if err := s.validateValue(fmt.Sprintf("%s[%d]", location, i), item, schema.Items); err != nil {
+				return err
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", location, value)
+		}
+
+		if schema.MinLength != nil && len(str) < *schema.MinLength {
+			return fmt.Errorf("%s: shorter than %d", location, *schema.MinLength)
+		}
+		if schema.MaxLength != nil && len(str) > *schema.MaxLength {
+			return fmt.Errorf("%s: longer than %d", location, *schema.MaxLength)
+		}
+		if schema.Pattern != "" && !regexp.MustCompile(schema.Pattern).MatchString(str) {
+			return fmt.Errorf("%s: %q doesn't match %s", location, str, schema.Pattern)
+		}
+
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected %s, got %T", location, schema.Type, value)
+		}
+
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected integer, got %v", location, n)
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			return fmt.Errorf("%s: %v is below %v", location, n, *schema.Minimum)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			return fmt.Errorf("%s: %v is above %v", location, n, *schema.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", location, value)
+		}
+	}
+
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", location, value, schema.Enum)
+	}
+
+	return nil
+}
+
+func (s *Spec) resolveSchema(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = s.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+
+	return schema
+}
+
+func (s *Spec) resolveParameter(param Parameter) Parameter {
+	if param.Ref != "" {
+		return s.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+	}
+
+	return param
+}
+
+// Matches "/api/confirm/{token}" against "/api/confirm/abc"
+func matchPath(template, path string) (map[string]string, bool) {
+	templateParts := strings.Split(template, "/")
+	pathParts := strings.Split(path, "/")
+	if len(templateParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, part := range templateParts {
+		if name, ok := strings.CutPrefix(part, "{"); ok {
+			params[strings.TrimSuffix(name, "}")] = pathParts[i]
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
@@ -0,0 +1,155 @@
+package privacy
+
+import (
+	"time"
+	"weather-app/internal/database/repository"
+
+	"github.com/google/uuid"
+)
+
+// JSON document returned to a subscriber asking for their data.
+// Token values are secrets, so only their metadata is exported
+type Export struct {
+	ExportedAt    time.Time            `json:"exported_at"`
+	User          ExportUser           `json:"user"`
+	Subscriptions []ExportSubscription `json:"subscriptions"`
+	Tokens        []ExportToken        `json:"tokens"`
+	Deliveries    []ExportDelivery     `json:"deliveries"`
+	Events        []ExportEvent        `json:"events"`
+	TelegramChats []ExportTelegramChat `json:"telegram_chats"`
+	PhoneNumbers  []ExportPhoneNumber  `json:"phone_numbers"`
+}
+
+type ExportUser struct {
+	ID          uuid.UUID `json:"id"`
+	Email       string    `json:"email"`
+	IsConfirmed bool      `json:"is_confirmed"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ExportSubscription struct {
+	City          string     `json:"city"`
+	Frequency     string     `json:"frequency"`
+	Timezone      string     `json:"timezone"`
+	SendTime      string     `json:"send_time"`
+	Weekday       int        `json:"weekday"`
+	IntervalHours int        `json:"interval_hours"`
+	CronExpr      string     `json:"cron,omitempty"`
+	ContentFields string     `json:"content_fields,omitempty"`
+	Units         string     `json:"units"`
+	Language      string     `json:"lang"`
+	PausedAt      *time.Time `json:"paused_at,omitempty"`
+	PausedUntil   *time.Time `json:"paused_until,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type ExportToken struct {
+	Type      string    `json:"type"`
+	NewEmail  string    `json:"new_email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportDelivery struct {
+	Channel   string    `json:"channel"`
+	Frequency string    `json:"frequency"`
+	City      string    `json:"city"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+type ExportTelegramChat struct {
+	ChatID   int64     `json:"chat_id"`
+	Username string    `json:"username,omitempty"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// One-time code hashes are left out like token values
+type ExportPhoneNumber struct {
+	Number      string     `json:"number"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ExportEvent struct {
+	Type      string    `json:"type"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newExport(data *repository.UserData) *Export {
+	export := Export{
+		ExportedAt: time.Now().UTC(),
+		User: ExportUser{
+			ID:          data.User.ID,
+			Email:       data.User.Email,
+			IsConfirmed: data.User.IsConfirmed,
+			CreatedAt:   data.User.CreatedAt,
+		},
+		Subscriptions: make([]ExportSubscription, 0, len(data.Subscriptions)),
+		Tokens:        make([]ExportToken, 0, len(data.Tokens)),
+		Deliveries:    make([]ExportDelivery, 0, len(data.Deliveries)),
+		Events:        make([]ExportEvent, 0, len(data.Events)),
+		TelegramChats: make([]ExportTelegramChat, 0, len(data.TelegramChats)),
+		PhoneNumbers:  make([]ExportPhoneNumber, 0, len(data.PhoneNumbers)),
+	}
+
+	for _, s := range data.Subscriptions {
+		export.Subscriptions = append(export.Subscriptions, ExportSubscription{
+			City:          s.City,
+			Frequency:     s.Frequency,
+			Timezone:      s.Timezone,
+			SendTime:      s.SendTime,
+			Weekday:       s.Weekday,
+			IntervalHours: s.IntervalHours,
+			CronExpr:      s.CronExpr,
+			ContentFields: s.ContentFields,
+			Units:         s.Units,
+			Language:      s.Language,
+			PausedAt:      s.PausedAt,
+			PausedUntil:   s.PausedUntil,
+			CreatedAt:     s.CreatedAt,
+		})
+	}
+
+	for _, t := range data.Tokens {
+		export.Tokens = append(export.Tokens, ExportToken{Type: t.Type, NewEmail: t.NewEmail, CreatedAt: t.CreatedAt})
+	}
+
+	for _, d := range data.Deliveries {
+		export.Deliveries = append(export.Deliveries, ExportDelivery{
+			Channel:   d.Channel,
+			Frequency: d.Frequency,
+			City:      d.City,
+			SentAt:    d.SentAt,
+		})
+	}
+
+	for _, e := range data.Events {
+		export.Events = append(export.Events, ExportEvent{
+			Type:      e.Type,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			Details:   e.Details,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+
+	for _, c := range data.TelegramChats {
+		export.TelegramChats = append(export.TelegramChats, ExportTelegramChat{
+			ChatID:   c.ChatID,
+			Username: c.Username,
+			LinkedAt: c.LinkedAt,
+		})
+	}
+
+	for _, p := range data.PhoneNumbers {
+		export.PhoneNumbers = append(export.PhoneNumbers, ExportPhoneNumber{
+			Number:      p.Number,
+			ConfirmedAt: p.ConfirmedAt,
+			CreatedAt:   p.CreatedAt,
+		})
+	}
+
+	return &export
+}
@@ -0,0 +1,101 @@
+package privacy
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"weather-app/internal/emailaddr"
+	"weather-app/internal/problem"
+	"weather-app/internal/subscription"
+)
+
+// Token errors are all 401 here, the token is a credential rather than a link
+var problems = problem.Mappings{
+	{Err: subscription.ErrTokenEmpty, Status: http.StatusUnauthorized, Code: "token_empty"},
+	{Err: subscription.ErrTokenNotFound, Status: http.StatusUnauthorized, Code: "token_not_found"},
+	{Err: subscription.ErrTokenWrongType, Status: http.StatusUnauthorized, Code: "token_wrong_type"},
+	{Err: emailaddr.ErrInvalidAddress, Status: http.StatusBadRequest, Code: "invalid_email"},
+}
+
+type PrivacyServiceInterface interface {
+	Export(tokenValue string) (*Export, error)
+	Erase(tokenValue string) error
+	EraseEmail(email string) error
+}
+
+type PrivacyHandler struct {
+	service PrivacyServiceInterface
+}
+
+func NewHandler(svc PrivacyServiceInterface) *PrivacyHandler {
+	return &PrivacyHandler{service: svc}
+}
+
+// Subscriber token comes from "Authorization: Bearer <token>" or the "token" query parameter
+func tokenFromRequest(req *http.Request) string {
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+
+	return req.URL.Query().Get("token")
+}
+
+func (h *PrivacyHandler) ExportHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	export, err := h.service.Export(tokenFromRequest(req))
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="weather-app-export.json"`)
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(export); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
+
+// Erases the subscriber on DELETE /api/me
+func (h *PrivacyHandler) EraseHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "DELETE" {
+		problem.MethodNotAllowed(w, req, "DELETE")
+		return
+	}
+
+	if err := h.service.Erase(tokenFromRequest(req)); err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Erases an address on POST /admin/api/erasures, form field "email". Behind
+// the admin key, for data subject requests of former subscribers
+func (h *PrivacyHandler) AdminEraseHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	}
+
+	email, err := emailaddr.Normalize(req.FormValue("email"))
+	if err != nil {
+		problems.Write(w, emailaddr.ErrInvalidAddress)
+		return
+	}
+
+	if err := h.service.EraseEmail(email); err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
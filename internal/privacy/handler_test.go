@@ -0,0 +1,148 @@
+package privacy_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weather-app/internal/privacy"
+	"weather-app/internal/subscription"
+)
+
+type mockPrivacyService struct {
+	ExportFunc func(tokenValue string) (*privacy.Export, error)
+	EraseFunc  func(tokenValue string) error
+
+	EraseEmailFunc func(email string) error
+}
+
+func (m *mockPrivacyService) Export(tokenValue string) (*privacy.Export, error) {
+	return m.ExportFunc(tokenValue)
+}
+
+func (m *mockPrivacyService) Erase(tokenValue string) error {
+	return m.EraseFunc(tokenValue)
+}
+
+func (m *mockPrivacyService) EraseEmail(email string) error {
+	return m.EraseEmailFunc(email)
+}
+
+func TestExportHandler_BearerToken(t *testing.T) {
+	var gotToken string
+	svc := &mockPrivacyService{
+		ExportFunc: func(token string) (*privacy.Export, error) {
+			gotToken = token
+			return &privacy.Export{User: privacy.ExportUser{Email: "test@example.com"}}, nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/me/export", nil)
+	req.Header.Set("Authorization", "Bearer token123")
+	w := httptest.NewRecorder()
+
+	privacy.NewHandler(svc).ExportHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if gotToken != "token123" {
+		t.Errorf("expected token123, got %q", gotToken)
+	}
+
+	var export privacy.Export
+	if err := json.NewDecoder(w.Body).Decode(&export); err != nil {
+		t.Fatalf("error decoding response: %v", err)
+	}
+	if export.User.Email != "test@example.com" {
+		t.Errorf("unexpected export: %+v", export)
+	}
+}
+
+func TestExportHandler_Unauthorized(t *testing.T) {
+	svc := &mockPrivacyService{
+		ExportFunc: func(token string) (*privacy.Export, error) {
+			return nil, subscription.ErrTokenNotFound
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/me/export?token=bad", nil)
+	w := httptest.NewRecorder()
+
+	privacy.NewHandler(svc).ExportHandler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestEraseHandler_Success(t *testing.T) {
+	svc := &mockPrivacyService{
+		EraseFunc: func(token string) error {
+			return nil
+		},
+	}
+
+	req := httptest.NewRequest("DELETE", "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer token123")
+	w := httptest.NewRecorder()
+
+	privacy.NewHandler(svc).EraseHandler(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+}
+
+func TestEraseHandler_UnsupportedMethod(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	w := httptest.NewRecorder()
+
+	privacy.NewHandler(&mockPrivacyService{}).EraseHandler(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "DELETE" {
+		t.Errorf("expected Allow DELETE, got %q", allow)
+	}
+	if !strings.Contains(w.Body.String(), "Unsupported method") {
+		t.Errorf("expected method error message, got: %s", w.Body.String())
+	}
+}
+
+func TestAdminEraseHandler_NormalizesEmail(t *testing.T) {
+	var gotEmail string
+	svc := &mockPrivacyService{
+		EraseEmailFunc: func(email string) error {
+			gotEmail = email
+			return nil
+		},
+	}
+
+	req := httptest.NewRequest("POST", "/admin/api/erasures", strings.NewReader("email=+Former@Example.com+"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	privacy.NewHandler(svc).AdminEraseHandler(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if gotEmail != "former@example.com" {
+		t.Errorf("expected normalized email, got %q", gotEmail)
+	}
+}
+
+func TestAdminEraseHandler_InvalidEmail(t *testing.T) {
+	req := httptest.NewRequest("POST", "/admin/api/erasures", strings.NewReader("email=not-an-email"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	privacy.NewHandler(&mockPrivacyService{}).AdminEraseHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
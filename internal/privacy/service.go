@@ -0,0 +1,84 @@
+package privacy
+
+import (
+	"fmt"
+	"log"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+)
+
+// Implemented by subscription.SubscriptionService, handles both database and signed tokens
+type TokenResolverInterface interface {
+	ResolveUser(tokenValue, tokenType string) (*models.Token, *models.User, error)
+}
+
+type PrivacyRepositoryInterface interface {
+	ExportUserData(userID uuid.UUID) (*repository.UserData, error)
+	EraseUser(user *models.User) error
+	EraseEmail(email string) error
+}
+
+// Answers data subject requests: access (export) and erasure
+type PrivacyService struct {
+	tokens      TokenResolverInterface
+	privacyRepo PrivacyRepositoryInterface
+}
+
+func NewPrivacyService(tokens TokenResolverInterface, privacyRepo PrivacyRepositoryInterface) *PrivacyService {
+	return &PrivacyService{tokens: tokens, privacyRepo: privacyRepo}
+}
+
+// Resolves the subscriber from their unsubscribe token
+func (srv *PrivacyService) authenticate(tokenValue string) (*models.User, error) {
+	_, user, err := srv.tokens.ResolveUser(tokenValue, models.TokenTypeUnsubscribe)
+	return user, err
+}
+
+func (srv *PrivacyService) Export(tokenValue string) (*Export, error) {
+	user, err := srv.authenticate(tokenValue)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := srv.privacyRepo.ExportUserData(user.ID)
+	if err != nil {
+		// Erased since it was resolved
+		if repository.IsErrNotFound(err) {
+			return nil, subscription.ErrTokenNotFound
+		}
+
+		return nil, fmt.Errorf("error exporting user data: %w", err)
+	}
+
+	return newExport(data), nil
+}
+
+func (srv *PrivacyService) Erase(tokenValue string) error {
+	user, err := srv.authenticate(tokenValue)
+	if err != nil {
+		return err
+	}
+
+	if err := srv.privacyRepo.EraseUser(user); err != nil {
+		return fmt.Errorf("error erasing user: %w", err)
+	}
+
+	log.Printf("Erased user %s\n", user.ID)
+
+	return nil
+}
+
+// Erases an address without a token, for requests handled by an admin. Also
+// covers former subscribers, whose tokens were deleted on unsubscribe
+func (srv *PrivacyService) EraseEmail(email string) error {
+	if err := srv.privacyRepo.EraseEmail(email); err != nil {
+		return fmt.Errorf("error erasing address: %w", err)
+	}
+
+	log.Printf("Erased address on admin request\n")
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package privacy_test
+
+import (
+	"errors"
+	"testing"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/privacy"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+)
+
+type mockTokenResolver struct {
+	ResolveUserFunc func(value, tokenType string) (*models.Token, *models.User, error)
+}
+
+func (r *mockTokenResolver) ResolveUser(value, tokenType string) (*models.Token, *models.User, error) {
+	return r.ResolveUserFunc(value, tokenType)
+}
+
+type mockPrivacyRepo struct {
+	ExportUserDataFunc func(userID uuid.UUID) (*repository.UserData, error)
+	EraseUserFunc      func(user *models.User) error
+	EraseEmailFunc     func(email string) error
+}
+
+func (r *mockPrivacyRepo) ExportUserData(userID uuid.UUID) (*repository.UserData, error) {
+	return r.ExportUserDataFunc(userID)
+}
+
+func (r *mockPrivacyRepo) EraseUser(user *models.User) error {
+	return r.EraseUserFunc(user)
+}
+
+func (r *mockPrivacyRepo) EraseEmail(email string) error {
+	return r.EraseEmailFunc(email)
+}
+
+func validTokenRepo(userID uuid.UUID) *mockTokenResolver {
+	return &mockTokenResolver{
+		ResolveUserFunc: func(value, tokenType string) (*models.Token, *models.User, error) {
+			token := &models.Token{Type: tokenType, UserID: userID, Value: value}
+			return token, &models.User{ID: userID, Email: "test@example.com"}, nil
+		},
+	}
+}
+
+func TestExport_Success(t *testing.T) {
+	userID := uuid.New()
+
+	repo := &mockPrivacyRepo{
+		ExportUserDataFunc: func(id uuid.UUID) (*repository.UserData, error) {
+			return &repository.UserData{
+				User:          models.User{ID: id, Email: "test@example.com"},
+				Subscriptions: []models.Subscription{{City: "Kyiv", Frequency: "daily"}},
+				Tokens:        []models.Token{{Type: models.TokenTypeUnsubscribe, Value: "secret"}},
+				Deliveries:    []models.Delivery{{Channel: "email", City: "Kyiv"}},
+			}, nil
+		},
+	}
+
+	svc := privacy.NewPrivacyService(validTokenRepo(userID), repo)
+
+	export, err := svc.Export("abc")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if export.User.ID != userID || export.User.Email != "test@example.com" {
+		t.Errorf("unexpected user: %+v", export.User)
+	}
+	if len(export.Subscriptions) != 1 || len(export.Tokens) != 1 || len(export.Deliveries) != 1 {
+		t.Errorf("unexpected export: %+v", export)
+	}
+}
+
+func TestExport_TokenNotFound(t *testing.T) {
+	resolver := &mockTokenResolver{
+		ResolveUserFunc: func(value, tokenType string) (*models.Token, *models.User, error) {
+			return nil, nil, subscription.ErrTokenNotFound
+		},
+	}
+
+	svc := privacy.NewPrivacyService(resolver, &mockPrivacyRepo{})
+
+	if _, err := svc.Export("abc"); err != subscription.ErrTokenNotFound {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestExport_RequiresUnsubscribeToken(t *testing.T) {
+	var gotType string
+	resolver := &mockTokenResolver{
+		ResolveUserFunc: func(value, tokenType string) (*models.Token, *models.User, error) {
+			gotType = tokenType
+			return nil, nil, subscription.ErrTokenWrongType
+		},
+	}
+
+	svc := privacy.NewPrivacyService(resolver, &mockPrivacyRepo{})
+
+	if _, err := svc.Export("abc"); err != subscription.ErrTokenWrongType {
+		t.Errorf("expected ErrTokenWrongType, got %v", err)
+	}
+	if gotType != models.TokenTypeUnsubscribe {
+		t.Errorf("expected unsubscribe token type, got %q", gotType)
+	}
+}
+
+func TestExport_UserGone(t *testing.T) {
+	repo := &mockPrivacyRepo{
+		ExportUserDataFunc: func(id uuid.UUID) (*repository.UserData, error) {
+			return nil, repository.ErrNotFound
+		},
+	}
+
+	svc := privacy.NewPrivacyService(validTokenRepo(uuid.New()), repo)
+
+	if _, err := svc.Export("abc"); err != subscription.ErrTokenNotFound {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestErase_Success(t *testing.T) {
+	userID := uuid.New()
+	var erased *models.User
+
+	repo := &mockPrivacyRepo{
+		EraseUserFunc: func(user *models.User) error {
+			erased = user
+			return nil
+		},
+	}
+
+	svc := privacy.NewPrivacyService(validTokenRepo(userID), repo)
+
+	if err := svc.Erase("abc"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if erased == nil || erased.ID != userID {
+		t.Errorf("expected user %s to be erased, got %+v", userID, erased)
+	}
+}
+
+func TestErase_DBError(t *testing.T) {
+	dbErr := errors.New("db down")
+
+	repo := &mockPrivacyRepo{
+		EraseUserFunc: func(user *models.User) error {
+			return dbErr
+		},
+	}
+
+	svc := privacy.NewPrivacyService(validTokenRepo(uuid.New()), repo)
+
+	if err := svc.Erase("abc"); !errors.Is(err, dbErr) {
+		t.Errorf("expected wrapped db error, got %v", err)
+	}
+}
+
+func TestEraseEmail_NeedsNoToken(t *testing.T) {
+	var erased string
+	repo := &mockPrivacyRepo{
+		EraseEmailFunc: func(email string) error {
+			erased = email
+			return nil
+		},
+	}
+	resolver := &mockTokenResolver{
+		ResolveUserFunc: func(value, tokenType string) (*models.Token, *models.User, error) {
+			t.Error("admin erasure must not resolve a token")
+			return nil, nil, subscription.ErrTokenNotFound
+		},
+	}
+
+	svc := privacy.NewPrivacyService(resolver, repo)
+
+	if err := svc.EraseEmail("former@example.com"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if erased != "former@example.com" {
+		t.Errorf("expected the address to be erased, got %q", erased)
+	}
+}
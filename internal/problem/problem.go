@@ -0,0 +1,94 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const ContentType = "application/problem+json"
+
+// Codes shared by every handler. Domain errors get their own codes through
+// Mappings
+const (
+	CodeInternal         = "internal_error"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUnauthorized     = "unauthorized"
+	CodeRateLimited      = "rate_limited"
+)
+
+const genericDetail = "Something went wrong"
+
+// RFC 7807 problem details. Code is a stable identifier of the error for
+// clients, Detail is meant for humans and may change
+type Details struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+}
+
+// Response for a domain error and the errors wrapping it
+type Mapping struct {
+	Err    error
+	Status int
+	Code   string
+}
+
+type Mappings []Mapping
+
+// Writes the problem of the first mapping err matches, with the mapped
+// error's message as detail, so wrapped context doesn't leak. Other errors
+// are answered with a generic 500. Server errors are logged
+func (m Mappings) Write(w http.ResponseWriter, err error) {
+	for _, mapping := range m {
+		if errors.Is(err, mapping.Err) {
+			if mapping.Status >= http.StatusInternalServerError {
+				log.Println(err.Error())
+			}
+
+			Write(w, mapping.Status, mapping.Code, mapping.Err.Error())
+			return
+		}
+	}
+
+	log.Println(err.Error())
+	Internal(w)
+}
+
+func Write(w http.ResponseWriter, status int, code, detail string) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	// Codes are documented, so there is no type URI to resolve
+	details := Details{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+
+	if err := json.NewEncoder(w).Encode(details); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
+
+func Internal(w http.ResponseWriter) {
+	Write(w, http.StatusInternalServerError, CodeInternal, genericDetail)
+}
+
+func NotFound(w http.ResponseWriter, req *http.Request) {
+	Write(w, http.StatusNotFound, CodeNotFound, "No resource at "+req.URL.Path)
+}
+
+// Answers 405 with the Allow header
+func MethodNotAllowed(w http.ResponseWriter, req *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	Write(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Unsupported method "+req.Method)
+}
@@ -0,0 +1,84 @@
+package problem_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weather-app/internal/problem"
+)
+
+var errNotFound = errors.New("thing not found")
+
+var mappings = problem.Mappings{
+	{Err: errNotFound, Status: http.StatusNotFound, Code: "thing_not_found"},
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) problem.Details {
+	t.Helper()
+
+	if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("expected %s, got %q", problem.ContentType, ct)
+	}
+
+	var details problem.Details
+	if err := json.NewDecoder(w.Body).Decode(&details); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+
+	return details
+}
+
+func TestMappings_Write(t *testing.T) {
+	w := httptest.NewRecorder()
+	mappings.Write(w, fmt.Errorf("error getting thing: %w", errNotFound))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+
+	want := problem.Details{
+		Type:   "about:blank",
+		Title:  "Not Found",
+		Status: http.StatusNotFound,
+		Detail: "thing not found",
+		Code:   "thing_not_found",
+	}
+	if got := decode(t, w); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestMappings_WriteUnmapped(t *testing.T) {
+	w := httptest.NewRecorder()
+	mappings.Write(w, errors.New("connection refused"))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+
+	details := decode(t, w)
+	if details.Code != problem.CodeInternal || strings.Contains(details.Detail, "connection refused") {
+		t.Errorf("internal error leaked or miscoded: %+v", details)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	w := httptest.NewRecorder()
+	problem.MethodNotAllowed(w, httptest.NewRequest("PUT", "/api/thing", nil), "GET", "POST")
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("expected Allow GET, POST, got %q", allow)
+	}
+
+	details := decode(t, w)
+	if details.Code != problem.CodeMethodNotAllowed || details.Detail != "Unsupported method PUT" {
+		t.Errorf("unexpected problem %+v", details)
+	}
+}
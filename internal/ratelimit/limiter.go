@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// In-memory token bucket per key. Each key may make up to limit requests in a
+// burst, and regains one request every window/limit
+type Limiter struct {
+	limit  float64
+	window time.Duration
+	rate   float64 // tokens per second
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewLimiter(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:   float64(limit),
+		window:  window,
+		rate:    float64(limit) / window.Seconds(),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Takes one request from key's bucket. When the bucket is empty it returns
+// false and how long until the next request is allowed
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.limit, last: now}
+		l.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.limit, b.tokens+elapsed*l.rate)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+
+	return false, wait
+}
+
+// Drops buckets that have refilled completely, at most once per window, so
+// one-off clients don't accumulate forever
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
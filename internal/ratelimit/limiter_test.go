@@ -0,0 +1,53 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+	"weather-app/internal/ratelimit"
+)
+
+func TestLimiter_AllowsBurstThenBlocks(t *testing.T) {
+	l := ratelimit.NewLimiter(3, time.Minute)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a", now); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+
+	ok, wait := l.Allow("a", now)
+	if ok {
+		t.Fatal("4th request should be blocked")
+	}
+	if wait != 20*time.Second {
+		t.Errorf("expected 20s wait, got %s", wait)
+	}
+}
+
+func TestLimiter_Refills(t *testing.T) {
+	l := ratelimit.NewLimiter(3, time.Minute)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		l.Allow("a", now)
+	}
+
+	if ok, _ := l.Allow("a", now.Add(10*time.Second)); ok {
+		t.Error("expected block before a token refills")
+	}
+	if ok, _ := l.Allow("a", now.Add(21*time.Second)); !ok {
+		t.Error("expected one token after 20s")
+	}
+}
+
+func TestLimiter_KeysAreIndependent(t *testing.T) {
+	l := ratelimit.NewLimiter(1, time.Minute)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	l.Allow("a", now)
+
+	if ok, _ := l.Allow("b", now); !ok {
+		t.Error("other key should not be affected")
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/emailaddr"
+	"weather-app/internal/problem"
+)
+
+const tooManyRequestsMsg = "Too many requests"
+
+// Selects the bucket for a request. Empty key skips the limiter
+type KeyFunc func(req *http.Request) string
+
+// Rejects requests over the limit with 429 and Retry-After in seconds
+func Middleware(l *Limiter, keyFunc KeyFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		key := keyFunc(req)
+		if key == "" {
+			next(w, req)
+			return
+		}
+
+		ok, wait := l.Allow(key, time.Now())
+		if !ok {
+			retryAfter := int(math.Ceil(wait.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+
+			log.Printf("Rate limit exceeded for %s %s\n", req.Method, req.URL.Path)
+
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			problem.Write(w, http.StatusTooManyRequests, problem.CodeRateLimited, tooManyRequestsMsg)
+			return
+		}
+
+		next(w, req)
+	}
+}
+
+// Keys by client IP, see audit.RemoteIP. X-Forwarded-For is only read behind
+// trusted proxies, otherwise clients could pick their own bucket
+func ByIP(trustedProxies int) KeyFunc {
+	return func(req *http.Request) string {
+		return "ip:" + audit.RemoteIP(req, trustedProxies)
+	}
+}
+
+// Keys by the normalized "email" form field, so one address can't be flooded
+// with confirmation emails from many IPs
+func ByEmail(req *http.Request) string {
+	if err := req.ParseForm(); err != nil {
+		return ""
+	}
+
+	email := req.PostForm.Get("email")
+	if email == "" {
+		return ""
+	}
+
+	normalized, err := emailaddr.Normalize(email)
+	if err != nil {
+		normalized = strings.ToLower(strings.TrimSpace(email))
+	}
+
+	return "email:" + normalized
+}
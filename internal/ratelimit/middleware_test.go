@@ -0,0 +1,97 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/ratelimit"
+)
+
+func okHandler(w http.ResponseWriter, req *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestMiddleware_TooManyRequests(t *testing.T) {
+	l := ratelimit.NewLimiter(1, time.Minute)
+	h := ratelimit.Middleware(l, ratelimit.ByIP(0), okHandler)
+
+	req := httptest.NewRequest("GET", "/api/confirm/abc", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	w := httptest.NewRecorder()
+	h(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("expected Retry-After 60, got %q", got)
+	}
+}
+
+func TestByIP_ForwardedForOnlyWhenTrusted(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.7, 10.0.0.1")
+
+	if got := ratelimit.ByIP(0)(req); got != "ip:192.0.2.1" {
+		t.Errorf("untrusted: got %q", got)
+	}
+	if got := ratelimit.ByIP(1)(req); got != "ip:10.0.0.1" {
+		t.Errorf("one proxy: got %q", got)
+	}
+	if got := ratelimit.ByIP(2)(req); got != "ip:198.51.100.7" {
+		t.Errorf("two proxies: got %q", got)
+	}
+}
+
+func TestByIP_ForgedForwardedForSharesBucket(t *testing.T) {
+	key := ratelimit.ByIP(1)
+
+	// A client rotating its own X-Forwarded-For through the proxy
+	keys := map[string]bool{}
+	for _, forged := range []string{"198.51.100.1", "198.51.100.2, 198.51.100.3"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Forwarded-For", forged+", 203.0.113.7")
+		keys[key(req)] = true
+	}
+
+	if len(keys) != 1 || !keys["ip:203.0.113.7"] {
+		t.Errorf("expected one bucket for the real client, got %v", keys)
+	}
+}
+
+func TestByEmail_Normalizes(t *testing.T) {
+	form := url.Values{}
+	form.Set("email", " User@Example.com ")
+
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if got := ratelimit.ByEmail(req); got != "email:user@example.com" {
+		t.Errorf("got %q", got)
+	}
+
+	// The form stays readable for the handler
+	if req.FormValue("email") != " User@Example.com " {
+		t.Error("expected form to remain parsed for the next handler")
+	}
+}
+
+func TestByEmail_NoEmailSkips(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(""))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if got := ratelimit.ByEmail(req); got != "" {
+		t.Errorf("expected empty key, got %q", got)
+	}
+}
@@ -0,0 +1,156 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCron     = errors.New("cron parameter is invalid")
+	ErrCronTooFrequent = errors.New("cron expression fires more than once per hour")
+)
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var weekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+type cronField struct {
+	values map[int]struct{}
+	any    bool // Field started with "*", e.g. "*/2", matters for day-of-month/day-of-week semantics
+}
+
+func (f cronField) matches(v int) bool {
+	_, ok := f.values[v]
+	return ok
+}
+
+// Standard five-field cron expression: minute hour day-of-month month day-of-week
+type CronExpr struct {
+	minute, hour, dom, month, dow cronField
+}
+
+func ParseCron(expr string) (*CronExpr, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d", ErrInvalidCron, len(fields))
+	}
+
+	var c CronExpr
+	var err error
+
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+
+	if c.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+
+	// 7 is an alias for Sunday
+	if c.dow, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, err
+	}
+
+	if c.dow.matches(7) {
+		c.dow.values[0] = struct{}{}
+	}
+
+	// Keep user-defined schedules from flooding inboxes
+	if len(c.minute.values) != 1 {
+		return nil, ErrCronTooFrequent
+	}
+
+	return &c, nil
+}
+
+func parseCronField(field string, min, max int, names map[string]int) (cronField, error) {
+	f := cronField{values: make(map[int]struct{}), any: strings.HasPrefix(field, "*")}
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			s, err := strconv.Atoi(stepPart)
+			if err != nil || s <= 0 {
+				return f, fmt.Errorf("%w: bad step %q", ErrInvalidCron, part)
+			}
+			step = s
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			loPart, hiPart, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			if lo, err = parseCronValue(loPart, names); err != nil {
+				return f, err
+			}
+
+			hi = lo
+			if isRange {
+				if hi, err = parseCronValue(hiPart, names); err != nil {
+					return f, err
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return f, fmt.Errorf("%w: %q out of range %d-%d", ErrInvalidCron, part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			f.values[v] = struct{}{}
+		}
+	}
+
+	return f, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad value %q", ErrInvalidCron, value)
+	}
+
+	return v, nil
+}
+
+// Reports whether the expression fires at the given minute. Like classic cron,
+// a restricted day-of-month and day-of-week match if either of them does.
+func (c *CronExpr) Matches(t time.Time) bool {
+	if !c.minute.matches(t.Minute()) || !c.hour.matches(t.Hour()) || !c.month.matches(int(t.Month())) {
+		return false
+	}
+
+	domMatch := c.dom.matches(t.Day())
+	dowMatch := c.dow.matches(int(t.Weekday()))
+
+	if c.dom.any || c.dow.any {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,86 @@
+package schedule_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"weather-app/internal/schedule"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	cases := []struct {
+		expr string
+		want error
+	}{
+		{"0 7 * *", schedule.ErrInvalidCron},
+		{"0 25 * * *", schedule.ErrInvalidCron},
+		{"0 7 * * funday", schedule.ErrInvalidCron},
+		{"*/0 7 * * *", schedule.ErrInvalidCron},
+		{"*/15 7 * * *", schedule.ErrCronTooFrequent},
+		{"* * * * *", schedule.ErrCronTooFrequent},
+	}
+
+	for _, tc := range cases {
+		if _, err := schedule.ParseCron(tc.expr); !errors.Is(err, tc.want) {
+			t.Errorf("%q: expected %v, got %v", tc.expr, tc.want, err)
+		}
+	}
+}
+
+func TestCron_Matches(t *testing.T) {
+	c, err := schedule.ParseCron("30 7 * * mon-fri")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	monday := time.Date(2025, 6, 2, 7, 30, 0, 0, time.UTC)
+	saturday := time.Date(2025, 6, 7, 7, 30, 0, 0, time.UTC)
+
+	if !c.Matches(monday) {
+		t.Error("expected match on Monday 07:30")
+	}
+	if c.Matches(saturday) {
+		t.Error("expected no match on Saturday")
+	}
+	if c.Matches(monday.Add(time.Hour)) {
+		t.Error("expected no match at 08:30")
+	}
+}
+
+func TestCron_DayOfMonthOrDayOfWeek(t *testing.T) {
+	// Classic cron: 1st of the month OR any Sunday
+	c, err := schedule.ParseCron("0 9 1 * 7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)  // Tuesday
+	sunday := time.Date(2025, 7, 6, 9, 0, 0, 0, time.UTC) // Sunday
+	other := time.Date(2025, 7, 8, 9, 0, 0, 0, time.UTC)  // Tuesday
+
+	if !c.Matches(first) || !c.Matches(sunday) {
+		t.Error("expected match on the 1st and on Sunday")
+	}
+	if c.Matches(other) {
+		t.Error("expected no match on a regular Tuesday")
+	}
+}
+
+func TestCron_StepFromStarIsUnrestricted(t *testing.T) {
+	// Like cron, "*/2" counts as "*": odd days that are also Mondays
+	c, err := schedule.ParseCron("0 9 */2 * 1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	oddMonday := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC)
+	evenMonday := time.Date(2025, 7, 14, 9, 0, 0, 0, time.UTC)
+	oddWednesday := time.Date(2025, 7, 9, 9, 0, 0, 0, time.UTC)
+
+	if !c.Matches(oddMonday) {
+		t.Error("expected match on an odd Monday")
+	}
+	if c.Matches(evenMonday) || c.Matches(oddWednesday) {
+		t.Error("expected both day fields to be required")
+	}
+}
@@ -0,0 +1,205 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Containers may ship without a zoneinfo database
+	_ "time/tzdata"
+)
+
+const (
+	FrequencyHourly      = "hourly"
+	FrequencyDaily       = "daily"
+	FrequencyWeekly      = "weekly"        // Once a week on Weekday at SendTime
+	FrequencyWeekdays    = "weekdays"      // Monday to Friday at SendTime
+	FrequencyEveryNHours = "every_n_hours" // At the top of every IntervalHours-th local hour
+	FrequencyCron        = "cron"          // Custom CronExpr
+)
+
+// All supported frequencies, in the order the mail-sender processes them
+var Frequencies = []string{
+	FrequencyHourly,
+	FrequencyDaily,
+	FrequencyWeekly,
+	FrequencyWeekdays,
+	FrequencyEveryNHours,
+	FrequencyCron,
+}
+
+const (
+	DefaultTimezone = "UTC"
+	DefaultSendTime = "12:00"
+)
+
+var (
+	ErrInvalidFrequency     = errors.New("frequency parameter is invalid")
+	ErrInvalidTimezone      = errors.New("timezone parameter is invalid")
+	ErrInvalidSendTime      = errors.New("send_time parameter is invalid")
+	ErrInvalidWeekday       = errors.New("weekday parameter is invalid")
+	ErrInvalidIntervalHours = errors.New("interval_hours parameter is invalid")
+)
+
+// Describes when updates for one subscription should be delivered
+type Schedule struct {
+	Frequency     string
+	Timezone      string       // IANA name, e.g. "Europe/Kyiv"
+	SendTime      string       // Local "HH:MM", used by daily, weekly and weekdays
+	Weekday       time.Weekday // Used by weekly
+	IntervalHours int          // Used by every_n_hours, must divide 24
+	CronExpr      string       // Used by cron, evaluated in Timezone
+}
+
+func IsValidFrequency(freq string) bool {
+	for _, f := range Frequencies {
+		if f == freq {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Fills empty optional fields with defaults
+func (s Schedule) WithDefaults() Schedule {
+	if s.Timezone == "" {
+		s.Timezone = DefaultTimezone
+	}
+
+	if s.SendTime == "" {
+		s.SendTime = DefaultSendTime
+	}
+
+	return s
+}
+
+func (s Schedule) Validate() error {
+	if !IsValidFrequency(s.Frequency) {
+		return ErrInvalidFrequency
+	}
+
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return ErrInvalidTimezone
+	}
+
+	if _, _, err := ParseSendTime(s.SendTime); err != nil {
+		return err
+	}
+
+	switch s.Frequency {
+	case FrequencyWeekly:
+		if s.Weekday < time.Sunday || s.Weekday > time.Saturday {
+			return ErrInvalidWeekday
+		}
+
+	case FrequencyEveryNHours:
+		if s.IntervalHours < 1 || s.IntervalHours > 24 || 24%s.IntervalHours != 0 {
+			return ErrInvalidIntervalHours
+		}
+
+	case FrequencyCron:
+		if _, err := ParseCron(s.CronExpr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Parses local "HH:MM" time
+func ParseSendTime(value string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %s", ErrInvalidSendTime, value)
+	}
+
+	return t.Hour(), t.Minute(), nil
+}
+
+// Accepts English day names ("monday", "Mon") or numbers 0-6 starting from Sunday
+func ParseWeekday(value string) (time.Weekday, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 0 || n > 6 {
+			return 0, ErrInvalidWeekday
+		}
+
+		return time.Weekday(n), nil
+	}
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if value == name || (len(value) == 3 && strings.HasPrefix(name, value)) {
+			return d, nil
+		}
+	}
+
+	return 0, ErrInvalidWeekday
+}
+
+// Reports whether the schedule fires at some minute in the (from, to] window.
+// Empty optional fields are treated as defaults, so rows created before
+// timezones existed keep the old behaviour.
+func (s Schedule) Due(from, to time.Time) bool {
+	s = s.WithDefaults()
+
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false
+	}
+
+	hour, minute, err := ParseSendTime(s.SendTime)
+	if err != nil {
+		return false
+	}
+
+	var cron *CronExpr
+	if s.Frequency == FrequencyCron {
+		if cron, err = ParseCron(s.CronExpr); err != nil {
+			return false
+		}
+	}
+
+	for t := from.Truncate(time.Minute).Add(time.Minute); !t.After(to); t = t.Add(time.Minute) {
+		local := t.In(loc)
+		atSendTime := local.Hour() == hour && local.Minute() == minute
+
+		switch s.Frequency {
+		case FrequencyHourly:
+			if local.Minute() == 0 {
+				return true
+			}
+
+		case FrequencyDaily:
+			if atSendTime {
+				return true
+			}
+
+		case FrequencyWeekly:
+			if atSendTime && local.Weekday() == s.Weekday {
+				return true
+			}
+
+		case FrequencyWeekdays:
+			if atSendTime && local.Weekday() != time.Saturday && local.Weekday() != time.Sunday {
+				return true
+			}
+
+		case FrequencyEveryNHours:
+			if s.IntervalHours > 0 && local.Minute() == 0 && local.Hour()%s.IntervalHours == 0 {
+				return true
+			}
+
+		case FrequencyCron:
+			if cron.Matches(local) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
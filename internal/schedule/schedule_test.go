@@ -0,0 +1,147 @@
+package schedule_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"weather-app/internal/schedule"
+)
+
+func TestValidate_Defaults(t *testing.T) {
+	s := schedule.Schedule{Frequency: schedule.FrequencyDaily}.WithDefaults()
+
+	if err := s.Validate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestValidate_InvalidFields(t *testing.T) {
+	cases := []struct {
+		name     string
+		schedule schedule.Schedule
+		want     error
+	}{
+		{"frequency", schedule.Schedule{Frequency: "yearly", Timezone: "UTC", SendTime: "08:00"}, schedule.ErrInvalidFrequency},
+		{"timezone", schedule.Schedule{Frequency: "daily", Timezone: "Nowhere/City", SendTime: "08:00"}, schedule.ErrInvalidTimezone},
+		{"send time", schedule.Schedule{Frequency: "daily", Timezone: "UTC", SendTime: "25:00"}, schedule.ErrInvalidSendTime},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.schedule.Validate(); !errors.Is(err, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestDue_DailyLocalTime(t *testing.T) {
+	s := schedule.Schedule{Frequency: schedule.FrequencyDaily, Timezone: "Asia/Kolkata", SendTime: "08:00"}
+
+	// 08:00 in Kolkata (UTC+5:30) is 02:30 UTC
+	from := time.Date(2025, 6, 1, 2, 15, 0, 0, time.UTC)
+	to := time.Date(2025, 6, 1, 2, 30, 0, 0, time.UTC)
+
+	if !s.Due(from, to) {
+		t.Error("expected schedule to be due")
+	}
+
+	if s.Due(to, to.Add(15*time.Minute)) {
+		t.Error("expected schedule not to be due in the next window")
+	}
+}
+
+func TestDue_Hourly(t *testing.T) {
+	s := schedule.Schedule{Frequency: schedule.FrequencyHourly}
+
+	from := time.Date(2025, 6, 1, 9, 45, 0, 0, time.UTC)
+
+	if !s.Due(from, from.Add(15*time.Minute)) {
+		t.Error("expected hourly schedule to be due at the top of the hour")
+	}
+
+	if s.Due(from.Add(15*time.Minute), from.Add(30*time.Minute)) {
+		t.Error("expected hourly schedule not to be due mid-hour")
+	}
+}
+
+func TestValidate_FrequencyParameters(t *testing.T) {
+	cases := []struct {
+		name     string
+		schedule schedule.Schedule
+		want     error
+	}{
+		{"weekly", schedule.Schedule{Frequency: "weekly", Weekday: time.Weekday(9)}, schedule.ErrInvalidWeekday},
+		{"interval zero", schedule.Schedule{Frequency: "every_n_hours"}, schedule.ErrInvalidIntervalHours},
+		{"interval not dividing day", schedule.Schedule{Frequency: "every_n_hours", IntervalHours: 5}, schedule.ErrInvalidIntervalHours},
+		{"cron", schedule.Schedule{Frequency: "cron", CronExpr: "bad"}, schedule.ErrInvalidCron},
+		{"valid interval", schedule.Schedule{Frequency: "every_n_hours", IntervalHours: 6}, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.schedule.WithDefaults().Validate(); !errors.Is(err, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	for _, value := range []string{"wednesday", "Wed", "3"} {
+		d, err := schedule.ParseWeekday(value)
+		if err != nil || d != time.Wednesday {
+			t.Errorf("%q: expected Wednesday, got %v (%v)", value, d, err)
+		}
+	}
+
+	if _, err := schedule.ParseWeekday("someday"); !errors.Is(err, schedule.ErrInvalidWeekday) {
+		t.Errorf("expected ErrInvalidWeekday, got %v", err)
+	}
+}
+
+func TestDue_WeekdaysAndWeekly(t *testing.T) {
+	// 2025-06-06 is a Friday, 2025-06-07 is a Saturday
+	friday := time.Date(2025, 6, 6, 8, 0, 0, 0, time.UTC)
+	saturday := friday.AddDate(0, 0, 1)
+
+	weekdays := schedule.Schedule{Frequency: schedule.FrequencyWeekdays, SendTime: "08:00"}
+	if !weekdays.Due(friday.Add(-time.Minute), friday) {
+		t.Error("expected weekdays schedule to be due on Friday")
+	}
+	if weekdays.Due(saturday.Add(-time.Minute), saturday) {
+		t.Error("expected weekdays schedule not to be due on Saturday")
+	}
+
+	weekly := schedule.Schedule{Frequency: schedule.FrequencyWeekly, Weekday: time.Saturday, SendTime: "08:00"}
+	if weekly.Due(friday.Add(-time.Minute), friday) {
+		t.Error("expected weekly schedule not to be due on Friday")
+	}
+	if !weekly.Due(saturday.Add(-time.Minute), saturday) {
+		t.Error("expected weekly schedule to be due on Saturday")
+	}
+}
+
+func TestDue_EveryNHours(t *testing.T) {
+	s := schedule.Schedule{Frequency: schedule.FrequencyEveryNHours, IntervalHours: 6}
+
+	six := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	if !s.Due(six.Add(-15*time.Minute), six) {
+		t.Error("expected schedule to be due at 06:00")
+	}
+
+	seven := six.Add(time.Hour)
+	if s.Due(seven.Add(-15*time.Minute), seven) {
+		t.Error("expected schedule not to be due at 07:00")
+	}
+}
+
+func TestDue_CronInTimezone(t *testing.T) {
+	s := schedule.Schedule{Frequency: schedule.FrequencyCron, Timezone: "Europe/Kyiv", CronExpr: "0 7 * * *"}
+
+	// 07:00 in Kyiv during summer time is 04:00 UTC
+	to := time.Date(2025, 6, 1, 4, 0, 0, 0, time.UTC)
+	if !s.Due(to.Add(-15*time.Minute), to) {
+		t.Error("expected cron schedule to be due at 07:00 Kyiv time")
+	}
+}
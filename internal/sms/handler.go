@@ -0,0 +1,73 @@
+package sms
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"weather-app/internal/audit"
+	"weather-app/internal/problem"
+	"weather-app/internal/subscription"
+)
+
+const phonePath = "/api/phone/"
+
+var problems = slices.Concat(subscription.TokenProblems, problem.Mappings{
+	{Err: ErrInvalidPhone, Status: http.StatusBadRequest, Code: "invalid_phone"},
+	{Err: ErrInvalidCode, Status: http.StatusBadRequest, Code: "invalid_code"},
+	{Err: ErrNoPendingCode, Status: http.StatusBadRequest, Code: "no_pending_code"},
+	{Err: ErrCodeExpired, Status: http.StatusGone, Code: "code_expired"},
+	{Err: ErrTooManyAttempts, Status: http.StatusTooManyRequests, Code: "too_many_attempts"},
+	{Err: ErrTooManyCodes, Status: http.StatusTooManyRequests, Code: "too_many_codes"},
+	{Err: ErrPhoneNotFound, Status: http.StatusNotFound, Code: "phone_not_found"},
+	{Err: ErrSendFailed, Status: http.StatusBadGateway, Code: "sms_send_failed"},
+})
+
+type ServiceInterface interface {
+	RequestCode(tokenValue, phone string, meta audit.Meta) error
+	ConfirmCode(tokenValue, code string, meta audit.Meta) error
+	RemovePhone(tokenValue string, meta audit.Meta) error
+}
+
+type Handler struct {
+	service ServiceInterface
+}
+
+func NewHandler(svc ServiceInterface) *Handler {
+	return &Handler{service: svc}
+}
+
+// Manages the subscriber's phone number, authorized by the unsubscribe token:
+//
+//	POST   /api/phone/{token}          form field "phone", texts a code
+//	POST   /api/phone/{token}/confirm  form field "code"
+//	DELETE /api/phone/{token}
+func (h *Handler) PhoneHandler(w http.ResponseWriter, req *http.Request) {
+	token, action, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, phonePath), "/")
+
+	var err error
+
+	switch {
+	case action == "" && req.Method == "POST":
+		err = h.service.RequestCode(token, req.FormValue("phone"), audit.MetaFromRequest(req))
+	case action == "confirm" && req.Method == "POST":
+		err = h.service.ConfirmCode(token, req.FormValue("code"), audit.MetaFromRequest(req))
+	case action == "" && req.Method == "DELETE":
+		err = h.service.RemovePhone(token, audit.MetaFromRequest(req))
+	case action == "":
+		problem.MethodNotAllowed(w, req, "POST", "DELETE")
+		return
+	case action == "confirm":
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	default:
+		problem.NotFound(w, req)
+		return
+	}
+
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
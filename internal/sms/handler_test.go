@@ -0,0 +1,116 @@
+package sms_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"weather-app/internal/audit"
+	"weather-app/internal/sms"
+	"weather-app/internal/subscription"
+)
+
+type mockService struct {
+	RequestCodeFunc func(token, phone string) error
+	ConfirmCodeFunc func(token, code string) error
+	RemovePhoneFunc func(token string) error
+}
+
+func (m *mockService) RequestCode(token, phone string, meta audit.Meta) error {
+	return m.RequestCodeFunc(token, phone)
+}
+
+func (m *mockService) ConfirmCode(token, code string, meta audit.Meta) error {
+	return m.ConfirmCodeFunc(token, code)
+}
+
+func (m *mockService) RemovePhone(token string, meta audit.Meta) error {
+	return m.RemovePhoneFunc(token)
+}
+
+func postForm(target string, values url.Values) *http.Request {
+	req := httptest.NewRequest("POST", target, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestPhoneHandler_Routes(t *testing.T) {
+	var calls []string
+
+	h := sms.NewHandler(&mockService{
+		RequestCodeFunc: func(token, phone string) error {
+			calls = append(calls, "request "+token+" "+phone)
+			return nil
+		},
+		ConfirmCodeFunc: func(token, code string) error {
+			calls = append(calls, "confirm "+token+" "+code)
+			return nil
+		},
+		RemovePhoneFunc: func(token string) error {
+			calls = append(calls, "remove "+token)
+			return nil
+		},
+	})
+
+	requests := []*http.Request{
+		postForm("/api/phone/tok", url.Values{"phone": {"+380501234567"}}),
+		postForm("/api/phone/tok/confirm", url.Values{"code": {"123456"}}),
+		httptest.NewRequest("DELETE", "/api/phone/tok", nil),
+	}
+
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		h.PhoneHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: expected 200, got %d", req.Method, req.URL.Path, w.Code)
+		}
+	}
+
+	want := []string{"request tok +380501234567", "confirm tok 123456", "remove tok"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+}
+
+func TestPhoneHandler_Errors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{subscription.ErrTokenNotFound, http.StatusNotFound},
+		{sms.ErrInvalidPhone, http.StatusBadRequest},
+		{sms.ErrInvalidCode, http.StatusBadRequest},
+		{sms.ErrCodeExpired, http.StatusGone},
+		{sms.ErrTooManyAttempts, http.StatusTooManyRequests},
+		{sms.ErrSendFailed, http.StatusBadGateway},
+	}
+
+	for _, c := range cases {
+		h := sms.NewHandler(&mockService{
+			ConfirmCodeFunc: func(token, code string) error { return c.err },
+		})
+
+		w := httptest.NewRecorder()
+		h.PhoneHandler(w, postForm("/api/phone/tok/confirm", url.Values{"code": {"1"}}))
+
+		if w.Code != c.status {
+			t.Errorf("%v: expected %d, got %d", c.err, c.status, w.Code)
+		}
+	}
+}
+
+func TestPhoneHandler_WrongMethod(t *testing.T) {
+	h := sms.NewHandler(&mockService{})
+
+	w := httptest.NewRecorder()
+	h.PhoneHandler(w, httptest.NewRequest("GET", "/api/phone/tok", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "POST, DELETE" {
+		t.Errorf("expected Allow POST, DELETE, got %q", allow)
+	}
+}
@@ -0,0 +1,31 @@
+package sms
+
+import (
+	"fmt"
+	"strings"
+	"weather-app/internal/notify"
+)
+
+// One SMS segment. The GSM-7 alphabet has no degree sign, temperatures are
+// written as "21C" so plain city names fit in a single segment
+const maxMessageLength = 160
+
+func UpdateMessage(update notify.Update) string {
+	report := update.Report()
+	text := fmt.Sprintf("%s: %.0f%s, %s", update.City, report.Degrees, report.Scale, strings.Join(report.Brief(), ", "))
+
+	return truncate(strings.ReplaceAll(text, "°", ""), maxMessageLength)
+}
+
+func codeMessage(code string) string {
+	return fmt.Sprintf("Your weather updates code is %s. It expires in %d minutes.", code, int(codeTTL.Minutes()))
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+
+	return string(runes[:length-3]) + "..."
+}
@@ -0,0 +1,23 @@
+package sms
+
+import (
+	"weather-app/internal/database/repository"
+	"weather-app/internal/notify"
+)
+
+// Recorded as delivery channel
+const ChannelName = "sms"
+
+// Delivers weather updates to confirmed phone numbers
+type Notifier struct {
+	sender SMSSender
+}
+
+func NewNotifier(sender SMSSender) *Notifier {
+	return &Notifier{sender: sender}
+}
+
+// Recipient's target is the E.164 number
+func (n *Notifier) Notify(recipient repository.ChannelRecipient, update notify.Update) error {
+	return n.sender.Send(recipient.Target, UpdateMessage(update))
+}
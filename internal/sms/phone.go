@@ -0,0 +1,47 @@
+package sms
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("phone parameter is invalid")
+
+// Normalizes a number to E.164: "+", a country code not starting with 0 and
+// at most 15 digits in total. Spaces, dashes, dots and parentheses are
+// dropped, "00" is accepted in place of "+"
+func NormalizePhone(value string) (string, error) {
+	value = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value))
+
+	if digits, ok := strings.CutPrefix(value, "00"); ok {
+		value = "+" + digits
+	}
+
+	digits, ok := strings.CutPrefix(value, "+")
+	if !ok || len(digits) < 7 || len(digits) > 15 || digits[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidPhone
+		}
+	}
+
+	return value, nil
+}
+
+// Last four digits only, for logs and audit details
+func MaskPhone(number string) string {
+	if len(number) <= 4 {
+		return number
+	}
+
+	return "***" + number[len(number)-4:]
+}
@@ -0,0 +1,44 @@
+package sms_test
+
+import (
+	"errors"
+	"testing"
+	"weather-app/internal/sms"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	cases := map[string]string{
+		"+380501234567":       "+380501234567",
+		" +1 (415) 555-0100 ": "+14155550100",
+		"0044 20.7946.0000":   "+442079460000",
+	}
+
+	for input, want := range cases {
+		got, err := sms.NormalizePhone(input)
+		if err != nil || got != want {
+			t.Errorf("NormalizePhone(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+}
+
+func TestNormalizePhone_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"0501234567",         // No country code
+		"+0501234567",        // Country codes don't start with 0
+		"+12345",             // Too short
+		"+1234567890123456",  // Over 15 digits
+		"+38050123456a",      // Not a digit
+		"+380 50 123 45 67#", // Not a digit
+	} {
+		if _, err := sms.NormalizePhone(input); !errors.Is(err, sms.ErrInvalidPhone) {
+			t.Errorf("NormalizePhone(%q): expected ErrInvalidPhone, got %v", input, err)
+		}
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	if got := sms.MaskPhone("+380501234567"); got != "***4567" {
+		t.Errorf("expected ***4567, got %q", got)
+	}
+}
@@ -0,0 +1,139 @@
+package sms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTwilioURL = "https://api.twilio.com"
+
+	requestTimeout = 10 * time.Second
+)
+
+// Sends one text message to an E.164 number
+type SMSSender interface {
+	Send(to, body string) error
+}
+
+// Error returned by the provider, e.g. 21211 for an invalid "To" number
+type ProviderError struct {
+	Status  int
+	Code    int
+	Message string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("sms provider error %d (status %d): %s", e.Code, e.Status, e.Message)
+}
+
+// Sends through the Twilio Messages API, or any provider that mirrors it
+type TwilioSender struct {
+	apiURL     string
+	accountSID string
+	authToken  string
+	from       string // Sender number, or a Messaging Service SID ("MG...")
+	client     *http.Client
+}
+
+// Empty apiURL uses DefaultTwilioURL, nil client one with a 10 second timeout
+func NewTwilioSender(apiURL, accountSID, authToken, from string, client *http.Client) *TwilioSender {
+	if apiURL == "" {
+		apiURL = DefaultTwilioURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+
+	return &TwilioSender{
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		client:     client,
+	}
+}
+
+func (s *TwilioSender) Send(to, body string) error {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if strings.HasPrefix(s.from, "MG") {
+		form.Set("MessagingServiceSid", s.from)
+	} else {
+		form.Set("From", s.from)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.apiURL, url.PathEscape(s.accountSID))
+
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(s.accountSID, s.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		providerErr := &ProviderError{Status: resp.StatusCode}
+
+		var body struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+			providerErr.Code = body.Code
+			providerErr.Message = body.Message
+		}
+
+		return providerErr
+	}
+
+	return nil
+}
+
+// Logs messages instead of sending them, for development and tests
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(to, body string) error {
+	log.Printf("SMS to %s: %s\n", to, body)
+
+	return nil
+}
+
+var ErrUnknownProvider = errors.New("unknown SMS_PROVIDER")
+
+// Reads SMS_PROVIDER: "twilio" sends with TWILIO_ACCOUNT_SID,
+// TWILIO_AUTH_TOKEN and SMS_FROM through SMS_API_URL, "log" only logs
+// messages. Returns nil when it is empty, which disables SMS
+func SenderFromEnv() (SMSSender, error) {
+	switch provider := os.Getenv("SMS_PROVIDER"); provider {
+	case "":
+		return nil, nil
+	case "log":
+		return NewLogSender(), nil
+	case "twilio":
+		accountSID, authToken, from := os.Getenv("TWILIO_ACCOUNT_SID"), os.Getenv("TWILIO_AUTH_TOKEN"), os.Getenv("SMS_FROM")
+		if accountSID == "" || authToken == "" || from == "" {
+			return nil, errors.New("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and SMS_FROM are required")
+		}
+		return NewTwilioSender(os.Getenv("SMS_API_URL"), accountSID, authToken, from, nil), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownProvider, provider)
+	}
+}
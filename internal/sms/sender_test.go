@@ -0,0 +1,154 @@
+package sms_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"weather-app/internal/content"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/notify"
+	"weather-app/internal/sms"
+	"weather-app/internal/weather"
+)
+
+type fakeTwilio struct {
+	status   int
+	response string
+	path     string
+	user     string
+	password string
+	form     url.Values
+}
+
+func (f *fakeTwilio) start(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		f.path = req.URL.Path
+		f.user, f.password, _ = req.BasicAuth()
+		req.ParseForm()
+		f.form = req.PostForm
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(f.status)
+		w.Write([]byte(f.response))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestTwilioSender_Send(t *testing.T) {
+	api := &fakeTwilio{status: http.StatusCreated, response: `{"sid": "SM1", "status": "queued"}`}
+	server := api.start(t)
+
+	sender := sms.NewTwilioSender(server.URL, "AC123", "auth-token", "+15005550006", server.Client())
+
+	if err := sender.Send("+380501234567", "Kyiv: 22C"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if api.path != "/2010-04-01/Accounts/AC123/Messages.json" {
+		t.Errorf("unexpected path %q", api.path)
+	}
+	if api.user != "AC123" || api.password != "auth-token" {
+		t.Errorf("unexpected credentials %q:%q", api.user, api.password)
+	}
+	if api.form.Get("To") != "+380501234567" || api.form.Get("From") != "+15005550006" || api.form.Get("Body") != "Kyiv: 22C" {
+		t.Errorf("unexpected form %v", api.form)
+	}
+}
+
+func TestTwilioSender_MessagingService(t *testing.T) {
+	api := &fakeTwilio{status: http.StatusCreated, response: `{}`}
+	server := api.start(t)
+
+	sender := sms.NewTwilioSender(server.URL, "AC123", "auth-token", "MG456", server.Client())
+
+	if err := sender.Send("+380501234567", "text"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if api.form.Get("MessagingServiceSid") != "MG456" || api.form.Has("From") {
+		t.Errorf("expected MessagingServiceSid instead of From, got %v", api.form)
+	}
+}
+
+func TestTwilioSender_ProviderError(t *testing.T) {
+	api := &fakeTwilio{status: http.StatusBadRequest, response: `{"code": 21211, "message": "Invalid 'To' Phone Number", "status": 400}`}
+	server := api.start(t)
+
+	sender := sms.NewTwilioSender(server.URL, "AC123", "auth-token", "+15005550006", server.Client())
+
+	err := sender.Send("+380501234567", "text")
+
+	var providerErr *sms.ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("expected ProviderError, got %v", err)
+	}
+	if providerErr.Status != http.StatusBadRequest || providerErr.Code != 21211 {
+		t.Errorf("unexpected provider error %+v", providerErr)
+	}
+}
+
+type recordingSender struct {
+	to, body []string
+	err      error
+}
+
+func (s *recordingSender) Send(to, body string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.to = append(s.to, to)
+	s.body = append(s.body, body)
+	return nil
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	sender := &recordingSender{}
+	notifier := sms.NewNotifier(sender)
+
+	update := notify.Update{
+		City:    "Kyiv",
+		Weather: weather.WeatherData{Temperature: 21.6, Humidity: 40, Description: "clear sky"},
+	}
+
+	if err := notifier.Notify(repository.ChannelRecipient{Target: "+380501234567"}, update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sender.to) != 1 || sender.to[0] != "+380501234567" {
+		t.Fatalf("unexpected recipients %v", sender.to)
+	}
+	if sender.body[0] != "Kyiv: 22C, clear sky, humidity 40%" {
+		t.Errorf("unexpected message %q", sender.body[0])
+	}
+}
+
+func TestUpdateMessage_Preferences(t *testing.T) {
+	update := notify.Update{
+		City:        "Kyiv",
+		Weather:     weather.WeatherData{Temperature: 20, Humidity: 40, Description: "clear sky", FeelsLike: 18.5},
+		Preferences: content.Preferences{Fields: []string{content.FieldFeelsLike}, Units: content.UnitsImperial},
+	}
+
+	// No degree sign in GSM-7
+	if got := sms.UpdateMessage(update); got != "Kyiv: 68F, clear sky, humidity 40%, feels like 65.3F" {
+		t.Errorf("unexpected message %q", got)
+	}
+}
+
+func TestUpdateMessage_FitsOneSegment(t *testing.T) {
+	update := notify.Update{
+		City:    strings.Repeat("Llanfair", 30),
+		Weather: weather.WeatherData{Temperature: 10, Description: "rain"},
+	}
+
+	if got := sms.UpdateMessage(update); len([]rune(got)) != 160 || !strings.HasSuffix(got, "...") {
+		t.Errorf("expected a truncated 160 character message, got %d: %q", len([]rune(got)), got)
+	}
+}
@@ -0,0 +1,207 @@
+package sms
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/ratelimit"
+
+	"github.com/google/uuid"
+)
+
+const (
+	codeTTL         = 10 * time.Minute
+	codeDigits      = 6
+	maxCodeAttempts = 5
+
+	// Every code is a paid SMS
+	codeLimit  = 3
+	codeWindow = time.Hour
+)
+
+var (
+	ErrInvalidCode     = errors.New("code is invalid")
+	ErrCodeExpired     = errors.New("code has expired")
+	ErrNoPendingCode   = errors.New("no code is pending")
+	ErrTooManyAttempts = errors.New("too many wrong codes, request a new one")
+	ErrTooManyCodes    = errors.New("too many codes requested, try again later")
+	ErrPhoneNotFound   = errors.New("no phone number is set")
+	ErrSendFailed      = errors.New("failed to send sms")
+)
+
+type TokenResolverInterface interface {
+	ResolveUser(tokenValue, tokenType string) (*models.Token, *models.User, error)
+}
+
+type RepositoryInterface interface {
+	GetPhone(userID uuid.UUID) (*models.PhoneNumber, error)
+	SavePendingPhone(userID uuid.UUID, number, codeHash string, expiresAt time.Time) error
+	RecordFailedAttempt(userID uuid.UUID) error
+	ConfirmPhone(userID uuid.UUID, confirmedAt time.Time) error
+	DeletePhone(userID uuid.UUID) error
+}
+
+type EventRepositoryInterface interface {
+	Create(event *models.SubscriptionEvent) error
+}
+
+type CodeHasherInterface interface {
+	Hash(value string) string
+}
+
+// Phone number capture and confirmation. Like the other management actions
+// it is authorized by the unsubscribe token
+type Service struct {
+	tokens    TokenResolverInterface
+	smsRepo   RepositoryInterface
+	eventRepo EventRepositoryInterface
+	sender    SMSSender
+	hasher    CodeHasherInterface
+
+	codeLimiter *ratelimit.Limiter
+}
+
+func NewService(tokens TokenResolverInterface, smsRepo RepositoryInterface,
+	eventRepo EventRepositoryInterface, sender SMSSender, hasher CodeHasherInterface) *Service {
+	return &Service{
+		tokens:      tokens,
+		smsRepo:     smsRepo,
+		eventRepo:   eventRepo,
+		sender:      sender,
+		hasher:      hasher,
+		codeLimiter: ratelimit.NewLimiter(codeLimit, codeWindow),
+	}
+}
+
+func (srv *Service) authenticate(tokenValue string) (*models.User, error) {
+	_, user, err := srv.tokens.ResolveUser(tokenValue, models.TokenTypeUnsubscribe)
+	return user, err
+}
+
+// Stores the number unconfirmed and texts it a one-time code
+func (srv *Service) RequestCode(tokenValue, phone string, meta audit.Meta) error {
+	number, err := NormalizePhone(phone)
+	if err != nil {
+		return err
+	}
+
+	user, err := srv.authenticate(tokenValue)
+	if err != nil {
+		return err
+	}
+
+	if allowed, _ := srv.codeLimiter.Allow(user.ID.String(), time.Now()); !allowed {
+		return ErrTooManyCodes
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return err
+	}
+
+	if err := srv.smsRepo.SavePendingPhone(user.ID, number, srv.hasher.Hash(code), time.Now().Add(codeTTL)); err != nil {
+		return fmt.Errorf("error saving phone number: %w", err)
+	}
+
+	if err := srv.sender.Send(number, codeMessage(code)); err != nil {
+		return fmt.Errorf("%w: %w", ErrSendFailed, err)
+	}
+
+	return srv.recordEvent(models.EventPhoneAdded, user, number, meta)
+}
+
+// Confirms the pending number. Updates are sent to it from then on
+func (srv *Service) ConfirmCode(tokenValue, code string, meta audit.Meta) error {
+	user, err := srv.authenticate(tokenValue)
+	if err != nil {
+		return err
+	}
+
+	phone, err := srv.smsRepo.GetPhone(user.ID)
+	if err != nil {
+		if repository.IsErrNotFound(err) {
+			return ErrNoPendingCode
+		}
+
+		return fmt.Errorf("error getting phone number: %w", err)
+	}
+
+	switch {
+	case phone.CodeExpiresAt == nil:
+		return ErrNoPendingCode
+	case phone.Attempts >= maxCodeAttempts:
+		return ErrTooManyAttempts
+	case time.Now().After(*phone.CodeExpiresAt):
+		return ErrCodeExpired
+	}
+
+	if !hmac.Equal([]byte(srv.hasher.Hash(code)), []byte(phone.CodeHash)) {
+		if err := srv.smsRepo.RecordFailedAttempt(user.ID); err != nil {
+			log.Printf("Failed to record code attempt for user %s: %s\n", user.ID, err.Error())
+		}
+
+		return ErrInvalidCode
+	}
+
+	if err := srv.smsRepo.ConfirmPhone(user.ID, time.Now()); err != nil {
+		return fmt.Errorf("error confirming phone number: %w", err)
+	}
+
+	return srv.recordEvent(models.EventPhoneConfirmed, user, phone.Number, meta)
+}
+
+// Stops SMS updates. The email subscription is not changed
+func (srv *Service) RemovePhone(tokenValue string, meta audit.Meta) error {
+	user, err := srv.authenticate(tokenValue)
+	if err != nil {
+		return err
+	}
+
+	phone, err := srv.smsRepo.GetPhone(user.ID)
+	if err == nil {
+		err = srv.smsRepo.DeletePhone(user.ID)
+	}
+	if err != nil {
+		if repository.IsErrNotFound(err) {
+			return ErrPhoneNotFound
+		}
+
+		return fmt.Errorf("error deleting phone number: %w", err)
+	}
+
+	return srv.recordEvent(models.EventPhoneRemoved, user, phone.Number, meta)
+}
+
+func (srv *Service) recordEvent(eventType string, user *models.User, number string, meta audit.Meta) error {
+	event := models.SubscriptionEvent{
+		Type:      eventType,
+		Email:     user.Email,
+		UserID:    user.ID,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Details:   "phone=" + MaskPhone(number),
+		CreatedAt: time.Now(),
+	}
+
+	if err := srv.eventRepo.Create(&event); err != nil {
+		return fmt.Errorf("error recording %s event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000)) // 10^codeDigits
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+
+	return fmt.Sprintf("%0*d", codeDigits, n.Int64()), nil
+}
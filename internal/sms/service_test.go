@@ -0,0 +1,255 @@
+package sms_test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/sms"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+)
+
+type mockTokenResolver struct {
+	ResolveUserFunc func(value, tokenType string) (*models.Token, *models.User, error)
+}
+
+func (r *mockTokenResolver) ResolveUser(value, tokenType string) (*models.Token, *models.User, error) {
+	return r.ResolveUserFunc(value, tokenType)
+}
+
+type memoryPhoneRepo struct {
+	phones map[uuid.UUID]*models.PhoneNumber
+}
+
+func (r *memoryPhoneRepo) GetPhone(userID uuid.UUID) (*models.PhoneNumber, error) {
+	phone, ok := r.phones[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	copied := *phone
+	return &copied, nil
+}
+
+func (r *memoryPhoneRepo) SavePendingPhone(userID uuid.UUID, number, codeHash string, expiresAt time.Time) error {
+	r.phones[userID] = &models.PhoneNumber{UserID: userID, Number: number, CodeHash: codeHash, CodeExpiresAt: &expiresAt}
+	return nil
+}
+
+func (r *memoryPhoneRepo) RecordFailedAttempt(userID uuid.UUID) error {
+	r.phones[userID].Attempts++
+	return nil
+}
+
+func (r *memoryPhoneRepo) ConfirmPhone(userID uuid.UUID, confirmedAt time.Time) error {
+	phone := r.phones[userID]
+	phone.CodeHash, phone.CodeExpiresAt, phone.Attempts, phone.ConfirmedAt = "", nil, 0, &confirmedAt
+	return nil
+}
+
+func (r *memoryPhoneRepo) DeletePhone(userID uuid.UUID) error {
+	if _, ok := r.phones[userID]; !ok {
+		return fmt.Errorf("%w: phone number not found", repository.ErrNotFound)
+	}
+	delete(r.phones, userID)
+	return nil
+}
+
+type mockEventRepo struct {
+	events []models.SubscriptionEvent
+}
+
+func (r *mockEventRepo) Create(event *models.SubscriptionEvent) error {
+	r.events = append(r.events, *event)
+	return nil
+}
+
+type plainHasher struct{}
+
+func (plainHasher) Hash(value string) string {
+	return "hash:" + value
+}
+
+type testEnv struct {
+	svc    *sms.Service
+	userID uuid.UUID
+	phones *memoryPhoneRepo
+	events *mockEventRepo
+	sender *recordingSender
+}
+
+func newTestEnv() *testEnv {
+	env := &testEnv{
+		userID: uuid.New(),
+		phones: &memoryPhoneRepo{phones: map[uuid.UUID]*models.PhoneNumber{}},
+		events: &mockEventRepo{},
+		sender: &recordingSender{},
+	}
+
+	tokens := &mockTokenResolver{
+		ResolveUserFunc: func(value, tokenType string) (*models.Token, *models.User, error) {
+			if value != "valid" {
+				return nil, nil, subscription.ErrTokenNotFound
+			}
+			return &models.Token{Type: tokenType, UserID: env.userID}, &models.User{ID: env.userID, Email: "test@example.com"}, nil
+		},
+	}
+
+	env.svc = sms.NewService(tokens, env.phones, env.events, env.sender, plainHasher{})
+
+	return env
+}
+
+var codePattern = regexp.MustCompile(`\d{6}`)
+
+func (env *testEnv) sentCode(t *testing.T) string {
+	t.Helper()
+
+	if len(env.sender.body) == 0 {
+		t.Fatal("no code was sent")
+	}
+
+	code := codePattern.FindString(env.sender.body[len(env.sender.body)-1])
+	if code == "" {
+		t.Fatalf("no code in %q", env.sender.body[len(env.sender.body)-1])
+	}
+
+	return code
+}
+
+func TestRequestAndConfirmCode(t *testing.T) {
+	env := newTestEnv()
+
+	if err := env.svc.RequestCode("valid", "+380 50 123 45 67", audit.Meta{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if env.sender.to[0] != "+380501234567" {
+		t.Errorf("expected code sent to the normalized number, got %q", env.sender.to[0])
+	}
+	if env.phones.phones[env.userID].ConfirmedAt != nil {
+		t.Error("number must stay unconfirmed until the code is entered")
+	}
+
+	if err := env.svc.ConfirmCode("valid", env.sentCode(t), audit.Meta{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if env.phones.phones[env.userID].ConfirmedAt == nil {
+		t.Error("expected confirmed number")
+	}
+
+	if len(env.events.events) != 2 ||
+		env.events.events[0].Type != models.EventPhoneAdded ||
+		env.events.events[1].Type != models.EventPhoneConfirmed ||
+		env.events.events[1].Details != "phone=***4567" {
+		t.Errorf("unexpected events %+v", env.events.events)
+	}
+
+	// The code is single use
+	if err := env.svc.ConfirmCode("valid", env.sentCode(t), audit.Meta{}); !errors.Is(err, sms.ErrNoPendingCode) {
+		t.Errorf("expected ErrNoPendingCode, got %v", err)
+	}
+}
+
+func TestRequestCode_InvalidPhone(t *testing.T) {
+	env := newTestEnv()
+
+	if err := env.svc.RequestCode("valid", "050 123 45 67", audit.Meta{}); !errors.Is(err, sms.ErrInvalidPhone) {
+		t.Errorf("expected ErrInvalidPhone, got %v", err)
+	}
+	if len(env.sender.to) != 0 {
+		t.Error("no sms should be sent")
+	}
+}
+
+func TestRequestCode_InvalidToken(t *testing.T) {
+	env := newTestEnv()
+
+	if err := env.svc.RequestCode("wrong", "+380501234567", audit.Meta{}); !errors.Is(err, subscription.ErrTokenNotFound) {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestRequestCode_RateLimited(t *testing.T) {
+	env := newTestEnv()
+
+	for i := 0; i < 3; i++ {
+		if err := env.svc.RequestCode("valid", "+380501234567", audit.Meta{}); err != nil {
+			t.Fatalf("request %d: unexpected error: %v", i, err)
+		}
+	}
+
+	if err := env.svc.RequestCode("valid", "+380501234567", audit.Meta{}); !errors.Is(err, sms.ErrTooManyCodes) {
+		t.Errorf("expected ErrTooManyCodes, got %v", err)
+	}
+}
+
+func TestRequestCode_SendFailed(t *testing.T) {
+	env := newTestEnv()
+	env.sender.err = errors.New("provider down")
+
+	if err := env.svc.RequestCode("valid", "+380501234567", audit.Meta{}); !errors.Is(err, sms.ErrSendFailed) {
+		t.Errorf("expected ErrSendFailed, got %v", err)
+	}
+}
+
+func TestConfirmCode_WrongCodeLimit(t *testing.T) {
+	env := newTestEnv()
+
+	if err := env.svc.RequestCode("valid", "+380501234567", audit.Meta{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := env.sentCode(t)
+
+	for i := 0; i < 5; i++ {
+		if err := env.svc.ConfirmCode("valid", "wrong", audit.Meta{}); !errors.Is(err, sms.ErrInvalidCode) {
+			t.Fatalf("attempt %d: expected ErrInvalidCode, got %v", i, err)
+		}
+	}
+
+	// Even the right code is refused once the attempts are used up
+	if err := env.svc.ConfirmCode("valid", code, audit.Meta{}); !errors.Is(err, sms.ErrTooManyAttempts) {
+		t.Errorf("expected ErrTooManyAttempts, got %v", err)
+	}
+}
+
+func TestConfirmCode_Expired(t *testing.T) {
+	env := newTestEnv()
+
+	if err := env.svc.RequestCode("valid", "+380501234567", audit.Meta{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	env.phones.phones[env.userID].CodeExpiresAt = &expired
+
+	if err := env.svc.ConfirmCode("valid", env.sentCode(t), audit.Meta{}); !errors.Is(err, sms.ErrCodeExpired) {
+		t.Errorf("expected ErrCodeExpired, got %v", err)
+	}
+}
+
+func TestRemovePhone(t *testing.T) {
+	env := newTestEnv()
+
+	if err := env.svc.RemovePhone("valid", audit.Meta{}); !errors.Is(err, sms.ErrPhoneNotFound) {
+		t.Errorf("expected ErrPhoneNotFound, got %v", err)
+	}
+
+	env.phones.phones[env.userID] = &models.PhoneNumber{UserID: env.userID, Number: "+380501234567"}
+
+	if err := env.svc.RemovePhone("valid", audit.Meta{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(env.phones.phones) != 0 {
+		t.Error("expected phone to be deleted")
+	}
+	if len(env.events.events) != 1 || env.events.events[0].Type != models.EventPhoneRemoved {
+		t.Errorf("unexpected events %+v", env.events.events)
+	}
+}
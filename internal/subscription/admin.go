@@ -0,0 +1,57 @@
+package subscription
+
+import (
+	"errors"
+	"fmt"
+	"weather-app/internal/audit"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+
+	"github.com/google/uuid"
+)
+
+// Recorded in event details for actions taken through the admin API
+const adminEventDetails = "by admin"
+
+var ErrUserNotFound = errors.New("user not found")
+
+// Confirms a subscriber without the confirmation link
+func (srv *SubscriptionService) ForceConfirm(userID uuid.UUID, meta audit.Meta) error {
+	user, err := srv.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := srv.userRepo.ConfirmUser(userID); err != nil {
+		return fmt.Errorf("error confirming user: %w", err)
+	}
+
+	return srv.recordEvent(models.EventConfirmed, user.Email, userID, nil, adminEventDetails, meta)
+}
+
+// Removes a subscriber. Unlike Unsubscribe it leaves no churn record
+func (srv *SubscriptionService) DeleteUser(userID uuid.UUID, meta audit.Meta) error {
+	user, err := srv.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := srv.userRepo.DeleteUser(userID); err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+
+	return srv.recordEvent(models.EventUnsubscribed, user.Email, userID, nil, adminEventDetails, meta)
+}
+
+func (srv *SubscriptionService) getUser(userID uuid.UUID) (*models.User, error) {
+	user, err := srv.userRepo.GetByID(userID)
+	if err != nil {
+		if repository.IsErrNotFound(err) {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,71 @@
+package subscription_test
+
+import (
+	"errors"
+	"testing"
+	"weather-app/internal/audit"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+)
+
+func TestForceConfirm(t *testing.T) {
+	userID := uuid.New()
+
+	confirmed := false
+	userRepo := &mockUserRepo{
+		ConfirmUserFunc: func(id uuid.UUID) error {
+			confirmed = id == userID
+			return nil
+		},
+	}
+	events := &mockEventRepo{}
+
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, events, nil, testLinks, nil, nil)
+
+	if err := svc.ForceConfirm(userID, audit.Meta{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !confirmed {
+		t.Error("expected user to be confirmed")
+	}
+	if len(events.Events) != 1 || events.Events[0].Type != models.EventConfirmed || events.Events[0].Details != "by admin" {
+		t.Errorf("expected admin confirmed event, got %+v", events.Events)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	deleted := false
+	userRepo := &mockUserRepo{
+		DeleteUserFunc: func(id uuid.UUID) error {
+			deleted = true
+			return nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	if err := svc.DeleteUser(uuid.New(), audit.Meta{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !deleted {
+		t.Error("expected user to be deleted")
+	}
+}
+
+func TestDeleteUser_NotFound(t *testing.T) {
+	userRepo := &mockUserRepo{
+		GetByIDFunc: func(id uuid.UUID) (*models.User, error) {
+			return nil, repository.ErrNotFound
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	if err := svc.DeleteUser(uuid.New(), audit.Meta{}); !errors.Is(err, subscription.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package subscription
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/links"
+)
+
+var (
+	ErrSameEmail          = errors.New("new email matches the current one")
+	ErrEmailChangeExpired = errors.New("email change link has expired")
+)
+
+// Starts moving the subscription to newEmail. The subscriber's unsubscribe
+// token authorizes the request, the address only changes once the new
+// mailbox confirms it
+func (srv *SubscriptionService) RequestEmailChange(tokenValue, newEmail string, meta audit.Meta) error {
+	_, user, err := srv.ResolveUser(tokenValue, models.TokenTypeUnsubscribe)
+	if err != nil {
+		return err
+	}
+
+	if newEmail == user.Email {
+		return ErrSameEmail
+	}
+
+	if err := srv.checkNewAddress(newEmail); err != nil {
+		return err
+	}
+
+	changeToken, err := srv.userRepo.CreateEmailChangeToken(user.ID, newEmail)
+	if err != nil {
+		return fmt.Errorf("error creating email change token: %w", err)
+	}
+
+	confirmUrl, err := srv.links.TokenURL(links.ActionConfirmEmail, changeToken.Value)
+	if err != nil {
+		return fmt.Errorf("error building email change url: %w", err)
+	}
+
+	if err := srv.recordEvent(models.EventEmailChangeRequested, user.Email, user.ID, nil, "", meta); err != nil {
+		return err
+	}
+
+	if err := srv.ms.SendEmailChangeMail(newEmail, confirmUrl); err != nil {
+		return fmt.Errorf("%w: %w", ErrConfirmationMailError, err)
+	}
+
+	return nil
+}
+
+// Swaps the address after the new mailbox followed the link, then tells the
+// old address about it
+func (srv *SubscriptionService) ConfirmEmailChange(tokenValue string, meta audit.Meta) error {
+	token, user, err := srv.ResolveUser(tokenValue, models.TokenTypeEmailChange)
+	if err != nil {
+		return err
+	}
+
+	if time.Since(token.CreatedAt) > models.EmailChangeTokenTTL {
+		return ErrEmailChangeExpired
+	}
+
+	// The address may have been taken or erased since the request
+	if err := srv.checkNewAddress(token.NewEmail); err != nil {
+		return err
+	}
+
+	err = srv.userRepo.ChangeEmailAndDeleteToken(user.ID, token.ID, token.NewEmail)
+	if err != nil {
+		if repository.IsErrDuplicate(err) {
+			return ErrUserAlreadyExists
+		}
+
+		return fmt.Errorf("error changing email: %w", err)
+	}
+
+	if err := srv.recordEvent(models.EventEmailChanged, token.NewEmail, user.ID, &token.ID, "", meta); err != nil {
+		return err
+	}
+
+	// The change already happened, a lost notice must not undo it
+	if err := srv.ms.SendEmailChangedNotice(user.Email, token.NewEmail); err != nil {
+		log.Printf("Failed to send email change notice for user %s: %s\n", user.ID, err.Error())
+	}
+
+	return nil
+}
+
+// Applies the signup checks to an address a subscriber wants to move to
+func (srv *SubscriptionService) checkNewAddress(email string) error {
+	_, err := srv.userRepo.GetByEmail(email)
+	if err == nil {
+		return ErrUserAlreadyExists
+	} else if !repository.IsErrNotFound(err) {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	suppressed, err := srv.userRepo.IsSuppressed(email)
+	if err != nil {
+		return fmt.Errorf("error checking suppression: %w", err)
+	}
+
+	if suppressed {
+		return ErrEmailSuppressed
+	}
+
+	if srv.verifier != nil {
+		if err := srv.verifier.Verify(email); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package subscription_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestRequestEmailChange_Success(t *testing.T) {
+	userID := uuid.New()
+
+	var gotEmail string
+	userRepo := &mockUserRepo{
+		GetByIDFunc: func(id uuid.UUID) (*models.User, error) {
+			return &models.User{ID: userID, Email: "old@example.com"}, nil
+		},
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		CreateEmailChangeTokenFunc: func(id uuid.UUID, newEmail string) (*models.Token, error) {
+			gotEmail = newEmail
+			return &models.Token{ID: uuid.New(), Value: "change-token", Type: models.TokenTypeEmailChange}, nil
+		},
+	}
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeUnsubscribe, UserID: userID}, nil
+		},
+	}
+
+	mail := &mockMailService{}
+	events := &mockEventRepo{}
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, events, mail, testLinks, nil, nil)
+
+	err := svc.RequestEmailChange("unsub", "new@example.com", audit.Meta{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotEmail != "new@example.com" {
+		t.Errorf("expected token for new@example.com, got %q", gotEmail)
+	}
+	if mail.ChangeURL != "https://test.com/api/confirm-email/change-token" {
+		t.Errorf("unexpected confirmation url %q", mail.ChangeURL)
+	}
+	if len(events.Events) != 1 || events.Events[0].Type != models.EventEmailChangeRequested {
+		t.Errorf("expected email_change_requested event, got %+v", events.Events)
+	}
+}
+
+func TestRequestEmailChange_AddressTaken(t *testing.T) {
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return &models.User{}, nil
+		},
+	}
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeUnsubscribe, UserID: uuid.New()}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, &mockEventRepo{}, &mockMailService{}, testLinks, nil, nil)
+
+	err := svc.RequestEmailChange("unsub", "taken@example.com", audit.Meta{})
+	if !errors.Is(err, subscription.ErrUserAlreadyExists) {
+		t.Fatalf("expected ErrUserAlreadyExists, got %v", err)
+	}
+}
+
+func TestRequestEmailChange_SameEmail(t *testing.T) {
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeUnsubscribe, UserID: uuid.New()}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, tokenRepo, nil, &mockEventRepo{}, &mockMailService{}, testLinks, nil, nil)
+
+	err := svc.RequestEmailChange("unsub", "test@example.com", audit.Meta{})
+	if !errors.Is(err, subscription.ErrSameEmail) {
+		t.Fatalf("expected ErrSameEmail, got %v", err)
+	}
+}
+
+func TestRequestEmailChange_RequiresUnsubscribeToken(t *testing.T) {
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeConfirm}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, tokenRepo, nil, &mockEventRepo{}, &mockMailService{}, testLinks, nil, nil)
+
+	err := svc.RequestEmailChange("confirm", "new@example.com", audit.Meta{})
+	if !errors.Is(err, subscription.ErrTokenWrongType) {
+		t.Fatalf("expected ErrTokenWrongType, got %v", err)
+	}
+}
+
+func TestConfirmEmailChange_Success(t *testing.T) {
+	userID := uuid.New()
+	tokenID := uuid.New()
+
+	var changedTo string
+	userRepo := &mockUserRepo{
+		GetByIDFunc: func(id uuid.UUID) (*models.User, error) {
+			return &models.User{ID: userID, Email: "old@example.com"}, nil
+		},
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		ChangeEmailAndDeleteTokenFunc: func(id, tid uuid.UUID, newEmail string) error {
+			if tid != tokenID {
+				t.Errorf("expected token %s to be deleted, got %s", tokenID, tid)
+			}
+			changedTo = newEmail
+			return nil
+		},
+	}
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{
+				ID:        tokenID,
+				Type:      models.TokenTypeEmailChange,
+				UserID:    userID,
+				NewEmail:  "new@example.com",
+				CreatedAt: time.Now().Add(-time.Hour),
+			}, nil
+		},
+	}
+
+	mail := &mockMailService{}
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, &mockEventRepo{}, mail, testLinks, nil, nil)
+
+	if err := svc.ConfirmEmailChange("change", audit.Meta{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if changedTo != "new@example.com" {
+		t.Errorf("expected email changed to new@example.com, got %q", changedTo)
+	}
+	if !mail.NoticeSent || mail.NoticeOld != "old@example.com" || mail.NoticeNew != "new@example.com" {
+		t.Errorf("expected notice to old address, got %+v", mail)
+	}
+}
+
+func TestConfirmEmailChange_Expired(t *testing.T) {
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{
+				Type:      models.TokenTypeEmailChange,
+				NewEmail:  "new@example.com",
+				CreatedAt: time.Now().Add(-25 * time.Hour),
+			}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, tokenRepo, nil, &mockEventRepo{}, &mockMailService{}, testLinks, nil, nil)
+
+	err := svc.ConfirmEmailChange("change", audit.Meta{})
+	if !errors.Is(err, subscription.ErrEmailChangeExpired) {
+		t.Fatalf("expected ErrEmailChangeExpired, got %v", err)
+	}
+}
+
+func TestConfirmEmailChange_AddressTakenMeanwhile(t *testing.T) {
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		ChangeEmailAndDeleteTokenFunc: func(id, tid uuid.UUID, newEmail string) error {
+			return repository.HandleDBError(gorm.ErrDuplicatedKey, "user")
+		},
+	}
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeEmailChange, NewEmail: "new@example.com", CreatedAt: time.Now()}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, &mockEventRepo{}, &mockMailService{}, testLinks, nil, nil)
+
+	err := svc.ConfirmEmailChange("change", audit.Meta{})
+	if !errors.Is(err, subscription.ErrUserAlreadyExists) {
+		t.Fatalf("expected ErrUserAlreadyExists, got %v", err)
+	}
+}
@@ -1,35 +1,83 @@
 package subscription
 
 import (
+	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
 	"net/http"
-	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/emailaddr"
+	"weather-app/internal/mail/page_templates"
+	"weather-app/internal/problem"
+	"weather-app/internal/schedule"
+
+	"github.com/google/uuid"
 )
 
-const (
-	genericErrorMsg = "Something went wrong"
-)
-
-var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-
 var (
+	ErrInvalidForm      = errors.New("form body is invalid")
 	ErrInvalidEmail     = errors.New("email parameter is invalid")
 	ErrInvalidCity      = errors.New("city parameter is invalid")
-	ErrInvalidFrequency = errors.New("frequency parameter is invalid")
+	ErrInvalidFrequency = schedule.ErrInvalidFrequency
+
+	ErrDisposableEmail = emailaddr.ErrDisposableDomain
+	ErrNoMailServer    = emailaddr.ErrNoMailServer
+
+	ErrInvalidOneClickBody = errors.New("expected List-Unsubscribe=One-Click body")
 )
 
-var validFrequencies = map[string]struct{}{
-	"hourly": {},
-	"daily":  {},
+// Validation errors of the subscription form fields, shared with the other
+// kinds of subscriptions
+var FormProblems = problem.Mappings{
+	{Err: ErrInvalidForm, Status: http.StatusBadRequest, Code: "invalid_form"},
+	{Err: ErrInvalidEmail, Status: http.StatusBadRequest, Code: "invalid_email"},
+	{Err: ErrInvalidCity, Status: http.StatusBadRequest, Code: "invalid_city"},
+	{Err: schedule.ErrInvalidFrequency, Status: http.StatusBadRequest, Code: "invalid_frequency"},
+	{Err: schedule.ErrInvalidTimezone, Status: http.StatusBadRequest, Code: "invalid_timezone"},
+	{Err: schedule.ErrInvalidSendTime, Status: http.StatusBadRequest, Code: "invalid_send_time"},
+	{Err: schedule.ErrInvalidWeekday, Status: http.StatusBadRequest, Code: "invalid_weekday"},
+	{Err: schedule.ErrInvalidIntervalHours, Status: http.StatusBadRequest, Code: "invalid_interval_hours"},
+	{Err: schedule.ErrInvalidCron, Status: http.StatusBadRequest, Code: "invalid_cron"},
+	{Err: schedule.ErrCronTooFrequent, Status: http.StatusBadRequest, Code: "cron_too_frequent"},
+	{Err: content.ErrInvalidFields, Status: http.StatusBadRequest, Code: "invalid_fields"},
+	{Err: content.ErrInvalidUnits, Status: http.StatusBadRequest, Code: "invalid_units"},
+	{Err: content.ErrInvalidLanguage, Status: http.StatusBadRequest, Code: "invalid_lang"},
+}
+
+// Errors of the subscriber tokens in links, shared with the handlers they
+// authorize
+var TokenProblems = problem.Mappings{
+	{Err: ErrTokenNotFound, Status: http.StatusNotFound, Code: "token_not_found"},
+	{Err: ErrTokenEmpty, Status: http.StatusBadRequest, Code: "token_empty"},
+	{Err: ErrTokenWrongType, Status: http.StatusBadRequest, Code: "token_wrong_type"},
 }
 
+var problems = slices.Concat(FormProblems, TokenProblems, problem.Mappings{
+	{Err: ErrInvalidOneClickBody, Status: http.StatusBadRequest, Code: "invalid_one_click_body"},
+	{Err: ErrInvalidPauseEnd, Status: http.StatusBadRequest, Code: "invalid_until"},
+	{Err: ErrSameEmail, Status: http.StatusBadRequest, Code: "same_email"},
+	{Err: ErrEmailChangeExpired, Status: http.StatusGone, Code: "email_change_expired"},
+	{Err: ErrUserAlreadyExists, Status: http.StatusConflict, Code: "user_already_exists"},
+	{Err: ErrEmailSuppressed, Status: http.StatusForbidden, Code: "email_suppressed"},
+	{Err: ErrDisposableEmail, Status: http.StatusBadRequest, Code: "disposable_email"},
+	{Err: ErrNoMailServer, Status: http.StatusBadRequest, Code: "no_mail_server"},
+})
+
 type SubscriptionServiceInterface interface {
-	Subscribe(email, city, frequency string) error
-	Confirm(tokenValue string) error
-	Unsubscribe(tokenValue string) error
+	Subscribe(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) error
+	Confirm(tokenValue string, meta audit.Meta) error
+	Unsubscribe(tokenValue string, meta audit.Meta) (uuid.UUID, error)
+	Pause(tokenValue, until string, meta audit.Meta) error
+	Resume(tokenValue string, meta audit.Meta) error
+	RequestEmailChange(tokenValue, newEmail string, meta audit.Meta) error
+	ConfirmEmailChange(tokenValue string, meta audit.Meta) error
+	GetPreferences(tokenValue string) (content.Preferences, error)
+	UpdatePreferences(tokenValue string, prefs content.Preferences, meta audit.Meta) error
 }
 
 type SubscriptionHandler struct {
@@ -41,86 +89,124 @@ func NewHandler(svc SubscriptionServiceInterface) *SubscriptionHandler {
 }
 
 type FormData struct {
-	Email     string
-	City      string
-	Frequency string
+	Email    string
+	City     string
+	Schedule schedule.Schedule
+	Content  content.Preferences
 }
 
 func isValidFrequency(freq string) bool {
-	_, ok := validFrequencies[freq]
-	return ok
+	return schedule.IsValidFrequency(freq)
 }
 
-func isValidEmail(email string) bool {
-	return emailRegex.MatchString(email)
-}
-
-func parseFormData(req *http.Request) (*FormData, error) {
+// Reads and validates the subscription form: email, city, the schedule and
+// the content fields. Errors map to FormProblems
+func ParseForm(req *http.Request) (*FormData, error) {
 	data := FormData{}
 
 	if err := req.ParseForm(); err != nil {
-		return nil, err
+		return nil, ErrInvalidForm
 	}
 
-	data.Email = req.FormValue("email")
-	if data.Email == "" {
+	if req.FormValue("email") == "" {
 		return nil, ErrInvalidEmail
 	}
 
-	if !isValidEmail(data.Email) {
+	email, err := emailaddr.Normalize(req.FormValue("email"))
+	if err != nil {
 		return nil, ErrInvalidEmail
 	}
+	data.Email = email
 
 	data.City = req.FormValue("city")
 	if data.City == "" {
 		return nil, ErrInvalidCity
 	}
 
-	data.Frequency = req.FormValue("frequency")
-	if data.Frequency == "" {
-		return nil, ErrInvalidFrequency
+	sched, err := ParseScheduleForm(req)
+	if err != nil {
+		return nil, err
 	}
+	data.Schedule = sched
 
-	if !isValidFrequency(data.Frequency) {
-		return nil, ErrInvalidFrequency
+	prefs, err := content.ParseForm(req)
+	if err != nil {
+		return nil, err
 	}
+	data.Content = prefs
 
 	return &data, nil
 }
 
+// Reads the schedule form fields shared by every kind of subscription:
+// frequency, timezone, send_time, weekday, interval_hours and cron
+func ParseScheduleForm(req *http.Request) (schedule.Schedule, error) {
+	var sched schedule.Schedule
+
+	sched.Frequency = req.FormValue("frequency")
+	if sched.Frequency == "" {
+		return schedule.Schedule{}, ErrInvalidFrequency
+	}
+
+	if !isValidFrequency(sched.Frequency) {
+		return schedule.Schedule{}, ErrInvalidFrequency
+	}
+
+	// Optional, defaults are applied when empty
+	sched.Timezone = req.FormValue("timezone")
+	sched.SendTime = req.FormValue("send_time")
+
+	// Only required by the frequencies that use them
+	if weekday := req.FormValue("weekday"); weekday != "" {
+		d, err := schedule.ParseWeekday(weekday)
+		if err != nil {
+			return schedule.Schedule{}, err
+		}
+		sched.Weekday = d
+	} else if sched.Frequency == schedule.FrequencyWeekly {
+		return schedule.Schedule{}, schedule.ErrInvalidWeekday
+	}
+
+	if interval := req.FormValue("interval_hours"); interval != "" {
+		n, err := strconv.Atoi(interval)
+		if err != nil {
+			return schedule.Schedule{}, schedule.ErrInvalidIntervalHours
+		}
+		sched.IntervalHours = n
+	}
+
+	sched.CronExpr = req.FormValue("cron")
+
+	if err := sched.WithDefaults().Validate(); err != nil {
+		return schedule.Schedule{}, err
+	}
+
+	return sched, nil
+}
+
 func (h *SubscriptionHandler) SubscribeHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != "POST" {
-		errorMessage := fmt.Sprintf("Unsupported method %s", req.Method)
-		http.Error(w, errorMessage, http.StatusBadRequest)
+		problem.MethodNotAllowed(w, req, "POST")
 		return
 	}
 
 	// Parse form data
-	data, err := parseFormData(req)
+	data, err := ParseForm(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-
+		problems.Write(w, err)
 		return
 	}
 
-	err = h.service.Subscribe(data.Email, data.City, data.Frequency)
+	err = h.service.Subscribe(data.Email, data.City, data.Schedule, data.Content, audit.MetaFromRequest(req))
 
 	if err != nil {
-		switch {
-		case errors.Is(err, ErrUserAlreadyExists):
-			http.Error(w, ErrUserAlreadyExists.Error(), http.StatusConflict)
-
-		case errors.Is(err, ErrConfirmationMailError):
-			log.Println(err.Error()) // We don't want to fail on confirmation mail error
-
-		default:
-			http.Error(w, genericErrorMsg, http.StatusInternalServerError)
-
+		// We don't want to fail on confirmation mail error
+		if !errors.Is(err, ErrConfirmationMailError) {
+			problems.Write(w, err)
+			return
 		}
 
 		log.Println(err.Error())
-
-		return
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -128,69 +214,230 @@ func (h *SubscriptionHandler) SubscribeHandler(w http.ResponseWriter, req *http.
 
 func (h *SubscriptionHandler) ConfirmHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != "GET" {
-		errorMessage := fmt.Sprintf("Unsupported method %s", req.Method)
-		http.Error(w, errorMessage, http.StatusBadRequest)
+		problem.MethodNotAllowed(w, req, "GET")
 		return
 	}
 
 	tokenValue := strings.TrimPrefix(req.URL.Path, "/api/confirm/")
 
-	log.Printf("Token is: %s\n", tokenValue)
+	err := h.service.Confirm(tokenValue, audit.MetaFromRequest(req))
+
+	switch {
+	case err == nil:
+		writeConfirmed(w, req, LinkStatusConfirmed)
+	case errors.Is(err, ErrAlreadyConfirmed):
+		writeConfirmed(w, req, LinkStatusAlreadyConfirmed)
+	default:
+		writeLinkError(w, req, err)
+	}
+}
+
+// GET comes from the link in the email body, POST is RFC 8058 one-click
+// unsubscribe sent by mail clients from the List-Unsubscribe header
+func (h *SubscriptionHandler) UnsubscribeHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "GET":
+
+	case "POST":
+		// Mail clients send urlencoded or multipart bodies
+		if req.PostFormValue("List-Unsubscribe") != "One-Click" {
+			problems.Write(w, ErrInvalidOneClickBody)
+			return
+		}
 
-	err := h.service.Confirm(tokenValue)
+	default:
+		problem.MethodNotAllowed(w, req, "GET", "POST")
+		return
+	}
+
+	tokenValue := strings.TrimPrefix(req.URL.Path, "/api/unsubscribe/")
+
+	churnID, err := h.service.Unsubscribe(tokenValue, audit.MetaFromRequest(req))
+
+	// Mail clients don't show the response
+	if req.Method == "POST" {
+		if err != nil {
+			problems.Write(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		return
+	}
 
 	if err != nil {
-		switch {
-		case errors.Is(err, ErrTokenNotFound):
-			http.Error(w, ErrTokenNotFound.Error(), http.StatusNotFound)
+		writeLinkError(w, req, err)
+		return
+	}
+
+	if wantsJSON(req) {
+		writeLinkStatus(w, LinkStatusUnsubscribed)
+		return
+	}
 
-		case errors.Is(err, ErrTokenEmpty):
-			http.Error(w, ErrTokenEmpty.Error(), http.StatusBadRequest)
+	writeUnsubscribedPage(w, churnID)
+}
+
+// Labels for the reason form, in models.ChurnReasons order
+var churnReasonLabels = map[string]string{
+	models.ChurnReasonTooFrequent: "Emails are too frequent",
+	models.ChurnReasonWrongCity:   "Wrong city",
+	models.ChurnReasonNotUseful:   "Not useful",
+	models.ChurnReasonInaccurate:  "Forecasts are inaccurate",
+	models.ChurnReasonOther:       "Something else",
+}
 
-		case errors.Is(err, ErrTokenWrongType):
-			http.Error(w, ErrTokenWrongType.Error(), http.StatusBadRequest)
+// Confirms the unsubscription and offers the optional reason form and a
+// way back
+func writeUnsubscribedPage(w http.ResponseWriter, churnID uuid.UUID) {
+	data := page_templates.UnsubscribedData{SubscribeURL: subscribeURL()}
 
-		default:
-			http.Error(w, genericErrorMsg, http.StatusInternalServerError)
+	if churnID != uuid.Nil {
+		data.ReasonURL = "/api/unsubscribe-reason/" + churnID.String()
+
+		for _, reason := range models.ChurnReasons {
+			data.Reasons = append(data.Reasons, page_templates.ReasonOption{
+				Value: reason,
+				Label: churnReasonLabels[reason],
+			})
 		}
+	}
 
-		log.Println(err.Error())
+	html, err := page_templates.FormUnsubscribedPage(&data)
+	if err != nil {
+		// The user is already gone, the page is a courtesy
+		log.Printf("Failed to render unsubscribe page: %s\n", err.Error())
+		w.WriteHeader(http.StatusOK)
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(html))
 }
 
-func (h *SubscriptionHandler) UnsubscribeHandler(w http.ResponseWriter, req *http.Request) {
+// Pauses updates. Optional "until" query parameter takes a date or RFC 3339 time
+func (h *SubscriptionHandler) PauseHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != "GET" {
-		errorMessage := fmt.Sprintf("Unsupported method %s", req.Method)
-		http.Error(w, errorMessage, http.StatusBadRequest)
+		problem.MethodNotAllowed(w, req, "GET")
 		return
 	}
 
-	tokenValue := strings.TrimPrefix(req.URL.Path, "/api/unsubscribe/")
+	tokenValue := strings.TrimPrefix(req.URL.Path, "/api/pause/")
+
+	err := h.service.Pause(tokenValue, req.URL.Query().Get("until"), audit.MetaFromRequest(req))
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
 
-	log.Printf("Token is: %s\n", tokenValue)
+func (h *SubscriptionHandler) ResumeHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
 
-	err := h.service.Unsubscribe(tokenValue)
+	tokenValue := strings.TrimPrefix(req.URL.Path, "/api/resume/")
+
+	err := h.service.Resume(tokenValue, audit.MetaFromRequest(req))
 	if err != nil {
-		switch {
-		case errors.Is(err, ErrTokenNotFound):
-			http.Error(w, ErrTokenNotFound.Error(), http.StatusNotFound)
+		problems.Write(w, err)
+		return
+	}
 
-		case errors.Is(err, ErrTokenEmpty):
-			http.Error(w, ErrTokenEmpty.Error(), http.StatusBadRequest)
+	w.WriteHeader(http.StatusOK)
+}
 
-		case errors.Is(err, ErrTokenWrongType):
-			http.Error(w, ErrTokenWrongType.Error(), http.StatusBadRequest)
+// Requests moving the subscription to the "email" form field. Authorized by
+// the unsubscribe token, the new address gets a confirmation link
+func (h *SubscriptionHandler) ChangeEmailHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	}
 
-		default:
-			http.Error(w, genericErrorMsg, http.StatusInternalServerError)
-		}
+	if err := req.ParseForm(); err != nil {
+		problems.Write(w, ErrInvalidForm)
+		return
+	}
 
-		log.Println(err.Error())
+	newEmail, err := emailaddr.Normalize(req.FormValue("email"))
+	if err != nil {
+		problems.Write(w, ErrInvalidEmail)
+		return
+	}
+
+	tokenValue := strings.TrimPrefix(req.URL.Path, "/api/change-email/")
+
+	err = h.service.RequestEmailChange(tokenValue, newEmail, audit.MetaFromRequest(req))
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *SubscriptionHandler) ConfirmEmailChangeHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	tokenValue := strings.TrimPrefix(req.URL.Path, "/api/confirm-email/")
+
+	err := h.service.ConfirmEmailChange(tokenValue, audit.MetaFromRequest(req))
+	if err != nil {
+		problems.Write(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// GET returns the content preferences as JSON. POST replaces them from form
+// fields "fields", "units" and "lang", omitted ones reset to defaults
+func (h *SubscriptionHandler) PreferencesHandler(w http.ResponseWriter, req *http.Request) {
+	tokenValue := strings.TrimPrefix(req.URL.Path, "/api/preferences/")
+
+	switch req.Method {
+	case "GET":
+		prefs, err := h.service.GetPreferences(tokenValue)
+		if err != nil {
+			problems.Write(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(prefs); err != nil {
+			log.Printf("Encoding error %s", err.Error())
+		}
+
+	case "POST":
+		if err := req.ParseForm(); err != nil {
+			problems.Write(w, ErrInvalidForm)
+			return
+		}
+
+		prefs, err := content.ParseForm(req)
+		if err != nil {
+			problems.Write(w, err)
+			return
+		}
+
+		if err := h.service.UpdatePreferences(tokenValue, prefs, audit.MetaFromRequest(req)); err != nil {
+			problems.Write(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		problem.MethodNotAllowed(w, req, "GET", "POST")
+	}
+}
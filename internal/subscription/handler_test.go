@@ -1,32 +1,77 @@
 package subscription_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/problem"
+	"weather-app/internal/schedule"
 	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
 )
 
 type mockSubscriptionService struct {
-	SubscribeFunc   func(email, city, frequency string) error
+	SubscribeFunc   func(email, city string, sched schedule.Schedule) error
 	ConfirmFunc     func(tokenValue string) error
-	UnsubscribeFunc func(tokenValue string) error
+	UnsubscribeFunc func(tokenValue string) (uuid.UUID, error)
+	PauseFunc       func(tokenValue, until string) error
+	ResumeFunc      func(tokenValue string) error
+
+	RequestEmailChangeFunc func(tokenValue, newEmail string) error
+	ConfirmEmailChangeFunc func(tokenValue string) error
+
+	GetPreferencesFunc    func(tokenValue string) (content.Preferences, error)
+	UpdatePreferencesFunc func(tokenValue string, prefs content.Preferences) error
+
+	LastPreferences content.Preferences
 }
 
-func (m *mockSubscriptionService) Subscribe(email, city, frequency string) error {
-	return m.SubscribeFunc(email, city, frequency)
+func (m *mockSubscriptionService) Subscribe(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) error {
+	m.LastPreferences = prefs
+	return m.SubscribeFunc(email, city, sched)
 }
 
-func (m *mockSubscriptionService) Confirm(tokenValue string) error {
+func (m *mockSubscriptionService) Confirm(tokenValue string, meta audit.Meta) error {
 	return m.ConfirmFunc(tokenValue)
 }
 
-func (m *mockSubscriptionService) Unsubscribe(tokenValue string) error {
+func (m *mockSubscriptionService) Unsubscribe(tokenValue string, meta audit.Meta) (uuid.UUID, error) {
 	return m.UnsubscribeFunc(tokenValue)
 }
 
+func (m *mockSubscriptionService) Pause(tokenValue, until string, meta audit.Meta) error {
+	return m.PauseFunc(tokenValue, until)
+}
+
+func (m *mockSubscriptionService) Resume(tokenValue string, meta audit.Meta) error {
+	return m.ResumeFunc(tokenValue)
+}
+
+func (m *mockSubscriptionService) RequestEmailChange(tokenValue, newEmail string, meta audit.Meta) error {
+	return m.RequestEmailChangeFunc(tokenValue, newEmail)
+}
+
+func (m *mockSubscriptionService) ConfirmEmailChange(tokenValue string, meta audit.Meta) error {
+	return m.ConfirmEmailChangeFunc(tokenValue)
+}
+
+func (m *mockSubscriptionService) GetPreferences(tokenValue string) (content.Preferences, error) {
+	return m.GetPreferencesFunc(tokenValue)
+}
+
+func (m *mockSubscriptionService) UpdatePreferences(tokenValue string, prefs content.Preferences, meta audit.Meta) error {
+	return m.UpdatePreferencesFunc(tokenValue, prefs)
+}
+
 func TestSubscribeHandler_Success(t *testing.T) {
 	form := url.Values{}
 	form.Set("email", "test@example.com")
@@ -34,7 +79,7 @@ func TestSubscribeHandler_Success(t *testing.T) {
 	form.Set("frequency", "daily")
 
 	svc := &mockSubscriptionService{
-		SubscribeFunc: func(email, city, freq string) error {
+		SubscribeFunc: func(email, city string, sched schedule.Schedule) error {
 			return nil
 		},
 	}
@@ -49,6 +94,42 @@ func TestSubscribeHandler_Success(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("expected 200, got %d", w.Code)
 	}
+	if svc.LastPreferences.Units != content.UnitsMetric || len(svc.LastPreferences.Fields) != 0 {
+		t.Errorf("expected default preferences, got %+v", svc.LastPreferences)
+	}
+}
+
+func TestSubscribeHandler_Preferences(t *testing.T) {
+	form := url.Values{}
+	form.Set("email", "test@example.com")
+	form.Set("city", "Kyiv")
+	form.Set("frequency", "daily")
+	form.Add("fields", "wind")
+	form.Add("fields", "sun,feels_like")
+	form.Set("units", "imperial")
+	form.Set("lang", "uk")
+
+	svc := &mockSubscriptionService{
+		SubscribeFunc: func(email, city string, sched schedule.Schedule) error {
+			return nil
+		},
+	}
+
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.SubscribeHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	prefs := svc.LastPreferences
+	if prefs.StoredFields() != "feels_like,wind,sun" || prefs.Units != "imperial" || prefs.Language != "uk" {
+		t.Errorf("unexpected preferences %+v", prefs)
+	}
 }
 
 func TestSubscribeHandler_InvalidEmail(t *testing.T) {
@@ -66,8 +147,16 @@ func TestSubscribeHandler_InvalidEmail(t *testing.T) {
 	handler := subscription.NewHandler(svc)
 	handler.SubscribeHandler(w, req)
 
-	if w.Code != http.StatusInternalServerError {
-		t.Errorf("expected 500, got %d", w.Code)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+
+	var details problem.Details
+	if err := json.NewDecoder(w.Body).Decode(&details); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+	if details.Code != "invalid_email" || details.Detail != subscription.ErrInvalidEmail.Error() {
+		t.Errorf("unexpected problem %+v", details)
 	}
 }
 
@@ -78,7 +167,7 @@ func TestSubscribeHandler_UserExists(t *testing.T) {
 	form.Set("frequency", "daily")
 
 	svc := &mockSubscriptionService{
-		SubscribeFunc: func(email, city, freq string) error {
+		SubscribeFunc: func(email, city string, sched schedule.Schedule) error {
 			return subscription.ErrUserAlreadyExists
 		},
 	}
@@ -93,6 +182,12 @@ func TestSubscribeHandler_UserExists(t *testing.T) {
 	if w.Code != http.StatusConflict {
 		t.Errorf("expected 409, got %d", w.Code)
 	}
+	if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("expected problem content type, got %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), `"code":"user_already_exists"`) {
+		t.Errorf("expected user_already_exists code, got: %s", w.Body.String())
+	}
 }
 
 func TestSubscribeHandler_UnsupportedMethod(t *testing.T) {
@@ -104,8 +199,11 @@ func TestSubscribeHandler_UnsupportedMethod(t *testing.T) {
 	handler := subscription.NewHandler(svc)
 	handler.SubscribeHandler(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 Bad Request, got %d", w.Code)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "POST" {
+		t.Errorf("expected Allow POST, got %q", allow)
 	}
 	if !strings.Contains(w.Body.String(), "Unsupported method") {
 		t.Errorf("expected method error message, got: %s", w.Body.String())
@@ -157,8 +255,11 @@ func TestConfirmHandler_UnsupportedMethod(t *testing.T) {
 	handler := subscription.NewHandler(svc)
 	handler.ConfirmHandler(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 Bad Request, got %d", w.Code)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET" {
+		t.Errorf("expected Allow GET, got %q", allow)
 	}
 	if !strings.Contains(w.Body.String(), "Unsupported method") {
 		t.Errorf("expected method error message, got: %s", w.Body.String())
@@ -167,8 +268,8 @@ func TestConfirmHandler_UnsupportedMethod(t *testing.T) {
 
 func TestUnsubscribeHandler_Success(t *testing.T) {
 	svc := &mockSubscriptionService{
-		UnsubscribeFunc: func(token string) error {
-			return nil
+		UnsubscribeFunc: func(token string) (uuid.UUID, error) {
+			return uuid.New(), nil
 		},
 	}
 
@@ -185,8 +286,8 @@ func TestUnsubscribeHandler_Success(t *testing.T) {
 
 func TestUnsubscribeHandler_TokenWrongType(t *testing.T) {
 	svc := &mockSubscriptionService{
-		UnsubscribeFunc: func(token string) error {
-			return subscription.ErrTokenWrongType
+		UnsubscribeFunc: func(token string) (uuid.UUID, error) {
+			return uuid.Nil, subscription.ErrTokenWrongType
 		},
 	}
 
@@ -204,16 +305,659 @@ func TestUnsubscribeHandler_TokenWrongType(t *testing.T) {
 func TestUnsubscribeHandler_UnsupportedMethod(t *testing.T) {
 	svc := &mockSubscriptionService{}
 
-	req := httptest.NewRequest("POST", "/api/unsubscribe/token123", nil)
+	req := httptest.NewRequest("PUT", "/api/unsubscribe/token123", nil)
 	w := httptest.NewRecorder()
 
 	handler := subscription.NewHandler(svc)
 	handler.UnsubscribeHandler(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected 400 Bad Request, got %d", w.Code)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("expected Allow GET, POST, got %q", allow)
 	}
 	if !strings.Contains(w.Body.String(), "Unsupported method") {
 		t.Errorf("expected method error message, got: %s", w.Body.String())
 	}
 }
+
+func TestSubscribeHandler_ScheduleFields(t *testing.T) {
+	form := url.Values{}
+	form.Set("email", "test@example.com")
+	form.Set("city", "Kyiv")
+	form.Set("frequency", "daily")
+	form.Set("timezone", "Europe/Kyiv")
+	form.Set("send_time", "07:30")
+
+	var got schedule.Schedule
+	svc := &mockSubscriptionService{
+		SubscribeFunc: func(email, city string, sched schedule.Schedule) error {
+			got = sched
+			return nil
+		},
+	}
+
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.SubscribeHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got.Timezone != "Europe/Kyiv" || got.SendTime != "07:30" {
+		t.Errorf("unexpected schedule: %+v", got)
+	}
+}
+
+func TestSubscribeHandler_InvalidTimezone(t *testing.T) {
+	form := url.Values{}
+	form.Set("email", "test@example.com")
+	form.Set("city", "Kyiv")
+	form.Set("frequency", "daily")
+	form.Set("timezone", "Mars/Olympus")
+
+	svc := &mockSubscriptionService{}
+
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.SubscribeHandler(w, req)
+
+	if !strings.Contains(w.Body.String(), schedule.ErrInvalidTimezone.Error()) {
+		t.Errorf("expected timezone error, got: %s", w.Body.String())
+	}
+}
+
+func TestSubscribeHandler_WeeklyWithoutWeekday(t *testing.T) {
+	form := url.Values{}
+	form.Set("email", "test@example.com")
+	form.Set("city", "Kyiv")
+	form.Set("frequency", "weekly")
+
+	svc := &mockSubscriptionService{}
+
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.SubscribeHandler(w, req)
+
+	if !strings.Contains(w.Body.String(), schedule.ErrInvalidWeekday.Error()) {
+		t.Errorf("expected weekday error, got: %s", w.Body.String())
+	}
+}
+
+func TestSubscribeHandler_Cron(t *testing.T) {
+	form := url.Values{}
+	form.Set("email", "test@example.com")
+	form.Set("city", "Kyiv")
+	form.Set("frequency", "cron")
+	form.Set("cron", "0 7 * * 1-5")
+
+	var got schedule.Schedule
+	svc := &mockSubscriptionService{
+		SubscribeFunc: func(email, city string, sched schedule.Schedule) error {
+			got = sched
+			return nil
+		},
+	}
+
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.SubscribeHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got.CronExpr != "0 7 * * 1-5" {
+		t.Errorf("unexpected schedule: %+v", got)
+	}
+}
+
+func TestPauseHandler_Success(t *testing.T) {
+	var gotToken, gotUntil string
+	svc := &mockSubscriptionService{
+		PauseFunc: func(token, until string) error {
+			gotToken, gotUntil = token, until
+			return nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/pause/token123?until=2030-01-01", nil)
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.PauseHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if gotToken != "token123" || gotUntil != "2030-01-01" {
+		t.Errorf("unexpected arguments: %q, %q", gotToken, gotUntil)
+	}
+}
+
+func TestPauseHandler_InvalidUntil(t *testing.T) {
+	svc := &mockSubscriptionService{
+		PauseFunc: func(token, until string) error {
+			return subscription.ErrInvalidPauseEnd
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/pause/token123?until=yesterday", nil)
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.PauseHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestResumeHandler_TokenNotFound(t *testing.T) {
+	svc := &mockSubscriptionService{
+		ResumeFunc: func(token string) error {
+			return subscription.ErrTokenNotFound
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/resume/token123", nil)
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.ResumeHandler(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestUnsubscribeHandler_OneClickPost(t *testing.T) {
+	var gotToken string
+	svc := &mockSubscriptionService{
+		UnsubscribeFunc: func(token string) (uuid.UUID, error) {
+			gotToken = token
+			return uuid.New(), nil
+		},
+	}
+
+	req := httptest.NewRequest("POST", "/api/unsubscribe/token123", strings.NewReader("List-Unsubscribe=One-Click"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.UnsubscribeHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if gotToken != "token123" {
+		t.Errorf("expected token123, got %q", gotToken)
+	}
+}
+
+func TestUnsubscribeHandler_OneClickMultipartPost(t *testing.T) {
+	var called bool
+	svc := &mockSubscriptionService{
+		UnsubscribeFunc: func(token string) (uuid.UUID, error) {
+			called = true
+			return uuid.New(), nil
+		},
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("List-Unsubscribe", "One-Click"); err != nil {
+		t.Fatal(err)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest("POST", "/api/unsubscribe/token123", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	subscription.NewHandler(svc).UnsubscribeHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if !called {
+		t.Error("expected the subscriber to be unsubscribed")
+	}
+}
+
+func TestUnsubscribeHandler_OneClickInQueryIgnored(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/unsubscribe/token123?List-Unsubscribe=One-Click", nil)
+	w := httptest.NewRecorder()
+
+	subscription.NewHandler(&mockSubscriptionService{}).UnsubscribeHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestUnsubscribeHandler_PostWithoutOneClickBody(t *testing.T) {
+	svc := &mockSubscriptionService{}
+
+	req := httptest.NewRequest("POST", "/api/unsubscribe/token123", strings.NewReader("foo=bar"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.UnsubscribeHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestSubscribeHandler_NormalizesEmail(t *testing.T) {
+	form := url.Values{}
+	form.Set("email", "  Test@München.DE ")
+	form.Set("city", "Kyiv")
+	form.Set("frequency", "daily")
+
+	var gotEmail string
+	svc := &mockSubscriptionService{
+		SubscribeFunc: func(email, city string, sched schedule.Schedule) error {
+			gotEmail = email
+			return nil
+		},
+	}
+
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.SubscribeHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if gotEmail != "test@xn--mnchen-3ya.de" {
+		t.Errorf("expected normalized email, got %q", gotEmail)
+	}
+}
+
+func TestSubscribeHandler_DisposableEmail(t *testing.T) {
+	form := url.Values{}
+	form.Set("email", "test@mailinator.com")
+	form.Set("city", "Kyiv")
+	form.Set("frequency", "daily")
+
+	svc := &mockSubscriptionService{
+		SubscribeFunc: func(email, city string, sched schedule.Schedule) error {
+			return subscription.ErrDisposableEmail
+		},
+	}
+
+	req := httptest.NewRequest("POST", "/api/subscribe", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.SubscribeHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestChangeEmailHandler_Success(t *testing.T) {
+	var gotToken, gotEmail string
+	svc := &mockSubscriptionService{
+		RequestEmailChangeFunc: func(token, newEmail string) error {
+			gotToken, gotEmail = token, newEmail
+			return nil
+		},
+	}
+
+	form := url.Values{}
+	form.Set("email", "New@Example.com")
+
+	req := httptest.NewRequest("POST", "/api/change-email/token123", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.ChangeEmailHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if gotToken != "token123" || gotEmail != "new@example.com" {
+		t.Errorf("unexpected arguments %q %q", gotToken, gotEmail)
+	}
+}
+
+func TestChangeEmailHandler_InvalidEmail(t *testing.T) {
+	svc := &mockSubscriptionService{}
+
+	form := url.Values{}
+	form.Set("email", "not-an-email")
+
+	req := httptest.NewRequest("POST", "/api/change-email/token123", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.ChangeEmailHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestChangeEmailHandler_AddressTaken(t *testing.T) {
+	svc := &mockSubscriptionService{
+		RequestEmailChangeFunc: func(token, newEmail string) error {
+			return subscription.ErrUserAlreadyExists
+		},
+	}
+
+	form := url.Values{}
+	form.Set("email", "taken@example.com")
+
+	req := httptest.NewRequest("POST", "/api/change-email/token123", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.ChangeEmailHandler(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409, got %d", w.Code)
+	}
+}
+
+func TestConfirmEmailChangeHandler_Expired(t *testing.T) {
+	svc := &mockSubscriptionService{
+		ConfirmEmailChangeFunc: func(token string) error {
+			return subscription.ErrEmailChangeExpired
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/confirm-email/token123", nil)
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.ConfirmEmailChangeHandler(w, req)
+
+	if w.Code != http.StatusGone {
+		t.Errorf("expected 410, got %d", w.Code)
+	}
+}
+
+func TestUnsubscribeHandler_RendersReasonForm(t *testing.T) {
+	churnID := uuid.New()
+	svc := &mockSubscriptionService{
+		UnsubscribeFunc: func(token string) (uuid.UUID, error) {
+			return churnID, nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/unsubscribe/token123", nil)
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.UnsubscribeHandler(w, req)
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("expected html, got %q", ct)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `action="/api/unsubscribe-reason/`+churnID.String()+`"`) {
+		t.Error("expected reason form posting to the churn record")
+	}
+	if !strings.Contains(body, `value="too_frequent"`) {
+		t.Error("expected reason options")
+	}
+}
+
+func TestUnsubscribeHandler_NoChurnRecordHidesForm(t *testing.T) {
+	svc := &mockSubscriptionService{
+		UnsubscribeFunc: func(token string) (uuid.UUID, error) {
+			return uuid.Nil, nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/unsubscribe/token123", nil)
+	w := httptest.NewRecorder()
+
+	handler := subscription.NewHandler(svc)
+	handler.UnsubscribeHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "<form") {
+		t.Error("expected no reason form without a churn record")
+	}
+}
+
+func TestPreferencesHandler_Get(t *testing.T) {
+	svc := &mockSubscriptionService{
+		GetPreferencesFunc: func(token string) (content.Preferences, error) {
+			if token != "token123" {
+				t.Errorf("unexpected token %q", token)
+			}
+			return content.Preferences{Fields: []string{"wind"}, Units: "metric", Language: "uk"}, nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/preferences/token123", nil)
+	w := httptest.NewRecorder()
+
+	subscription.NewHandler(svc).PreferencesHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"fields":["wind"],"units":"metric","lang":"uk"}` {
+		t.Errorf("unexpected body %s", got)
+	}
+}
+
+func TestPreferencesHandler_Update(t *testing.T) {
+	var got content.Preferences
+	svc := &mockSubscriptionService{
+		UpdatePreferencesFunc: func(token string, prefs content.Preferences) error {
+			got = prefs
+			return nil
+		},
+	}
+
+	form := url.Values{"fields": {"air_quality,pressure"}, "lang": {"uk"}}
+	req := httptest.NewRequest("POST", "/api/preferences/token123", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	subscription.NewHandler(svc).PreferencesHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if got.StoredFields() != "pressure,air_quality" || got.Units != "metric" || got.Language != "uk" {
+		t.Errorf("unexpected preferences %+v", got)
+	}
+}
+
+func TestPreferencesHandler_InvalidField(t *testing.T) {
+	svc := &mockSubscriptionService{}
+
+	form := url.Values{"fields": {"wind,moon_phase"}}
+	req := httptest.NewRequest("POST", "/api/preferences/token123", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	subscription.NewHandler(svc).PreferencesHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), content.ErrInvalidFields.Error()) {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
+
+func TestPreferencesHandler_TokenNotFound(t *testing.T) {
+	svc := &mockSubscriptionService{
+		GetPreferencesFunc: func(token string) (content.Preferences, error) {
+			return content.Preferences{}, subscription.ErrTokenNotFound
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/preferences/unknown", nil)
+	w := httptest.NewRecorder()
+
+	subscription.NewHandler(svc).PreferencesHandler(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestConfirmHandler_LandingPages(t *testing.T) {
+	t.Setenv("SUBSCRIBE_PAGE_URL", "https://weather.example.com/")
+
+	cases := map[string]struct {
+		err       error
+		status    int
+		heading   string
+		subscribe bool
+	}{
+		"confirmed":         {nil, http.StatusOK, "Subscription confirmed", false},
+		"already confirmed": {subscription.ErrAlreadyConfirmed, http.StatusOK, "Already confirmed", false},
+		"expired":           {subscription.ErrTokenExpired, http.StatusNotFound, "This link has expired", true},
+		"unknown":           {subscription.ErrTokenNotFound, http.StatusNotFound, "This link is not valid", true},
+		"wrong type":        {subscription.ErrTokenWrongType, http.StatusBadRequest, "This link is not valid", true},
+		"failure":           {errors.New("database is down"), http.StatusInternalServerError, "Something went wrong", false},
+	}
+
+	for name, tc := range cases {
+		svc := &mockSubscriptionService{
+			ConfirmFunc: func(token string) error {
+				return tc.err
+			},
+		}
+
+		w := httptest.NewRecorder()
+		subscription.NewHandler(svc).ConfirmHandler(w, httptest.NewRequest("GET", "/api/confirm/token123", nil))
+
+		if w.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d", name, tc.status, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+			t.Errorf("%s: expected html, got %q", name, ct)
+		}
+
+		body := w.Body.String()
+		if !strings.Contains(body, tc.heading) {
+			t.Errorf("%s: expected heading %q, got: %s", name, tc.heading, body)
+		}
+		if strings.Contains(body, `href="https://weather.example.com/"`) != tc.subscribe {
+			t.Errorf("%s: expected subscribe link %v, got: %s", name, tc.subscribe, body)
+		}
+	}
+}
+
+func TestConfirmHandler_JSON(t *testing.T) {
+	cases := map[string]struct {
+		err    error
+		status int
+		body   string
+	}{
+		"confirmed":         {nil, http.StatusOK, `{"status":"confirmed"}`},
+		"already confirmed": {subscription.ErrAlreadyConfirmed, http.StatusOK, `{"status":"already_confirmed"}`},
+		"expired":           {subscription.ErrTokenExpired, http.StatusNotFound, `"code":"token_not_found"`},
+	}
+
+	for name, tc := range cases {
+		svc := &mockSubscriptionService{
+			ConfirmFunc: func(token string) error {
+				return tc.err
+			},
+		}
+
+		req := httptest.NewRequest("GET", "/api/confirm/token123", nil)
+		req.Header.Set("Accept", "application/json")
+		w := httptest.NewRecorder()
+		subscription.NewHandler(svc).ConfirmHandler(w, req)
+
+		if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.body) {
+			t.Errorf("%s: expected %d %s, got %d %s", name, tc.status, tc.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestUnsubscribeHandler_JSON(t *testing.T) {
+	svc := &mockSubscriptionService{
+		UnsubscribeFunc: func(token string) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+
+	req := httptest.NewRequest("GET", "/api/unsubscribe/token123", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	subscription.NewHandler(svc).UnsubscribeHandler(w, req)
+
+	var status subscription.LinkStatus
+	if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || status.Status != subscription.LinkStatusUnsubscribed {
+		t.Errorf("expected 200 unsubscribed, got %d %+v", w.Code, status)
+	}
+}
+
+func TestUnsubscribeHandler_ExpiredLinkPage(t *testing.T) {
+	svc := &mockSubscriptionService{
+		UnsubscribeFunc: func(token string) (uuid.UUID, error) {
+			return uuid.Nil, subscription.ErrTokenExpired
+		},
+	}
+
+	w := httptest.NewRecorder()
+	subscription.NewHandler(svc).UnsubscribeHandler(w, httptest.NewRequest("GET", "/api/unsubscribe/token123", nil))
+
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "This link has expired") {
+		t.Errorf("expected expired page, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUnsubscribeHandler_SubscribeAgainLink(t *testing.T) {
+	svc := &mockSubscriptionService{
+		UnsubscribeFunc: func(token string) (uuid.UUID, error) {
+			return uuid.New(), nil
+		},
+	}
+
+	t.Setenv("SUBSCRIBE_PAGE_URL", "")
+	w := httptest.NewRecorder()
+	subscription.NewHandler(svc).UnsubscribeHandler(w, httptest.NewRequest("GET", "/api/unsubscribe/token123", nil))
+	if strings.Contains(w.Body.String(), "Subscribe again") {
+		t.Error("expected no subscribe link without SUBSCRIBE_PAGE_URL")
+	}
+
+	t.Setenv("SUBSCRIBE_PAGE_URL", "https://weather.example.com/")
+	w = httptest.NewRecorder()
+	subscription.NewHandler(svc).UnsubscribeHandler(w, httptest.NewRequest("GET", "/api/unsubscribe/token123", nil))
+	if !strings.Contains(w.Body.String(), `href="https://weather.example.com/"`) {
+		t.Errorf("expected subscribe link, got: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,105 @@
+package subscription
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"weather-app/internal/mail/page_templates"
+	"weather-app/internal/problem"
+)
+
+// Values of LinkStatus.Status
+const (
+	LinkStatusConfirmed        = page_templates.LandingConfirmed
+	LinkStatusAlreadyConfirmed = page_templates.LandingAlreadyConfirmed
+	LinkStatusUnsubscribed     = "unsubscribed"
+)
+
+// Result of an email link for clients that ask for JSON
+type LinkStatus struct {
+	Status string `json:"status"`
+}
+
+// Links from emails are opened in browsers, API clients ask for JSON with
+// the Accept header
+func wantsJSON(req *http.Request) bool {
+	for _, part := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		if mediaType == "application/json" || mediaType == problem.ContentType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Page users land on to subscribe again, SUBSCRIBE_PAGE_URL. Empty hides
+// the links to it
+func subscribeURL() string {
+	return os.Getenv("SUBSCRIBE_PAGE_URL")
+}
+
+// Answers a successful confirmation link with the landing page in state, or
+// with LinkStatus for JSON clients
+func writeConfirmed(w http.ResponseWriter, req *http.Request, state string) {
+	if wantsJSON(req) {
+		writeLinkStatus(w, state)
+		return
+	}
+
+	writeLandingPage(w, http.StatusOK, state)
+}
+
+// Answers a failed link with a landing page explaining it, or with problem
+// details for JSON clients. Statuses are the same for both
+func writeLinkError(w http.ResponseWriter, req *http.Request, err error) {
+	if wantsJSON(req) {
+		problems.Write(w, err)
+		return
+	}
+
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		writeLandingPage(w, http.StatusNotFound, page_templates.LandingExpired)
+	case errors.Is(err, ErrTokenNotFound):
+		writeLandingPage(w, http.StatusNotFound, page_templates.LandingInvalid)
+	case errors.Is(err, ErrTokenEmpty), errors.Is(err, ErrTokenWrongType):
+		writeLandingPage(w, http.StatusBadRequest, page_templates.LandingInvalid)
+	default:
+		log.Println(err.Error())
+		writeLandingPage(w, http.StatusInternalServerError, page_templates.LandingError)
+	}
+}
+
+func writeLandingPage(w http.ResponseWriter, status int, state string) {
+	html, err := page_templates.FormLandingPage(&page_templates.LandingData{
+		State:        state,
+		SubscribeURL: subscribeURL(),
+	})
+	if err != nil {
+		log.Printf("Failed to render %s landing page: %s\n", state, err.Error())
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(html))
+}
+
+func writeLinkStatus(w http.ResponseWriter, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(LinkStatus{Status: status}); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
@@ -0,0 +1,45 @@
+package subscription
+
+import (
+	"fmt"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+)
+
+// Content preferences of the subscription the unsubscribe token belongs to
+func (srv *SubscriptionService) GetPreferences(tokenValue string) (content.Preferences, error) {
+	_, user, err := srv.ResolveUser(tokenValue, models.TokenTypeUnsubscribe)
+	if err != nil {
+		return content.Preferences{}, err
+	}
+
+	sub, err := srv.subRepo.GetByUserID(user.ID)
+	if err != nil {
+		// Deleted together with the user, so the link is gone as well
+		if repository.IsErrNotFound(err) {
+			return content.Preferences{}, ErrTokenNotFound
+		}
+
+		return content.Preferences{}, fmt.Errorf("error getting subscription: %w", err)
+	}
+
+	return content.FromStored(sub.ContentFields, sub.Units, sub.Language), nil
+}
+
+// Replaces the fields, units and language of update messages. The
+// subscriber's unsubscribe token authorizes the request
+func (srv *SubscriptionService) UpdatePreferences(tokenValue string, prefs content.Preferences, meta audit.Meta) error {
+	token, err := srv.ResolveToken(tokenValue, models.TokenTypeUnsubscribe)
+	if err != nil {
+		return err
+	}
+
+	if err := srv.subRepo.UpdatePreferences(token.UserID, prefs); err != nil {
+		return fmt.Errorf("error updating preferences: %w", err)
+	}
+
+	return srv.recordTokenEvent(models.EventPreferencesChanged, token, nil,
+		fmt.Sprintf("fields=%s units=%s lang=%s", prefs.StoredFields(), prefs.Units, prefs.Language), meta)
+}
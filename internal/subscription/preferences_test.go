@@ -0,0 +1,88 @@
+package subscription_test
+
+import (
+	"errors"
+	"testing"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+)
+
+func TestGetPreferences_FromSubscription(t *testing.T) {
+	subRepo := &mockSubscriptionRepo{
+		GetByUserIDFunc: func(userID uuid.UUID) (*models.Subscription, error) {
+			return &models.Subscription{ContentFields: "sun,wind", Units: "imperial", Language: "uk"}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, unsubscribeTokenRepo(), subRepo, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	prefs, err := svc.GetPreferences("abc")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Stored order doesn't matter, messages follow content.Fields
+	if prefs.StoredFields() != "wind,sun" || prefs.Units != "imperial" || prefs.Language != "uk" {
+		t.Errorf("unexpected preferences %+v", prefs)
+	}
+}
+
+func TestGetPreferences_SubscriptionGone(t *testing.T) {
+	subRepo := &mockSubscriptionRepo{
+		GetByUserIDFunc: func(userID uuid.UUID) (*models.Subscription, error) {
+			return nil, repository.ErrNotFound
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, unsubscribeTokenRepo(), subRepo, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	if _, err := svc.GetPreferences("abc"); !errors.Is(err, subscription.ErrTokenNotFound) {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestUpdatePreferences_RecordsEvent(t *testing.T) {
+	var saved content.Preferences
+	subRepo := &mockSubscriptionRepo{
+		UpdatePreferencesFunc: func(userID uuid.UUID, prefs content.Preferences) error {
+			saved = prefs
+			return nil
+		},
+	}
+	events := &mockEventRepo{}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, unsubscribeTokenRepo(), subRepo, events, nil, testLinks, nil, nil)
+
+	prefs := content.Preferences{Fields: []string{content.FieldWind}, Units: content.UnitsImperial, Language: content.LanguageEnglish}
+	if err := svc.UpdatePreferences("abc", prefs, audit.Meta{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if saved.Units != content.UnitsImperial {
+		t.Errorf("expected preferences to be saved, got %+v", saved)
+	}
+	if len(events.Events) != 1 || events.Events[0].Type != models.EventPreferencesChanged ||
+		events.Events[0].Details != "fields=wind units=imperial lang=en" {
+		t.Errorf("unexpected events %+v", events.Events)
+	}
+}
+
+func TestUpdatePreferences_WrongTokenType(t *testing.T) {
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeConfirm, UserID: uuid.New()}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, tokenRepo, &mockSubscriptionRepo{}, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	err := svc.UpdatePreferences("abc", content.Default(), audit.Meta{})
+	if !errors.Is(err, subscription.ErrTokenWrongType) {
+		t.Errorf("expected ErrTokenWrongType, got %v", err)
+	}
+}
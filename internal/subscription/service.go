@@ -1,16 +1,16 @@
 package subscription
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
-	"net/url"
-	"os"
-	"path"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
 	"weather-app/internal/database/models"
 	"weather-app/internal/database/repository"
+	"weather-app/internal/links"
+	"weather-app/internal/schedule"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -20,153 +20,356 @@ var (
 	ErrTokenNotFound  = errors.New("token not found")
 	ErrTokenWrongType = errors.New("invalid token type")
 	ErrTokenEmpty     = errors.New("token is empty")
+
+	// A signed link past its expiry, handled like an unknown token by the API
+	ErrTokenExpired = fmt.Errorf("%w: link has expired", ErrTokenNotFound)
 )
 
 type ConfirmationMailServiceInterface interface {
 	SendConfirmationMail(email, confirmationUrl, unsubscribeUrl string) error
+	SendEmailChangeMail(newEmail, confirmationUrl string) error
+	SendEmailChangedNotice(oldEmail, newEmail string) error
 }
 
 type UserRepositoryInterface interface {
 	CreateUserWithSubscriptionAndTokens(
-		email, city, frequency string,
+		email, city string,
+		sched schedule.Schedule,
+		prefs content.Preferences,
 		tokenTypes []string,
-		generateToken func() (string, error),
 	) (*repository.CreateUserWithSubscriptionAndTokensResult, error)
 
 	GetByEmail(email string) (*models.User, error)
+	GetByID(id uuid.UUID) (*models.User, error)
+	IsSuppressed(email string) (bool, error)
 	UpdateUserConfirmationAndDeleteToken(userID uuid.UUID, tokenID uuid.UUID) error
-	DeleteUserWithTokensAndSubscription(userID uuid.UUID) error
+	DeleteUserWithTokensAndSubscription(userID uuid.UUID) (*models.Churn, error)
+	CreateEmailChangeToken(userID uuid.UUID, newEmail string) (*models.Token, error)
+	ChangeEmailAndDeleteToken(userID uuid.UUID, tokenID uuid.UUID, newEmail string) error
+	ConfirmUser(userID uuid.UUID) error
+	DeleteUser(userID uuid.UUID) error
+	CreateTelegramToken(userID uuid.UUID) (*models.Token, error)
+	LinkTelegramChat(userID uuid.UUID, chatID int64, username string, tokenID *uuid.UUID) error
+	UnlinkTelegramChat(chatID int64) (*models.TelegramChat, error)
 }
 
 type TokenRepositoryInterface interface {
 	GetToken(value string) (*models.Token, error)
 }
 
+type SubscriptionRepositoryInterface interface {
+	GetByID(id uuid.UUID) (*models.Subscription, error)
+	GetByUserID(userID uuid.UUID) (*models.Subscription, error)
+	Pause(userID uuid.UUID, until *time.Time) error
+	Resume(userID uuid.UUID) error
+	UpdatePreferences(userID uuid.UUID, prefs content.Preferences) error
+}
+
+type EventRepositoryInterface interface {
+	Create(event *models.SubscriptionEvent) error
+}
+
+type WebhookPublisherInterface interface {
+	Publish(event *models.SubscriptionEvent) error
+}
+
+type EmailVerifierInterface interface {
+	Verify(email string) error
+}
+
 type SubscriptionService struct {
 	userRepo  UserRepositoryInterface
 	tokenRepo TokenRepositoryInterface
+	subRepo   SubscriptionRepositoryInterface
+	eventRepo EventRepositoryInterface
 
-	ms ConfirmationMailServiceInterface
+	ms       ConfirmationMailServiceInterface
+	links    *links.Builder
+	verifier EmailVerifierInterface
+	webhooks WebhookPublisherInterface
 }
 
 func NewSubscriptionService(
 	userRepo UserRepositoryInterface,
 	tokenRepo TokenRepositoryInterface,
+	subRepo SubscriptionRepositoryInterface,
+	eventRepo EventRepositoryInterface,
 	mailService ConfirmationMailServiceInterface,
+	linkBuilder *links.Builder,
+	emailVerifier EmailVerifierInterface,
+	webhooks WebhookPublisherInterface,
 ) *SubscriptionService {
-	return &SubscriptionService{userRepo: userRepo, tokenRepo: tokenRepo, ms: mailService}
+	return &SubscriptionService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		subRepo:   subRepo,
+		eventRepo: eventRepo,
+		ms:        mailService,
+		links:     linkBuilder,
+		verifier:  emailVerifier,
+		webhooks:  webhooks,
+	}
+}
+
+// Appends an audit event. Failures are returned, callers that have already
+// committed their action log them instead, so a retry doesn't repeat it
+func (srv *SubscriptionService) recordEvent(eventType, email string, userID uuid.UUID, tokenID *uuid.UUID, details string, meta audit.Meta) error {
+	event := models.SubscriptionEvent{
+		Type:      eventType,
+		Email:     email,
+		UserID:    userID,
+		TokenID:   tokenID,
+		IP:        meta.IP,
+		UserAgent: meta.UserAgent,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+
+	if err := srv.eventRepo.Create(&event); err != nil {
+		return fmt.Errorf("error recording %s event: %w", eventType, err)
+	}
+
+	if srv.webhooks != nil {
+		if err := srv.webhooks.Publish(&event); err != nil {
+			log.Printf("Failed to queue webhooks for %s event of user %s: %s\n", eventType, userID, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Records an event for a token-authorized action, looking up the email by user
+func (srv *SubscriptionService) recordTokenEvent(eventType string, token *models.Token, tokenID *uuid.UUID, details string, meta audit.Meta) error {
+	user, err := srv.userRepo.GetByID(token.UserID)
+	if err != nil {
+		return fmt.Errorf("error getting user for %s event: %w", eventType, err)
+	}
+
+	return srv.recordEvent(eventType, user.Email, token.UserID, tokenID, details, meta)
 }
 
 var (
 	ErrUserAlreadyExists     = errors.New("user already exists")
 	ErrConfirmationMailError = errors.New("something went wrong with confirmation email")
+	ErrInvalidPauseEnd       = errors.New("until parameter is invalid")
+	ErrEmailSuppressed       = errors.New("email address is suppressed")
+	ErrSubscriptionNotFound  = errors.New("subscription not found")
+	ErrAlreadyConfirmed      = errors.New("subscription is already confirmed")
 )
 
-func generateTokenDefault() (string, error) {
-	return generateToken(32)
+// TODO: Validate city
+func (srv *SubscriptionService) Subscribe(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) error {
+	_, err := srv.subscribe(email, city, sched, prefs, meta)
+	return err
 }
 
-func generateToken(n int) (string, error) {
-	b := make([]byte, n)
-	if _, err := rand.Read(b); err != nil {
-		return "", fmt.Errorf("failed to generate random token: %w", err)
+// Like Subscribe, returns the new subscription. It is also returned with
+// ErrConfirmationMailError, the subscription exists by then
+func (srv *SubscriptionService) CreateSubscription(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) (*models.Subscription, error) {
+	result, err := srv.subscribe(email, city, sched, prefs, meta)
+	if result == nil {
+		return nil, err
 	}
 
-	return base64.RawURLEncoding.EncodeToString(b), nil
+	return result.Subscription, err
 }
 
-// TODO: Move to other place. Should be common
-func BuildTokenURL(base, apiPath, token string) (string, error) {
-	u, err := url.Parse(base)
+// Subscription with the confirmation state of its subscriber
+type Details struct {
+	Subscription models.Subscription
+	Confirmed    bool
+}
+
+func (srv *SubscriptionService) GetSubscription(id uuid.UUID) (*Details, error) {
+	sub, err := srv.subRepo.GetByID(id)
 	if err != nil {
-		return "", fmt.Errorf("invalid base URL: %w", err)
+		if repository.IsErrNotFound(err) {
+			return nil, ErrSubscriptionNotFound
+		}
+
+		return nil, fmt.Errorf("error getting subscription: %w", err)
 	}
 
-	// Join the path and token properly
-	u.Path = path.Join(u.Path, apiPath, token)
+	user, err := srv.userRepo.GetByID(sub.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
 
-	return u.String(), nil
+	return &Details{Subscription: *sub, Confirmed: user.IsConfirmed}, nil
 }
 
-// TODO: Validate city
-func (srv *SubscriptionService) Subscribe(email, city, frequency string) error {
+// Creates the subscriber and sends the confirmation mail. Returns what was
+// created, also when only the mail failed
+func (srv *SubscriptionService) subscribe(email, city string, sched schedule.Schedule, prefs content.Preferences, meta audit.Meta) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
 	_, err := srv.userRepo.GetByEmail(email)
 
 	if err == nil {
 		// user already exists
 		log.Printf("User %s already exists\n", email)
 
-		return ErrUserAlreadyExists
+		return nil, ErrUserAlreadyExists
 
-	} else if !errors.Is(err, repository.ErrNotFound) {
+	} else if !repository.IsErrNotFound(err) {
 		// database error
 		log.Printf("Database error: %s\n", err.Error())
 
-		return fmt.Errorf("error getting user: %w", err)
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	// Erased addresses keep a tombstone and must not be mailed again
+	suppressed, err := srv.userRepo.IsSuppressed(email)
+	if err != nil {
+		return nil, fmt.Errorf("error checking suppression: %w", err)
 	}
 
+	if suppressed {
+		log.Printf("Subscription attempt for suppressed address\n")
+
+		return nil, ErrEmailSuppressed
+	}
+
+	// Disposable domains and domains without mail servers hurt sender reputation
+	if srv.verifier != nil {
+		if err := srv.verifier.Verify(email); err != nil {
+			log.Printf("Rejected signup for %s: %s\n", email, err.Error())
+
+			return nil, err
+		}
+	}
+
+	// Signed links carry everything needed, so no token rows are stored
 	tokenTypes := []string{models.TokenTypeConfirm, models.TokenTypeUnsubscribe}
+	if srv.links.Signed() {
+		tokenTypes = nil
+	}
 
-	result, err := srv.userRepo.CreateUserWithSubscriptionAndTokens(email, city, frequency, tokenTypes, generateTokenDefault)
+	result, err := srv.userRepo.CreateUserWithSubscriptionAndTokens(email, city, sched.WithDefaults(), prefs, tokenTypes)
 
 	if err != nil {
 		// database error
 
-		return fmt.Errorf("error creating user: %w", err)
+		return nil, fmt.Errorf("error creating user: %w", err)
 	}
-	var confirmationToken, unsubscribeToken *models.Token
 
-	confirmationToken, ok := result.Tokens[models.TokenTypeConfirm]
+	var confirmationValue, unsubscribeValue string
 
-	if !ok {
-		return fmt.Errorf("error getting confirmation token: %w", err)
-	}
+	if !srv.links.Signed() {
+		confirmationToken, ok := result.Tokens[models.TokenTypeConfirm]
 
-	unsubscribeToken, ok = result.Tokens[models.TokenTypeUnsubscribe]
+		if !ok {
+			return nil, fmt.Errorf("error getting confirmation token")
+		}
+
+		unsubscribeToken, ok := result.Tokens[models.TokenTypeUnsubscribe]
+
+		if !ok {
+			return nil, fmt.Errorf("error getting unsubscribe token")
+		}
 
-	if !ok {
-		return fmt.Errorf("error getting unsubscribe token: %w", err)
+		confirmationValue, unsubscribeValue = confirmationToken.Value, unsubscribeToken.Value
 	}
 
-	confirmUrl, err := BuildTokenURL(os.Getenv("BASE_URL"), "/api/confirm/", confirmationToken.Value)
+	if err := srv.recordEvent(models.EventSubscribed, email, result.User.ID, nil,
+		fmt.Sprintf("city=%s frequency=%s", city, sched.Frequency), meta); err != nil {
+		log.Println(err.Error())
+	}
+
+	confirmUrl, err := srv.links.ActionURL(links.ActionConfirm, result.User.ID, confirmationValue)
 	if err != nil {
-		return fmt.Errorf("error building confirmation url: %w", err)
+		return nil, fmt.Errorf("error building confirmation url: %w", err)
 	}
 
-	unsubscribeUrl, err := BuildTokenURL(os.Getenv("BASE_URL"), "/api/unsubscribe/", unsubscribeToken.Value)
+	unsubscribeUrl, err := srv.links.ActionURL(links.ActionUnsubscribe, result.User.ID, unsubscribeValue)
 	if err != nil {
-		return fmt.Errorf("error building unsubscribe url: %w", err)
+		return nil, fmt.Errorf("error building unsubscribe url: %w", err)
 	}
 
 	// TODO: Move to mail-sender container and send in chunks. Not one by one
 	err = srv.ms.SendConfirmationMail(email, confirmUrl, unsubscribeUrl)
 
 	if err != nil {
-		return fmt.Errorf("%w: %w", ErrConfirmationMailError, err)
+		return result, fmt.Errorf("%w: %w", ErrConfirmationMailError, err)
 	}
 
-	return nil
+	return result, nil
 }
 
-func (srv *SubscriptionService) Confirm(tokenValue string) error {
+// Resolves a link token and checks that it has the expected type. Signed
+// links are verified without touching the database and resolve to a token
+// with no ID. Database tokens keep working in signed mode for older links
+func (srv *SubscriptionService) ResolveToken(tokenValue, tokenType string) (*models.Token, error) {
 	if tokenValue == "" {
-		return ErrTokenEmpty
+		return nil, ErrTokenEmpty
+	}
+
+	if srv.links.Signed() && links.IsSigned(tokenValue) {
+		claims, err := srv.links.Signer().Verify(tokenValue, time.Now())
+		if err != nil {
+			log.Printf("Signed link rejected: %s\n", err.Error())
+
+			if errors.Is(err, links.ErrExpired) {
+				return nil, ErrTokenExpired
+			}
+
+			return nil, ErrTokenNotFound
+		}
+
+		if claims.Action != tokenType {
+			return nil, ErrTokenWrongType
+		}
+
+		return &models.Token{Type: claims.Action, UserID: claims.UserID}, nil
 	}
 
 	token, err := srv.tokenRepo.GetToken(tokenValue)
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return ErrTokenNotFound
+			return nil, ErrTokenNotFound
 		} else {
 			// database error
 
-			return fmt.Errorf("error getting token: %w", err)
+			return nil, fmt.Errorf("error getting token: %w", err)
 		}
 	}
 
-	if token.Type != models.TokenTypeConfirm {
-		return ErrTokenWrongType
+	if token.Type != tokenType {
+		return nil, ErrTokenWrongType
+	}
+
+	return token, nil
+}
+
+// Resolves a link token and loads the subscriber it belongs to. Signed links
+// outlive the user they were issued for, a missing user is ErrTokenNotFound
+func (srv *SubscriptionService) ResolveUser(tokenValue, tokenType string) (*models.Token, *models.User, error) {
+	token, err := srv.ResolveToken(tokenValue, tokenType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := srv.userRepo.GetByID(token.UserID)
+	if err != nil {
+		if repository.IsErrNotFound(err) {
+			return nil, nil, ErrTokenNotFound
+		}
+
+		return nil, nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	return token, user, nil
+}
+
+// Returns ErrAlreadyConfirmed for links of confirmed subscribers, which
+// only signed links can be, database tokens are deleted on use
+func (srv *SubscriptionService) Confirm(tokenValue string, meta audit.Meta) error {
+	token, user, err := srv.ResolveUser(tokenValue, models.TokenTypeConfirm)
+	if err != nil {
+		return err
+	}
+
+	if user.IsConfirmed {
+		return ErrAlreadyConfirmed
 	}
 
 	err = srv.userRepo.UpdateUserConfirmationAndDeleteToken(token.UserID, token.ID)
@@ -177,37 +380,111 @@ func (srv *SubscriptionService) Confirm(tokenValue string) error {
 		return fmt.Errorf("error deleting token: %w", err)
 	}
 
-	return nil
+	var tokenID *uuid.UUID
+	if token.ID != uuid.Nil {
+		tokenID = &token.ID
+	}
+
+	return srv.recordEvent(models.EventConfirmed, user.Email, token.UserID, tokenID, "", meta)
 }
 
-func (srv *SubscriptionService) Unsubscribe(tokenValue string) error {
-	if tokenValue == "" {
-		return ErrTokenEmpty
+// Deletes the subscriber. Returns the ID of the anonymous churn record the
+// unsubscribe page attaches a reason to, uuid.Nil when none was recorded
+func (srv *SubscriptionService) Unsubscribe(tokenValue string, meta audit.Meta) (uuid.UUID, error) {
+	// Email has to be captured before the user row is gone
+	token, user, err := srv.ResolveUser(tokenValue, models.TokenTypeUnsubscribe)
+	if err != nil {
+		return uuid.Nil, err
 	}
 
-	token, err := srv.tokenRepo.GetToken(tokenValue)
+	churn, err := srv.userRepo.DeleteUserWithTokensAndSubscription(token.UserID)
 
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return ErrTokenNotFound
-		} else {
-			// database error
+		// database error
+
+		return uuid.Nil, fmt.Errorf("error deleting user: %w", err)
+	}
+
+	if err := srv.recordEvent(models.EventUnsubscribed, user.Email, token.UserID, nil, "", meta); err != nil {
+		log.Println(err.Error())
+	}
+
+	if churn == nil {
+		return uuid.Nil, nil
+	}
+
+	return churn.ID, nil
+}
+
+// Parses pause end. Accepts RFC 3339 timestamps or dates, where a date means
+// the start of that day in the subscription's timezone
+func parsePauseEnd(value, timezone string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, ErrInvalidPauseEnd
+	}
+
+	return t, nil
+}
 
-			return fmt.Errorf("error getting token: %w", err)
+// Pauses updates until the given date, or indefinitely when until is empty.
+// The subscriber's unsubscribe token authorizes the request
+func (srv *SubscriptionService) Pause(tokenValue, until string, meta audit.Meta) error {
+	token, err := srv.ResolveToken(tokenValue, models.TokenTypeUnsubscribe)
+	if err != nil {
+		return err
+	}
+
+	var pausedUntil *time.Time
+
+	if until != "" {
+		sub, err := srv.subRepo.GetByUserID(token.UserID)
+		if err != nil {
+			return fmt.Errorf("error getting subscription: %w", err)
+		}
+
+		end, err := parsePauseEnd(until, sub.Timezone)
+		if err != nil {
+			return err
+		}
+
+		if !end.After(time.Now()) {
+			return ErrInvalidPauseEnd
 		}
+
+		pausedUntil = &end
+	}
+
+	if err := srv.subRepo.Pause(token.UserID, pausedUntil); err != nil {
+		return fmt.Errorf("error pausing subscription: %w", err)
 	}
 
-	if token.Type != models.TokenTypeUnsubscribe {
-		return ErrTokenWrongType
+	details := "indefinitely"
+	if pausedUntil != nil {
+		details = "until=" + pausedUntil.Format(time.RFC3339)
 	}
 
-	err = srv.userRepo.DeleteUserWithTokensAndSubscription(token.UserID)
+	return srv.recordTokenEvent(models.EventPaused, token, nil, details, meta)
+}
 
+func (srv *SubscriptionService) Resume(tokenValue string, meta audit.Meta) error {
+	token, err := srv.ResolveToken(tokenValue, models.TokenTypeUnsubscribe)
 	if err != nil {
-		// database error
+		return err
+	}
 
-		return fmt.Errorf("error deleting user: %w", err)
+	if err := srv.subRepo.Resume(token.UserID); err != nil {
+		return fmt.Errorf("error resuming subscription: %w", err)
 	}
 
-	return nil
+	return srv.recordTokenEvent(models.EventResumed, token, nil, "", meta)
 }
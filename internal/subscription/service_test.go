@@ -3,18 +3,32 @@ package subscription_test
 import (
 	"errors"
 	"os"
+	"strings"
 	"testing"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
 	"weather-app/internal/database/models"
 	"weather-app/internal/database/repository"
+	"weather-app/internal/emailaddr"
+	"weather-app/internal/links"
+	"weather-app/internal/schedule"
 	"weather-app/internal/subscription"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var testLinks = links.NewBuilder("https://test.com", nil)
+
 type mockMailService struct {
 	Called bool
 	Err    error
+
+	ChangeURL  string
+	NoticeSent bool
+	NoticeOld  string
+	NoticeNew  string
 }
 
 func (m *mockMailService) SendConfirmationMail(email, confirmURL, unsubscribeURL string) error {
@@ -22,25 +36,79 @@ func (m *mockMailService) SendConfirmationMail(email, confirmURL, unsubscribeURL
 	return m.Err
 }
 
+func (m *mockMailService) SendEmailChangeMail(newEmail, confirmURL string) error {
+	m.Called = true
+	m.ChangeURL = confirmURL
+	return m.Err
+}
+
+func (m *mockMailService) SendEmailChangedNotice(oldEmail, newEmail string) error {
+	m.NoticeSent = true
+	m.NoticeOld, m.NoticeNew = oldEmail, newEmail
+	return nil
+}
+
 type mockUserRepo struct {
 	GetByEmailFunc                           func(email string) (*models.User, error)
-	CreateUserWithSubscriptionAndTokensFunc  func(email, city, frequency string, tokenTypes []string, gen func() (string, error)) (*repository.CreateUserWithSubscriptionAndTokensResult, error)
+	GetByIDFunc                              func(id uuid.UUID) (*models.User, error)
+	IsSuppressedFunc                         func(email string) (bool, error)
+	CreateUserWithSubscriptionAndTokensFunc  func(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error)
 	UpdateUserConfirmationAndDeleteTokenFunc func(userID uuid.UUID, tokenID uuid.UUID) error
-	DeleteUserWithTokensAndSubscriptionFunc  func(userID uuid.UUID) error
+	DeleteUserWithTokensAndSubscriptionFunc  func(userID uuid.UUID) (*models.Churn, error)
+	CreateEmailChangeTokenFunc               func(userID uuid.UUID, newEmail string) (*models.Token, error)
+	ChangeEmailAndDeleteTokenFunc            func(userID uuid.UUID, tokenID uuid.UUID, newEmail string) error
+	ConfirmUserFunc                          func(userID uuid.UUID) error
+	DeleteUserFunc                           func(userID uuid.UUID) error
+	CreateTelegramTokenFunc                  func(userID uuid.UUID) (*models.Token, error)
+	LinkTelegramChatFunc                     func(userID uuid.UUID, chatID int64, username string, tokenID *uuid.UUID) error
+	UnlinkTelegramChatFunc                   func(chatID int64) (*models.TelegramChat, error)
 }
 
 func (r *mockUserRepo) GetByEmail(email string) (*models.User, error) {
 	return r.GetByEmailFunc(email)
 }
-func (r *mockUserRepo) CreateUserWithSubscriptionAndTokens(email, city, frequency string, tokenTypes []string, gen func() (string, error)) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
-	return r.CreateUserWithSubscriptionAndTokensFunc(email, city, frequency, tokenTypes, gen)
+func (r *mockUserRepo) GetByID(id uuid.UUID) (*models.User, error) {
+	if r.GetByIDFunc == nil {
+		return &models.User{ID: id, Email: "test@example.com"}, nil
+	}
+	return r.GetByIDFunc(id)
+}
+func (r *mockUserRepo) IsSuppressed(email string) (bool, error) {
+	if r.IsSuppressedFunc == nil {
+		return false, nil
+	}
+	return r.IsSuppressedFunc(email)
+}
+func (r *mockUserRepo) CreateUserWithSubscriptionAndTokens(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
+	return r.CreateUserWithSubscriptionAndTokensFunc(email, city, sched, prefs, tokenTypes)
 }
 func (r *mockUserRepo) UpdateUserConfirmationAndDeleteToken(userID uuid.UUID, tokenID uuid.UUID) error {
 	return r.UpdateUserConfirmationAndDeleteTokenFunc(userID, tokenID)
 }
-func (r *mockUserRepo) DeleteUserWithTokensAndSubscription(userID uuid.UUID) error {
+func (r *mockUserRepo) DeleteUserWithTokensAndSubscription(userID uuid.UUID) (*models.Churn, error) {
 	return r.DeleteUserWithTokensAndSubscriptionFunc(userID)
 }
+func (r *mockUserRepo) CreateEmailChangeToken(userID uuid.UUID, newEmail string) (*models.Token, error) {
+	return r.CreateEmailChangeTokenFunc(userID, newEmail)
+}
+func (r *mockUserRepo) ChangeEmailAndDeleteToken(userID uuid.UUID, tokenID uuid.UUID, newEmail string) error {
+	return r.ChangeEmailAndDeleteTokenFunc(userID, tokenID, newEmail)
+}
+func (r *mockUserRepo) ConfirmUser(userID uuid.UUID) error {
+	return r.ConfirmUserFunc(userID)
+}
+func (r *mockUserRepo) DeleteUser(userID uuid.UUID) error {
+	return r.DeleteUserFunc(userID)
+}
+func (r *mockUserRepo) CreateTelegramToken(userID uuid.UUID) (*models.Token, error) {
+	return r.CreateTelegramTokenFunc(userID)
+}
+func (r *mockUserRepo) LinkTelegramChat(userID uuid.UUID, chatID int64, username string, tokenID *uuid.UUID) error {
+	return r.LinkTelegramChatFunc(userID, chatID, username, tokenID)
+}
+func (r *mockUserRepo) UnlinkTelegramChat(chatID int64) (*models.TelegramChat, error) {
+	return r.UnlinkTelegramChatFunc(chatID)
+}
 
 type mockTokenRepo struct {
 	GetTokenFunc func(value string) (*models.Token, error)
@@ -50,6 +118,53 @@ func (r *mockTokenRepo) GetToken(value string) (*models.Token, error) {
 	return r.GetTokenFunc(value)
 }
 
+type mockSubscriptionRepo struct {
+	GetByIDFunc           func(id uuid.UUID) (*models.Subscription, error)
+	GetByUserIDFunc       func(userID uuid.UUID) (*models.Subscription, error)
+	PauseFunc             func(userID uuid.UUID, until *time.Time) error
+	ResumeFunc            func(userID uuid.UUID) error
+	UpdatePreferencesFunc func(userID uuid.UUID, prefs content.Preferences) error
+}
+
+func (r *mockSubscriptionRepo) GetByID(id uuid.UUID) (*models.Subscription, error) {
+	return r.GetByIDFunc(id)
+}
+func (r *mockSubscriptionRepo) GetByUserID(userID uuid.UUID) (*models.Subscription, error) {
+	return r.GetByUserIDFunc(userID)
+}
+func (r *mockSubscriptionRepo) Pause(userID uuid.UUID, until *time.Time) error {
+	return r.PauseFunc(userID, until)
+}
+func (r *mockSubscriptionRepo) Resume(userID uuid.UUID) error {
+	return r.ResumeFunc(userID)
+}
+func (r *mockSubscriptionRepo) UpdatePreferences(userID uuid.UUID, prefs content.Preferences) error {
+	return r.UpdatePreferencesFunc(userID, prefs)
+}
+
+type mockEventRepo struct {
+	Events []models.SubscriptionEvent
+	Err    error
+}
+
+func (r *mockEventRepo) Create(event *models.SubscriptionEvent) error {
+	if r.Err != nil {
+		return r.Err
+	}
+
+	r.Events = append(r.Events, *event)
+	return nil
+}
+
+type mockWebhookPublisher struct {
+	Events []models.SubscriptionEvent
+}
+
+func (p *mockWebhookPublisher) Publish(event *models.SubscriptionEvent) error {
+	p.Events = append(p.Events, *event)
+	return nil
+}
+
 func TestSubscribe_Success(t *testing.T) {
 	os.Setenv("BASE_URL", "https://test.com")
 
@@ -57,8 +172,9 @@ func TestSubscribe_Success(t *testing.T) {
 		GetByEmailFunc: func(email string) (*models.User, error) {
 			return nil, gorm.ErrRecordNotFound
 		},
-		CreateUserWithSubscriptionAndTokensFunc: func(email, city, frequency string, tokenTypes []string, gen func() (string, error)) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
+		CreateUserWithSubscriptionAndTokensFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
 			return &repository.CreateUserWithSubscriptionAndTokensResult{
+				User: &models.User{ID: uuid.New(), Email: email},
 				Tokens: map[string]*models.Token{
 					models.TokenTypeConfirm:     {Value: "confirm-token"},
 					models.TokenTypeUnsubscribe: {Value: "unsubscribe-token"},
@@ -68,9 +184,9 @@ func TestSubscribe_Success(t *testing.T) {
 	}
 
 	mail := &mockMailService{}
-	svc := subscription.NewSubscriptionService(userRepo, nil, mail)
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, mail, testLinks, nil, nil)
 
-	err := svc.Subscribe("test@example.com", "Kyiv", "daily")
+	err := svc.Subscribe("test@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, content.Default(), audit.Meta{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -86,9 +202,9 @@ func TestSubscribe_ExistingUser(t *testing.T) {
 		},
 	}
 
-	svc := subscription.NewSubscriptionService(userRepo, nil, &mockMailService{})
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, &mockMailService{}, testLinks, nil, nil)
 
-	err := svc.Subscribe("test@example.com", "Kyiv", "daily")
+	err := svc.Subscribe("test@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, content.Default(), audit.Meta{})
 	if err != subscription.ErrUserAlreadyExists {
 		t.Errorf("expected ErrUserAlreadyExists, got: %v", err)
 	}
@@ -99,8 +215,9 @@ func TestSubscribe_MailError(t *testing.T) {
 		GetByEmailFunc: func(email string) (*models.User, error) {
 			return nil, gorm.ErrRecordNotFound
 		},
-		CreateUserWithSubscriptionAndTokensFunc: func(email, city, frequency string, tokenTypes []string, gen func() (string, error)) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
+		CreateUserWithSubscriptionAndTokensFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
 			return &repository.CreateUserWithSubscriptionAndTokensResult{
+				User: &models.User{ID: uuid.New(), Email: email},
 				Tokens: map[string]*models.Token{
 					models.TokenTypeConfirm:     {Value: "c"},
 					models.TokenTypeUnsubscribe: {Value: "u"},
@@ -110,14 +227,36 @@ func TestSubscribe_MailError(t *testing.T) {
 	}
 
 	mail := &mockMailService{Err: errors.New("mail error")}
-	svc := subscription.NewSubscriptionService(userRepo, nil, mail)
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, mail, testLinks, nil, nil)
 
-	err := svc.Subscribe("test@example.com", "Kyiv", "daily")
+	err := svc.Subscribe("test@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, content.Default(), audit.Meta{})
 	if err == nil || !errors.Is(err, subscription.ErrConfirmationMailError) {
 		t.Errorf("expected confirmation mail error, got %v", err)
 	}
 }
 
+func TestSubscribe_Suppressed(t *testing.T) {
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		IsSuppressedFunc: func(email string) (bool, error) {
+			return true, nil
+		},
+	}
+
+	mail := &mockMailService{}
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, mail, testLinks, nil, nil)
+
+	err := svc.Subscribe("erased@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, content.Default(), audit.Meta{})
+	if err != subscription.ErrEmailSuppressed {
+		t.Errorf("expected ErrEmailSuppressed, got %v", err)
+	}
+	if mail.Called {
+		t.Error("expected no mail to suppressed address")
+	}
+}
+
 func TestConfirm_Success(t *testing.T) {
 	token := &models.Token{
 		Value:  "token123",
@@ -138,8 +277,8 @@ func TestConfirm_Success(t *testing.T) {
 		},
 	}
 
-	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil)
-	err := svc.Confirm("token123")
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+	err := svc.Confirm("token123", audit.Meta{})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -152,17 +291,17 @@ func TestConfirm_WrongType(t *testing.T) {
 		},
 	}
 
-	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil)
-	err := svc.Confirm("abc")
+	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+	err := svc.Confirm("abc", audit.Meta{})
 	if err != subscription.ErrTokenWrongType {
 		t.Errorf("expected ErrTokenWrongType, got %v", err)
 	}
 }
 
 func TestConfirm_TokenEmpty(t *testing.T) {
-	svc := subscription.NewSubscriptionService(nil, nil, nil)
+	svc := subscription.NewSubscriptionService(nil, nil, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
 
-	err := svc.Confirm("")
+	err := svc.Confirm("", audit.Meta{})
 	if err != subscription.ErrTokenEmpty {
 		t.Errorf("expected ErrTokenEmpty, got %v", err)
 	}
@@ -174,9 +313,9 @@ func TestConfirm_TokenNotFound(t *testing.T) {
 		},
 	}
 
-	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil)
+	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
 
-	err := svc.Confirm("nonexistent-token")
+	err := svc.Confirm("nonexistent-token", audit.Meta{})
 	if err != subscription.ErrTokenNotFound {
 		t.Errorf("expected ErrTokenNotFound, got %v", err)
 	}
@@ -191,9 +330,9 @@ func TestConfirm_TokenDBError(t *testing.T) {
 		},
 	}
 
-	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil)
+	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
 
-	err := svc.Confirm("token123")
+	err := svc.Confirm("token123", audit.Meta{})
 	if err == nil || !errors.Is(err, expectedDBErr) {
 		t.Errorf("expected wrapped db error, got %v", err)
 	}
@@ -205,9 +344,10 @@ func TestUnsubscribe_Success(t *testing.T) {
 		UserID: uuid.New(),
 	}
 
+	churnID := uuid.New()
 	userRepo := &mockUserRepo{
-		DeleteUserWithTokensAndSubscriptionFunc: func(userID uuid.UUID) error {
-			return nil
+		DeleteUserWithTokensAndSubscriptionFunc: func(userID uuid.UUID) (*models.Churn, error) {
+			return &models.Churn{ID: churnID}, nil
 		},
 	}
 
@@ -217,17 +357,20 @@ func TestUnsubscribe_Success(t *testing.T) {
 		},
 	}
 
-	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil)
-	err := svc.Unsubscribe("abc")
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+	gotID, err := svc.Unsubscribe("abc", audit.Meta{})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
+	if gotID != churnID {
+		t.Errorf("expected churn ID %s, got %s", churnID, gotID)
+	}
 }
 
 func TestUnsubscribe_TokenEmpty(t *testing.T) {
-	svc := subscription.NewSubscriptionService(nil, nil, nil)
+	svc := subscription.NewSubscriptionService(nil, nil, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
 
-	err := svc.Unsubscribe("")
+	_, err := svc.Unsubscribe("", audit.Meta{})
 	if err != subscription.ErrTokenEmpty {
 		t.Errorf("expected ErrTokenEmpty, got %v", err)
 	}
@@ -240,9 +383,9 @@ func TestUnsubscribe_TokenNotFound(t *testing.T) {
 		},
 	}
 
-	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil)
+	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
 
-	err := svc.Unsubscribe("nonexistent-token")
+	_, err := svc.Unsubscribe("nonexistent-token", audit.Meta{})
 	if err != subscription.ErrTokenNotFound {
 		t.Errorf("expected ErrTokenNotFound, got %v", err)
 	}
@@ -257,9 +400,9 @@ func TestUnsubscribe_TokenDBError(t *testing.T) {
 		},
 	}
 
-	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil)
+	svc := subscription.NewSubscriptionService(nil, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
 
-	err := svc.Unsubscribe("token123")
+	_, err := svc.Unsubscribe("token123", audit.Meta{})
 	if err == nil || !errors.Is(err, expectedDBErr) {
 		t.Errorf("expected wrapped db error, got %v", err)
 	}
@@ -272,14 +415,495 @@ func TestUnsubscribe_WrongType(t *testing.T) {
 		},
 	}
 	userRepo := &mockUserRepo{
-		DeleteUserWithTokensAndSubscriptionFunc: func(userID uuid.UUID) error {
-			return nil
+		DeleteUserWithTokensAndSubscriptionFunc: func(userID uuid.UUID) (*models.Churn, error) {
+			return &models.Churn{ID: uuid.New()}, nil
 		},
 	}
 
-	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil)
-	err := svc.Unsubscribe("abc")
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+	_, err := svc.Unsubscribe("abc", audit.Meta{})
 	if err != subscription.ErrTokenWrongType {
 		t.Errorf("expected ErrTokenWrongType, got %v", err)
 	}
 }
+
+func unsubscribeTokenRepo() *mockTokenRepo {
+	return &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeUnsubscribe, UserID: uuid.New()}, nil
+		},
+	}
+}
+
+func TestPause_Indefinitely(t *testing.T) {
+	var called bool
+	subRepo := &mockSubscriptionRepo{
+		PauseFunc: func(userID uuid.UUID, until *time.Time) error {
+			called = true
+			if until != nil {
+				t.Errorf("expected indefinite pause, got %v", until)
+			}
+			return nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, unsubscribeTokenRepo(), subRepo, &mockEventRepo{}, nil, testLinks, nil, nil)
+	if err := svc.Pause("abc", "", audit.Meta{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !called {
+		t.Error("expected subscription to be paused")
+	}
+}
+
+func TestPause_UntilDateInSubscriptionTimezone(t *testing.T) {
+	var got *time.Time
+	subRepo := &mockSubscriptionRepo{
+		GetByUserIDFunc: func(userID uuid.UUID) (*models.Subscription, error) {
+			return &models.Subscription{Timezone: "Europe/Kyiv"}, nil
+		},
+		PauseFunc: func(userID uuid.UUID, until *time.Time) error {
+			got = until
+			return nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, unsubscribeTokenRepo(), subRepo, &mockEventRepo{}, nil, testLinks, nil, nil)
+	if err := svc.Pause("abc", "2099-07-01", audit.Meta{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	want := time.Date(2099, 7, 1, 0, 0, 0, 0, kyiv)
+	if got == nil || !got.Equal(want) {
+		t.Errorf("expected pause until %v, got %v", want, got)
+	}
+}
+
+func TestPause_EndInPast(t *testing.T) {
+	subRepo := &mockSubscriptionRepo{
+		GetByUserIDFunc: func(userID uuid.UUID) (*models.Subscription, error) {
+			return &models.Subscription{Timezone: "UTC"}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, unsubscribeTokenRepo(), subRepo, &mockEventRepo{}, nil, testLinks, nil, nil)
+	if err := svc.Pause("abc", "2000-01-01", audit.Meta{}); !errors.Is(err, subscription.ErrInvalidPauseEnd) {
+		t.Errorf("expected ErrInvalidPauseEnd, got %v", err)
+	}
+}
+
+func TestPause_WrongTokenType(t *testing.T) {
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeConfirm}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(nil, tokenRepo, &mockSubscriptionRepo{}, &mockEventRepo{}, nil, testLinks, nil, nil)
+	if err := svc.Pause("abc", "", audit.Meta{}); err != subscription.ErrTokenWrongType {
+		t.Errorf("expected ErrTokenWrongType, got %v", err)
+	}
+}
+
+func TestResume_Success(t *testing.T) {
+	var called bool
+	subRepo := &mockSubscriptionRepo{
+		ResumeFunc: func(userID uuid.UUID) error {
+			called = true
+			return nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, unsubscribeTokenRepo(), subRepo, &mockEventRepo{}, nil, testLinks, nil, nil)
+	if err := svc.Resume("abc", audit.Meta{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !called {
+		t.Error("expected subscription to be resumed")
+	}
+}
+
+func TestResume_EventFailureReturnsError(t *testing.T) {
+	subRepo := &mockSubscriptionRepo{
+		ResumeFunc: func(userID uuid.UUID) error { return nil },
+	}
+	events := &mockEventRepo{Err: errors.New("db down")}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, unsubscribeTokenRepo(), subRepo, events, nil, testLinks, nil, nil)
+	if err := svc.Resume("abc", audit.Meta{}); err == nil {
+		t.Fatal("expected the audit failure to be returned")
+	}
+}
+
+func TestSubscribe_EventFailureAfterCommitSucceeds(t *testing.T) {
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		CreateUserWithSubscriptionAndTokensFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
+			return &repository.CreateUserWithSubscriptionAndTokensResult{
+				User: &models.User{ID: uuid.New(), Email: email},
+				Tokens: map[string]*models.Token{
+					models.TokenTypeConfirm:     {Value: "confirm-token"},
+					models.TokenTypeUnsubscribe: {Value: "unsubscribe-token"},
+				},
+			}, nil
+		},
+	}
+	events := &mockEventRepo{Err: errors.New("db down")}
+
+	mail := &mockMailService{}
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, events, mail, testLinks, nil, nil)
+	if err := svc.Subscribe("test@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, content.Default(), audit.Meta{}); err != nil {
+		t.Fatalf("expected the committed subscription to succeed, got %v", err)
+	}
+	if !mail.Called {
+		t.Error("expected the confirmation mail to be sent")
+	}
+}
+
+func TestUnsubscribe_EventFailureAfterCommitSucceeds(t *testing.T) {
+	churnID := uuid.New()
+	userRepo := &mockUserRepo{
+		DeleteUserWithTokensAndSubscriptionFunc: func(userID uuid.UUID) (*models.Churn, error) {
+			return &models.Churn{ID: churnID}, nil
+		},
+	}
+	events := &mockEventRepo{Err: errors.New("db down")}
+
+	svc := subscription.NewSubscriptionService(userRepo, unsubscribeTokenRepo(), nil, events, nil, testLinks, nil, nil)
+	gotID, err := svc.Unsubscribe("abc", audit.Meta{})
+	if err != nil {
+		t.Fatalf("expected the committed unsubscribe to succeed, got %v", err)
+	}
+	if gotID != churnID {
+		t.Errorf("expected churn ID %s, got %s", churnID, gotID)
+	}
+}
+
+func TestConfirm_RecordsEvent(t *testing.T) {
+	token := &models.Token{
+		Type:   models.TokenTypeConfirm,
+		ID:     uuid.New(),
+		UserID: uuid.New(),
+	}
+
+	userRepo := &mockUserRepo{
+		UpdateUserConfirmationAndDeleteTokenFunc: func(userID uuid.UUID, tokenID uuid.UUID) error {
+			return nil
+		},
+	}
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return token, nil
+		},
+	}
+	events := &mockEventRepo{}
+
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, events, nil, testLinks, nil, nil)
+	err := svc.Confirm("token123", audit.Meta{IP: "10.0.0.1", UserAgent: "test-agent"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(events.Events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events.Events))
+	}
+
+	e := events.Events[0]
+	if e.Type != models.EventConfirmed || e.IP != "10.0.0.1" || e.UserAgent != "test-agent" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if e.TokenID == nil || *e.TokenID != token.ID {
+		t.Errorf("expected token ID %s, got %v", token.ID, e.TokenID)
+	}
+	if e.Email != "test@example.com" {
+		t.Errorf("expected email to be recorded, got %q", e.Email)
+	}
+}
+
+func TestConfirm_PublishesWebhook(t *testing.T) {
+	token := &models.Token{
+		Type:   models.TokenTypeConfirm,
+		ID:     uuid.New(),
+		UserID: uuid.New(),
+	}
+
+	userRepo := &mockUserRepo{
+		UpdateUserConfirmationAndDeleteTokenFunc: func(userID uuid.UUID, tokenID uuid.UUID) error {
+			return nil
+		},
+	}
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return token, nil
+		},
+	}
+	webhooks := &mockWebhookPublisher{}
+
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, webhooks)
+	if err := svc.Confirm("token123", audit.Meta{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(webhooks.Events) != 1 || webhooks.Events[0].Type != models.EventConfirmed || webhooks.Events[0].UserID != token.UserID {
+		t.Errorf("expected confirmed event to be published, got %+v", webhooks.Events)
+	}
+}
+
+func TestUnsubscribe_RecordsEventWithEmail(t *testing.T) {
+	userRepo := &mockUserRepo{
+		GetByIDFunc: func(id uuid.UUID) (*models.User, error) {
+			return &models.User{ID: id, Email: "gone@example.com"}, nil
+		},
+		DeleteUserWithTokensAndSubscriptionFunc: func(userID uuid.UUID) (*models.Churn, error) {
+			return &models.Churn{ID: uuid.New()}, nil
+		},
+	}
+	events := &mockEventRepo{}
+
+	svc := subscription.NewSubscriptionService(userRepo, unsubscribeTokenRepo(), nil, events, nil, testLinks, nil, nil)
+	if _, err := svc.Unsubscribe("abc", audit.Meta{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(events.Events) != 1 || events.Events[0].Type != models.EventUnsubscribed || events.Events[0].Email != "gone@example.com" {
+		t.Errorf("unexpected events: %+v", events.Events)
+	}
+}
+
+func signedLinks(t *testing.T) *links.Builder {
+	t.Helper()
+
+	signer, err := links.NewSigner([]links.Key{{ID: "k1", Secret: []byte("0123456789abcdef0123456789abcdef")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return links.NewBuilder("https://test.com", signer)
+}
+
+func TestSubscribe_SignedLinksCreateNoTokens(t *testing.T) {
+	var gotTokenTypes []string
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, repository.ErrNotFound
+		},
+		CreateUserWithSubscriptionAndTokensFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
+			gotTokenTypes = tokenTypes
+			return &repository.CreateUserWithSubscriptionAndTokensResult{User: &models.User{ID: uuid.New()}}, nil
+		},
+	}
+
+	mail := &mockMailService{}
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, mail, signedLinks(t), nil, nil)
+
+	if err := svc.Subscribe("test@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, content.Default(), audit.Meta{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(gotTokenTypes) != 0 {
+		t.Errorf("expected no token rows, got %v", gotTokenTypes)
+	}
+	if !mail.Called {
+		t.Error("expected mail service to be called")
+	}
+}
+
+func TestConfirm_SignedLink(t *testing.T) {
+	builder := signedLinks(t)
+	userID := uuid.New()
+
+	url, err := builder.ActionURL(links.ActionConfirm, userID, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token := url[strings.LastIndex(url, "/")+1:]
+
+	var confirmed uuid.UUID
+	userRepo := &mockUserRepo{
+		UpdateUserConfirmationAndDeleteTokenFunc: func(uid uuid.UUID, tokenID uuid.UUID) error {
+			confirmed = uid
+			return nil
+		},
+	}
+
+	// Token repository must not be consulted for signed links
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, nil, builder, nil, nil)
+
+	if err := svc.Confirm(token, audit.Meta{}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if confirmed != userID {
+		t.Errorf("expected user %s to be confirmed, got %s", userID, confirmed)
+	}
+
+	if _, err := svc.Unsubscribe(token, audit.Meta{}); err != subscription.ErrTokenWrongType {
+		t.Errorf("expected ErrTokenWrongType for confirm link, got %v", err)
+	}
+
+	if err := svc.Confirm(token+"x", audit.Meta{}); err != subscription.ErrTokenNotFound {
+		t.Errorf("expected ErrTokenNotFound for tampered link, got %v", err)
+	}
+}
+
+type mockVerifier struct {
+	err error
+}
+
+func (m *mockVerifier) Verify(email string) error {
+	return m.err
+}
+
+func TestSubscribe_RejectedByVerifier(t *testing.T) {
+	created := false
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		CreateUserWithSubscriptionAndTokensFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
+			created = true
+			return nil, nil
+		},
+	}
+
+	verifier := &mockVerifier{err: emailaddr.ErrDisposableDomain}
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, &mockMailService{}, testLinks, verifier, nil)
+
+	err := svc.Subscribe("test@mailinator.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, content.Default(), audit.Meta{})
+	if !errors.Is(err, subscription.ErrDisposableEmail) {
+		t.Fatalf("expected ErrDisposableEmail, got %v", err)
+	}
+	if created {
+		t.Error("expected no user to be created")
+	}
+}
+
+func TestCreateSubscription_MailError(t *testing.T) {
+	subID := uuid.New()
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		CreateUserWithSubscriptionAndTokensFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
+			return &repository.CreateUserWithSubscriptionAndTokensResult{
+				User:         &models.User{ID: uuid.New(), Email: email},
+				Subscription: &models.Subscription{ID: subID, City: city},
+				Tokens: map[string]*models.Token{
+					models.TokenTypeConfirm:     {Value: "c"},
+					models.TokenTypeUnsubscribe: {Value: "u"},
+				},
+			}, nil
+		},
+	}
+
+	mail := &mockMailService{Err: errors.New("mail error")}
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, mail, testLinks, nil, nil)
+
+	sub, err := svc.CreateSubscription("test@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, content.Default(), audit.Meta{})
+	if !errors.Is(err, subscription.ErrConfirmationMailError) {
+		t.Errorf("expected confirmation mail error, got %v", err)
+	}
+	if sub == nil || sub.ID != subID {
+		t.Errorf("expected the created subscription with the error, got %+v", sub)
+	}
+}
+
+func TestGetSubscription(t *testing.T) {
+	id := uuid.New()
+	userID := uuid.New()
+
+	subRepo := &mockSubscriptionRepo{
+		GetByIDFunc: func(got uuid.UUID) (*models.Subscription, error) {
+			if got != id {
+				return nil, repository.ErrNotFound
+			}
+			return &models.Subscription{ID: id, UserID: userID, City: "Kyiv"}, nil
+		},
+	}
+	userRepo := &mockUserRepo{
+		GetByIDFunc: func(got uuid.UUID) (*models.User, error) {
+			return &models.User{ID: got, IsConfirmed: true}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(userRepo, nil, subRepo, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	details, err := svc.GetSubscription(id)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if details.Subscription.City != "Kyiv" || !details.Confirmed {
+		t.Errorf("unexpected details %+v", details)
+	}
+
+	if _, err := svc.GetSubscription(uuid.New()); !errors.Is(err, subscription.ErrSubscriptionNotFound) {
+		t.Errorf("expected ErrSubscriptionNotFound, got %v", err)
+	}
+}
+
+func TestConfirm_SignedLinkAlreadyConfirmed(t *testing.T) {
+	builder := signedLinks(t)
+
+	url, err := builder.ActionURL(links.ActionConfirm, uuid.New(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token := url[strings.LastIndex(url, "/")+1:]
+
+	userRepo := &mockUserRepo{
+		GetByIDFunc: func(id uuid.UUID) (*models.User, error) {
+			return &models.User{ID: id, IsConfirmed: true}, nil
+		},
+		UpdateUserConfirmationAndDeleteTokenFunc: func(uid uuid.UUID, tokenID uuid.UUID) error {
+			t.Error("expected a confirmed user not to be confirmed again")
+			return nil
+		},
+	}
+	events := &mockEventRepo{}
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, events, nil, builder, nil, nil)
+
+	if err := svc.Confirm(token, audit.Meta{}); err != subscription.ErrAlreadyConfirmed {
+		t.Errorf("expected ErrAlreadyConfirmed, got %v", err)
+	}
+	if len(events.Events) != 0 {
+		t.Errorf("expected no events, got %+v", events.Events)
+	}
+}
+
+func TestConfirm_SignedLinkExpired(t *testing.T) {
+	builder := signedLinks(t)
+	token := builder.Signer().Sign(links.Claims{
+		UserID:    uuid.New(),
+		Action:    links.ActionConfirm,
+		ExpiresAt: time.Now().Add(-time.Hour),
+	})
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, nil, nil, &mockEventRepo{}, nil, builder, nil, nil)
+
+	err := svc.Confirm(token, audit.Meta{})
+	if !errors.Is(err, subscription.ErrTokenExpired) || !errors.Is(err, subscription.ErrTokenNotFound) {
+		t.Errorf("expected ErrTokenExpired wrapping ErrTokenNotFound, got %v", err)
+	}
+}
+
+func TestConfirm_SignedLinkUserDeleted(t *testing.T) {
+	builder := signedLinks(t)
+
+	url, err := builder.ActionURL(links.ActionConfirm, uuid.New(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token := url[strings.LastIndex(url, "/")+1:]
+
+	userRepo := &mockUserRepo{
+		GetByIDFunc: func(id uuid.UUID) (*models.User, error) {
+			return nil, repository.ErrNotFound
+		},
+	}
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, nil, builder, nil, nil)
+
+	if err := svc.Confirm(token, audit.Meta{}); err != subscription.ErrTokenNotFound {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package subscription
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/schedule"
+)
+
+// How long a Telegram deep link can be used
+const telegramTokenTTL = time.Hour
+
+var (
+	ErrTelegramLinkExpired = errors.New("telegram link has expired")
+	ErrChatNotLinked       = errors.New("chat is not linked to a subscription")
+)
+
+// Issues the parameter of a Telegram deep link for the subscriber. Like the
+// other management actions it is authorized by the unsubscribe token
+func (srv *SubscriptionService) RequestTelegramLink(tokenValue string) (string, error) {
+	_, user, err := srv.ResolveUser(tokenValue, models.TokenTypeUnsubscribe)
+	if err != nil {
+		return "", err
+	}
+
+	linkToken, err := srv.userRepo.CreateTelegramToken(user.ID)
+	if err != nil {
+		return "", fmt.Errorf("error creating telegram token: %w", err)
+	}
+
+	return linkToken.Value, nil
+}
+
+// Links the chat that opened the bot with a deep link to the subscriber who
+// requested it
+func (srv *SubscriptionService) LinkTelegramChat(tokenValue string, chatID int64, username string, meta audit.Meta) error {
+	token, err := srv.ResolveToken(tokenValue, models.TokenTypeTelegram)
+	if err != nil {
+		return err
+	}
+
+	if time.Since(token.CreatedAt) > telegramTokenTTL {
+		return ErrTelegramLinkExpired
+	}
+
+	if err := srv.userRepo.LinkTelegramChat(token.UserID, chatID, username, &token.ID); err != nil {
+		return fmt.Errorf("error linking telegram chat: %w", err)
+	}
+
+	return srv.recordTokenEvent(models.EventTelegramLinked, token, &token.ID, telegramDetails(chatID), meta)
+}
+
+// Subscribes the address like Subscribe, including the confirmation mail, and
+// links the chat to the new subscriber. Updates start once the address is
+// confirmed. Existing subscribers have to use a deep link instead, so a chat
+// can't attach itself to someone else's subscription
+func (srv *SubscriptionService) SubscribeTelegramChat(email, city string, sched schedule.Schedule, chatID int64, username string, meta audit.Meta) error {
+	// Like SubscribeHandler, a failed confirmation mail leaves the subscriber
+	// in place, so the chat is linked and ErrConfirmationMailError returned
+	result, subscribeErr := srv.subscribe(email, city, sched, content.Default(), meta)
+	if result == nil {
+		return subscribeErr
+	}
+	user := result.User
+
+	if err := srv.userRepo.LinkTelegramChat(user.ID, chatID, username, nil); err != nil {
+		return fmt.Errorf("error linking telegram chat: %w", err)
+	}
+
+	if err := srv.recordEvent(models.EventTelegramLinked, user.Email, user.ID, nil, telegramDetails(chatID), meta); err != nil {
+		return err
+	}
+
+	return subscribeErr
+}
+
+// Stops updates to the chat. The subscription itself stays
+func (srv *SubscriptionService) UnlinkTelegramChat(chatID int64, meta audit.Meta) error {
+	chat, err := srv.userRepo.UnlinkTelegramChat(chatID)
+	if err != nil {
+		if repository.IsErrNotFound(err) {
+			return ErrChatNotLinked
+		}
+
+		return fmt.Errorf("error unlinking telegram chat: %w", err)
+	}
+
+	return srv.recordTokenEvent(models.EventTelegramUnlinked, &models.Token{UserID: chat.UserID}, nil, telegramDetails(chatID), meta)
+}
+
+func telegramDetails(chatID int64) string {
+	return "chat=" + strconv.FormatInt(chatID, 10)
+}
@@ -0,0 +1,201 @@
+package subscription_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/schedule"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestRequestTelegramLink(t *testing.T) {
+	userID := uuid.New()
+
+	userRepo := &mockUserRepo{
+		CreateTelegramTokenFunc: func(id uuid.UUID) (*models.Token, error) {
+			if id != userID {
+				t.Errorf("expected token for %s, got %s", userID, id)
+			}
+			return &models.Token{Value: "link-token", Type: models.TokenTypeTelegram}, nil
+		},
+	}
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeUnsubscribe, UserID: userID}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	value, err := svc.RequestTelegramLink("unsub")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "link-token" {
+		t.Errorf("expected link-token, got %q", value)
+	}
+}
+
+func TestLinkTelegramChat(t *testing.T) {
+	token := &models.Token{ID: uuid.New(), Type: models.TokenTypeTelegram, UserID: uuid.New(), CreatedAt: time.Now()}
+
+	var gotTokenID *uuid.UUID
+	var gotChat int64
+	userRepo := &mockUserRepo{
+		LinkTelegramChatFunc: func(userID uuid.UUID, chatID int64, username string, tokenID *uuid.UUID) error {
+			gotChat, gotTokenID = chatID, tokenID
+			return nil
+		},
+	}
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return token, nil
+		},
+	}
+	events := &mockEventRepo{}
+
+	svc := subscription.NewSubscriptionService(userRepo, tokenRepo, nil, events, nil, testLinks, nil, nil)
+
+	if err := svc.LinkTelegramChat("link-token", 42, "alice", audit.Meta{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotChat != 42 || gotTokenID == nil || *gotTokenID != token.ID {
+		t.Errorf("expected chat 42 linked with token %s, got chat %d token %v", token.ID, gotChat, gotTokenID)
+	}
+	if len(events.Events) != 1 || events.Events[0].Type != models.EventTelegramLinked || events.Events[0].Details != "chat=42" {
+		t.Errorf("expected telegram_linked event, got %+v", events.Events)
+	}
+}
+
+func TestLinkTelegramChat_Expired(t *testing.T) {
+	tokenRepo := &mockTokenRepo{
+		GetTokenFunc: func(value string) (*models.Token, error) {
+			return &models.Token{Type: models.TokenTypeTelegram, CreatedAt: time.Now().Add(-2 * time.Hour)}, nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(&mockUserRepo{}, tokenRepo, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	if err := svc.LinkTelegramChat("link-token", 42, "", audit.Meta{}); !errors.Is(err, subscription.ErrTelegramLinkExpired) {
+		t.Fatalf("expected ErrTelegramLinkExpired, got %v", err)
+	}
+}
+
+func TestSubscribeTelegramChat(t *testing.T) {
+	userID := uuid.New()
+
+	var linkedUser uuid.UUID
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		CreateUserWithSubscriptionAndTokensFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
+			return &repository.CreateUserWithSubscriptionAndTokensResult{
+				User: &models.User{ID: userID, Email: email},
+				Tokens: map[string]*models.Token{
+					models.TokenTypeConfirm:     {Value: "confirm-token"},
+					models.TokenTypeUnsubscribe: {Value: "unsubscribe-token"},
+				},
+			}, nil
+		},
+		LinkTelegramChatFunc: func(id uuid.UUID, chatID int64, username string, tokenID *uuid.UUID) error {
+			linkedUser = id
+			if tokenID != nil {
+				t.Error("expected no token for a new subscriber")
+			}
+			return nil
+		},
+	}
+	mail := &mockMailService{}
+
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, mail, testLinks, nil, nil)
+
+	err := svc.SubscribeTelegramChat("new@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, 42, "alice", audit.Meta{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if linkedUser != userID {
+		t.Errorf("expected chat linked to %s, got %s", userID, linkedUser)
+	}
+	if !mail.Called {
+		t.Error("expected confirmation mail to be sent")
+	}
+}
+
+func TestSubscribeTelegramChat_MailFailureStillLinks(t *testing.T) {
+	userID := uuid.New()
+
+	var linkedUser uuid.UUID
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return nil, gorm.ErrRecordNotFound
+		},
+		CreateUserWithSubscriptionAndTokensFunc: func(email, city string, sched schedule.Schedule, prefs content.Preferences, tokenTypes []string) (*repository.CreateUserWithSubscriptionAndTokensResult, error) {
+			return &repository.CreateUserWithSubscriptionAndTokensResult{
+				User: &models.User{ID: userID, Email: email},
+				Tokens: map[string]*models.Token{
+					models.TokenTypeConfirm:     {Value: "confirm-token"},
+					models.TokenTypeUnsubscribe: {Value: "unsubscribe-token"},
+				},
+			}, nil
+		},
+		LinkTelegramChatFunc: func(id uuid.UUID, chatID int64, username string, tokenID *uuid.UUID) error {
+			linkedUser = id
+			return nil
+		},
+	}
+	mail := &mockMailService{Err: errors.New("smtp down")}
+
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, mail, testLinks, nil, nil)
+
+	err := svc.SubscribeTelegramChat("new@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, 42, "alice", audit.Meta{})
+	if !errors.Is(err, subscription.ErrConfirmationMailError) {
+		t.Fatalf("expected ErrConfirmationMailError, got %v", err)
+	}
+
+	if linkedUser != userID {
+		t.Errorf("expected chat linked to %s despite the mail failure, got %s", userID, linkedUser)
+	}
+}
+
+func TestSubscribeTelegramChat_ExistingUserNotLinked(t *testing.T) {
+	userRepo := &mockUserRepo{
+		GetByEmailFunc: func(email string) (*models.User, error) {
+			return &models.User{ID: uuid.New(), Email: email}, nil
+		},
+		LinkTelegramChatFunc: func(id uuid.UUID, chatID int64, username string, tokenID *uuid.UUID) error {
+			t.Error("chat must not be linked to an existing subscriber")
+			return nil
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	err := svc.SubscribeTelegramChat("taken@example.com", "Kyiv", schedule.Schedule{Frequency: "daily"}, 42, "", audit.Meta{})
+	if !errors.Is(err, subscription.ErrUserAlreadyExists) {
+		t.Fatalf("expected ErrUserAlreadyExists, got %v", err)
+	}
+}
+
+func TestUnlinkTelegramChat_NotLinked(t *testing.T) {
+	userRepo := &mockUserRepo{
+		UnlinkTelegramChatFunc: func(chatID int64) (*models.TelegramChat, error) {
+			return nil, repository.ErrNotFound
+		},
+	}
+
+	svc := subscription.NewSubscriptionService(userRepo, nil, nil, &mockEventRepo{}, nil, testLinks, nil, nil)
+
+	if err := svc.UnlinkTelegramChat(42, audit.Meta{}); !errors.Is(err, subscription.ErrChatNotLinked) {
+		t.Fatalf("expected ErrChatNotLinked, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package team
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/problem"
+	"weather-app/internal/subscription"
+
+	"github.com/google/uuid"
+)
+
+const subscriptionsPath = "/admin/team-subscriptions"
+
+var (
+	ErrInvalidChannel       = errors.New("channel parameter is invalid")
+	ErrInvalidURL           = errors.New("url parameter is invalid")
+	ErrInvalidCity          = errors.New("city parameter is invalid")
+	ErrSubscriptionNotFound = errors.New("team subscription not found")
+)
+
+var problems = slices.Concat(subscription.FormProblems, problem.Mappings{
+	{Err: ErrInvalidChannel, Status: http.StatusBadRequest, Code: "invalid_channel"},
+	{Err: ErrInvalidURL, Status: http.StatusBadRequest, Code: "invalid_url"},
+	{Err: ErrInvalidCity, Status: http.StatusBadRequest, Code: "invalid_city"},
+	{Err: ErrSubscriptionNotFound, Status: http.StatusNotFound, Code: "team_subscription_not_found"},
+})
+
+type RepositoryInterface interface {
+	Create(sub *models.TeamSubscription) error
+	List() ([]models.TeamSubscription, error)
+	Delete(id uuid.UUID) error
+}
+
+type Handler struct {
+	repo RepositoryInterface
+}
+
+func NewHandler(repo RepositoryInterface) *Handler {
+	return &Handler{repo: repo}
+}
+
+type SubscriptionResponse struct {
+	ID            uuid.UUID `json:"id"`
+	Name          string    `json:"name"`
+	Channel       string    `json:"channel"`
+	URL           string    `json:"url"`
+	Secret        string    `json:"secret,omitempty"`
+	City          string    `json:"city"`
+	Frequency     string    `json:"frequency"`
+	Timezone      string    `json:"timezone"`
+	SendTime      string    `json:"send_time"`
+	Weekday       int       `json:"weekday"`
+	IntervalHours int       `json:"interval_hours,omitempty"`
+	CronExpr      string    `json:"cron,omitempty"`
+
+	Content content.Preferences `json:"content"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GET lists team subscriptions. POST creates one from form fields "channel"
+// ("slack" or "webhook"), "url", "city", optional "name" and the schedule and
+// content fields of /api/subscribe. Secrets of "webhook" subscriptions are
+// only returned here
+func (h *Handler) SubscriptionsHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "GET":
+		h.list(w)
+	case "POST":
+		h.create(w, req)
+	default:
+		problem.MethodNotAllowed(w, req, "GET", "POST")
+	}
+}
+
+func (h *Handler) list(w http.ResponseWriter) {
+	subs, err := h.repo.List()
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	response := []SubscriptionResponse{}
+	for _, s := range subs {
+		response = append(response, subscriptionResponse(s, false))
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) create(w http.ResponseWriter, req *http.Request) {
+	sub, err := parseSubscriptionForm(req)
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	if sub.Channel == models.TeamChannelWebhook {
+		if sub.Secret, err = generateSecret(); err != nil {
+			log.Println(err.Error())
+			problem.Internal(w)
+			return
+		}
+	}
+
+	if err := h.repo.Create(sub); err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, subscriptionResponse(*sub, true))
+}
+
+// DELETE /admin/team-subscriptions/{id}
+func (h *Handler) SubscriptionHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "DELETE" {
+		problem.MethodNotAllowed(w, req, "DELETE")
+		return
+	}
+
+	id, err := uuid.Parse(strings.TrimPrefix(req.URL.Path, subscriptionsPath+"/"))
+	if err != nil {
+		problems.Write(w, ErrSubscriptionNotFound)
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		if repository.IsErrNotFound(err) {
+			problems.Write(w, ErrSubscriptionNotFound)
+			return
+		}
+
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func parseSubscriptionForm(req *http.Request) (*models.TeamSubscription, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, subscription.ErrInvalidForm
+	}
+
+	channel := req.FormValue("channel")
+	if channel != models.TeamChannelSlack && channel != models.TeamChannelWebhook {
+		return nil, ErrInvalidChannel
+	}
+
+	// Slack webhook URLs are credentials, only send them over TLS
+	target := req.FormValue("url")
+	if u, err := url.Parse(target); err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+
+	city := req.FormValue("city")
+	if city == "" {
+		return nil, ErrInvalidCity
+	}
+
+	sched, err := subscription.ParseScheduleForm(req)
+	if err != nil {
+		return nil, err
+	}
+	sched = sched.WithDefaults()
+
+	prefs, err := content.ParseForm(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TeamSubscription{
+		Name:          req.FormValue("name"),
+		Channel:       channel,
+		URL:           target,
+		City:          city,
+		Frequency:     sched.Frequency,
+		Timezone:      sched.Timezone,
+		SendTime:      sched.SendTime,
+		Weekday:       int(sched.Weekday),
+		IntervalHours: sched.IntervalHours,
+		CronExpr:      sched.CronExpr,
+		ContentFields: prefs.StoredFields(),
+		Units:         prefs.Units,
+		Language:      prefs.Language,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate team subscription secret: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+func subscriptionResponse(s models.TeamSubscription, withSecret bool) SubscriptionResponse {
+	response := SubscriptionResponse{
+		ID:            s.ID,
+		Name:          s.Name,
+		Channel:       s.Channel,
+		URL:           s.URL,
+		City:          s.City,
+		Frequency:     s.Frequency,
+		Timezone:      s.Timezone,
+		SendTime:      s.SendTime,
+		Weekday:       s.Weekday,
+		IntervalHours: s.IntervalHours,
+		CronExpr:      s.CronExpr,
+		Content:       content.FromStored(s.ContentFields, s.Units, s.Language),
+		CreatedAt:     s.CreatedAt,
+	}
+
+	if withSecret {
+		response.Secret = s.Secret
+	}
+
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
@@ -0,0 +1,165 @@
+package team_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/team"
+
+	"github.com/google/uuid"
+)
+
+type memoryRepo struct {
+	subs []models.TeamSubscription
+}
+
+func (m *memoryRepo) Create(sub *models.TeamSubscription) error {
+	sub.ID = uuid.New()
+	m.subs = append(m.subs, *sub)
+	return nil
+}
+
+func (m *memoryRepo) List() ([]models.TeamSubscription, error) {
+	return m.subs, nil
+}
+
+func (m *memoryRepo) Delete(id uuid.UUID) error {
+	for i, s := range m.subs {
+		if s.ID == id {
+			m.subs = append(m.subs[:i], m.subs[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: team subscription not found", repository.ErrNotFound)
+}
+
+func postForm(target string, values url.Values) *http.Request {
+	req := httptest.NewRequest("POST", target, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestSubscriptionsHandler_CreateSlack(t *testing.T) {
+	repo := &memoryRepo{}
+	h := team.NewHandler(repo)
+
+	w := httptest.NewRecorder()
+	h.SubscriptionsHandler(w, postForm("/admin/team-subscriptions", url.Values{
+		"name":      {"Office"},
+		"channel":   {"slack"},
+		"url":       {"https://hooks.slack.com/services/T0/B0/XXX"},
+		"city":      {"Kyiv"},
+		"frequency": {"daily"},
+		"timezone":  {"Europe/Kyiv"},
+		"send_time": {"09:00"},
+	}))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(repo.subs) != 1 {
+		t.Fatalf("expected one stored subscription, got %d", len(repo.subs))
+	}
+	stored := repo.subs[0]
+	if stored.Channel != models.TeamChannelSlack || stored.SendTime != "09:00" || stored.Timezone != "Europe/Kyiv" {
+		t.Errorf("unexpected stored subscription %+v", stored)
+	}
+	if stored.Secret != "" {
+		t.Error("slack subscriptions don't need a secret")
+	}
+}
+
+func TestSubscriptionsHandler_CreateWebhook(t *testing.T) {
+	repo := &memoryRepo{}
+	h := team.NewHandler(repo)
+
+	w := httptest.NewRecorder()
+	h.SubscriptionsHandler(w, postForm("/admin/team-subscriptions", url.Values{
+		"channel":   {"webhook"},
+		"url":       {"https://intranet.example.com/weather"},
+		"city":      {"Lviv"},
+		"frequency": {"hourly"},
+	}))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var created team.SubscriptionResponse
+	json.NewDecoder(w.Body).Decode(&created)
+
+	if len(created.Secret) != 64 || repo.subs[0].Secret != created.Secret {
+		t.Errorf("expected a generated secret, got %q", created.Secret)
+	}
+	if repo.subs[0].Timezone != "UTC" {
+		t.Errorf("expected schedule defaults, got %+v", repo.subs[0])
+	}
+
+	// Listing never shows secrets
+	w = httptest.NewRecorder()
+	h.SubscriptionsHandler(w, httptest.NewRequest("GET", "/admin/team-subscriptions", nil))
+
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), created.Secret) {
+		t.Errorf("secret leaked in subscription list: %s", w.Body.String())
+	}
+}
+
+func TestSubscriptionsHandler_CreateInvalid(t *testing.T) {
+	h := team.NewHandler(&memoryRepo{})
+
+	valid := url.Values{
+		"channel":   {"slack"},
+		"url":       {"https://hooks.slack.com/services/T0/B0/XXX"},
+		"city":      {"Kyiv"},
+		"frequency": {"daily"},
+	}
+
+	cases := map[string]string{
+		"channel":   "email",
+		"url":       "http://hooks.slack.com/services/T0/B0/XXX",
+		"city":      "",
+		"frequency": "monthly",
+	}
+
+	for field, value := range cases {
+		values := url.Values{}
+		for k, v := range valid {
+			values[k] = v
+		}
+		values.Set(field, value)
+
+		w := httptest.NewRecorder()
+		h.SubscriptionsHandler(w, postForm("/admin/team-subscriptions", values))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s=%q: expected 400, got %d", field, value, w.Code)
+		}
+	}
+}
+
+func TestSubscriptionHandler_Delete(t *testing.T) {
+	id := uuid.New()
+	repo := &memoryRepo{subs: []models.TeamSubscription{{ID: id, Channel: models.TeamChannelSlack}}}
+	h := team.NewHandler(repo)
+
+	w := httptest.NewRecorder()
+	h.SubscriptionHandler(w, httptest.NewRequest("DELETE", "/admin/team-subscriptions/"+id.String(), nil))
+
+	if w.Code != http.StatusOK || len(repo.subs) != 0 {
+		t.Fatalf("expected deletion, got %d and %d subscriptions", w.Code, len(repo.subs))
+	}
+
+	w = httptest.NewRecorder()
+	h.SubscriptionHandler(w, httptest.NewRequest("DELETE", "/admin/team-subscriptions/"+id.String(), nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown subscription, got %d", w.Code)
+	}
+}
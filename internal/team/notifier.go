@@ -0,0 +1,185 @@
+package team
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"weather-app/internal/content"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/notify"
+	"weather-app/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	maxErrorLength = 512
+
+	// X-Webhook-Event of JSON updates
+	EventWeatherUpdate = "weather_update"
+)
+
+// Posts Block Kit messages to Slack incoming webhooks. Recipient's target
+// is the webhook URL
+type SlackNotifier struct {
+	client *http.Client
+}
+
+// Nil client uses one with a 10 second timeout
+func NewSlackNotifier(client *http.Client) *SlackNotifier {
+	return &SlackNotifier{client: defaultClient(client)}
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"` // Fallback for notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+func (n *SlackNotifier) Notify(recipient repository.ChannelRecipient, update notify.Update) error {
+	body, err := json.Marshal(slackUpdateMessage(update))
+	if err != nil {
+		return fmt.Errorf("failed to encode slack message: %w", err)
+	}
+
+	return post(n.client, recipient.Target, body, nil)
+}
+
+// Slack allows up to 10 fields per section
+const slackSectionFields = 10
+
+func slackUpdateMessage(update notify.Update) slackMessage {
+	report := update.Report()
+
+	message := slackMessage{
+		Text:   fmt.Sprintf("%s: %s, %s", slackEscape(report.Title), report.Temperature, slackEscape(report.Description)),
+		Blocks: []slackBlock{{Type: "header", Text: &slackText{Type: "plain_text", Text: report.Title}}},
+	}
+
+	lines := report.Lines()
+	for start := 0; start < len(lines); start += slackSectionFields {
+		section := slackBlock{Type: "section"}
+		for _, line := range lines[start:min(start+slackSectionFields, len(lines))] {
+			section.Fields = append(section.Fields, slackText{
+				Type: "mrkdwn",
+				Text: fmt.Sprintf("*%s*\n%s", slackEscape(line.Label), slackEscape(line.Value)),
+			})
+		}
+		message.Blocks = append(message.Blocks, section)
+	}
+
+	return message
+}
+
+// Slack mrkdwn only needs &, < and > escaped
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// Posts signed JSON updates to any HTTPS endpoint. Requests carry the same
+// signature headers as lifecycle webhooks, keyed by the recipient's secret
+type JSONNotifier struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// Nil client uses one with a 10 second timeout
+func NewJSONNotifier(client *http.Client) *JSONNotifier {
+	return &JSONNotifier{client: defaultClient(client), now: time.Now}
+}
+
+type JSONPayload struct {
+	ID      uuid.UUID      `json:"id"`
+	Type    string         `json:"type"`
+	City    string         `json:"city"`
+	SentAt  time.Time      `json:"sent_at"`
+	Weather content.Values `json:"weather"`
+}
+
+func (n *JSONNotifier) Notify(recipient repository.ChannelRecipient, update notify.Update) error {
+	now := n.now()
+
+	payload := JSONPayload{
+		ID:      uuid.New(),
+		Type:    EventWeatherUpdate,
+		City:    update.City,
+		SentAt:  now.UTC(),
+		Weather: update.Values(),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode weather payload: %w", err)
+	}
+
+	timestamp := now.Unix()
+
+	return post(n.client, recipient.Target, body, map[string]string{
+		webhook.EventHeader:     EventWeatherUpdate,
+		webhook.DeliveryHeader:  payload.ID.String(),
+		webhook.TimestampHeader: strconv.FormatInt(timestamp, 10),
+		webhook.SignatureHeader: webhook.Sign(recipient.Secret, timestamp, body),
+	})
+}
+
+func post(client *http.Client, target string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest("POST", target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("team channel request failed: %w", stripURL(err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "weather-app")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("team channel request failed: %w", stripURL(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return fmt.Errorf("team channel returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// Slack webhook URLs are credentials, keep them out of logs
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+
+	return err
+}
+
+func defaultClient(client *http.Client) *http.Client {
+	if client == nil {
+		return &http.Client{Timeout: requestTimeout}
+	}
+
+	return client
+}
@@ -0,0 +1,130 @@
+package team_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/notify"
+	"weather-app/internal/team"
+	"weather-app/internal/weather"
+	"weather-app/internal/webhook"
+)
+
+var testUpdate = notify.Update{
+	City:    "Kyiv",
+	Weather: weather.WeatherData{Temperature: 21.5, Humidity: 40, Description: "clear <sky>"},
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, status int) (*httptest.Server, *[]receivedRequest) {
+	t.Helper()
+
+	var received []receivedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		received = append(received, receivedRequest{header: req.Header, body: body})
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &received
+}
+
+func TestSlackNotifier_Notify(t *testing.T) {
+	server, received := newReceiver(t, http.StatusOK)
+	notifier := team.NewSlackNotifier(server.Client())
+
+	if err := notifier.Notify(repository.ChannelRecipient{Target: server.URL}, testUpdate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*received) != 1 {
+		t.Fatalf("expected one request, got %d", len(*received))
+	}
+
+	var message struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type   string `json:"type"`
+			Fields []struct {
+				Text string `json:"text"`
+			} `json:"fields"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal((*received)[0].body, &message); err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+
+	if len(message.Blocks) != 2 || message.Blocks[0].Type != "header" || message.Blocks[1].Type != "section" {
+		t.Fatalf("unexpected blocks %+v", message.Blocks)
+	}
+	if !strings.Contains(message.Text, "Kyiv") || !strings.Contains(message.Text, "21.5°C") {
+		t.Errorf("unexpected fallback text %q", message.Text)
+	}
+	if got := message.Blocks[1].Fields[2].Text; got != "*Conditions*\nclear &lt;sky&gt;" {
+		t.Errorf("expected escaped description, got %q", got)
+	}
+}
+
+func TestSlackNotifier_Rejected(t *testing.T) {
+	server, _ := newReceiver(t, http.StatusNotFound)
+	notifier := team.NewSlackNotifier(server.Client())
+
+	if err := notifier.Notify(repository.ChannelRecipient{Target: server.URL}, testUpdate); err == nil {
+		t.Error("expected error for non-2xx response")
+	}
+}
+
+func TestJSONNotifier_Notify(t *testing.T) {
+	server, received := newReceiver(t, http.StatusNoContent)
+	notifier := team.NewJSONNotifier(server.Client())
+
+	recipient := repository.ChannelRecipient{Target: server.URL, Secret: "team-secret"}
+	if err := notifier.Notify(recipient, testUpdate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*received) != 1 {
+		t.Fatalf("expected one request, got %d", len(*received))
+	}
+	request := (*received)[0]
+
+	err := webhook.Verify("team-secret", request.header.Get(webhook.TimestampHeader),
+		request.header.Get(webhook.SignatureHeader), request.body, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatalf("signature check failed: %v", err)
+	}
+	if got := request.header.Get(webhook.EventHeader); got != team.EventWeatherUpdate {
+		t.Errorf("expected event header %q, got %q", team.EventWeatherUpdate, got)
+	}
+
+	var payload team.JSONPayload
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+
+	if payload.City != "Kyiv" || payload.Weather.Temperature != 21.5 || payload.Weather.Description != "clear <sky>" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if request.header.Get(webhook.DeliveryHeader) != payload.ID.String() {
+		t.Error("expected delivery header to match payload id")
+	}
+}
+
+func TestJSONNotifier_Rejected(t *testing.T) {
+	server, _ := newReceiver(t, http.StatusInternalServerError)
+	notifier := team.NewJSONNotifier(server.Client())
+
+	if err := notifier.Notify(repository.ChannelRecipient{Target: server.URL, Secret: "s"}, testUpdate); err == nil {
+		t.Error("expected error for non-2xx response")
+	}
+}
@@ -0,0 +1,297 @@
+package telegram
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"weather-app/internal/audit"
+	"weather-app/internal/content"
+	"weather-app/internal/emailaddr"
+	"weather-app/internal/problem"
+	"weather-app/internal/ratelimit"
+	"weather-app/internal/schedule"
+	"weather-app/internal/subscription"
+	"weather-app/internal/weather"
+)
+
+const (
+	SecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+	linkPath = "/api/telegram/link/"
+
+	// /subscribe sends a confirmation mail, so a chat can't use it as a mailer
+	subscribeLimit  = 3
+	subscribeWindow = time.Hour
+
+	// Recorded as user agent of audit events from the bot
+	botUserAgent = "telegram"
+)
+
+type SubscriptionServiceInterface interface {
+	RequestTelegramLink(tokenValue string) (string, error)
+	LinkTelegramChat(tokenValue string, chatID int64, username string, meta audit.Meta) error
+	SubscribeTelegramChat(email, city string, sched schedule.Schedule, chatID int64, username string, meta audit.Meta) error
+	UnlinkTelegramChat(chatID int64, meta audit.Meta) error
+}
+
+type WeatherServiceInterface interface {
+	GetWeather(city string) (*weather.WeatherData, error)
+}
+
+type Bot struct {
+	sender   MessageSenderInterface
+	subs     SubscriptionServiceInterface
+	weather  WeatherServiceInterface
+	username string // Bot username, used in deep links
+	secret   string // Expected in SecretHeader of webhook requests
+
+	subscribeLimiter *ratelimit.Limiter
+}
+
+func NewBot(sender MessageSenderInterface, subs SubscriptionServiceInterface, weatherService WeatherServiceInterface, username, secret string) *Bot {
+	return &Bot{
+		sender:           sender,
+		subs:             subs,
+		weather:          weatherService,
+		username:         username,
+		secret:           secret,
+		subscribeLimiter: ratelimit.NewLimiter(subscribeLimit, subscribeWindow),
+	}
+}
+
+// Receives updates pushed by Telegram. Every authenticated update is
+// acknowledged, Telegram would otherwise keep redelivering it
+func (b *Bot) WebhookHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	}
+
+	if b.secret == "" || subtle.ConstantTimeCompare([]byte(req.Header.Get(SecretHeader)), []byte(b.secret)) != 1 {
+		problem.Write(w, http.StatusUnauthorized, problem.CodeUnauthorized, "Secret token is missing or invalid")
+		return
+	}
+
+	var update Update
+	if err := json.NewDecoder(req.Body).Decode(&update); err != nil {
+		log.Printf("Invalid telegram update: %s\n", err.Error())
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	b.HandleUpdate(update)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+type LinkResponse struct {
+	URL string `json:"url"`
+}
+
+// POST /api/telegram/link/{token} returns a deep link that opens the bot and
+// links the chat to the subscription. The token is the unsubscribe token
+func (b *Bot) LinkHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	}
+
+	linkToken, err := b.subs.RequestTelegramLink(strings.TrimPrefix(req.URL.Path, linkPath))
+	if err != nil {
+		subscription.TokenProblems.Write(w, err)
+		return
+	}
+
+	response := LinkResponse{URL: DeepLink(b.username, linkToken)}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
+
+// Link that opens a chat with the bot and sends "/start <payload>"
+func DeepLink(botUsername, payload string) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s", botUsername, url.QueryEscape(payload))
+}
+
+func (b *Bot) HandleUpdate(update Update) {
+	if member := update.MyChatMember; member != nil {
+		// Blocked bots can't deliver anything, so the link is dropped
+		if member.NewChatMember.Status == "kicked" {
+			err := b.subs.UnlinkTelegramChat(member.Chat.ID, audit.Meta{UserAgent: botUserAgent})
+			if err != nil && !errors.Is(err, subscription.ErrChatNotLinked) {
+				log.Printf("Failed to unlink blocked telegram chat %d: %s\n", member.Chat.ID, err.Error())
+			}
+		}
+		return
+	}
+
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	reply := b.handleCommand(update.Message)
+
+	if err := b.sender.SendMessage(update.Message.Chat.ID, reply); err != nil {
+		log.Printf("Failed to reply to telegram chat %d: %s\n", update.Message.Chat.ID, err.Error())
+	}
+}
+
+// Runs the command in the message and returns the reply
+func (b *Bot) handleCommand(message *Message) string {
+	command, args := parseCommand(message.Text)
+	meta := audit.Meta{UserAgent: botUserAgent}
+
+	switch command {
+	case "/start":
+		if args == "" {
+			return helpMessage
+		}
+		return b.link(message, args, meta)
+	case "/weather":
+		return b.currentWeather(args)
+	case "/subscribe":
+		return b.subscribe(message, args, meta)
+	case "/stop":
+		return b.unlink(message, meta)
+	default:
+		return helpMessage
+	}
+}
+
+func (b *Bot) link(message *Message, tokenValue string, meta audit.Meta) string {
+	err := b.subs.LinkTelegramChat(tokenValue, message.Chat.ID, chatUsername(message), meta)
+
+	switch {
+	case err == nil:
+		return linkedMessage
+	case errors.Is(err, subscription.ErrTelegramLinkExpired):
+		return linkExpiredMessage
+	case errors.Is(err, subscription.ErrTokenNotFound), errors.Is(err, subscription.ErrTokenWrongType):
+		return linkInvalidMessage
+	default:
+		log.Println(err.Error())
+		return errorMessage
+	}
+}
+
+func (b *Bot) currentWeather(city string) string {
+	if city == "" {
+		return weatherUsageMessage
+	}
+
+	data, err := b.weather.GetWeather(city)
+	if err != nil {
+		if errors.Is(err, weather.ErrCityNotFound) {
+			return cityNotFoundMessage
+		}
+
+		log.Println(err.Error())
+		return errorMessage
+	}
+
+	return weatherMessage(city, content.Render(city, *data, content.Default()))
+}
+
+func (b *Bot) subscribe(message *Message, args string, meta audit.Meta) string {
+	email, city, frequency, ok := parseSubscribeArgs(args)
+	if !ok {
+		return subscribeUsageMessage
+	}
+
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return subscribeUsageMessage
+	}
+
+	if allowed, _ := b.subscribeLimiter.Allow(strconv.FormatInt(message.Chat.ID, 10), time.Now()); !allowed {
+		return tooManyRequestsMessage
+	}
+
+	err = b.subs.SubscribeTelegramChat(email, city, schedule.Schedule{Frequency: frequency},
+		message.Chat.ID, chatUsername(message), meta)
+
+	switch {
+	case err == nil:
+		return subscribedMessage(email)
+	case errors.Is(err, subscription.ErrConfirmationMailError):
+		// The chat is linked, only the mail is missing
+		log.Println(err.Error())
+		return confirmationDelayedMessage(email)
+	case errors.Is(err, subscription.ErrUserAlreadyExists):
+		return alreadySubscribedMessage
+	case errors.Is(err, subscription.ErrEmailSuppressed),
+		errors.Is(err, emailaddr.ErrDisposableDomain),
+		errors.Is(err, emailaddr.ErrNoMailServer):
+		return rejectedAddressMessage
+	default:
+		log.Println(err.Error())
+		return errorMessage
+	}
+}
+
+func (b *Bot) unlink(message *Message, meta audit.Meta) string {
+	err := b.subs.UnlinkTelegramChat(message.Chat.ID, meta)
+
+	switch {
+	case err == nil:
+		return unlinkedMessage
+	case errors.Is(err, subscription.ErrChatNotLinked):
+		return notLinkedMessage
+	default:
+		log.Println(err.Error())
+		return errorMessage
+	}
+}
+
+// Splits "/command@BotName arguments" into "/command" and "arguments"
+func parseCommand(text string) (string, string) {
+	command, args, _ := strings.Cut(strings.TrimSpace(text), " ")
+	command, _, _ = strings.Cut(command, "@")
+
+	return strings.ToLower(command), strings.TrimSpace(args)
+}
+
+// Parses "email city [hourly|daily]". The city may have several words,
+// the frequency defaults to daily
+func parseSubscribeArgs(args string) (email, city, frequency string, ok bool) {
+	fields := strings.Fields(args)
+	if len(fields) < 2 {
+		return "", "", "", false
+	}
+
+	frequency = schedule.FrequencyDaily
+
+	last := strings.ToLower(fields[len(fields)-1])
+	if last == schedule.FrequencyHourly || last == schedule.FrequencyDaily {
+		frequency = last
+		fields = fields[:len(fields)-1]
+	}
+
+	if len(fields) < 2 {
+		return "", "", "", false
+	}
+
+	return fields[0], strings.Join(fields[1:], " "), frequency, true
+}
+
+func chatUsername(message *Message) string {
+	if message.Chat.Username != "" {
+		return message.Chat.Username
+	}
+	if message.From != nil {
+		return message.From.Username
+	}
+
+	return ""
+}
@@ -0,0 +1,284 @@
+package telegram_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"weather-app/internal/audit"
+	"weather-app/internal/schedule"
+	"weather-app/internal/subscription"
+	"weather-app/internal/telegram"
+	"weather-app/internal/weather"
+)
+
+const webhookSecret = "webhook-secret"
+
+type mockSubscriptionService struct {
+	RequestTelegramLinkFunc   func(tokenValue string) (string, error)
+	LinkTelegramChatFunc      func(tokenValue string, chatID int64, username string, meta audit.Meta) error
+	SubscribeTelegramChatFunc func(email, city string, sched schedule.Schedule, chatID int64, username string, meta audit.Meta) error
+	UnlinkTelegramChatFunc    func(chatID int64, meta audit.Meta) error
+}
+
+func (m *mockSubscriptionService) RequestTelegramLink(tokenValue string) (string, error) {
+	return m.RequestTelegramLinkFunc(tokenValue)
+}
+
+func (m *mockSubscriptionService) LinkTelegramChat(tokenValue string, chatID int64, username string, meta audit.Meta) error {
+	return m.LinkTelegramChatFunc(tokenValue, chatID, username, meta)
+}
+
+func (m *mockSubscriptionService) SubscribeTelegramChat(email, city string, sched schedule.Schedule, chatID int64, username string, meta audit.Meta) error {
+	return m.SubscribeTelegramChatFunc(email, city, sched, chatID, username, meta)
+}
+
+func (m *mockSubscriptionService) UnlinkTelegramChat(chatID int64, meta audit.Meta) error {
+	return m.UnlinkTelegramChatFunc(chatID, meta)
+}
+
+type mockWeather struct{}
+
+func (mockWeather) GetWeather(city string) (*weather.WeatherData, error) {
+	if city == "Atlantis" {
+		return nil, weather.ErrCityNotFound
+	}
+	return &weather.WeatherData{Temperature: 18, Humidity: 55, Description: "light rain"}, nil
+}
+
+// Posts the update to the bot's webhook like Telegram does
+func deliver(t *testing.T, bot *telegram.Bot, update string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/api/telegram/webhook", strings.NewReader(update))
+	req.Header.Set(telegram.SecretHeader, webhookSecret)
+	w := httptest.NewRecorder()
+
+	bot.WebhookHandler(w, req)
+
+	return w
+}
+
+func message(chatID int, text string) string {
+	body, _ := json.Marshal(map[string]any{
+		"update_id": 1,
+		"message": map[string]any{
+			"message_id": 1,
+			"chat":       map[string]any{"id": chatID, "type": "private", "username": "alice"},
+			"text":       text,
+		},
+	})
+	return string(body)
+}
+
+func TestWebhookHandler_RejectsWrongSecret(t *testing.T) {
+	api := newFakeBotAPI(t)
+	bot := telegram.NewBot(api.client(), &mockSubscriptionService{}, mockWeather{}, "weather_bot", webhookSecret)
+
+	req := httptest.NewRequest("POST", "/api/telegram/webhook", strings.NewReader(message(1, "/weather Kyiv")))
+	req.Header.Set(telegram.SecretHeader, "guess")
+	w := httptest.NewRecorder()
+
+	bot.WebhookHandler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+	if len(api.sent()) != 0 {
+		t.Error("expected no reply to an unauthenticated update")
+	}
+}
+
+func TestBot_Weather(t *testing.T) {
+	api := newFakeBotAPI(t)
+	bot := telegram.NewBot(api.client(), &mockSubscriptionService{}, mockWeather{}, "weather_bot", webhookSecret)
+
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"/weather New York", "light rain"},
+		{"/weather@weather_bot Kyiv", "18.0°C"},
+		{"/weather Atlantis", "City not found"},
+		{"/weather", "Usage"},
+	}
+
+	for _, tc := range cases {
+		if w := deliver(t, bot, message(7, tc.text)); w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", tc.text, w.Code)
+		}
+
+		sent := api.sent()
+		last := sent[len(sent)-1]
+		if last.ChatID != 7 || !strings.Contains(last.Text, tc.want) {
+			t.Errorf("%s: expected reply containing %q, got %q", tc.text, tc.want, last.Text)
+		}
+	}
+}
+
+func TestBot_StartLinksChat(t *testing.T) {
+	api := newFakeBotAPI(t)
+
+	var gotToken, gotUsername string
+	var gotChat int64
+	subs := &mockSubscriptionService{
+		LinkTelegramChatFunc: func(tokenValue string, chatID int64, username string, meta audit.Meta) error {
+			gotToken, gotChat, gotUsername = tokenValue, chatID, username
+			if tokenValue == "old" {
+				return subscription.ErrTelegramLinkExpired
+			}
+			return nil
+		},
+	}
+	bot := telegram.NewBot(api.client(), subs, mockWeather{}, "weather_bot", webhookSecret)
+
+	deliver(t, bot, message(9, "/start abc123"))
+
+	if gotToken != "abc123" || gotChat != 9 || gotUsername != "alice" {
+		t.Errorf("unexpected link call: token %q chat %d username %q", gotToken, gotChat, gotUsername)
+	}
+	if sent := api.sent(); !strings.Contains(sent[len(sent)-1].Text, "linked") {
+		t.Errorf("expected linked reply, got %q", sent[len(sent)-1].Text)
+	}
+
+	deliver(t, bot, message(9, "/start old"))
+
+	if sent := api.sent(); !strings.Contains(sent[len(sent)-1].Text, "expired") {
+		t.Errorf("expected expired reply, got %q", sent[len(sent)-1].Text)
+	}
+}
+
+func TestBot_Subscribe(t *testing.T) {
+	api := newFakeBotAPI(t)
+
+	var gotEmail, gotCity string
+	var gotSchedule schedule.Schedule
+	subs := &mockSubscriptionService{
+		SubscribeTelegramChatFunc: func(email, city string, sched schedule.Schedule, chatID int64, username string, meta audit.Meta) error {
+			gotEmail, gotCity, gotSchedule = email, city, sched
+			if email == "taken@example.com" {
+				return subscription.ErrUserAlreadyExists
+			}
+			return nil
+		},
+	}
+	bot := telegram.NewBot(api.client(), subs, mockWeather{}, "weather_bot", webhookSecret)
+
+	deliver(t, bot, message(5, "/subscribe Alice@Example.com New York hourly"))
+
+	if gotEmail != "alice@example.com" || gotCity != "New York" || gotSchedule.Frequency != "hourly" {
+		t.Errorf("unexpected subscription %q %q %+v", gotEmail, gotCity, gotSchedule)
+	}
+	if sent := api.sent(); !strings.Contains(sent[len(sent)-1].Text, "confirm") {
+		t.Errorf("expected confirmation hint, got %q", sent[len(sent)-1].Text)
+	}
+
+	deliver(t, bot, message(5, "/subscribe taken@example.com Kyiv"))
+
+	if gotSchedule.Frequency != "daily" {
+		t.Errorf("expected daily default, got %q", gotSchedule.Frequency)
+	}
+	if sent := api.sent(); !strings.Contains(sent[len(sent)-1].Text, "already subscribed") {
+		t.Errorf("expected already subscribed reply, got %q", sent[len(sent)-1].Text)
+	}
+
+	// Two requests used, one left in the hour
+	deliver(t, bot, message(5, "/subscribe other@example.com Kyiv"))
+	deliver(t, bot, message(5, "/subscribe another@example.com Kyiv"))
+
+	if sent := api.sent(); !strings.Contains(sent[len(sent)-1].Text, "Too many requests") {
+		t.Errorf("expected rate limit reply, got %q", sent[len(sent)-1].Text)
+	}
+
+	deliver(t, bot, message(6, "/subscribe not-an-email"))
+
+	if sent := api.sent(); !strings.Contains(sent[len(sent)-1].Text, "Usage") {
+		t.Errorf("expected usage reply, got %q", sent[len(sent)-1].Text)
+	}
+}
+
+func TestBot_SubscribeMailDelayed(t *testing.T) {
+	api := newFakeBotAPI(t)
+
+	subs := &mockSubscriptionService{
+		SubscribeTelegramChatFunc: func(email, city string, sched schedule.Schedule, chatID int64, username string, meta audit.Meta) error {
+			return fmt.Errorf("%w: smtp down", subscription.ErrConfirmationMailError)
+		},
+	}
+	bot := telegram.NewBot(api.client(), subs, mockWeather{}, "weather_bot", webhookSecret)
+
+	deliver(t, bot, message(5, "/subscribe alice@example.com Kyiv"))
+
+	if sent := api.sent(); !strings.Contains(sent[len(sent)-1].Text, "may be delayed") {
+		t.Errorf("expected delayed mail reply, got %q", sent[len(sent)-1].Text)
+	}
+}
+
+func TestBot_StopAndBlock(t *testing.T) {
+	api := newFakeBotAPI(t)
+
+	var unlinked []int64
+	subs := &mockSubscriptionService{
+		UnlinkTelegramChatFunc: func(chatID int64, meta audit.Meta) error {
+			unlinked = append(unlinked, chatID)
+			if chatID == 4 {
+				return subscription.ErrChatNotLinked
+			}
+			return nil
+		},
+	}
+	bot := telegram.NewBot(api.client(), subs, mockWeather{}, "weather_bot", webhookSecret)
+
+	deliver(t, bot, message(3, "/stop"))
+	deliver(t, bot, message(4, "/stop"))
+
+	sent := api.sent()
+	if !strings.Contains(sent[0].Text, "stopped") || !strings.Contains(sent[1].Text, "not linked") {
+		t.Errorf("unexpected replies %+v", sent)
+	}
+
+	// Blocking the bot unlinks the chat without a reply
+	deliver(t, bot, `{"update_id": 2, "my_chat_member": {"chat": {"id": 8}, "new_chat_member": {"status": "kicked"}}}`)
+
+	if len(unlinked) != 3 || unlinked[2] != 8 {
+		t.Errorf("expected chat 8 to be unlinked, got %v", unlinked)
+	}
+	if len(api.sent()) != 2 {
+		t.Errorf("expected no message to a blocked chat")
+	}
+}
+
+func TestLinkHandler(t *testing.T) {
+	subs := &mockSubscriptionService{
+		RequestTelegramLinkFunc: func(tokenValue string) (string, error) {
+			if tokenValue != "unsub-token" {
+				return "", subscription.ErrTokenNotFound
+			}
+			return "link-token", nil
+		},
+	}
+	bot := telegram.NewBot(nil, subs, mockWeather{}, "weather_bot", webhookSecret)
+
+	w := httptest.NewRecorder()
+	bot.LinkHandler(w, httptest.NewRequest("POST", "/api/telegram/link/unsub-token", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var response telegram.LinkResponse
+	json.NewDecoder(w.Body).Decode(&response)
+
+	if response.URL != "https://t.me/weather_bot?start=link-token" {
+		t.Errorf("unexpected deep link %q", response.URL)
+	}
+
+	w = httptest.NewRecorder()
+	bot.LinkHandler(w, httptest.NewRequest("POST", "/api/telegram/link/wrong", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAPIURL = "https://api.telegram.org"
+
+	requestTimeout = 10 * time.Second
+)
+
+// Error returned by the Bot API, e.g. 403 when the user blocked the bot
+type APIError struct {
+	Code        int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram API error %d: %s", e.Code, e.Description)
+}
+
+// Minimal Bot API client, covers what the bot and the notifier use
+type Client struct {
+	apiURL string
+	token  string
+	client *http.Client
+}
+
+// Empty apiURL uses DefaultAPIURL, nil client one with a 10 second timeout
+func NewClient(apiURL, token string, client *http.Client) *Client {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+
+	return &Client{
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		token:  token,
+		client: client,
+	}
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
+
+func (c *Client) call(method string, params any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/%s", c.apiURL, c.token, method)
+
+	resp, err := c.client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL carries the bot token, keep it out of logs
+		return fmt.Errorf("telegram %s request failed: %w", method, unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode telegram %s response (status %d): %w", method, resp.StatusCode, err)
+	}
+
+	if !result.OK {
+		return &APIError{Code: result.ErrorCode, Description: result.Description}
+	}
+
+	return nil
+}
+
+// Sends an HTML formatted message
+func (c *Client) SendMessage(chatID int64, text string) error {
+	return c.call("sendMessage", map[string]any{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	})
+}
+
+// Points the bot's updates at webhookURL. Telegram sends secret in the
+// X-Telegram-Bot-Api-Secret-Token header of every update
+func (c *Client) SetWebhook(webhookURL, secret string) error {
+	return c.call("setWebhook", map[string]any{
+		"url":             webhookURL,
+		"secret_token":    secret,
+		"allowed_updates": []string{"message", "my_chat_member"},
+	})
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+
+	return err
+}
+
+// Incoming update, only the fields the bot reads
+type Update struct {
+	UpdateID     int64              `json:"update_id"`
+	Message      *Message           `json:"message"`
+	MyChatMember *ChatMemberUpdated `json:"my_chat_member"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	From      *User  `json:"from"`
+	Text      string `json:"text"`
+}
+
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Username string `json:"username"`
+}
+
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// Sent when the bot is added to, blocked in or removed from a chat
+type ChatMemberUpdated struct {
+	Chat          Chat `json:"chat"`
+	NewChatMember struct {
+		Status string `json:"status"` // "kicked" once the user blocks the bot
+	} `json:"new_chat_member"`
+}
@@ -0,0 +1,163 @@
+package telegram_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"weather-app/internal/content"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/notify"
+	"weather-app/internal/telegram"
+	"weather-app/internal/weather"
+)
+
+const testToken = "123:secret"
+
+type sentMessage struct {
+	ChatID int64  `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+// Fake Bot API that records sendMessage calls. Chats listed in blocked
+// answer like Telegram does for users who blocked the bot
+type fakeBotAPI struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	messages []sentMessage
+	methods  []string
+	blocked  map[int64]bool
+}
+
+func newFakeBotAPI(t *testing.T) *fakeBotAPI {
+	api := &fakeBotAPI{blocked: map[int64]bool{}}
+
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, ok := strings.CutPrefix(r.URL.Path, "/bot"+testToken+"/")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": 404, "description": "Not Found"})
+			return
+		}
+
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		api.methods = append(api.methods, method)
+
+		if method == "sendMessage" {
+			var msg sentMessage
+			json.NewDecoder(r.Body).Decode(&msg)
+
+			if api.blocked[msg.ChatID] {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"})
+				return
+			}
+
+			api.messages = append(api.messages, msg)
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": true})
+	}))
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+func (api *fakeBotAPI) client() *telegram.Client {
+	return telegram.NewClient(api.URL, testToken, api.Server.Client())
+}
+
+func (api *fakeBotAPI) sent() []sentMessage {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return append([]sentMessage(nil), api.messages...)
+}
+
+func TestClient_SendMessage(t *testing.T) {
+	api := newFakeBotAPI(t)
+
+	if err := api.client().SendMessage(42, "hello"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := api.sent()
+	if len(sent) != 1 || sent[0].ChatID != 42 || sent[0].Text != "hello" {
+		t.Errorf("unexpected messages %+v", sent)
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	api := newFakeBotAPI(t)
+	api.blocked[42] = true
+
+	err := api.client().SendMessage(42, "hello")
+
+	var apiErr *telegram.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 API error, got %v", err)
+	}
+}
+
+func TestClient_SetWebhook(t *testing.T) {
+	api := newFakeBotAPI(t)
+
+	if err := api.client().SetWebhook("https://example.com/api/telegram/webhook", "s3cret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(api.methods) != 1 || api.methods[0] != "setWebhook" {
+		t.Errorf("expected setWebhook call, got %v", api.methods)
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	api := newFakeBotAPI(t)
+	notifier := telegram.NewNotifier(api.client())
+
+	update := notify.Update{
+		City:    "Kyiv",
+		Weather: weather.WeatherData{Temperature: 21.5, Humidity: 40, Description: "clear sky"},
+	}
+
+	if err := notifier.Notify(repository.ChannelRecipient{Target: "42"}, update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := api.sent()
+	if len(sent) != 1 || sent[0].ChatID != 42 {
+		t.Fatalf("expected one message to chat 42, got %+v", sent)
+	}
+	for _, want := range []string{"Kyiv", "21.5°C", "clear sky", "40%", "/stop"} {
+		if !strings.Contains(sent[0].Text, want) {
+			t.Errorf("expected message to contain %q, got %q", want, sent[0].Text)
+		}
+	}
+
+	if err := notifier.Notify(repository.ChannelRecipient{Target: "not-a-chat"}, update); err == nil {
+		t.Error("expected error for invalid chat id")
+	}
+}
+
+func TestNotifier_NotifyPreferences(t *testing.T) {
+	api := newFakeBotAPI(t)
+	notifier := telegram.NewNotifier(api.client())
+
+	update := notify.Update{
+		City:        "Kyiv",
+		Weather:     weather.WeatherData{Temperature: 21.5, Humidity: 40, Description: "ясно", Pressure: 1012},
+		Preferences: content.Preferences{Fields: []string{content.FieldPressure}, Language: content.LanguageUkrainian},
+	}
+
+	if err := notifier.Notify(repository.ChannelRecipient{Target: "42"}, update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := api.sent()
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Text, "<b>Оновлення погоди для Kyiv</b>\n21.5°C, ясно\nВологість: 40%\nТиск: 1012 гПа") {
+		t.Errorf("unexpected message %+v", sent)
+	}
+}
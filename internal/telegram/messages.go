@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"weather-app/internal/content"
+	"weather-app/internal/notify"
+)
+
+const (
+	helpMessage = "Commands:\n" +
+		"/weather <i>city</i> - current weather\n" +
+		"/subscribe <i>email city</i> [hourly|daily] - get updates here and by email\n" +
+		"/stop - stop updates in this chat"
+
+	linkedMessage            = "This chat is linked to your subscription, updates will arrive here. Send /stop to unlink it."
+	linkExpiredMessage       = "This link has expired, please request a new one."
+	linkInvalidMessage       = "This link is not valid. " + helpMessage
+	unlinkedMessage          = "Updates to this chat are stopped. Your email subscription is not changed."
+	notLinkedMessage         = "This chat is not linked to a subscription."
+	weatherUsageMessage      = "Usage: /weather <i>city</i>"
+	cityNotFoundMessage      = "City not found."
+	subscribeUsageMessage    = "Usage: /subscribe <i>email city</i> [hourly|daily]"
+	alreadySubscribedMessage = "This address is already subscribed. Open the Telegram link from your subscription settings to connect this chat."
+	rejectedAddressMessage   = "This address can't be subscribed."
+	tooManyRequestsMessage   = "Too many requests, please try again later."
+	errorMessage             = "Something went wrong, please try again later."
+)
+
+func weatherMessage(heading string, report content.Report) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "<b>%s</b>\n%s, %s\n%s: %s", html.EscapeString(heading), report.Temperature,
+		html.EscapeString(report.Description), html.EscapeString(report.Text.Humidity), report.Humidity)
+	for _, line := range report.Details {
+		fmt.Fprintf(&b, "\n%s: %s", html.EscapeString(line.Label), html.EscapeString(line.Value))
+	}
+
+	return b.String()
+}
+
+func updateMessage(update notify.Update) string {
+	report := update.Report()
+
+	return weatherMessage(report.Title, report) + "\n\nSend /stop to stop updates in this chat."
+}
+
+func subscribedMessage(email string) string {
+	return fmt.Sprintf("Almost done: confirm the subscription from the email sent to %s. Updates will arrive here afterwards.",
+		html.EscapeString(email))
+}
+
+func confirmationDelayedMessage(email string) string {
+	return fmt.Sprintf("Almost done: the confirmation email to %s may be delayed. Confirm the subscription from it, updates will arrive here afterwards.",
+		html.EscapeString(email))
+}
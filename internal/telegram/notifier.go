@@ -0,0 +1,34 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/notify"
+)
+
+// Recorded as delivery channel
+const ChannelName = "telegram"
+
+type MessageSenderInterface interface {
+	SendMessage(chatID int64, text string) error
+}
+
+// Delivers weather updates to linked chats
+type Notifier struct {
+	sender MessageSenderInterface
+}
+
+func NewNotifier(sender MessageSenderInterface) *Notifier {
+	return &Notifier{sender: sender}
+}
+
+// Recipient's target is the chat ID
+func (n *Notifier) Notify(recipient repository.ChannelRecipient, update notify.Update) error {
+	chatID, err := strconv.ParseInt(recipient.Target, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid telegram chat id %q: %w", recipient.Target, err)
+	}
+
+	return n.sender.SendMessage(chatID, updateMessage(update))
+}
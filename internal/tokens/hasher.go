@@ -0,0 +1,57 @@
+package tokens
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const minKeyLength = 32
+
+var ErrKeyTooShort = errors.New("token hash key must be at least 32 bytes")
+
+// Keyed hashing for link tokens. Only hashes are stored, plaintext values are
+// derived from the token ID, so they can be rebuilt for outgoing emails by
+// anyone holding the key, and by nobody with only database access.
+// Changing the key invalidates every issued token
+type Hasher struct {
+	key []byte
+}
+
+func NewHasher(key string) (*Hasher, error) {
+	if len(key) < minKeyLength {
+		return nil, ErrKeyTooShort
+	}
+
+	return &Hasher{key: []byte(key)}, nil
+}
+
+func (h *Hasher) mac(label, data string) []byte {
+	m := hmac.New(sha256.New, h.key)
+	m.Write([]byte(label))
+	m.Write([]byte{0})
+	m.Write([]byte(data))
+
+	return m.Sum(nil)
+}
+
+// Stored form of a token value
+func (h *Hasher) Hash(value string) string {
+	return hex.EncodeToString(h.mac("hash", value))
+}
+
+// Plaintext value of the token with the given ID, as sent in links
+func (h *Hasher) Value(tokenID uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(h.mac("value", tokenID.String()))
+}
+
+// Fingerprint of an address for suppression tombstones and erased audit
+// events. Keyed, so a list of addresses can't be matched against it
+func (h *Hasher) EmailHash(email string) string {
+	return hex.EncodeToString(h.mac("email", strings.ToLower(strings.TrimSpace(email))))
+}
@@ -0,0 +1,63 @@
+package tokens_test
+
+import (
+	"strings"
+	"testing"
+	"weather-app/internal/tokens"
+
+	"github.com/google/uuid"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+func TestNewHasher_ShortKey(t *testing.T) {
+	if _, err := tokens.NewHasher("short"); err != tokens.ErrKeyTooShort {
+		t.Errorf("expected ErrKeyTooShort, got %v", err)
+	}
+}
+
+func TestHasher_ValueIsStableAndKeyed(t *testing.T) {
+	h1, _ := tokens.NewHasher(testKey)
+	h2, _ := tokens.NewHasher(strings.Repeat("x", 32))
+
+	id := uuid.New()
+
+	if h1.Value(id) != h1.Value(id) {
+		t.Error("expected derived value to be stable")
+	}
+	if h1.Value(id) == h2.Value(id) {
+		t.Error("expected derived value to depend on the key")
+	}
+	if h1.Value(id) == h1.Value(uuid.New()) {
+		t.Error("expected derived value to depend on the token ID")
+	}
+}
+
+func TestHasher_HashDoesNotLeakValue(t *testing.T) {
+	h, _ := tokens.NewHasher(testKey)
+
+	value := h.Value(uuid.New())
+	hash := h.Hash(value)
+
+	if hash == value || strings.Contains(hash, value) {
+		t.Error("expected hash to differ from value")
+	}
+	if h.Hash(value) != hash {
+		t.Error("expected hash to be deterministic")
+	}
+}
+
+func TestHasher_EmailHash(t *testing.T) {
+	h1, _ := tokens.NewHasher(testKey)
+	h2, _ := tokens.NewHasher(strings.Repeat("x", 32))
+
+	if h1.EmailHash(" User@Example.com ") != h1.EmailHash("user@example.com") {
+		t.Error("expected spellings of the same address to match")
+	}
+	if h1.EmailHash("user@example.com") == h2.EmailHash("user@example.com") {
+		t.Error("expected the hash to depend on the key")
+	}
+	if h1.EmailHash("user@example.com") == h1.Hash("user@example.com") {
+		t.Error("expected email hashes to be separate from token hashes")
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 type CacheItem struct {
-	Data      *weather.WeatherData
+	Report    *weather.Report
 	ExpiresAt time.Time
 }
 
@@ -24,7 +24,7 @@ func NewWeatherCache(ttl time.Duration) *WeatherCache {
 	}
 }
 
-func (c *WeatherCache) Get(city string) (*weather.WeatherData, bool) {
+func (c *WeatherCache) Get(city string) (*weather.Report, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -33,15 +33,15 @@ func (c *WeatherCache) Get(city string) (*weather.WeatherData, bool) {
 		return nil, false
 	}
 
-	return item.Data, true
+	return item.Report, true
 }
 
-func (c *WeatherCache) Set(city string, data *weather.WeatherData) {
+func (c *WeatherCache) Set(city string, report *weather.Report) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.store[city] = &CacheItem{
-		Data:      data,
+		Report:    report,
 		ExpiresAt: time.Now().Add(c.ttl),
 	}
 }
@@ -10,11 +10,11 @@ import (
 func TestWeatherCache_SetAndGet(t *testing.T) {
 	weatherCache := cache.NewWeatherCache(1 * time.Minute)
 
-	expected := &weather.WeatherData{
+	expected := &weather.Report{Data: weather.WeatherData{
 		Temperature: 20.5,
 		Humidity:    80,
 		Description: "Cloudy",
-	}
+	}}
 
 	weatherCache.Set("Kyiv", expected)
 
@@ -23,9 +23,9 @@ func TestWeatherCache_SetAndGet(t *testing.T) {
 		t.Fatal("expected weatherCache hit but got miss")
 	}
 
-	if result.Temperature != expected.Temperature ||
-		result.Humidity != expected.Humidity ||
-		result.Description != expected.Description {
+	if result.Data.Temperature != expected.Data.Temperature ||
+		result.Data.Humidity != expected.Data.Humidity ||
+		result.Data.Description != expected.Data.Description {
 		t.Errorf("got %+v, want %+v", result, expected)
 	}
 }
@@ -33,7 +33,7 @@ func TestWeatherCache_SetAndGet(t *testing.T) {
 func TestWeatherCache_ExpiredEntry(t *testing.T) {
 	weatherCache := cache.NewWeatherCache(10 * time.Millisecond)
 
-	weatherCache.Set("Lviv", &weather.WeatherData{Temperature: 18})
+	weatherCache.Set("Lviv", &weather.Report{Data: weather.WeatherData{Temperature: 18}})
 	time.Sleep(20 * time.Millisecond)
 
 	_, ok := weatherCache.Get("Lviv")
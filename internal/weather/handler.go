@@ -5,12 +5,28 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"weather-app/internal/problem"
 )
 
-const (
-	GenericErrorMsg = "Something went wrong"
+var (
+	ErrEmptyCity       = errors.New("city parameter is empty")
+	ErrInvalidLanguage = errors.New("lang parameter is invalid")
 )
 
+var problems = problem.Mappings{
+	{Err: ErrEmptyCity, Status: http.StatusBadRequest, Code: "empty_city"},
+	{Err: ErrInvalidLanguage, Status: http.StatusBadRequest, Code: "invalid_lang"},
+	{Err: ErrCityNotFound, Status: http.StatusNotFound, Code: "city_not_found"},
+}
+
+// Body of /api/weather. Update messages use more of WeatherData, the public
+// response keeps its original fields
+type CurrentWeather struct {
+	Temperature float64 `json:"temperature"`
+	Humidity    int     `json:"humidity"`
+	Description string  `json:"description"`
+}
+
 type WeatherHandler struct {
 	service WeatherServiceInterface
 }
@@ -21,7 +37,7 @@ func NewHandler(svc WeatherServiceInterface) *WeatherHandler {
 
 func (wh *WeatherHandler) Handler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != "GET" {
-		http.Error(w, "Unsupported method", http.StatusBadRequest)
+		problem.MethodNotAllowed(w, req, "GET")
 		return
 	}
 
@@ -29,27 +45,32 @@ func (wh *WeatherHandler) Handler(w http.ResponseWriter, req *http.Request) {
 
 	city := query.Get("city")
 	if city == "" {
-		http.Error(w, "City parameter is empty", http.StatusBadRequest)
+		problems.Write(w, ErrEmptyCity)
 		return
 	}
 
-	weatherData, err := wh.service.GetWeather(city)
-	if err != nil {
-		if errors.Is(err, ErrCityNotFound) {
-			http.Error(w, ErrCityNotFound.Error(), http.StatusNotFound)
-
-		} else {
-			http.Error(w, GenericErrorMsg, http.StatusInternalServerError)
-		}
+	// Optional, translates the description
+	lang := query.Get("lang")
+	if lang != "" && !IsValidLanguage(lang) {
+		problems.Write(w, ErrInvalidLanguage)
+		return
+	}
 
+	weatherData, err := wh.service.GetLocalizedWeather(city, lang)
+	if err != nil {
+		problems.Write(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	if err = json.NewEncoder(w).Encode(weatherData); err != nil {
-		log.Printf("Encoding error %s", err.Error())
+	current := CurrentWeather{
+		Temperature: weatherData.Temperature,
+		Humidity:    weatherData.Humidity,
+		Description: weatherData.Description,
+	}
 
-		http.Error(w, "Encoding error", http.StatusInternalServerError)
+	if err = json.NewEncoder(w).Encode(current); err != nil {
+		log.Printf("Encoding error %s", err.Error())
 	}
 }
@@ -7,14 +7,17 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"weather-app/internal/problem"
 	"weather-app/internal/weather"
 )
 
 type MockWeatherService struct {
 	GetWeatherFunc func(city string) (*weather.WeatherData, error)
+	LastLang       string
 }
 
-func (m *MockWeatherService) GetWeather(city string) (*weather.WeatherData, error) {
+func (m *MockWeatherService) GetLocalizedWeather(city, lang string) (*weather.WeatherData, error) {
+	m.LastLang = lang
 	return m.GetWeatherFunc(city)
 }
 
@@ -71,8 +74,17 @@ func TestWeatherHandler_CityNotFound(t *testing.T) {
 		t.Errorf("expected 404, got %d", rec.Code)
 	}
 
-	if !strings.Contains(rec.Body.String(), "city not found") {
-		t.Errorf("expected 'city not found' error, got %s", rec.Body.String())
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("expected problem content type, got %q", ct)
+	}
+
+	var details problem.Details
+	if err := json.NewDecoder(rec.Body).Decode(&details); err != nil {
+		t.Fatalf("invalid problem body: %v", err)
+	}
+
+	if details.Code != "city_not_found" || details.Status != http.StatusNotFound || details.Detail != "city not found" {
+		t.Errorf("unexpected problem %+v", details)
 	}
 }
 
@@ -94,8 +106,8 @@ func TestWeatherHandler_InternalError(t *testing.T) {
 		t.Errorf("expected 500, got %d", rec.Code)
 	}
 
-	if !strings.Contains(rec.Body.String(), weather.GenericErrorMsg) {
-		t.Errorf("expected generic error message, got %s", rec.Body.String())
+	if !strings.Contains(rec.Body.String(), problem.CodeInternal) || strings.Contains(rec.Body.String(), "DB timeout") {
+		t.Errorf("expected generic error, got %s", rec.Body.String())
 	}
 }
 
@@ -113,7 +125,7 @@ func TestWeatherHandler_MissingCity(t *testing.T) {
 		t.Errorf("expected 400, got %d", rec.Code)
 	}
 
-	if !strings.Contains(rec.Body.String(), "City parameter is empty") {
+	if !strings.Contains(rec.Body.String(), `"code":"empty_city"`) {
 		t.Errorf("expected missing city error, got %s", rec.Body.String())
 	}
 }
@@ -128,11 +140,67 @@ func TestWeatherHandler_UnsupportedMethod(t *testing.T) {
 
 	handler.Handler(rec, req)
 
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+
+	if allow := rec.Header().Get("Allow"); allow != "GET" {
+		t.Errorf("expected Allow GET, got %q", allow)
+	}
+
+	if !strings.Contains(rec.Body.String(), "Unsupported method POST") {
+		t.Errorf("expected method error, got %s", rec.Body.String())
+	}
+}
+
+func TestWeatherHandler_Language(t *testing.T) {
+	mockSvc := &MockWeatherService{
+		GetWeatherFunc: func(city string) (*weather.WeatherData, error) {
+			return &weather.WeatherData{Description: "ясно"}, nil
+		},
+	}
+
+	handler := weather.NewHandler(mockSvc)
+
+	req := httptest.NewRequest(http.MethodGet, "/weather?city=Kyiv&lang=uk", nil)
+	rec := httptest.NewRecorder()
+
+	handler.Handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	if mockSvc.LastLang != "uk" {
+		t.Errorf("expected lang uk to reach the service, got %q", mockSvc.LastLang)
+	}
+}
+
+func TestWeatherHandler_InvalidLanguage(t *testing.T) {
+	handler := weather.NewHandler(&MockWeatherService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/weather?city=Kyiv&lang=../etc", nil)
+	rec := httptest.NewRecorder()
+
+	handler.Handler(rec, req)
+
 	if rec.Code != http.StatusBadRequest {
 		t.Errorf("expected 400, got %d", rec.Code)
 	}
+}
 
-	if !strings.Contains(rec.Body.String(), "Unsupported method") {
-		t.Errorf("expected method error, got %s", rec.Body.String())
+func TestWeatherHandler_ResponseShape(t *testing.T) {
+	mockSvc := &MockWeatherService{
+		GetWeatherFunc: func(city string) (*weather.WeatherData, error) {
+			return &weather.WeatherData{Temperature: 21.5, Humidity: 40, Description: "clear sky", FeelsLike: 20.9, Pressure: 1012}, nil
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	weather.NewHandler(mockSvc).Handler(rec, httptest.NewRequest(http.MethodGet, "/weather?city=Kyiv", nil))
+
+	expected := `{"temperature":21.5,"humidity":40,"description":"clear sky"}`
+	if body := strings.TrimSpace(rec.Body.String()); body != expected {
+		t.Errorf("expected %s, got %s", expected, body)
 	}
 }
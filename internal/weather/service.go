@@ -7,7 +7,11 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
+	"time"
 )
 
 type HTTPClient interface {
@@ -21,12 +25,12 @@ type WeatherService struct {
 }
 
 type WeatherServiceInterface interface {
-	GetWeather(city string) (*WeatherData, error)
+	GetLocalizedWeather(city, lang string) (*WeatherData, error)
 }
 
 type WeatherCacheInterface interface {
-	Get(city string) (*WeatherData, bool)
-	Set(city string, data *WeatherData)
+	Get(city string) (*Report, bool)
+	Set(city string, report *Report)
 }
 
 func NewWeatherService(client HTTPClient, apiKey string, weatherCache WeatherCacheInterface) *WeatherService {
@@ -42,40 +46,173 @@ func NewWeatherService(client HTTPClient, apiKey string, weatherCache WeatherCac
 }
 
 type WeatherResponse struct {
+	Name  string `json:"name"` // The provider's name of the matched city
+	Dt    int64  `json:"dt"`   // Observation time, Unix
+	Coord struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	} `json:"coord"`
 	Main struct {
-		Temp     float64 `json:"temp"`
-		Humidity int     `json:"humidity"`
+		Temp      float64 `json:"temp"`
+		FeelsLike float64 `json:"feels_like"`
+		Pressure  int     `json:"pressure"`
+		Humidity  int     `json:"humidity"`
 	} `json:"main"`
 	Weather []struct {
 		Description string `json:"description"`
 	} `json:"weather"`
+	Wind struct {
+		Speed float64 `json:"speed"`
+	} `json:"wind"`
+	Sys struct {
+		Country string `json:"country"`
+		Sunrise int64  `json:"sunrise"`
+		Sunset  int64  `json:"sunset"`
+	} `json:"sys"`
+	Timezone int `json:"timezone"` // Shift from UTC in seconds
 }
 
+// 3-hour steps of the forecast API
+type ForecastResponse struct {
+	List []struct {
+		Main struct {
+			TempMin float64 `json:"temp_min"`
+			TempMax float64 `json:"temp_max"`
+		} `json:"main"`
+		Weather []struct {
+			Description string `json:"description"`
+		} `json:"weather"`
+	} `json:"list"`
+}
+
+type AirPollutionResponse struct {
+	List []struct {
+		Main struct {
+			AQI int `json:"aqi"`
+		} `json:"main"`
+	} `json:"list"`
+}
+
+// Metric units, the description is in the requested language
 type WeatherData struct {
 	Temperature float64 `json:"temperature"`
 	Humidity    int     `json:"humidity"`
 	Description string  `json:"description"`
+
+	FeelsLike float64 `json:"feels_like"`
+	Pressure  int     `json:"pressure"`          // hPa
+	WindSpeed float64 `json:"wind_speed"`        // m/s
+	Sunrise   int64   `json:"sunrise,omitempty"` // Unix time
+	Sunset    int64   `json:"sunset,omitempty"`
+	UTCOffset int     `json:"utc_offset"` // City's shift from UTC in seconds
+
+	// Only set when requested with Extras and the APIs are configured
+	Forecast   Forecast `json:"forecast,omitzero"`
+	AirQuality int      `json:"air_quality,omitempty"` // 1 (good) to 5 (very poor)
+}
+
+// Summary of the next 24 hours
+type Forecast struct {
+	Low         float64 `json:"low"`
+	High        float64 `json:"high"`
+	Description string  `json:"description"` // Most frequent condition
+}
+
+// Data beyond the current weather. Each one costs another request to the
+// provider, so only updates whose preferences show it ask for it
+type Extras struct {
+	Forecast   bool
+	AirQuality bool
+}
+
+// Source of the data in reports
+const Provider = "openweathermap"
+
+// City the provider matched the query to
+type Location struct {
+	Name    string
+	Country string // ISO 3166 code
+	Lat     float64
+	Lon     float64
+}
+
+// Weather with where, when and how it was obtained
+type Report struct {
+	Data       WeatherData
+	Location   Location
+	ObservedAt time.Time // Measured by the provider, zero when unknown
+	FetchedAt  time.Time // Received from the provider
+	Source     string
+	Cached     bool // Served from the cache instead of the provider
 }
 
 var ErrCityNotFound = errors.New("city not found")
 
-func (ws *WeatherService) callWeatherAPI(city string) (*WeatherResponse, error) {
+// Current weather API, e.g. ".../weather?q=%s&appid=%s&units=metric"
+func (ws *WeatherService) callWeatherAPI(city, lang string) (*WeatherResponse, error) {
 	var result WeatherResponse
 
 	var api_addres = os.Getenv("WEATHER_API_ADDRESS")
 
 	url := fmt.Sprintf(api_addres, city, ws.apiKey)
 
+	if err := ws.fetch(withLanguage(url, lang), &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Optional forecast API, e.g. ".../forecast?q=%s&appid=%s&units=metric&cnt=8".
+// Returns nil when not configured
+func (ws *WeatherService) callForecastAPI(city, lang string) (*ForecastResponse, error) {
+	api_addres := os.Getenv("WEATHER_FORECAST_API_ADDRESS")
+	if api_addres == "" {
+		return nil, nil
+	}
+
+	var result ForecastResponse
+
+	url := fmt.Sprintf(api_addres, city, ws.apiKey)
+
+	if err := ws.fetch(withLanguage(url, lang), &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Optional air pollution API, e.g. ".../air_pollution?lat=%s&lon=%s&appid=%s".
+// Returns nil when not configured
+func (ws *WeatherService) callAirPollutionAPI(lat, lon float64) (*AirPollutionResponse, error) {
+	api_addres := os.Getenv("WEATHER_AIR_API_ADDRESS")
+	if api_addres == "" {
+		return nil, nil
+	}
+
+	var result AirPollutionResponse
+
+	url := fmt.Sprintf(api_addres,
+		strconv.FormatFloat(lat, 'f', -1, 64), strconv.FormatFloat(lon, 'f', -1, 64), ws.apiKey)
+
+	if err := ws.fetch(url, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (ws *WeatherService) fetch(url string, result any) error {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	resp, err := ws.client.Do(req)
 	if err != nil {
 		log.Printf("failed to fetch weather: %s\n", err.Error())
 
-		return nil, err
+		return err
 	}
 
 	defer resp.Body.Close()
@@ -85,41 +222,179 @@ func (ws *WeatherService) callWeatherAPI(city string) (*WeatherResponse, error)
 		log.Printf("API error: %s\n", string(body))
 
 		if resp.StatusCode == http.StatusNotFound {
-			return nil, ErrCityNotFound
+			return ErrCityNotFound
 		} else {
-			return nil, fmt.Errorf("API error %s", string(body))
+			return fmt.Errorf("API error %s", string(body))
 		}
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
 		log.Printf("invalid response JSON: %s\n", err.Error())
 
-		return nil, err
+		return err
 	}
 
-	return &result, nil
+	return nil
+}
+
+// Adds the "lang" query parameter, OpenWeatherMap translates descriptions
+func withLanguage(rawURL, lang string) string {
+	if lang == "" {
+		return rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	q := u.Query()
+	q.Set("lang", lang)
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+var languagePattern = regexp.MustCompile(`^[a-z]{2}$`)
+
+// Reports whether lang looks like a two letter language code
+func IsValidLanguage(lang string) bool {
+	return languagePattern.MatchString(lang)
 }
 
 func (ws *WeatherService) GetWeather(city string) (*WeatherData, error) {
+	return ws.GetLocalizedWeather(city, "")
+}
+
+// Current weather with descriptions in lang, empty lang leaves the API default
+func (ws *WeatherService) GetLocalizedWeather(city, lang string) (*WeatherData, error) {
+	return ws.GetWeatherWithExtras(city, lang, Extras{})
+}
+
+// Like GetLocalizedWeather, also fetching the requested extras
+func (ws *WeatherService) GetWeatherWithExtras(city, lang string, extras Extras) (*WeatherData, error) {
+	report, err := ws.getReport(city, lang, extras)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report.Data, nil
+}
+
+// Like GetLocalizedWeather, with the location and the origin of the data
+func (ws *WeatherService) GetReport(city, lang string) (*Report, error) {
+	return ws.getReport(city, lang, Extras{})
+}
+
+func (ws *WeatherService) getReport(city, lang string, extras Extras) (*Report, error) {
+	key := city
+	if lang != "" {
+		key = city + "|" + lang
+	}
+	if extras.Forecast {
+		key += "|forecast"
+	}
+	if extras.AirQuality {
+		key += "|air"
+	}
+
 	// Check cache first
-	if data, found := ws.weatherCache.Get(city); found {
+	if cached, found := ws.weatherCache.Get(key); found {
 		log.Printf("Cache hit for city: %s\n", city)
-		return data, nil
+
+		report := *cached
+		report.Cached = true
+		return &report, nil
 	}
 
 	// Fallback to external API
-	weatherResponse, err := ws.callWeatherAPI(city)
+	weatherResponse, err := ws.callWeatherAPI(city, lang)
 	if err != nil {
 		return nil, err
 	}
 
-	var weatherData WeatherData
+	report := Report{
+		Location: Location{
+			Name:    weatherResponse.Name,
+			Country: weatherResponse.Sys.Country,
+			Lat:     weatherResponse.Coord.Lat,
+			Lon:     weatherResponse.Coord.Lon,
+		},
+		FetchedAt: time.Now().UTC(),
+		Source:    Provider,
+	}
+	if weatherResponse.Dt != 0 {
+		report.ObservedAt = time.Unix(weatherResponse.Dt, 0).UTC()
+	}
+
+	weatherData := &report.Data
 
 	weatherData.Temperature = weatherResponse.Main.Temp
 	weatherData.Humidity = weatherResponse.Main.Humidity
-	weatherData.Description = weatherResponse.Weather[0].Description
+	if len(weatherResponse.Weather) > 0 {
+		weatherData.Description = weatherResponse.Weather[0].Description
+	}
+
+	weatherData.FeelsLike = weatherResponse.Main.FeelsLike
+	weatherData.Pressure = weatherResponse.Main.Pressure
+	weatherData.WindSpeed = weatherResponse.Wind.Speed
+	weatherData.Sunrise = weatherResponse.Sys.Sunrise
+	weatherData.Sunset = weatherResponse.Sys.Sunset
+	weatherData.UTCOffset = weatherResponse.Timezone
+
+	// Extras are best effort, the current weather is still worth sending
+	if extras.Forecast {
+		if forecast, err := ws.callForecastAPI(city, lang); err != nil {
+			log.Printf("Forecast unavailable for %s: %s\n", city, err.Error())
+		} else if forecast != nil {
+			weatherData.Forecast = summarizeForecast(forecast)
+		}
+	}
+
+	if extras.AirQuality {
+		if air, err := ws.callAirPollutionAPI(weatherResponse.Coord.Lat, weatherResponse.Coord.Lon); err != nil {
+			log.Printf("Air quality unavailable for %s: %s\n", city, err.Error())
+		} else if air != nil && len(air.List) > 0 {
+			weatherData.AirQuality = air.List[0].Main.AQI
+		}
+	}
+
+	ws.weatherCache.Set(key, &report)
+
+	return &report, nil
+}
+
+// Lowest and highest temperature of the steps and their most frequent condition
+func summarizeForecast(forecast *ForecastResponse) Forecast {
+	var summary Forecast
+	if len(forecast.List) == 0 {
+		return summary
+	}
+
+	counts := map[string]int{}
+	best := 0
+
+	for i, step := range forecast.List {
+		if i == 0 || step.Main.TempMin < summary.Low {
+			summary.Low = step.Main.TempMin
+		}
+		if i == 0 || step.Main.TempMax > summary.High {
+			summary.High = step.Main.TempMax
+		}
+
+		if len(step.Weather) == 0 {
+			continue
+		}
 
-	ws.weatherCache.Set(city, &weatherData)
+		description := step.Weather[0].Description
+		counts[description]++
+
+		// Ties go to the earlier condition
+		if counts[description] > best {
+			best = counts[description]
+			summary.Description = description
+		}
+	}
 
-	return &weatherData, err
+	return summary
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 	"weather-app/internal/weather"
@@ -137,3 +138,184 @@ func TestGetWeather_RequestIssue(t *testing.T) {
 		t.Fatal("expected error due to some generic issue, got nil")
 	}
 }
+
+func TestGetLocalizedWeather_Details(t *testing.T) {
+	var langs []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		langs = append(langs, r.URL.Path+":"+r.URL.Query().Get("lang"))
+
+		switch r.URL.Path {
+		case "/forecast":
+			fmt.Fprintln(w, `{"list": [
+				{"main": {"temp_min": 12, "temp_max": 15}, "weather": [{"description": "дощ"}]},
+				{"main": {"temp_min": 10, "temp_max": 19}, "weather": [{"description": "ясно"}]},
+				{"main": {"temp_min": 11, "temp_max": 17}, "weather": [{"description": "ясно"}]}
+			]}`)
+		case "/air":
+			if r.URL.Query().Get("lat") != "50.45" || r.URL.Query().Get("lon") != "30.52" {
+				t.Errorf("unexpected coordinates %s", r.URL.RawQuery)
+			}
+			fmt.Fprintln(w, `{"list": [{"main": {"aqi": 2}}]}`)
+		default:
+			fmt.Fprintln(w, `{
+				"coord": {"lat": 50.45, "lon": 30.52},
+				"main": {"temp": 14.2, "feels_like": 13.1, "pressure": 1012, "humidity": 60},
+				"weather": [{"description": "ясно"}],
+				"wind": {"speed": 3.4},
+				"sys": {"sunrise": 1700000000, "sunset": 1700030000},
+				"timezone": 7200
+			}`)
+		}
+	}))
+	defer server.Close()
+
+	withEnv("WEATHER_API_ADDRESS", server.URL+"/weather?q=%s&appid=%s", func() {
+		withEnv("WEATHER_FORECAST_API_ADDRESS", server.URL+"/forecast?q=%s&appid=%s", func() {
+			withEnv("WEATHER_AIR_API_ADDRESS", server.URL+"/air?lat=%s&lon=%s&appid=%s", func() {
+				ws := weather.NewWeatherService(nil, "test_api", cache.NewWeatherCache(time.Minute*30))
+
+				extras := weather.Extras{Forecast: true, AirQuality: true}
+
+				data, err := ws.GetWeatherWithExtras("Kyiv", "uk", extras)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+
+				expected := weather.WeatherData{
+					Temperature: 14.2,
+					Humidity:    60,
+					Description: "ясно",
+					FeelsLike:   13.1,
+					Pressure:    1012,
+					WindSpeed:   3.4,
+					Sunrise:     1700000000,
+					Sunset:      1700030000,
+					UTCOffset:   7200,
+					Forecast:    weather.Forecast{Low: 10, High: 19, Description: "ясно"},
+					AirQuality:  2,
+				}
+				if *data != expected {
+					t.Errorf("unexpected result: %+v", data)
+				}
+
+				// The air pollution API has no translations
+				if strings.Join(langs, ",") != "/weather:uk,/forecast:uk,/air:" {
+					t.Errorf("unexpected requests %v", langs)
+				}
+
+				// Cached per language
+				if _, err := ws.GetWeatherWithExtras("Kyiv", "uk", extras); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if _, err := ws.GetWeatherWithExtras("Kyiv", "en", extras); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if len(langs) != 6 {
+					t.Errorf("expected one cached and one new lookup, got requests %v", langs)
+				}
+			})
+		})
+	})
+}
+
+func TestGetLocalizedWeather_ExtrasAreOptional(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/forecast" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, `{"main": {"temp": 22.5, "humidity": 60}, "weather": [{"description": "clear sky"}]}`)
+	}))
+	defer server.Close()
+
+	withEnv("WEATHER_API_ADDRESS", server.URL+"/weather?q=%s&appid=%s", func() {
+		withEnv("WEATHER_FORECAST_API_ADDRESS", server.URL+"/forecast?q=%s&appid=%s", func() {
+			withEnv("WEATHER_AIR_API_ADDRESS", "", func() {
+				ws := weather.NewWeatherService(nil, "test_api", cache.NewWeatherCache(time.Minute*30))
+
+				data, err := ws.GetWeatherWithExtras("Kyiv", "", weather.Extras{Forecast: true, AirQuality: true})
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+
+				if data.Temperature != 22.5 || data.Forecast != (weather.Forecast{}) || data.AirQuality != 0 {
+					t.Errorf("unexpected result: %+v", data)
+				}
+			})
+		})
+	})
+}
+
+func TestGetLocalizedWeather_SkipsExtrasNotRequested(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		fmt.Fprintln(w, `{"main": {"temp": 22.5, "humidity": 60}, "weather": [{"description": "clear sky"}]}`)
+	}))
+	defer server.Close()
+
+	withEnv("WEATHER_API_ADDRESS", server.URL+"/weather?q=%s&appid=%s", func() {
+		withEnv("WEATHER_FORECAST_API_ADDRESS", server.URL+"/forecast?q=%s&appid=%s", func() {
+			withEnv("WEATHER_AIR_API_ADDRESS", server.URL+"/air?lat=%s&lon=%s&appid=%s", func() {
+				ws := weather.NewWeatherService(nil, "test_api", cache.NewWeatherCache(time.Minute*30))
+
+				if _, err := ws.GetLocalizedWeather("Kyiv", ""); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if _, err := ws.GetWeatherWithExtras("Lviv", "", weather.Extras{Forecast: true}); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+
+				if strings.Join(paths, ",") != "/weather,/weather,/forecast" {
+					t.Errorf("unexpected requests %v", paths)
+				}
+			})
+		})
+	})
+}
+
+func TestGetReport_MetadataAndCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintln(w, `{
+			"name": "Kyiv", "dt": 1748767800,
+			"coord": {"lat": 50.45, "lon": 30.52},
+			"sys": {"country": "UA"},
+			"main": {"temp": 22.5, "humidity": 60},
+			"weather": [{"description": "clear sky"}]
+		}`)
+	}))
+	defer server.Close()
+
+	withEnv("WEATHER_API_ADDRESS", server.URL+"?q=%s&appid=%s", func() {
+		ws := weather.NewWeatherService(nil, "test_api", cache.NewWeatherCache(time.Minute*30))
+
+		report, err := ws.GetReport("kyiv", "")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if report.Location != (weather.Location{Name: "Kyiv", Country: "UA", Lat: 50.45, Lon: 30.52}) {
+			t.Errorf("unexpected location %+v", report.Location)
+		}
+		if !report.ObservedAt.Equal(time.Unix(1748767800, 0)) || report.FetchedAt.IsZero() {
+			t.Errorf("unexpected times %v, %v", report.ObservedAt, report.FetchedAt)
+		}
+		if report.Source != weather.Provider || report.Cached {
+			t.Errorf("expected a fresh report from the provider, got %+v", report)
+		}
+
+		cached, err := ws.GetReport("kyiv", "")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !cached.Cached || !cached.FetchedAt.Equal(report.FetchedAt) || calls != 1 {
+			t.Errorf("expected the cached report, got %+v after %d calls", cached, calls)
+		}
+		if report.Cached {
+			t.Error("marking a cache hit must not change the cached report")
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"weather-app/internal/database/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Attempts before a delivery is marked failed. With RetryDelay the last
+	// one is made about 15 hours after the first
+	MaxAttempts = 12
+
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 6 * time.Hour
+
+	requestTimeout = 10 * time.Second
+	batchSize      = 20
+
+	// Long enough for a whole batch to time out, so a delivery is never
+	// picked up by another dispatcher while still being sent
+	claimLease = batchSize * requestTimeout * 2
+
+	// Stored part of a failed response body
+	maxErrorLength = 500
+)
+
+// Event types sent to endpoints
+var EventTypes = []string{
+	models.EventSubscribed,
+	models.EventConfirmed,
+	models.EventUnsubscribed,
+	models.EventPaused,
+	models.EventResumed,
+	models.EventEmailChangeRequested,
+	models.EventEmailChanged,
+}
+
+type RepositoryInterface interface {
+	ListEndpoints() ([]models.WebhookEndpoint, error)
+	GetEndpoint(id uuid.UUID) (*models.WebhookEndpoint, error)
+	CreateDeliveries(deliveries []models.WebhookDelivery) error
+	ClaimDue(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	SaveAttempt(delivery *models.WebhookDelivery) error
+}
+
+type Dispatcher struct {
+	repo   RepositoryInterface
+	client *http.Client
+}
+
+// Nil client uses one with a 10 second timeout
+func NewDispatcher(repo RepositoryInterface, client *http.Client) *Dispatcher {
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+
+	return &Dispatcher{repo: repo, client: client}
+}
+
+// Request body sent to endpoints
+type Payload struct {
+	ID        uuid.UUID `json:"id"` // Event ID, stable across retries and replays
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      EventData `json:"data"`
+}
+
+type EventData struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Email   string    `json:"email"`
+	Details string    `json:"details,omitempty"`
+}
+
+// Queues the event for every endpoint subscribed to its type. Sending happens
+// in DeliverDue
+func (d *Dispatcher) Publish(event *models.SubscriptionEvent) error {
+	if !slices.Contains(EventTypes, event.Type) {
+		return nil
+	}
+
+	endpoints, err := d.repo.ListEndpoints()
+	if err != nil {
+		return err
+	}
+
+	eventID := event.ID
+	if eventID == uuid.Nil {
+		eventID = uuid.New()
+	}
+
+	body, err := json.Marshal(Payload{
+		ID:        eventID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Data: EventData{
+			UserID:  event.UserID,
+			Email:   event.Email,
+			Details: event.Details,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, endpoint := range endpoints {
+		if !Subscribed(endpoint, event.Type) {
+			continue
+		}
+
+		deliveries = append(deliveries, models.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventID:       eventID,
+			EventType:     event.Type,
+			UserID:        event.UserID,
+			Payload:       body,
+			Status:        models.WebhookStatusPending,
+			NextAttemptAt: event.CreatedAt,
+			CreatedAt:     event.CreatedAt,
+		})
+	}
+
+	return d.repo.CreateDeliveries(deliveries)
+}
+
+// Reports whether the endpoint receives events of the type
+func Subscribed(endpoint models.WebhookEndpoint, eventType string) bool {
+	if endpoint.Events == "" {
+		return true
+	}
+
+	return slices.Contains(strings.Split(endpoint.Events, ","), eventType)
+}
+
+// Sends pending deliveries that are due, a batch at a time until none are left
+func (d *Dispatcher) DeliverDue(now time.Time) {
+	endpoints := make(map[uuid.UUID]*models.WebhookEndpoint)
+
+	for {
+		batch, err := d.repo.ClaimDue(now, claimLease, batchSize)
+		if err != nil {
+			log.Printf("Failed to load webhook deliveries: %s\n", err.Error())
+			return
+		}
+
+		for i := range batch {
+			delivery := &batch[i]
+
+			endpoint, ok := endpoints[delivery.EndpointID]
+			if !ok {
+				endpoint, err = d.repo.GetEndpoint(delivery.EndpointID)
+				if err != nil {
+					log.Printf("Failed to load webhook endpoint %s: %s\n", delivery.EndpointID, err.Error())
+					continue
+				}
+				endpoints[delivery.EndpointID] = endpoint
+			}
+
+			d.attempt(endpoint, delivery, time.Now())
+
+			if err := d.repo.SaveAttempt(delivery); err != nil {
+				log.Println(err.Error())
+			}
+		}
+
+		if len(batch) < batchSize {
+			return
+		}
+	}
+}
+
+// Sends the delivery once and updates it with the outcome
+func (d *Dispatcher) attempt(endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery, now time.Time) {
+	delivery.Attempts++
+
+	statusCode, err := d.send(endpoint, delivery, now)
+	delivery.LastStatusCode = statusCode
+
+	if err == nil {
+		delivery.Status = models.WebhookStatusSucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	log.Printf("Webhook delivery %s to %s failed (attempt %d): %s\n", delivery.ID, endpoint.URL, delivery.Attempts, err.Error())
+
+	if delivery.Attempts >= MaxAttempts {
+		delivery.Status = models.WebhookStatusFailed
+		return
+	}
+
+	delivery.NextAttemptAt = now.Add(RetryDelay(delivery.Attempts))
+}
+
+func (d *Dispatcher) send(endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequest("POST", endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := now.Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "weather-app-webhooks")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return resp.StatusCode, fmt.Errorf("endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Exponential backoff after the given number of failed attempts:
+// 30s, 1m, 2m, 4m ... capped at 6h
+func RetryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, retryMaxDelay)
+}
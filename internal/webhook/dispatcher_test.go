@@ -0,0 +1,273 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+// In-memory stand-in for WebhookRepository
+type memoryRepo struct {
+	mu         sync.Mutex
+	endpoints  []models.WebhookEndpoint
+	deliveries []models.WebhookDelivery
+}
+
+func (r *memoryRepo) addEndpoint(url, secret, events string) models.WebhookEndpoint {
+	endpoint := models.WebhookEndpoint{ID: uuid.New(), URL: url, Secret: secret, Events: events}
+	r.endpoints = append(r.endpoints, endpoint)
+	return endpoint
+}
+
+func (r *memoryRepo) CreateEndpoint(endpoint *models.WebhookEndpoint) error {
+	endpoint.ID = uuid.New()
+	r.endpoints = append(r.endpoints, *endpoint)
+	return nil
+}
+
+func (r *memoryRepo) ListEndpoints() ([]models.WebhookEndpoint, error) {
+	return r.endpoints, nil
+}
+
+func (r *memoryRepo) GetEndpoint(id uuid.UUID) (*models.WebhookEndpoint, error) {
+	for _, e := range r.endpoints {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memoryRepo) DeleteEndpoint(id uuid.UUID) error {
+	for i, e := range r.endpoints {
+		if e.ID == id {
+			r.endpoints = append(r.endpoints[:i], r.endpoints[i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *memoryRepo) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range deliveries {
+		deliveries[i].ID = uuid.New()
+	}
+	r.deliveries = append(r.deliveries, deliveries...)
+	return nil
+}
+
+func (r *memoryRepo) GetDelivery(id uuid.UUID) (*models.WebhookDelivery, error) {
+	for _, d := range r.deliveries {
+		if d.ID == id {
+			return &d, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memoryRepo) ListDeliveries(endpointID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error) {
+	var result []models.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.EndpointID == endpointID && (status == "" || d.Status == status) {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryRepo) ClaimDue(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var claimed []models.WebhookDelivery
+	for i := range r.deliveries {
+		d := &r.deliveries[i]
+		if d.Status == models.WebhookStatusPending && !d.NextAttemptAt.After(now) && len(claimed) < limit {
+			claimed = append(claimed, *d)
+			d.NextAttemptAt = now.Add(lease)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *memoryRepo) SaveAttempt(delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.deliveries {
+		if r.deliveries[i].ID == delivery.ID {
+			r.deliveries[i] = *delivery
+		}
+	}
+	return nil
+}
+
+func confirmedEvent(createdAt time.Time) *models.SubscriptionEvent {
+	return &models.SubscriptionEvent{
+		ID:        uuid.New(),
+		Type:      models.EventConfirmed,
+		Email:     "user@example.com",
+		UserID:    uuid.New(),
+		CreatedAt: createdAt,
+	}
+}
+
+func TestPublish_FiltersEndpoints(t *testing.T) {
+	repo := &memoryRepo{}
+	all := repo.addEndpoint("http://all.test", "s1", "")
+	confirms := repo.addEndpoint("http://confirms.test", "s2", "subscribed,confirmed")
+	repo.addEndpoint("http://unsubscribes.test", "s3", "unsubscribed")
+
+	dispatcher := webhook.NewDispatcher(repo, nil)
+
+	if err := dispatcher.Publish(confirmedEvent(time.Now())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(repo.deliveries) != 2 {
+		t.Fatalf("expected 2 deliveries, got %d", len(repo.deliveries))
+	}
+	if repo.deliveries[0].EndpointID != all.ID || repo.deliveries[1].EndpointID != confirms.ID {
+		t.Errorf("deliveries queued for wrong endpoints")
+	}
+	if repo.deliveries[0].Status != models.WebhookStatusPending {
+		t.Errorf("expected pending delivery, got %s", repo.deliveries[0].Status)
+	}
+}
+
+func TestPublish_IgnoresOtherEvents(t *testing.T) {
+	repo := &memoryRepo{}
+	repo.addEndpoint("http://all.test", "s1", "")
+
+	event := confirmedEvent(time.Now())
+	event.Type = models.EventErased
+
+	if err := webhook.NewDispatcher(repo, nil).Publish(event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.deliveries) != 0 {
+		t.Errorf("expected no deliveries, got %d", len(repo.deliveries))
+	}
+}
+
+func TestDeliverDue_SignsRequest(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := &memoryRepo{}
+	repo.addEndpoint(server.URL, "secret", "")
+
+	dispatcher := webhook.NewDispatcher(repo, server.Client())
+	now := time.Now()
+	event := confirmedEvent(now)
+	dispatcher.Publish(event)
+
+	dispatcher.DeliverDue(now)
+
+	req := <-requests
+
+	if req.header.Get(webhook.EventHeader) != models.EventConfirmed {
+		t.Errorf("unexpected event header %q", req.header.Get(webhook.EventHeader))
+	}
+	err := webhook.Verify("secret", req.header.Get(webhook.TimestampHeader), req.header.Get(webhook.SignatureHeader),
+		req.body, time.Now(), time.Minute)
+	if err != nil {
+		t.Errorf("signature verification failed: %v", err)
+	}
+
+	var payload webhook.Payload
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.ID != event.ID || payload.Data.Email != "user@example.com" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+
+	d := repo.deliveries[0]
+	if d.Status != models.WebhookStatusSucceeded || d.Attempts != 1 || d.DeliveredAt == nil || d.LastStatusCode != http.StatusNoContent {
+		t.Errorf("unexpected delivery state %+v", d)
+	}
+}
+
+func TestDeliverDue_RetriesWithBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	repo := &memoryRepo{}
+	repo.addEndpoint(server.URL, "secret", "")
+
+	dispatcher := webhook.NewDispatcher(repo, server.Client())
+	now := time.Now()
+	dispatcher.Publish(confirmedEvent(now))
+
+	dispatcher.DeliverDue(now)
+
+	d := repo.deliveries[0]
+	if d.Status != models.WebhookStatusPending || d.Attempts != 1 {
+		t.Fatalf("expected pending delivery after one attempt, got %+v", d)
+	}
+	if d.LastStatusCode != http.StatusServiceUnavailable || d.LastError == "" {
+		t.Errorf("expected failure to be recorded, got %+v", d)
+	}
+	if !d.NextAttemptAt.After(now) {
+		t.Errorf("expected next attempt to be scheduled after %v, got %v", now, d.NextAttemptAt)
+	}
+
+	// Not due yet
+	dispatcher.DeliverDue(now)
+	if repo.deliveries[0].Attempts != 1 {
+		t.Errorf("expected no attempt before backoff, got %d", repo.deliveries[0].Attempts)
+	}
+
+	// Exhaust the remaining attempts
+	later := now
+	for i := 1; i < webhook.MaxAttempts; i++ {
+		later = later.Add(7 * time.Hour)
+		dispatcher.DeliverDue(later)
+	}
+
+	d = repo.deliveries[0]
+	if d.Status != models.WebhookStatusFailed || d.Attempts != webhook.MaxAttempts {
+		t.Errorf("expected failed delivery after %d attempts, got %+v", webhook.MaxAttempts, d)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		11: 6 * time.Hour,
+		50: 6 * time.Hour,
+	}
+
+	for attempts, want := range cases {
+		if got := webhook.RetryDelay(attempts); got != want {
+			t.Errorf("RetryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
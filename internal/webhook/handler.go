@@ -0,0 +1,362 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/problem"
+
+	"github.com/google/uuid"
+)
+
+const (
+	endpointsPath  = "/admin/webhooks"
+	deliveriesPath = "/admin/webhook-deliveries"
+
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+var (
+	ErrInvalidURL       = errors.New("url parameter is invalid")
+	ErrInvalidEvents    = errors.New("events parameter is invalid")
+	ErrInvalidStatus    = errors.New("status parameter is invalid")
+	ErrInvalidLimit     = errors.New("limit parameter is invalid")
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrDeliveryPending  = errors.New("webhook delivery is still pending")
+)
+
+var problems = problem.Mappings{
+	{Err: ErrInvalidURL, Status: http.StatusBadRequest, Code: "invalid_url"},
+	{Err: ErrInvalidEvents, Status: http.StatusBadRequest, Code: "invalid_events"},
+	{Err: ErrInvalidStatus, Status: http.StatusBadRequest, Code: "invalid_status"},
+	{Err: ErrInvalidLimit, Status: http.StatusBadRequest, Code: "invalid_limit"},
+	{Err: ErrEndpointNotFound, Status: http.StatusNotFound, Code: "webhook_endpoint_not_found"},
+	{Err: ErrDeliveryNotFound, Status: http.StatusNotFound, Code: "webhook_delivery_not_found"},
+	{Err: ErrDeliveryPending, Status: http.StatusConflict, Code: "webhook_delivery_pending"},
+}
+
+type HandlerRepositoryInterface interface {
+	CreateEndpoint(endpoint *models.WebhookEndpoint) error
+	ListEndpoints() ([]models.WebhookEndpoint, error)
+	DeleteEndpoint(id uuid.UUID) error
+	ListDeliveries(endpointID uuid.UUID, status string, limit int) ([]models.WebhookDelivery, error)
+	GetDelivery(id uuid.UUID) (*models.WebhookDelivery, error)
+	CreateDeliveries(deliveries []models.WebhookDelivery) error
+}
+
+type WebhookHandler struct {
+	repo HandlerRepositoryInterface
+}
+
+func NewHandler(repo HandlerRepositoryInterface) *WebhookHandler {
+	return &WebhookHandler{repo: repo}
+}
+
+type EndpointResponse struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"` // Empty means all
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeliveryResponse struct {
+	ID             uuid.UUID       `json:"id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+// GET lists endpoints. POST registers one from form fields "url" and optional
+// "events" (comma-separated), the generated secret is only returned here
+func (h *WebhookHandler) EndpointsHandler(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case "GET":
+		h.listEndpoints(w)
+	case "POST":
+		h.createEndpoint(w, req)
+	default:
+		problem.MethodNotAllowed(w, req, "GET", "POST")
+	}
+}
+
+func (h *WebhookHandler) listEndpoints(w http.ResponseWriter) {
+	endpoints, err := h.repo.ListEndpoints()
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	response := []EndpointResponse{}
+	for _, e := range endpoints {
+		response = append(response, endpointResponse(e, false))
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *WebhookHandler) createEndpoint(w http.ResponseWriter, req *http.Request) {
+	endpointURL := req.FormValue("url")
+	if u, err := url.Parse(endpointURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		problems.Write(w, ErrInvalidURL)
+		return
+	}
+
+	events, err := parseEvents(req.FormValue("events"))
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	endpoint := models.WebhookEndpoint{
+		URL:       endpointURL,
+		Secret:    secret,
+		Events:    strings.Join(events, ","),
+		CreatedAt: time.Now(),
+	}
+
+	if err := h.repo.CreateEndpoint(&endpoint); err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, endpointResponse(endpoint, true))
+}
+
+// Handles a single endpoint:
+//
+//	DELETE /admin/webhooks/{id}
+//	GET    /admin/webhooks/{id}/deliveries?status={status}&limit={limit}
+func (h *WebhookHandler) EndpointHandler(w http.ResponseWriter, req *http.Request) {
+	rawID, action, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, endpointsPath+"/"), "/")
+
+	id, err := uuid.Parse(rawID)
+	if err != nil || (action != "" && action != "deliveries") {
+		problems.Write(w, ErrEndpointNotFound)
+		return
+	}
+
+	method := "GET"
+	if action == "" {
+		method = "DELETE"
+	}
+
+	if req.Method != method {
+		problem.MethodNotAllowed(w, req, method)
+		return
+	}
+
+	if action == "" {
+		h.deleteEndpoint(w, id)
+		return
+	}
+
+	h.listDeliveries(w, req, id)
+}
+
+func (h *WebhookHandler) deleteEndpoint(w http.ResponseWriter, id uuid.UUID) {
+	if err := h.repo.DeleteEndpoint(id); err != nil {
+		if repository.IsErrNotFound(err) {
+			problems.Write(w, ErrEndpointNotFound)
+			return
+		}
+
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *WebhookHandler) listDeliveries(w http.ResponseWriter, req *http.Request, endpointID uuid.UUID) {
+	status := req.URL.Query().Get("status")
+	if status != "" && status != models.WebhookStatusPending &&
+		status != models.WebhookStatusSucceeded && status != models.WebhookStatusFailed {
+		problems.Write(w, ErrInvalidStatus)
+		return
+	}
+
+	limit := defaultDeliveryLimit
+	if value := req.URL.Query().Get("limit"); value != "" {
+		l, err := strconv.Atoi(value)
+		if err != nil || l < 1 || l > maxDeliveryLimit {
+			problems.Write(w, ErrInvalidLimit)
+			return
+		}
+		limit = l
+	}
+
+	deliveries, err := h.repo.ListDeliveries(endpointID, status, limit)
+	if err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	response := []DeliveryResponse{}
+	for _, d := range deliveries {
+		response = append(response, deliveryResponse(d))
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// POST /admin/webhook-deliveries/{id}/replay queues a finished delivery again
+// as a new delivery with the same payload
+func (h *WebhookHandler) ReplayHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	}
+
+	rawID, ok := strings.CutSuffix(strings.TrimPrefix(req.URL.Path, deliveriesPath+"/"), "/replay")
+	id, err := uuid.Parse(rawID)
+	if !ok || err != nil {
+		problems.Write(w, ErrDeliveryNotFound)
+		return
+	}
+
+	original, err := h.repo.GetDelivery(id)
+	if err != nil {
+		if repository.IsErrNotFound(err) {
+			problems.Write(w, ErrDeliveryNotFound)
+			return
+		}
+
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	// Replaying a delivery that is still being retried would send it twice
+	if original.Status == models.WebhookStatusPending {
+		problems.Write(w, ErrDeliveryPending)
+		return
+	}
+
+	now := time.Now()
+	replay := []models.WebhookDelivery{{
+		EndpointID:    original.EndpointID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		UserID:        original.UserID,
+		Payload:       original.Payload,
+		Status:        models.WebhookStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}}
+
+	if err := h.repo.CreateDeliveries(replay); err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, deliveryResponse(replay[0]))
+}
+
+func parseEvents(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var events []string
+	for _, event := range strings.Split(value, ",") {
+		event = strings.TrimSpace(event)
+		if !slices.Contains(EventTypes, event) {
+			return nil, ErrInvalidEvents
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+func endpointResponse(e models.WebhookEndpoint, withSecret bool) EndpointResponse {
+	response := EndpointResponse{
+		ID:        e.ID,
+		URL:       e.URL,
+		Events:    []string{},
+		CreatedAt: e.CreatedAt,
+	}
+
+	if e.Events != "" {
+		response.Events = strings.Split(e.Events, ",")
+	}
+	if withSecret {
+		response.Secret = e.Secret
+	}
+
+	return response
+}
+
+func deliveryResponse(d models.WebhookDelivery) DeliveryResponse {
+	response := DeliveryResponse{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+		Payload:        d.Payload,
+	}
+
+	if d.Status == models.WebhookStatusPending {
+		response.NextAttemptAt = &d.NextAttemptAt
+	}
+
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
@@ -0,0 +1,159 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/database/models"
+	"weather-app/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+func postForm(target string, values url.Values) *http.Request {
+	req := httptest.NewRequest("POST", target, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestEndpointsHandler_Create(t *testing.T) {
+	repo := &memoryRepo{}
+	h := webhook.NewHandler(repo)
+
+	w := httptest.NewRecorder()
+	h.EndpointsHandler(w, postForm("/admin/webhooks", url.Values{
+		"url":    {"https://crm.example.com/hooks"},
+		"events": {"subscribed, confirmed"},
+	}))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var created webhook.EndpointResponse
+	json.NewDecoder(w.Body).Decode(&created)
+
+	if len(created.Secret) != 64 {
+		t.Errorf("expected a generated secret, got %q", created.Secret)
+	}
+	if len(repo.endpoints) != 1 || repo.endpoints[0].Events != "subscribed,confirmed" {
+		t.Errorf("unexpected stored endpoints %+v", repo.endpoints)
+	}
+
+	// Listing never shows secrets
+	w = httptest.NewRecorder()
+	h.EndpointsHandler(w, httptest.NewRequest("GET", "/admin/webhooks", nil))
+
+	if strings.Contains(w.Body.String(), created.Secret) {
+		t.Error("secret leaked in endpoint list")
+	}
+}
+
+func TestEndpointsHandler_CreateInvalid(t *testing.T) {
+	h := webhook.NewHandler(&memoryRepo{})
+
+	cases := []url.Values{
+		{"url": {"ftp://crm.example.com"}},
+		{"url": {"not a url"}},
+		{"url": {"https://crm.example.com"}, "events": {"subscribed,exploded"}},
+	}
+
+	for _, values := range cases {
+		w := httptest.NewRecorder()
+		h.EndpointsHandler(w, postForm("/admin/webhooks", values))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %d", values, w.Code)
+		}
+	}
+}
+
+func TestEndpointHandler_Deliveries(t *testing.T) {
+	repo := &memoryRepo{}
+	endpoint := repo.addEndpoint("https://crm.example.com", "secret", "")
+	repo.CreateDeliveries([]models.WebhookDelivery{
+		{EndpointID: endpoint.ID, EventType: models.EventConfirmed, Status: models.WebhookStatusSucceeded, Payload: []byte(`{}`)},
+		{EndpointID: endpoint.ID, EventType: models.EventSubscribed, Status: models.WebhookStatusFailed, Payload: []byte(`{}`)},
+	})
+
+	h := webhook.NewHandler(repo)
+
+	w := httptest.NewRecorder()
+	h.EndpointHandler(w, httptest.NewRequest("GET", "/admin/webhooks/"+endpoint.ID.String()+"/deliveries?status=failed", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var deliveries []webhook.DeliveryResponse
+	json.NewDecoder(w.Body).Decode(&deliveries)
+
+	if len(deliveries) != 1 || deliveries[0].EventType != models.EventSubscribed {
+		t.Errorf("unexpected deliveries %+v", deliveries)
+	}
+}
+
+func TestEndpointHandler_Delete(t *testing.T) {
+	repo := &memoryRepo{}
+	endpoint := repo.addEndpoint("https://crm.example.com", "secret", "")
+	h := webhook.NewHandler(repo)
+
+	w := httptest.NewRecorder()
+	h.EndpointHandler(w, httptest.NewRequest("DELETE", "/admin/webhooks/"+endpoint.ID.String(), nil))
+
+	if w.Code != http.StatusOK || len(repo.endpoints) != 0 {
+		t.Fatalf("expected endpoint to be deleted, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.EndpointHandler(w, httptest.NewRequest("DELETE", "/admin/webhooks/"+endpoint.ID.String(), nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown endpoint, got %d", w.Code)
+	}
+}
+
+func TestReplayHandler(t *testing.T) {
+	repo := &memoryRepo{}
+	endpoint := repo.addEndpoint("https://crm.example.com", "secret", "")
+	eventID := uuid.New()
+	repo.CreateDeliveries([]models.WebhookDelivery{
+		{EndpointID: endpoint.ID, EventID: eventID, EventType: models.EventConfirmed, Status: models.WebhookStatusFailed,
+			Attempts: webhook.MaxAttempts, Payload: []byte(`{"type":"confirmed"}`)},
+		{EndpointID: endpoint.ID, EventType: models.EventSubscribed, Status: models.WebhookStatusPending, Payload: []byte(`{}`)},
+	})
+	failed, pending := repo.deliveries[0], repo.deliveries[1]
+
+	h := webhook.NewHandler(repo)
+
+	w := httptest.NewRecorder()
+	h.ReplayHandler(w, httptest.NewRequest("POST", "/admin/webhook-deliveries/"+failed.ID.String()+"/replay", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+
+	replay := repo.deliveries[2]
+	if replay.EventID != eventID || replay.Status != models.WebhookStatusPending || replay.Attempts != 0 ||
+		string(replay.Payload) != string(failed.Payload) || replay.NextAttemptAt.After(time.Now()) {
+		t.Errorf("unexpected replay %+v", replay)
+	}
+
+	w = httptest.NewRecorder()
+	h.ReplayHandler(w, httptest.NewRequest("POST", "/admin/webhook-deliveries/"+pending.ID.String()+"/replay", nil))
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 for pending delivery, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ReplayHandler(w, httptest.NewRequest("POST", "/admin/webhook-deliveries/"+uuid.NewString()+"/replay", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown delivery, got %d", w.Code)
+	}
+}
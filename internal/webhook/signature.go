@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp" // Unix seconds
+	SignatureHeader = "X-Webhook-Signature" // "sha256=" + hex HMAC of "<timestamp>.<body>"
+
+	signaturePrefix = "sha256="
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature is invalid")
+	ErrStaleTimestamp   = errors.New("webhook timestamp is outside the tolerance")
+)
+
+// Signs the body together with the timestamp, so a captured request can't be
+// replayed later with a new timestamp
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Checks the headers of a received webhook. Receivers should reject requests
+// older than tolerance
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, now time.Time, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return ErrStaleTimestamp
+	}
+
+	if !strings.HasPrefix(signatureHeader, signaturePrefix) ||
+		!hmac.Equal([]byte(signatureHeader), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package webhook_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+	"weather-app/internal/webhook"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"confirmed"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := webhook.Sign("secret", now.Unix(), body)
+
+	if err := webhook.Verify("secret", timestamp, signature, body, now, time.Minute); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+
+	cases := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		now       time.Time
+		want      error
+	}{
+		{"wrong secret", "other", timestamp, body, now, webhook.ErrInvalidSignature},
+		{"tampered body", "secret", timestamp, []byte(`{"type":"unsubscribed"}`), now, webhook.ErrInvalidSignature},
+		{"changed timestamp", "secret", strconv.FormatInt(now.Unix()+1, 10), body, now, webhook.ErrInvalidSignature},
+		{"stale", "secret", timestamp, body, now.Add(time.Hour), webhook.ErrStaleTimestamp},
+		{"malformed timestamp", "secret", "yesterday", body, now, webhook.ErrInvalidSignature},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := webhook.Verify(tc.secret, tc.timestamp, signature, tc.body, tc.now, time.Minute)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// One record holds the whole message. Push services accept 4096 byte
+	// bodies, the header takes 86 of them and the tag 16
+	recordSize = 4096
+	headerSize = 16 + 4 + 1 + 65
+	tagSize    = 16
+
+	MaxPayloadSize = recordSize - headerSize - tagSize - 1 // 1 for the padding delimiter
+)
+
+var (
+	ErrInvalidKeys     = errors.New("p256dh or auth parameter is invalid")
+	ErrPayloadTooLarge = errors.New("push payload is too large")
+)
+
+// Encrypts payload for the browser with the subscription's p256dh public key
+// and auth secret using the aes128gcm content coding (RFC 8291, RFC 8188)
+func Encrypt(payload []byte, p256dh, auth string) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	uaPublic, authSecret, err := parseKeys(p256dh, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	// A fresh key and salt per message, both travel in the header
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate push key: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate push salt: %w", err)
+	}
+
+	return encrypt(payload, uaPublic, authSecret, asPrivate, salt)
+}
+
+func encrypt(payload []byte, uaPublic *ecdh.PublicKey, authSecret []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, ErrInvalidKeys
+	}
+
+	cek, nonce, err := deriveKeys(sharedSecret, authSecret, salt, uaPublic.Bytes(), asPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, headerSize, headerSize+len(payload)+1+tagSize)
+	copy(body, salt)
+	binary.BigEndian.PutUint32(body[16:20], recordSize)
+	body[20] = byte(len(asPublic))
+	copy(body[21:], asPublic)
+
+	// 0x02 marks the last (and only) record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+// Checks the keys of a subscription from pushManager.subscribe
+func ValidateKeys(p256dh, auth string) error {
+	_, _, err := parseKeys(p256dh, auth)
+	return err
+}
+
+func parseKeys(p256dh, auth string) (*ecdh.PublicKey, []byte, error) {
+	rawPublic, err := decode(p256dh)
+	if err != nil {
+		return nil, nil, ErrInvalidKeys
+	}
+
+	publicKey, err := ecdh.P256().NewPublicKey(rawPublic)
+	if err != nil {
+		return nil, nil, ErrInvalidKeys
+	}
+
+	authSecret, err := decode(auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, nil, ErrInvalidKeys
+	}
+
+	return publicKey, authSecret, nil
+}
+
+// Content encryption key and nonce of RFC 8291 section 3.4
+func deriveKeys(sharedSecret, authSecret, salt, uaPublic, asPublic []byte) (cek, nonce []byte, err error) {
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cek, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16); err != nil {
+		return nil, nil, err
+	}
+	if nonce, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12); err != nil {
+		return nil, nil, err
+	}
+
+	return cek, nonce, nil
+}
@@ -0,0 +1,211 @@
+package webpush
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"weather-app/internal/content"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/problem"
+	"weather-app/internal/subscription"
+)
+
+// Hosts of the push services of Chrome, Firefox, Edge and Safari. Subdomains
+// are allowed too
+var DefaultAllowedHosts = []string{
+	"fcm.googleapis.com",
+	"push.services.mozilla.com",
+	"notify.windows.com",
+	"push.apple.com",
+}
+
+var (
+	ErrInvalidEndpoint      = errors.New("endpoint parameter is invalid")
+	ErrInvalidCity          = errors.New("city parameter is invalid")
+	ErrSubscriptionNotFound = errors.New("push subscription not found")
+)
+
+var problems = slices.Concat(subscription.FormProblems, problem.Mappings{
+	{Err: ErrInvalidEndpoint, Status: http.StatusBadRequest, Code: "invalid_endpoint"},
+	{Err: ErrInvalidKeys, Status: http.StatusBadRequest, Code: "invalid_keys"},
+	{Err: ErrInvalidCity, Status: http.StatusBadRequest, Code: "invalid_city"},
+	{Err: ErrSubscriptionNotFound, Status: http.StatusNotFound, Code: "push_subscription_not_found"},
+})
+
+type HandlerRepositoryInterface interface {
+	Save(sub *models.PushSubscription) error
+	DeleteByEndpoint(endpoint string) error
+}
+
+type Handler struct {
+	repo         HandlerRepositoryInterface
+	vapid        *VAPID
+	allowedHosts []string
+}
+
+// Endpoints must be on one of allowedHosts, so the service can't be used to
+// send requests anywhere else
+func NewHandler(repo HandlerRepositoryInterface, vapid *VAPID, allowedHosts []string) *Handler {
+	return &Handler{repo: repo, vapid: vapid, allowedHosts: allowedHosts}
+}
+
+type KeyResponse struct {
+	PublicKey string `json:"public_key"`
+}
+
+// GET /api/push/vapid-public-key returns the applicationServerKey for
+// pushManager.subscribe
+func (h *Handler) KeyHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		problem.MethodNotAllowed(w, req, "GET")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(KeyResponse{PublicKey: h.vapid.PublicKey()}); err != nil {
+		log.Printf("Encoding error %s", err.Error())
+	}
+}
+
+// POST /api/push/subscribe stores a browser subscription. Form fields
+// "endpoint", "p256dh" and "auth" come from the PushSubscription, "city",
+// the schedule and the content fields are those of /api/subscribe
+func (h *Handler) SubscribeHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	}
+
+	sub, err := h.parseSubscriptionForm(req)
+	if err != nil {
+		problems.Write(w, err)
+		return
+	}
+
+	if err := h.repo.Save(sub); err != nil {
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// POST /api/push/unsubscribe with form field "endpoint". Knowing the endpoint
+// is enough, it is only shared between the browser and us
+func (h *Handler) UnsubscribeHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		problem.MethodNotAllowed(w, req, "POST")
+		return
+	}
+
+	endpoint := req.FormValue("endpoint")
+	if endpoint == "" {
+		problems.Write(w, ErrInvalidEndpoint)
+		return
+	}
+
+	if err := h.repo.DeleteByEndpoint(endpoint); err != nil {
+		if repository.IsErrNotFound(err) {
+			problems.Write(w, ErrSubscriptionNotFound)
+			return
+		}
+
+		log.Println(err.Error())
+		problem.Internal(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) parseSubscriptionForm(req *http.Request) (*models.PushSubscription, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, subscription.ErrInvalidForm
+	}
+
+	endpoint := req.FormValue("endpoint")
+	if !h.allowedEndpoint(endpoint) {
+		return nil, ErrInvalidEndpoint
+	}
+
+	p256dh, auth := req.FormValue("p256dh"), req.FormValue("auth")
+	if err := ValidateKeys(p256dh, auth); err != nil {
+		return nil, err
+	}
+
+	city := req.FormValue("city")
+	if city == "" {
+		return nil, ErrInvalidCity
+	}
+
+	sched, err := subscription.ParseScheduleForm(req)
+	if err != nil {
+		return nil, err
+	}
+	sched = sched.WithDefaults()
+
+	prefs, err := content.ParseForm(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PushSubscription{
+		Endpoint:      endpoint,
+		P256dh:        p256dh,
+		Auth:          auth,
+		City:          city,
+		Frequency:     sched.Frequency,
+		Timezone:      sched.Timezone,
+		SendTime:      sched.SendTime,
+		Weekday:       int(sched.Weekday),
+		IntervalHours: sched.IntervalHours,
+		CronExpr:      sched.CronExpr,
+		ContentFields: prefs.StoredFields(),
+		Units:         prefs.Units,
+		Language:      prefs.Language,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+func (h *Handler) allowedEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return false
+	}
+
+	host := u.Hostname()
+	for _, allowed := range h.allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Reads PUSH_ALLOWED_HOSTS, a comma-separated list. Empty uses DefaultAllowedHosts
+func AllowedHostsFromEnv() []string {
+	value := os.Getenv("PUSH_ALLOWED_HOSTS")
+	if value == "" {
+		return DefaultAllowedHosts
+	}
+
+	var hosts []string
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return hosts
+}
@@ -0,0 +1,143 @@
+package webpush_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"weather-app/internal/database/models"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/webpush"
+)
+
+type memoryRepo struct {
+	subs map[string]models.PushSubscription
+}
+
+func (m *memoryRepo) Save(sub *models.PushSubscription) error {
+	m.subs[sub.Endpoint] = *sub
+	return nil
+}
+
+func (m *memoryRepo) DeleteByEndpoint(endpoint string) error {
+	if _, ok := m.subs[endpoint]; !ok {
+		return fmt.Errorf("%w: push subscription not found", repository.ErrNotFound)
+	}
+	delete(m.subs, endpoint)
+	return nil
+}
+
+func postForm(target string, values url.Values) *http.Request {
+	req := httptest.NewRequest("POST", target, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+const fcmEndpoint = "https://fcm.googleapis.com/fcm/send/abc"
+
+func subscribeForm(t *testing.T, endpoint string) url.Values {
+	sub := newBrowser(t).subscription(endpoint)
+
+	return url.Values{
+		"endpoint":  {sub.Endpoint},
+		"p256dh":    {sub.P256dh},
+		"auth":      {sub.Auth},
+		"city":      {"Kyiv"},
+		"frequency": {"daily"},
+		"send_time": {"08:00"},
+	}
+}
+
+func TestKeyHandler(t *testing.T) {
+	vapid := newVAPID(t)
+	h := webpush.NewHandler(&memoryRepo{}, vapid, webpush.DefaultAllowedHosts)
+
+	w := httptest.NewRecorder()
+	h.KeyHandler(w, httptest.NewRequest("GET", "/api/push/vapid-public-key", nil))
+
+	var response webpush.KeyResponse
+	json.NewDecoder(w.Body).Decode(&response)
+
+	if w.Code != http.StatusOK || response.PublicKey != vapid.PublicKey() {
+		t.Errorf("expected the public key, got %d %+v", w.Code, response)
+	}
+}
+
+func TestSubscribeHandler(t *testing.T) {
+	repo := &memoryRepo{subs: map[string]models.PushSubscription{}}
+	h := webpush.NewHandler(repo, newVAPID(t), webpush.DefaultAllowedHosts)
+
+	w := httptest.NewRecorder()
+	h.SubscribeHandler(w, postForm("/api/push/subscribe", subscribeForm(t, fcmEndpoint)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	stored, ok := repo.subs[fcmEndpoint]
+	if !ok || stored.City != "Kyiv" || stored.SendTime != "08:00" || stored.Timezone != "UTC" {
+		t.Errorf("unexpected stored subscription %+v", stored)
+	}
+}
+
+func TestSubscribeHandler_Invalid(t *testing.T) {
+	h := webpush.NewHandler(&memoryRepo{subs: map[string]models.PushSubscription{}}, newVAPID(t), webpush.DefaultAllowedHosts)
+
+	cases := map[string]func(url.Values){
+		"http endpoint":   func(v url.Values) { v.Set("endpoint", "http://fcm.googleapis.com/fcm/send/abc") },
+		"unknown host":    func(v url.Values) { v.Set("endpoint", "https://internal.example.com/push") },
+		"lookalike host":  func(v url.Values) { v.Set("endpoint", "https://evilfcm.googleapis.com.example.com/x") },
+		"invalid p256dh":  func(v url.Values) { v.Set("p256dh", "BAAA") },
+		"missing auth":    func(v url.Values) { v.Del("auth") },
+		"missing city":    func(v url.Values) { v.Del("city") },
+		"wrong frequency": func(v url.Values) { v.Set("frequency", "monthly") },
+	}
+
+	for name, modify := range cases {
+		values := subscribeForm(t, fcmEndpoint)
+		modify(values)
+
+		w := httptest.NewRecorder()
+		h.SubscribeHandler(w, postForm("/api/push/subscribe", values))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestSubscribeHandler_AllowedSubdomain(t *testing.T) {
+	repo := &memoryRepo{subs: map[string]models.PushSubscription{}}
+	h := webpush.NewHandler(repo, newVAPID(t), webpush.DefaultAllowedHosts)
+
+	endpoint := "https://updates.push.services.mozilla.com/wpush/v2/abc"
+
+	w := httptest.NewRecorder()
+	h.SubscribeHandler(w, postForm("/api/push/subscribe", subscribeForm(t, endpoint)))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	repo := &memoryRepo{subs: map[string]models.PushSubscription{fcmEndpoint: {Endpoint: fcmEndpoint}}}
+	h := webpush.NewHandler(repo, newVAPID(t), webpush.DefaultAllowedHosts)
+
+	w := httptest.NewRecorder()
+	h.UnsubscribeHandler(w, postForm("/api/push/unsubscribe", url.Values{"endpoint": {fcmEndpoint}}))
+
+	if w.Code != http.StatusOK || len(repo.subs) != 0 {
+		t.Fatalf("expected deletion, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.UnsubscribeHandler(w, postForm("/api/push/unsubscribe", url.Values{"endpoint": {fcmEndpoint}}))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown endpoint, got %d", w.Code)
+	}
+}
@@ -0,0 +1,85 @@
+package webpush
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"weather-app/internal/content"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/notify"
+)
+
+// Recorded as delivery channel
+const ChannelName = "push"
+
+type PushSenderInterface interface {
+	Send(sub Subscription, payload []byte) error
+}
+
+type SubscriptionRepositoryInterface interface {
+	DeleteByEndpoint(endpoint string) error
+}
+
+// Message the service worker turns into a notification
+type Message struct {
+	Title   string         `json:"title"`
+	Body    string         `json:"body"`
+	City    string         `json:"city"`
+	Weather content.Values `json:"weather"`
+}
+
+// Delivers weather updates to browsers. Subscriptions the push service
+// reports gone are deleted on Flush
+type Notifier struct {
+	sender PushSenderInterface
+	repo   SubscriptionRepositoryInterface
+
+	mu   sync.Mutex
+	gone []repository.ChannelRecipient
+}
+
+func NewNotifier(sender PushSenderInterface, repo SubscriptionRepositoryInterface) *Notifier {
+	return &Notifier{sender: sender, repo: repo}
+}
+
+// Recipient's target is the endpoint, the secret "<p256dh>.<auth>"
+func (n *Notifier) Notify(recipient repository.ChannelRecipient, update notify.Update) error {
+	p256dh, auth, _ := strings.Cut(recipient.Secret, ".")
+
+	report := update.Report()
+	payload, err := json.Marshal(Message{
+		Title:   report.Title,
+		Body:    report.Summary(),
+		City:    update.City,
+		Weather: update.Values(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode push message: %w", err)
+	}
+
+	err = n.sender.Send(Subscription{Endpoint: recipient.Target, P256dh: p256dh, Auth: auth}, payload)
+	if errors.Is(err, ErrSubscriptionGone) {
+		n.mu.Lock()
+		n.gone = append(n.gone, recipient)
+		n.mu.Unlock()
+	}
+
+	return err
+}
+
+// Deletes the subscriptions reported gone since the last call, see notify.Flusher
+func (n *Notifier) Flush() {
+	n.mu.Lock()
+	gone := n.gone
+	n.gone = nil
+	n.mu.Unlock()
+
+	for _, recipient := range gone {
+		if err := n.repo.DeleteByEndpoint(recipient.Target); err != nil && !repository.IsErrNotFound(err) {
+			log.Printf("Failed to delete gone push subscription %s: %s\n", recipient.RecipientID, err.Error())
+		}
+	}
+}
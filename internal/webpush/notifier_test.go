@@ -0,0 +1,88 @@
+package webpush_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"weather-app/internal/content"
+	"weather-app/internal/database/repository"
+	"weather-app/internal/notify"
+	"weather-app/internal/weather"
+	"weather-app/internal/webpush"
+)
+
+type mockDeleter struct {
+	deleted []string
+}
+
+func (m *mockDeleter) DeleteByEndpoint(endpoint string) error {
+	m.deleted = append(m.deleted, endpoint)
+	return nil
+}
+
+var testUpdate = notify.Update{
+	City:    "Kyiv",
+	Weather: weather.WeatherData{Temperature: 21.5, Humidity: 40, Description: "clear sky"},
+}
+
+func recipientFor(b *browser, endpoint string) repository.ChannelRecipient {
+	sub := b.subscription(endpoint)
+	return repository.ChannelRecipient{Target: endpoint, Secret: sub.P256dh + "." + sub.Auth}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	vapid := newVAPID(t)
+	push, server := newFakePushService(t, vapid, http.StatusCreated)
+	b := newBrowser(t)
+	repo := &mockDeleter{}
+
+	notifier := webpush.NewNotifier(webpush.NewSender(vapid, server.Client()), repo)
+
+	if err := notifier.Notify(recipientFor(b, server.URL+"/push/abc"), testUpdate); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	plaintext, err := b.decrypt(push.bodies[0])
+	if err != nil {
+		t.Fatalf("browser could not read the message: %v", err)
+	}
+
+	var message webpush.Message
+	if err := json.Unmarshal(plaintext, &message); err != nil {
+		t.Fatalf("invalid message %q: %v", plaintext, err)
+	}
+
+	if message.Title != "Weather update for Kyiv" || message.Body != "21.5°C, clear sky, humidity 40%" ||
+		message.Weather.Temperature != 21.5 || message.Weather.Units != content.UnitsMetric {
+		t.Errorf("unexpected message %+v", message)
+	}
+	if len(repo.deleted) != 0 {
+		t.Error("live subscription must not be deleted")
+	}
+}
+
+func TestNotifier_GoneSubscriptionDeleted(t *testing.T) {
+	vapid := newVAPID(t)
+	_, server := newFakePushService(t, vapid, http.StatusGone)
+	repo := &mockDeleter{}
+
+	notifier := webpush.NewNotifier(webpush.NewSender(vapid, server.Client()), repo)
+
+	endpoint := server.URL + "/push/abc"
+	err := notifier.Notify(recipientFor(newBrowser(t), endpoint), testUpdate)
+
+	if !errors.Is(err, webpush.ErrSubscriptionGone) {
+		t.Errorf("expected ErrSubscriptionGone, got %v", err)
+	}
+	if len(repo.deleted) != 0 {
+		t.Errorf("expected the deletion to wait for Flush, got %v", repo.deleted)
+	}
+
+	notifier.Flush()
+	notifier.Flush()
+
+	if len(repo.deleted) != 1 || repo.deleted[0] != endpoint {
+		t.Errorf("expected gone subscription to be deleted, got %v", repo.deleted)
+	}
+}
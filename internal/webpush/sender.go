@@ -0,0 +1,91 @@
+package webpush
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Push services hold messages for offline browsers this long. An older
+	// weather update is not worth showing
+	messageTTL = time.Hour
+
+	requestTimeout = 10 * time.Second
+	maxErrorLength = 512
+)
+
+// Returned when the push service no longer knows the subscription, e.g. the
+// user revoked the permission. The subscription should be dropped
+var ErrSubscriptionGone = errors.New("push subscription is gone")
+
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Sends encrypted messages to push services
+type Sender struct {
+	vapid  *VAPID
+	client *http.Client
+}
+
+// Nil client uses one with a 10 second timeout
+func NewSender(vapid *VAPID, client *http.Client) *Sender {
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+
+	return &Sender{vapid: vapid, client: client}
+}
+
+func (s *Sender) Send(sub Subscription, payload []byte) error {
+	body, err := Encrypt(payload, sub.P256dh, sub.Auth)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := s.vapid.Authorization(sub.Endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(messageTTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Endpoints are capability URLs, keep them out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("push request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+		return fmt.Errorf("push service returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
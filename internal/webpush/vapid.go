@@ -0,0 +1,131 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// How long a VAPID token is valid, push services reject more than 24 hours
+const vapidTokenTTL = 12 * time.Hour
+
+var ErrInvalidVAPIDKey = errors.New("VAPID private key must be a base64url P-256 scalar")
+
+// Application server identity (RFC 8292). Push services only deliver to a
+// subscription with tokens signed by the key the browser subscribed with
+type VAPID struct {
+	key       *ecdsa.PrivateKey
+	publicKey []byte // Uncompressed point, the browser's applicationServerKey
+	subject   string // "mailto:" or "https:" contact for the push service
+}
+
+// Returns a new private key and its public key, both base64url encoded
+func GenerateVAPIDKey() (privateKey, publicKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate VAPID key: %w", err)
+	}
+
+	return encode(key.Bytes()), encode(key.PublicKey().Bytes()), nil
+}
+
+func NewVAPID(privateKey, subject string) (*VAPID, error) {
+	raw, err := decode(privateKey)
+	if err != nil {
+		return nil, ErrInvalidVAPIDKey
+	}
+
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, ErrInvalidVAPIDKey
+	}
+
+	publicKey := key.PublicKey().Bytes()
+
+	return &VAPID{
+		key: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(publicKey[1:33]),
+				Y:     new(big.Int).SetBytes(publicKey[33:]),
+			},
+			D: new(big.Int).SetBytes(raw),
+		},
+		publicKey: publicKey,
+		subject:   subject,
+	}, nil
+}
+
+// Base64url public key, passed to pushManager.subscribe in the browser
+func (v *VAPID) PublicKey() string {
+	return encode(v.publicKey)
+}
+
+// Authorization header for a request to the push service at endpoint
+func (v *VAPID) Authorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid push endpoint: %w", err)
+	}
+
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(vapidTokenTTL).Unix(),
+		"sub": v.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	r, s, err := ecdsa.Sign(rand.Reader, v.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+
+	// JWS wants r and s as fixed size big-endian integers, not ASN.1
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	return fmt.Sprintf("vapid t=%s.%s, k=%s", unsigned, encode(signature), v.PublicKey()), nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Browsers send unpadded base64url, some libraries pad it
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// Reads VAPID_PRIVATE_KEY and VAPID_SUBJECT. Returns nil when the key is
+// empty, which disables Web Push
+func VAPIDFromEnv() (*VAPID, error) {
+	privateKey := os.Getenv("VAPID_PRIVATE_KEY")
+	if privateKey == "" {
+		return nil, nil
+	}
+
+	subject := os.Getenv("VAPID_SUBJECT")
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https:") {
+		return nil, errors.New("VAPID_SUBJECT must be a mailto: or https: URL")
+	}
+
+	return NewVAPID(privateKey, subject)
+}
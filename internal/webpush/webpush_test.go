@@ -0,0 +1,342 @@
+package webpush_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"weather-app/internal/webpush"
+)
+
+var b64 = base64.RawURLEncoding
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := b64.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid base64 %q: %v", s, err)
+	}
+	return b
+}
+
+// Browser side of a push subscription
+type browser struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newBrowser(t *testing.T) *browser {
+	t.Helper()
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+
+	return &browser{key: key, auth: auth}
+}
+
+func (b *browser) subscription(endpoint string) webpush.Subscription {
+	return webpush.Subscription{
+		Endpoint: endpoint,
+		P256dh:   b64.EncodeToString(b.key.PublicKey().Bytes()),
+		Auth:     b64.EncodeToString(b.auth),
+	}
+}
+
+// Decrypts an aes128gcm body the way a browser does (RFC 8291 section 3.4)
+func (b *browser) decrypt(body []byte) ([]byte, error) {
+	if len(body) < 21 {
+		return nil, errors.New("short body")
+	}
+
+	salt := body[:16]
+	recordSize := binary.BigEndian.Uint32(body[16:20])
+	idLength := int(body[20])
+	asPublicRaw := body[21 : 21+idLength]
+	ciphertext := body[21+idLength:]
+
+	if len(ciphertext) > int(recordSize) {
+		return nil, errors.New("record larger than record size")
+	}
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicRaw)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := b.key.ECDH(asPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := "WebPush: info\x00" + string(b.key.PublicKey().Bytes()) + string(asPublicRaw)
+	ikm, _ := hkdf.Key(sha256.New, shared, b.auth, keyInfo, 32)
+	prk, _ := hkdf.Extract(sha256.New, ikm, salt)
+	cek, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Strip padding, the last record ends with 0x02 followed by zeros
+	end := bytes.LastIndexByte(plaintext, 0x02)
+	if end < 0 || len(bytes.TrimRight(plaintext[end+1:], "\x00")) > 0 {
+		return nil, errors.New("missing last record delimiter")
+	}
+
+	return plaintext[:end], nil
+}
+
+// Checks the test decryption against the example of RFC 8291 appendix A
+func TestDecrypt_RFC8291Example(t *testing.T) {
+	uaPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &browser{key: uaPrivate, auth: mustDecode(t, "BTBZMqHH6r4Tts7J_aSIgg")}
+
+	if got := b64.EncodeToString(uaPrivate.PublicKey().Bytes()); got != "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4" {
+		t.Fatalf("unexpected user agent public key %s", got)
+	}
+
+	body := mustDecode(t, "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN")
+
+	plaintext, err := b.decrypt(body)
+	if err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	if string(plaintext) != "When I grow up, I want to be a watermelon" {
+		t.Errorf("unexpected plaintext %q", plaintext)
+	}
+}
+
+func TestEncrypt_RoundTrip(t *testing.T) {
+	b := newBrowser(t)
+	sub := b.subscription("")
+
+	body, err := webpush.Encrypt([]byte("hello"), sub.P256dh, sub.Auth)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	plaintext, err := b.decrypt(body)
+	if err != nil || string(plaintext) != "hello" {
+		t.Fatalf("expected hello, got %q, %v", plaintext, err)
+	}
+
+	// Every message uses a fresh key and salt
+	again, _ := webpush.Encrypt([]byte("hello"), sub.P256dh, sub.Auth)
+	if bytes.Equal(body, again) {
+		t.Error("expected different ciphertexts for the same payload")
+	}
+}
+
+func TestEncrypt_Invalid(t *testing.T) {
+	sub := newBrowser(t).subscription("")
+
+	if _, err := webpush.Encrypt([]byte("x"), "not-a-key", sub.Auth); !errors.Is(err, webpush.ErrInvalidKeys) {
+		t.Errorf("expected ErrInvalidKeys for bad p256dh, got %v", err)
+	}
+	if _, err := webpush.Encrypt([]byte("x"), sub.P256dh, "c2hvcnQ"); !errors.Is(err, webpush.ErrInvalidKeys) {
+		t.Errorf("expected ErrInvalidKeys for short auth, got %v", err)
+	}
+
+	tooLarge := make([]byte, webpush.MaxPayloadSize+1)
+	if _, err := webpush.Encrypt(tooLarge, sub.P256dh, sub.Auth); !errors.Is(err, webpush.ErrPayloadTooLarge) {
+		t.Errorf("expected ErrPayloadTooLarge, got %v", err)
+	}
+}
+
+func newVAPID(t *testing.T) *webpush.VAPID {
+	t.Helper()
+
+	privateKey, _, err := webpush.GenerateVAPIDKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vapid, err := webpush.NewVAPID(privateKey, "mailto:admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vapid
+}
+
+type vapidClaims struct {
+	Aud string `json:"aud"`
+	Exp int64  `json:"exp"`
+	Sub string `json:"sub"`
+}
+
+// Checks an Authorization header like a push service does and returns the claims
+func verifyVAPID(header, wantKey string) (*vapidClaims, error) {
+	token, key, ok := strings.Cut(strings.TrimPrefix(header, "vapid t="), ", k=")
+	if !ok || key != wantKey {
+		return nil, errors.New("malformed header or wrong key")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	rawKey, _ := b64.DecodeString(key)
+	publicKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(rawKey[1:33]),
+		Y:     new(big.Int).SetBytes(rawKey[33:]),
+	}
+
+	signature, _ := b64.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if len(signature) != 64 ||
+		!ecdsa.Verify(publicKey, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		return nil, errors.New("bad signature")
+	}
+
+	var claims vapidClaims
+	payload, _ := b64.DecodeString(parts[1])
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+func TestVAPID_Authorization(t *testing.T) {
+	vapid := newVAPID(t)
+	now := time.Now()
+
+	header, err := vapid.Authorization("https://fcm.googleapis.com/fcm/send/abc", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claims, err := verifyVAPID(header, vapid.PublicKey())
+	if err != nil {
+		t.Fatalf("invalid authorization %q: %v", header, err)
+	}
+
+	if claims.Aud != "https://fcm.googleapis.com" || claims.Sub != "mailto:admin@example.com" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if exp := time.Unix(claims.Exp, 0); exp.Before(now) || exp.After(now.Add(24*time.Hour)) {
+		t.Errorf("expiry %v must be within 24 hours", exp)
+	}
+}
+
+func TestNewVAPID_InvalidKey(t *testing.T) {
+	if _, err := webpush.NewVAPID("short", "mailto:admin@example.com"); !errors.Is(err, webpush.ErrInvalidVAPIDKey) {
+		t.Errorf("expected ErrInvalidVAPIDKey, got %v", err)
+	}
+}
+
+// Local stand-in for a browser vendor's push service
+type fakePushService struct {
+	t      *testing.T
+	vapid  *webpush.VAPID
+	status int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newFakePushService(t *testing.T, vapid *webpush.VAPID, status int) (*fakePushService, *httptest.Server) {
+	f := &fakePushService{t: t, vapid: vapid, status: status}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		f.mu.Lock()
+		f.requests = append(f.requests, req)
+		f.bodies = append(f.bodies, body)
+		f.mu.Unlock()
+
+		if _, err := verifyVAPID(req.Header.Get("Authorization"), vapid.PublicKey()); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(f.status)
+	}))
+	t.Cleanup(server.Close)
+
+	return f, server
+}
+
+func TestSender_Send(t *testing.T) {
+	vapid := newVAPID(t)
+	push, server := newFakePushService(t, vapid, http.StatusCreated)
+	b := newBrowser(t)
+
+	sender := webpush.NewSender(vapid, server.Client())
+
+	if err := sender.Send(b.subscription(server.URL+"/push/abc"), []byte(`{"title":"hi"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(push.requests) != 1 {
+		t.Fatalf("expected one push, got %d", len(push.requests))
+	}
+
+	req := push.requests[0]
+	if req.Header.Get("Content-Encoding") != "aes128gcm" || req.Header.Get("TTL") == "" {
+		t.Errorf("unexpected headers %v", req.Header)
+	}
+
+	plaintext, err := b.decrypt(push.bodies[0])
+	if err != nil || string(plaintext) != `{"title":"hi"}` {
+		t.Errorf("browser could not read the message: %q, %v", plaintext, err)
+	}
+}
+
+func TestSender_Gone(t *testing.T) {
+	vapid := newVAPID(t)
+	_, server := newFakePushService(t, vapid, http.StatusGone)
+
+	sender := webpush.NewSender(vapid, server.Client())
+
+	err := sender.Send(newBrowser(t).subscription(server.URL+"/push/abc"), []byte("x"))
+	if !errors.Is(err, webpush.ErrSubscriptionGone) {
+		t.Errorf("expected ErrSubscriptionGone, got %v", err)
+	}
+}
+
+func TestSender_Rejected(t *testing.T) {
+	vapid := newVAPID(t)
+	_, server := newFakePushService(t, vapid, http.StatusTooManyRequests)
+
+	sender := webpush.NewSender(vapid, server.Client())
+
+	err := sender.Send(newBrowser(t).subscription(server.URL+"/push/abc"), []byte("x"))
+	if err == nil || errors.Is(err, webpush.ErrSubscriptionGone) {
+		t.Errorf("expected a plain error, got %v", err)
+	}
+}
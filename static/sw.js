@@ -0,0 +1,16 @@
+// Shows weather updates sent by the API as browser notifications
+self.addEventListener('push', event => {
+  const message = event.data ? event.data.json() : { title: 'Weather update', body: '' };
+
+  event.waitUntil(
+    self.registration.showNotification(message.title, {
+      body: message.body,
+      tag: 'weather-' + message.city
+    })
+  );
+});
+
+self.addEventListener('notificationclick', event => {
+  event.notification.close();
+  event.waitUntil(clients.openWindow('/main.html'));
+});